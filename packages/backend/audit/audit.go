@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+)
+
+// Actor identifies who made a change: a user, or a background process such as the crawler.
+type Actor struct {
+	UserID *int
+	Name   string
+}
+
+// Crawler is the actor recorded for changes made by the publication crawler.
+var Crawler = Actor{Name: "crawler"}
+
+type Logger struct {
+	repo repository.AuditRepo
+}
+
+func NewLogger(repo repository.AuditRepo) *Logger {
+	return &Logger{repo: repo}
+}
+
+// Record stores an audit entry for a change to an entity. before is nil for
+// creates and after is nil for deletes. Failures are logged, not returned, so
+// auditing never blocks the change itself. A nil Logger records nothing.
+func (l *Logger) Record(actor Actor, entity string, entityID int, action string, before, after interface{}) {
+	if l == nil {
+		return
+	}
+
+	entry := models.AuditEntry{
+		ActorID:  actor.UserID,
+		Actor:    actor.Name,
+		Entity:   entity,
+		EntityID: entityID,
+		Action:   action,
+	}
+
+	var err error
+	if entry.Before, err = marshal(before); err != nil {
+		log.Printf("Failed to encode audit state of %s %d: %v", entity, entityID, err)
+		return
+	}
+	if entry.After, err = marshal(after); err != nil {
+		log.Printf("Failed to encode audit state of %s %d: %v", entity, entityID, err)
+		return
+	}
+	if entry.Before != nil && entry.After != nil {
+		entry.Diff = Diff(entry.Before, entry.After)
+	}
+
+	if _, err := l.repo.Create(entry); err != nil {
+		log.Printf("Failed to record audit entry for %s %d: %v", entity, entityID, err)
+	}
+}
+
+func marshal(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// Diff compares two JSON objects field by field and returns the top-level
+// fields whose values differ. Either side may be nil.
+func Diff(before, after json.RawMessage) map[string]models.AuditChange {
+	beforeFields := map[string]json.RawMessage{}
+	afterFields := map[string]json.RawMessage{}
+	if before != nil {
+		if err := json.Unmarshal(before, &beforeFields); err != nil {
+			return nil
+		}
+	}
+	if after != nil {
+		if err := json.Unmarshal(after, &afterFields); err != nil {
+			return nil
+		}
+	}
+
+	diff := map[string]models.AuditChange{}
+	for field, oldValue := range beforeFields {
+		newValue, ok := afterFields[field]
+		if !ok || !bytes.Equal(compact(oldValue), compact(newValue)) {
+			diff[field] = models.AuditChange{Before: oldValue, After: newValue}
+		}
+	}
+	for field, newValue := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			diff[field] = models.AuditChange{After: newValue}
+		}
+	}
+	return diff
+}
+
+func compact(data json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,667 @@
+// Package bibliography converts publications to and from bibliographic
+// exchange formats.
+package bibliography
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// Entry is one BibTeX entry. Type and field names are lower-cased; field
+// values are kept as written, with macros expanded.
+type Entry struct {
+	Type   string
+	Key    string
+	Fields map[string]string
+	// Err is set when the entry could not be parsed; Key may still be known.
+	Err error
+}
+
+// monthMacros are the standard BibTeX month macros, January first.
+var monthMacros = [12]string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// ParseBibTeX returns every entry of a BibTeX file. @string macros are
+// expanded, @comment and @preamble blocks are skipped, and a malformed entry
+// is returned with Err set rather than aborting the whole file.
+func ParseBibTeX(src string) []Entry {
+	p := &bibParser{src: src, macros: map[string]string{}}
+	for i, name := range monthMacros {
+		p.macros[name] = strconv.Itoa(i + 1)
+	}
+
+	entries := []Entry{}
+	for {
+		start := strings.IndexByte(p.src[p.pos:], '@')
+		if start < 0 {
+			return entries
+		}
+		p.pos += start + 1
+		entryStart := p.pos
+
+		entry, ok := p.parseEntry()
+		if !ok {
+			continue
+		}
+		if entry.Err != nil {
+			p.skipToNextEntry(entryStart)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+type bibParser struct {
+	src    string
+	pos    int
+	macros map[string]string
+}
+
+// parseEntry parses the block after an '@'. ok is false for blocks that are
+// not entries, such as @string and @comment.
+func (p *bibParser) parseEntry() (entry Entry, ok bool) {
+	entry.Type = strings.ToLower(p.readIdentifier())
+	if entry.Type == "" {
+		return entry, false
+	}
+
+	p.skipSpace()
+	closing, err := p.openBlock()
+	if err != nil {
+		return Entry{Type: entry.Type, Err: err}, true
+	}
+
+	switch entry.Type {
+	case "comment", "preamble":
+		p.skipBlock(closing)
+		return entry, false
+	case "string":
+		name, value, err := p.parseField()
+		if err == nil {
+			p.macros[name] = value
+		}
+		p.skipBlock(closing)
+		return entry, false
+	}
+
+	end := strings.IndexAny(p.src[p.pos:], ",\n"+string(closing))
+	if end < 0 {
+		entry.Err = fmt.Errorf("unterminated entry")
+		return entry, true
+	}
+	entry.Key = strings.TrimSpace(p.src[p.pos : p.pos+end])
+	p.pos += end
+	entry.Fields = map[string]string{}
+
+	for {
+		p.skipSpace()
+		if p.consume(',') {
+			continue
+		}
+		if p.consume(closing) {
+			return entry, true
+		}
+		if p.pos >= len(p.src) {
+			entry.Err = fmt.Errorf("unterminated entry")
+			return entry, true
+		}
+
+		name, value, err := p.parseField()
+		if err != nil {
+			entry.Err = err
+			return entry, true
+		}
+		entry.Fields[name] = value
+	}
+}
+
+// Field returns a field value decoded from LaTeX to plain text.
+func (e Entry) Field(name string) string {
+	return decodeLaTeX(e.Fields[name])
+}
+
+// Names returns the people listed in a name field such as author or editor.
+func (e Entry) Names(field string) []Name {
+	return ParseNames(e.Fields[field])
+}
+
+// Publication maps the entry to a publication. Authors are left out: matching
+// them to researchers is up to the caller, see Names.
+func (e Entry) Publication() (models.Publication, error) {
+	title := e.Field("title")
+	if title == "" {
+		return models.Publication{}, errors.New("missing title")
+	}
+
+	publishedAt, err := e.date()
+	if err != nil {
+		return models.Publication{}, err
+	}
+
+	doi := DOI(e.Field("doi"))
+	if doi == "" {
+		doi = e.Field("doi")
+	}
+	link := e.Field("url")
+	if link == "" && doi != "" {
+		link = "https://doi.org/" + doi
+	}
+
+	pages := e.Field("pages")
+	if first, last := splitPages(pages); last != "" {
+		pages = first + "-" + last
+	}
+
+	return models.Publication{
+		Title:       models.LocalizedString{En: title, Ru: title},
+		Type:        publicationType(e.Type),
+		Venue:       e.firstField("journal", "journaltitle", "booktitle", "howpublished"),
+		Volume:      e.Field("volume"),
+		Issue:       e.firstField("number", "issue"),
+		Pages:       pages,
+		Publisher:   e.firstField("publisher", "school", "institution"),
+		DOI:         doi,
+		Abstract:    e.Field("abstract"),
+		PublishedAt: publishedAt,
+		Link:        link,
+	}, nil
+}
+
+func (e Entry) firstField(names ...string) string {
+	for _, name := range names {
+		if value := e.Field(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// date returns the publication date as YYYY-MM-DD from the biblatex date field
+// or from year, month and day, defaulting to the first month and day.
+func (e Entry) date() (string, error) {
+	year, month, day := e.Field("year"), e.Field("month"), e.Field("day")
+	if date := e.Field("date"); date != "" {
+		parts := strings.SplitN(strings.SplitN(date, "/", 2)[0], "-", 3)
+		year = parts[0]
+		if len(parts) > 1 {
+			month = parts[1]
+		}
+		if len(parts) > 2 {
+			day = parts[2]
+		}
+	}
+
+	y, err := strconv.Atoi(year)
+	if err != nil || y < 1000 || y > 9999 {
+		return "", fmt.Errorf("missing or invalid year %q", year)
+	}
+
+	m := parseMonth(month)
+	d, err := strconv.Atoi(day)
+	if err != nil || d < 1 || d > 31 || m == 0 {
+		d = 1
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", y, max(m, 1), d), nil
+}
+
+// parseMonth accepts a month number or an English month name or abbreviation,
+// returning 0 when month is empty or unrecognised.
+func parseMonth(month string) int {
+	month = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(month), "."))
+	if n, err := strconv.Atoi(month); err == nil && n >= 1 && n <= 12 {
+		return n
+	}
+	if len(month) >= 3 {
+		for i, name := range monthMacros {
+			if strings.HasPrefix(month, name) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// parseField parses "name = value" and returns the raw, undecoded value.
+func (p *bibParser) parseField() (name, value string, err error) {
+	p.skipSpace()
+	name = strings.ToLower(p.readIdentifier())
+	if name == "" {
+		return "", "", p.errorf("expected a field name")
+	}
+
+	p.skipSpace()
+	if !p.consume('=') {
+		return "", "", p.errorf("expected '=' after %q", name)
+	}
+
+	var parts []string
+	for {
+		p.skipSpace()
+		part, err := p.parseValuePart()
+		if err != nil {
+			return "", "", err
+		}
+		parts = append(parts, part)
+
+		p.skipSpace()
+		if !p.consume('#') {
+			return name, strings.Join(parts, ""), nil
+		}
+	}
+}
+
+// parseValuePart parses a braced or quoted string, a number or a macro name.
+func (p *bibParser) parseValuePart() (string, error) {
+	if p.pos >= len(p.src) {
+		return "", p.errorf("expected a value")
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		p.pos++
+		return p.readUntil('}')
+	case c == '"':
+		p.pos++
+		return p.readUntil('"')
+	case c >= '0' && c <= '9':
+		start := p.pos
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		return p.src[start:p.pos], nil
+	}
+
+	name := p.readIdentifier()
+	if name == "" {
+		return "", p.errorf("expected a value")
+	}
+	value, ok := p.macros[strings.ToLower(name)]
+	if !ok {
+		return "", p.errorf("undefined macro %q", name)
+	}
+	return value, nil
+}
+
+// readUntil reads up to the closing delimiter at brace depth zero, keeping
+// nested braces in the result, and consumes the delimiter.
+func (p *bibParser) readUntil(closing byte) (string, error) {
+	start := p.pos
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '\\':
+			p.pos++
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == closing && depth == 0:
+			value := p.src[start:p.pos]
+			p.pos++
+			return value, nil
+		case c == '}':
+			return "", p.errorf("unbalanced braces")
+		}
+	}
+	return "", p.errorf("unterminated value")
+}
+
+func (p *bibParser) openBlock() (closing byte, err error) {
+	switch {
+	case p.consume('{'):
+		return '}', nil
+	case p.consume('('):
+		return ')', nil
+	}
+	return 0, p.errorf("expected '{' or '('")
+}
+
+// skipBlock moves past the end of the current block.
+func (p *bibParser) skipBlock(closing byte) {
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == closing && depth == 0:
+			p.pos++
+			return
+		}
+	}
+}
+
+// skipToNextEntry moves to the first line starting with '@' after from, the
+// start of a malformed entry, so that an unterminated value does not swallow
+// the entries that follow it.
+func (p *bibParser) skipToNextEntry(from int) {
+	next := strings.Index(p.src[from:], "\n@")
+	if next < 0 {
+		p.pos = len(p.src)
+		return
+	}
+	p.pos = from + next + 1
+}
+
+func (p *bibParser) readIdentifier() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c <= ' ' || strings.IndexByte(`{}(),="#%'@\`, c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *bibParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		case '%':
+			// Comments run to the end of the line.
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *bibParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *bibParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// Name is a person's name split the way BibTeX does.
+type Name struct {
+	First string
+	Last  string
+}
+
+func (n Name) String() string {
+	return strings.TrimSpace(n.First + " " + n.Last)
+}
+
+// bibTeX writes the name in "Last, First" form, which keeps multi-word last
+// names intact. A name without a first name is braced so that it is not
+// split at all.
+func (n Name) bibTeX() string {
+	last := encodeLaTeX(n.Last)
+	if n.First == "" {
+		return "{" + last + "}"
+	}
+	if strings.Contains(last, ",") {
+		last = "{" + last + "}"
+	}
+	first := encodeLaTeX(n.First)
+	if strings.Contains(first, ",") {
+		first = "{" + first + "}"
+	}
+	return last + ", " + first
+}
+
+// ParseNames splits a raw BibTeX author list on "and" and parses each name in
+// either "Last, First" or "First von Last" form. Braced groups are kept
+// together, so "{Institute of Physics}" stays a single last name.
+func ParseNames(raw string) []Name {
+	names := []Name{}
+	for _, part := range splitTopLevel(raw, func(words []string, i int) bool {
+		return strings.EqualFold(words[i], "and")
+	}) {
+		if name := parseName(part); name.Last != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parseName(words []string) Name {
+	if len(words) == 0 {
+		return Name{}
+	}
+
+	// "Last, First" and "von Last, Jr, First".
+	joined := strings.Join(words, " ")
+	if commaParts := splitOnTopLevelComma(joined); len(commaParts) > 1 {
+		return Name{
+			First: decodeLaTeX(commaParts[len(commaParts)-1]),
+			Last:  decodeLaTeX(commaParts[0]),
+		}
+	}
+
+	if len(words) == 1 {
+		return Name{Last: decodeLaTeX(words[0])}
+	}
+
+	// The last name starts at the first lower-case "von" particle, or is the
+	// final word.
+	split := len(words) - 1
+	for i := 1; i < len(words)-1; i++ {
+		if r := []rune(words[i]); len(r) > 0 && unicode.IsLower(r[0]) {
+			split = i
+			break
+		}
+	}
+	return Name{
+		First: decodeLaTeX(strings.Join(words[:split], " ")),
+		Last:  decodeLaTeX(strings.Join(words[split:], " ")),
+	}
+}
+
+// splitTopLevel splits raw into whitespace-separated words outside braces and
+// groups them, cutting at the words isSeparator accepts.
+func splitTopLevel(raw string, isSeparator func(words []string, i int) bool) [][]string {
+	var words []string
+	var current strings.Builder
+	depth := 0
+	for _, c := range raw {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case unicode.IsSpace(c) && depth == 0:
+			if current.Len() > 0 {
+				words = append(words, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(c)
+	}
+	if current.Len() > 0 {
+		words = append(words, current.String())
+	}
+
+	groups := [][]string{}
+	var group []string
+	for i := range words {
+		if isSeparator(words, i) {
+			groups = append(groups, group)
+			group = nil
+			continue
+		}
+		group = append(group, words[i])
+	}
+	return append(groups, group)
+}
+
+func splitOnTopLevelComma(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// WriteBibTeX writes references as BibTeX entries with unique citation keys.
+// Articles without a venue are written as @misc. A date on the first of
+// January is taken to carry the year only, so no month is written for it, and
+// the day is written in the biblatex day field unless it is the first.
+func WriteBibTeX(w io.Writer, refs []Reference) error {
+	keys := citationKeys{}
+	for _, ref := range refs {
+		pub := ref.Publication
+		entryType, _, _ := typeNames(pub)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "@%s{%s,\n", entryType, keys.next(ref))
+
+		writeBibField(&b, "author", joinNames(ref.Authors))
+		writeBibField(&b, "title", encodeLaTeX(localized(pub.Title)))
+		switch entryType {
+		case "article":
+			writeBibField(&b, "journal", encodeLaTeX(pub.Venue))
+		case "inproceedings", "incollection":
+			writeBibField(&b, "booktitle", encodeLaTeX(pub.Venue))
+		default:
+			writeBibField(&b, "howpublished", encodeLaTeX(pub.Venue))
+		}
+		writeBibField(&b, "volume", encodeLaTeX(pub.Volume))
+		writeBibField(&b, "number", encodeLaTeX(pub.Issue))
+		if first, last := splitPages(pub.Pages); last != "" {
+			writeBibField(&b, "pages", encodeLaTeX(first)+"--"+encodeLaTeX(last))
+		} else {
+			writeBibField(&b, "pages", encodeLaTeX(pub.Pages))
+		}
+		if entryType == "phdthesis" {
+			writeBibField(&b, "school", encodeLaTeX(pub.Publisher))
+		} else {
+			writeBibField(&b, "publisher", encodeLaTeX(pub.Publisher))
+		}
+
+		year, month, day := splitDate(pub.PublishedAt)
+		writeBibField(&b, "year", year)
+		if month > 0 {
+			fmt.Fprintf(&b, "  month = %s,\n", monthMacros[month-1])
+		}
+		if day > 1 {
+			writeBibField(&b, "day", strconv.Itoa(day))
+		}
+		writeBibField(&b, "doi", encodeLaTeX(publicationDOI(pub)))
+		writeBibField(&b, "url", pub.Link)
+		writeBibField(&b, "abstract", encodeLaTeX(pub.Abstract))
+		b.WriteString("}\n\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBibField(b *strings.Builder, name, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "  %s = {%s},\n", name, value)
+	}
+}
+
+// splitDate splits a YYYY-MM-DD date; month is 0 when unknown or when the date
+// is the first of January.
+func splitDate(date string) (year string, month, day int) {
+	parts := strings.SplitN(date, "-", 3)
+	year = parts[0]
+	if len(parts) == 3 && parts[1]+"-"+parts[2] == "01-01" {
+		return year, 0, 0
+	}
+	if len(parts) > 1 {
+		month, _ = strconv.Atoi(parts[1])
+		if month < 1 || month > 12 {
+			month = 0
+		}
+	}
+	if len(parts) > 2 {
+		day, _ = strconv.Atoi(parts[2])
+	}
+	return year, month, day
+}
+
+// localized prefers the English text and falls back to Russian.
+func localized(s models.LocalizedString) string {
+	if s.En != "" {
+		return s.En
+	}
+	return s.Ru
+}
+
+// keyStopWords are skipped when picking the title word of a citation key.
+var keyStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "of": true, "for": true,
+	"and": true, "with": true, "in": true, "to": true, "from": true, "by": true,
+}
+
+// citationKeys hands out keys of the form lastname + year + titleword, adding
+// a letter suffix when a key is already taken.
+type citationKeys map[string]bool
+
+func (k citationKeys) next(ref Reference) string {
+	author := "anon"
+	if len(ref.Authors) > 0 {
+		if word := asciiWord(ref.Authors[0].Last); word != "" {
+			author = word
+		}
+	}
+
+	year, _, _ := splitDate(ref.Publication.PublishedAt)
+
+	titleWord := ""
+	for _, word := range strings.Fields(localized(ref.Publication.Title)) {
+		word = asciiWord(word)
+		if word != "" && !keyStopWords[word] {
+			titleWord = word
+			break
+		}
+	}
+
+	base := author + asciiWord(year) + titleWord
+	key := base
+	for n := 1; k[key]; n++ {
+		if n < 26 {
+			key = base + string(rune('a'+n))
+		} else {
+			key = base + "-" + strconv.Itoa(n)
+		}
+	}
+	k[key] = true
+	return key
+}
+
+// asciiWord keeps the ASCII letters and digits of s, lower-cased.
+func asciiWord(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package bibliography
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// cslItem is the subset of a CSL-JSON item that maps onto a publication.
+type cslItem struct {
+	ID             json.RawMessage `json:"id"`
+	Type           string          `json:"type"`
+	Title          string          `json:"title,omitempty"`
+	ContainerTitle string          `json:"container-title,omitempty"`
+	Volume         cslString       `json:"volume,omitempty"`
+	Issue          cslString       `json:"issue,omitempty"`
+	Page           cslString       `json:"page,omitempty"`
+	Publisher      string          `json:"publisher,omitempty"`
+	Abstract       string          `json:"abstract,omitempty"`
+	Author         []cslName       `json:"author,omitempty"`
+	Issued         *cslDate        `json:"issued,omitempty"`
+	DOI            string          `json:"DOI,omitempty"`
+	URL            string          `json:"URL,omitempty"`
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+// cslString is a string field that some writers emit as a number.
+type cslString string
+
+func (s *cslString) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*s = cslString(value)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*s = cslString(number.String())
+	return nil
+}
+
+// cslDate holds either date parts, such as [[2021, 3, 15]], or a raw date
+// string.
+type cslDate struct {
+	DateParts [][]json.Number `json:"date-parts,omitempty"`
+	Raw       string          `json:"raw,omitempty"`
+}
+
+// WriteCSLJSON writes references as a CSL-JSON array, the format citeproc
+// processors and Zotero read. Items are typed after the publication type;
+// articles without a venue are written as documents.
+func WriteCSLJSON(w io.Writer, refs []Reference) error {
+	keys := citationKeys{}
+	items := make([]cslItem, 0, len(refs))
+	for _, ref := range refs {
+		pub := ref.Publication
+		id, err := json.Marshal(keys.next(ref))
+		if err != nil {
+			return err
+		}
+
+		_, _, itemType := typeNames(pub)
+		item := cslItem{
+			ID:             id,
+			Type:           itemType,
+			Title:          localized(pub.Title),
+			ContainerTitle: pub.Venue,
+			Volume:         cslString(pub.Volume),
+			Issue:          cslString(pub.Issue),
+			Page:           cslString(pub.Pages),
+			Publisher:      pub.Publisher,
+			Abstract:       pub.Abstract,
+			DOI:            publicationDOI(pub),
+			URL:            pub.Link,
+		}
+		for _, name := range ref.Authors {
+			if name.First == "" {
+				item.Author = append(item.Author, cslName{Literal: name.Last})
+			} else {
+				item.Author = append(item.Author, cslName{Family: name.Last, Given: name.First})
+			}
+		}
+
+		if year, month, day := splitDate(pub.PublishedAt); year != "" {
+			parts := []json.Number{json.Number(strings.TrimLeft(year, "0"))}
+			if month > 0 {
+				parts = append(parts, json.Number(strconv.Itoa(month)))
+				if day > 0 {
+					parts = append(parts, json.Number(strconv.Itoa(day)))
+				}
+			}
+			item.Issued = &cslDate{DateParts: [][]json.Number{parts}}
+		}
+		items = append(items, item)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// ParseCSLJSON returns the items of a CSL-JSON array, or of a single item, as
+// entries with BibTeX field names, so that they import the same way BibTeX
+// entries do.
+func ParseCSLJSON(src string) ([]Entry, error) {
+	src = strings.TrimSpace(strings.TrimPrefix(src, "\ufeff"))
+
+	var items []cslItem
+	if strings.HasPrefix(src, "{") {
+		var item cslItem
+		if err := json.Unmarshal([]byte(src), &item); err != nil {
+			return nil, fmt.Errorf("invalid CSL-JSON: %w", err)
+		}
+		items = append(items, item)
+	} else if err := json.Unmarshal([]byte(src), &items); err != nil {
+		return nil, fmt.Errorf("invalid CSL-JSON: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(items))
+	for _, item := range items {
+		entry := Entry{Type: item.Type, Key: cslID(item.ID), Fields: map[string]string{}}
+		setField := func(name, value string) {
+			if value = strings.TrimSpace(value); value != "" {
+				entry.Fields[name] = encodeLaTeX(value)
+			}
+		}
+		setField("title", item.Title)
+		setField("journal", item.ContainerTitle)
+		setField("volume", string(item.Volume))
+		setField("number", string(item.Issue))
+		setField("pages", string(item.Page))
+		setField("publisher", item.Publisher)
+		setField("abstract", item.Abstract)
+		setField("doi", item.DOI)
+		setField("url", item.URL)
+
+		names := make([]Name, 0, len(item.Author))
+		for _, author := range item.Author {
+			if author.Family == "" && author.Literal != "" {
+				names = append(names, Name{Last: author.Literal})
+			} else if author.Family != "" {
+				names = append(names, Name{First: author.Given, Last: author.Family})
+			}
+		}
+		if len(names) > 0 {
+			entry.Fields["author"] = joinNames(names)
+		}
+
+		if item.Issued != nil {
+			if len(item.Issued.DateParts) > 0 {
+				parts := item.Issued.DateParts[0]
+				for i, field := range []string{"year", "month", "day"} {
+					if i < len(parts) {
+						entry.Fields[field] = parts[i].String()
+					}
+				}
+			} else {
+				setField("date", item.Issued.Raw)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// cslID reads an item ID, which CSL-JSON allows to be a string or a number.
+func cslID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return strings.TrimSpace(string(raw))
+}
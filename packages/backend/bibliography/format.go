@@ -0,0 +1,106 @@
+package bibliography
+
+import (
+	"io"
+	"mime"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format is a bibliographic exchange format.
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Write       func(w io.Writer, refs []Reference) error
+	// Parse returns the entries of a file; an error means the file as a whole
+	// could not be read, while problems with single entries are set on them.
+	Parse func(src string) ([]Entry, error)
+}
+
+var (
+	BibTeX = Format{
+		Name:        "bibtex",
+		ContentType: "application/x-bibtex",
+		Extension:   ".bib",
+		Write:       WriteBibTeX,
+		Parse:       func(src string) ([]Entry, error) { return ParseBibTeX(src), nil },
+	}
+	RIS = Format{
+		Name:        "ris",
+		ContentType: "application/x-research-info-systems",
+		Extension:   ".ris",
+		Write:       WriteRIS,
+		Parse:       func(src string) ([]Entry, error) { return ParseRIS(src), nil },
+	}
+	CSLJSON = Format{
+		Name:        "csl-json",
+		ContentType: "application/vnd.citationstyles.csl+json",
+		Extension:   ".json",
+		Write:       WriteCSLJSON,
+		Parse:       ParseCSLJSON,
+	}
+)
+
+// Formats lists the supported formats, BibTeX being the default.
+var Formats = []Format{BibTeX, RIS, CSLJSON}
+
+// FormatByName looks a format up by name or file extension, ignoring case,
+// so that "csl-json", "json" and ".json" all find CSL-JSON.
+func FormatByName(name string) (Format, bool) {
+	name = strings.ToLower(name)
+	for _, format := range Formats {
+		if name == format.Name || name == format.Extension || "."+name == format.Extension {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// FormatByFilename picks the format from a file name's extension.
+func FormatByFilename(filename string) (Format, bool) {
+	ext := path.Ext(filename)
+	if ext == "" {
+		return Format{}, false
+	}
+	return FormatByName(ext)
+}
+
+// NegotiateFormat picks the format from an Accept header, preferring media
+// types with a higher quality value. ok is false when nothing in the header
+// names a supported format, wildcards included.
+func NegotiateFormat(accept string) (format Format, ok bool) {
+	type candidate struct {
+		mediaType string
+		quality   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{mediaType, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		for _, format := range Formats {
+			if c.mediaType == format.ContentType {
+				return format, true
+			}
+		}
+	}
+	return Format{}, false
+}
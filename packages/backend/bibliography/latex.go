@@ -0,0 +1,168 @@
+package bibliography
+
+import (
+	"strings"
+	"unicode"
+)
+
+// latexAccents maps accent commands followed by a letter to the accented
+// letter, covering the Latin characters common in author names.
+var latexAccents = map[string]string{
+	`'a`: "á", `'e`: "é", `'i`: "í", `'o`: "ó", `'u`: "ú", `'y`: "ý", `'c`: "ć", `'n`: "ń", `'s`: "ś", `'z`: "ź",
+	`'A`: "Á", `'E`: "É", `'I`: "Í", `'O`: "Ó", `'U`: "Ú", `'Y`: "Ý", `'C`: "Ć", `'N`: "Ń", `'S`: "Ś", `'Z`: "Ź",
+	"`a": "à", "`e": "è", "`i": "ì", "`o": "ò", "`u": "ù",
+	"`A": "À", "`E": "È", "`I": "Ì", "`O": "Ò", "`U": "Ù",
+	`^a`: "â", `^e`: "ê", `^i`: "î", `^o`: "ô", `^u`: "û",
+	`^A`: "Â", `^E`: "Ê", `^I`: "Î", `^O`: "Ô", `^U`: "Û",
+	`"a`: "ä", `"e`: "ë", `"i`: "ï", `"o`: "ö", `"u`: "ü", `"y`: "ÿ",
+	`"A`: "Ä", `"E`: "Ë", `"I`: "Ï", `"O`: "Ö", `"U`: "Ü",
+	`~a`: "ã", `~o`: "õ", `~n`: "ñ", `~A`: "Ã", `~O`: "Õ", `~N`: "Ñ",
+	`cc`: "ç", `cC`: "Ç", `cs`: "ş", `cS`: "Ş",
+	`vc`: "č", `vs`: "š", `vz`: "ž", `vr`: "ř", `ve`: "ě", `vn`: "ň",
+	`vC`: "Č", `vS`: "Š", `vZ`: "Ž", `vR`: "Ř", `vE`: "Ě", `vN`: "Ň",
+	`ug`: "ğ", `uG`: "Ğ", `Ho`: "ő", `Hu`: "ű", `HO`: "Ő", `HU`: "Ű",
+	`ka`: "ą", `ke`: "ę", `kA`: "Ą", `kE`: "Ę", `.z`: "ż", `.Z`: "Ż",
+}
+
+// latexSymbols maps argument-less commands to their characters.
+var latexSymbols = map[string]string{
+	"ss": "ß", "o": "ø", "O": "Ø", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ",
+	"aa": "å", "AA": "Å", "l": "ł", "L": "Ł", "i": "ı", "j": "ȷ",
+	"TeX": "TeX", "LaTeX": "LaTeX", "BibTeX": "BibTeX",
+	"textbackslash": `\`, "textasciitilde": "~", "textasciicircum": "^",
+	"textendash": "–", "textemdash": "—", "textquoteright": "’", "textquoteleft": "‘",
+}
+
+// decodeLaTeX converts a BibTeX field value to plain text: accents and symbols
+// are replaced by their characters, escaped specials are unescaped, other
+// commands and grouping braces are dropped and whitespace is collapsed.
+func decodeLaTeX(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '{', '}':
+			continue
+		case '~':
+			out.WriteByte(' ')
+			continue
+		case '-':
+			if strings.HasPrefix(s[i:], "---") {
+				out.WriteString("—")
+				i += 2
+				continue
+			}
+			if strings.HasPrefix(s[i:], "--") {
+				out.WriteString("–")
+				i++
+				continue
+			}
+		case '\\':
+			i = decodeCommand(s, i, &out)
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return strings.Join(strings.Fields(out.String()), " ")
+}
+
+// decodeCommand writes the text of the command starting at s[i] and returns
+// the index of its last byte.
+func decodeCommand(s string, i int, out *strings.Builder) int {
+	if i+1 >= len(s) {
+		return i
+	}
+	next := s[i+1]
+
+	switch {
+	case strings.IndexByte(`&%$#_{}\ `, next) >= 0:
+		if next == '\\' {
+			out.WriteByte(' ')
+		} else {
+			out.WriteByte(next)
+		}
+		return i + 1
+	case strings.IndexByte("'`^\"~=.", next) >= 0:
+		return decodeAccent(s, i+1, next, out)
+	}
+
+	// A command name is a run of letters.
+	end := i + 1
+	for end < len(s) && isASCIILetter(s[end]) {
+		end++
+	}
+	name := s[i+1 : end]
+	if len(name) == 1 && strings.IndexByte("cvuHk", name[0]) >= 0 && end < len(s) {
+		return decodeAccent(s, end-1, name[0], out)
+	}
+	if symbol, ok := latexSymbols[name]; ok {
+		out.WriteString(symbol)
+	}
+	// Skip the space that terminates a command name.
+	if end < len(s) && s[end] == ' ' {
+		end++
+	}
+	return end - 1
+}
+
+// decodeAccent applies the accent at s[at] to the following letter, written
+// either bare, as in \'e, or braced, as in \'{e} or \c{c}.
+func decodeAccent(s string, at int, accent byte, out *strings.Builder) int {
+	i := at + 1
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	braced := i < len(s) && s[i] == '{'
+	if braced {
+		i++
+	}
+	if i >= len(s) {
+		return len(s) - 1
+	}
+
+	letter := s[i]
+	if letter == '\\' && i+1 < len(s) && (s[i+1] == 'i' || s[i+1] == 'j') {
+		// \'{\i} is a dotless i carrying the accent.
+		i++
+		letter = s[i]
+	}
+	if accented, ok := latexAccents[string(accent)+string(letter)]; ok {
+		out.WriteString(accented)
+	} else {
+		out.WriteByte(letter)
+	}
+	if braced && i+1 < len(s) && s[i+1] == '}' {
+		i++
+	}
+	return i
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// encodeLaTeX escapes the characters LaTeX treats as special so a plain-text
+// value can be written into a BibTeX field.
+func encodeLaTeX(s string) string {
+	var out strings.Builder
+	for _, c := range s {
+		switch c {
+		case '&', '%', '$', '#', '_', '{', '}':
+			out.WriteByte('\\')
+			out.WriteRune(c)
+		case '\\':
+			out.WriteString(`\textbackslash{}`)
+		case '~':
+			out.WriteString(`\textasciitilde{}`)
+		case '^':
+			out.WriteString(`\textasciicircum{}`)
+		default:
+			if unicode.IsControl(c) {
+				out.WriteByte(' ')
+				continue
+			}
+			out.WriteRune(c)
+		}
+	}
+	return out.String()
+}
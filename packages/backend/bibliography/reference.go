@@ -0,0 +1,123 @@
+package bibliography
+
+import (
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// Reference is a publication prepared for export, with its authors' names
+// split into first and last names as the exchange formats expect.
+type Reference struct {
+	Publication models.Publication
+	Authors     []Name
+}
+
+// NewReference pairs a publication with its authors' names. Authors linked to
+// researchers take their names from researchers, which is the publication's
+// author list as returned by PublicationRepo.GetAuthors; external authors
+// have their names split the way BibTeX would.
+func NewReference(pub models.Publication, researchers []models.Researcher) Reference {
+	byID := make(map[int]models.Researcher, len(researchers))
+	for _, researcher := range researchers {
+		byID[researcher.ID] = researcher
+	}
+
+	names := make([]Name, 0, len(pub.Authors))
+	for _, author := range pub.Authors {
+		if author.ID != nil {
+			if researcher, ok := byID[*author.ID]; ok {
+				names = append(names, Name{First: localized(researcher.Name), Last: localized(researcher.LastName)})
+				continue
+			}
+		}
+
+		if full := localized(author.Name); full != "" {
+			names = append(names, SplitName(full))
+		}
+	}
+	return Reference{Publication: pub, Authors: names}
+}
+
+// SplitName splits a plain-text name such as "Ivan Ivanov" or "Ivanov, Ivan"
+// into first and last names. Text that reads as several names is kept whole
+// as a last name.
+func SplitName(full string) Name {
+	if parsed := ParseNames(encodeLaTeX(full)); len(parsed) == 1 {
+		return parsed[0]
+	}
+	return Name{Last: full}
+}
+
+// DOI extracts the DOI from a doi.org link, returning "" for other links.
+func DOI(link string) string {
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/"} {
+		if strings.HasPrefix(link, prefix) {
+			return strings.TrimPrefix(link, prefix)
+		}
+	}
+	return ""
+}
+
+// publicationTypes lists the names each format uses for a publication type.
+var publicationTypes = []struct {
+	pubType, bibTeX, ris, csl string
+}{
+	{models.PublicationTypeArticle, "article", "JOUR", "article-journal"},
+	{models.PublicationTypeConference, "inproceedings", "CONF", "paper-conference"},
+	{models.PublicationTypeBookChapter, "incollection", "CHAP", "chapter"},
+	{models.PublicationTypeThesis, "phdthesis", "THES", "thesis"},
+	{models.PublicationTypePatent, "patent", "PAT", "patent"},
+}
+
+// typeAliases maps further entry type names to publication types.
+var typeAliases = map[string]string{
+	"conference":    models.PublicationTypeConference,
+	"cpaper":        models.PublicationTypeConference,
+	"inbook":        models.PublicationTypeBookChapter,
+	"mastersthesis": models.PublicationTypeThesis,
+	"thesis":        models.PublicationTypeThesis,
+}
+
+// publicationType maps the type of a BibTeX entry, RIS record or CSL-JSON
+// item to a publication type, defaulting to an article.
+func publicationType(entryType string) string {
+	entryType = strings.ToLower(entryType)
+	for _, t := range publicationTypes {
+		if entryType == t.bibTeX || entryType == strings.ToLower(t.ris) || entryType == t.csl {
+			return t.pubType
+		}
+	}
+	if pubType, ok := typeAliases[entryType]; ok {
+		return pubType
+	}
+	return models.PublicationTypeArticle
+}
+
+// typeNames returns the BibTeX, RIS and CSL-JSON types of pub. Articles
+// without a venue are written as generic documents.
+func typeNames(pub models.Publication) (bibTeX, ris, csl string) {
+	if pub.Type != models.PublicationTypeArticle || pub.Venue != "" {
+		for _, t := range publicationTypes {
+			if t.pubType == pub.Type {
+				return t.bibTeX, t.ris, t.csl
+			}
+		}
+	}
+	return "misc", "GEN", "document"
+}
+
+// publicationDOI returns the DOI of pub, taking it from a doi.org link when
+// the DOI field is empty.
+func publicationDOI(pub models.Publication) string {
+	if pub.DOI != "" {
+		return pub.DOI
+	}
+	return DOI(pub.Link)
+}
+
+// splitPages splits a page range such as "10-20" into its first and last page.
+func splitPages(pages string) (first, last string) {
+	first, last, _ = strings.Cut(strings.ReplaceAll(strings.ReplaceAll(pages, "–", "-"), "--", "-"), "-")
+	return strings.TrimSpace(first), strings.TrimSpace(last)
+}
@@ -0,0 +1,206 @@
+package bibliography
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteRIS writes references as RIS records, typed after the publication
+// type; articles without a venue are written as GEN. The citation key goes
+// into the ID tag.
+func WriteRIS(w io.Writer, refs []Reference) error {
+	keys := citationKeys{}
+	for _, ref := range refs {
+		pub := ref.Publication
+		_, recordType, _ := typeNames(pub)
+
+		var b strings.Builder
+		writeRISTag(&b, "TY", recordType)
+		writeRISTag(&b, "ID", keys.next(ref))
+		for _, name := range ref.Authors {
+			author := name.Last
+			if name.First != "" {
+				author += ", " + name.First
+			}
+			writeRISTag(&b, "AU", author)
+		}
+		writeRISTag(&b, "TI", localized(pub.Title))
+		writeRISTag(&b, "T2", pub.Venue)
+		writeRISTag(&b, "VL", pub.Volume)
+		writeRISTag(&b, "IS", pub.Issue)
+		if first, last := splitPages(pub.Pages); first != "" {
+			writeRISTag(&b, "SP", first)
+			writeRISTag(&b, "EP", last)
+		}
+		writeRISTag(&b, "PB", pub.Publisher)
+
+		year, month, day := splitDate(pub.PublishedAt)
+		writeRISTag(&b, "PY", year)
+		if month > 0 {
+			date := fmt.Sprintf("%s/%02d/", year, month)
+			if day > 0 {
+				date += fmt.Sprintf("%02d", day)
+			}
+			writeRISTag(&b, "DA", date+"/")
+		}
+		writeRISTag(&b, "DO", publicationDOI(pub))
+		writeRISTag(&b, "UR", pub.Link)
+		writeRISTag(&b, "AB", pub.Abstract)
+		b.WriteString("ER  - \r\n\r\n")
+
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRISTag writes one tag line. RIS has no escaping, so line breaks in the
+// value are folded into spaces.
+func writeRISTag(b *strings.Builder, tag, value string) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value != "" {
+		fmt.Fprintf(b, "%s  - %s\r\n", tag, value)
+	}
+}
+
+// risFields maps RIS tags to the BibTeX fields they are imported as. Authors
+// and dates are handled separately.
+var risFields = map[string]string{
+	"TI": "title", "T1": "title", "CT": "title",
+	"T2": "journal", "JO": "journal", "JF": "journal", "JA": "journal", "J2": "journal", "BT": "booktitle",
+	"VL": "volume", "IS": "number",
+	"PB": "publisher",
+	"AB": "abstract", "N2": "abstract",
+	"UR": "url", "L2": "url",
+	"DO": "doi",
+}
+
+// ParseRIS returns every record of a RIS file as an entry with BibTeX field
+// names, so that records import the same way BibTeX entries do. Lines that do
+// not start with a tag continue the previous value.
+func ParseRIS(src string) []Entry {
+	entries := []Entry{}
+
+	var entry *Entry
+	var authors []Name
+	var lastTag string
+	finish := func() {
+		if entry == nil {
+			return
+		}
+		if len(authors) > 0 {
+			entry.Fields["author"] = joinNames(authors)
+		}
+		entries = append(entries, *entry)
+		entry, authors = nil, nil
+	}
+
+	for i, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line = strings.TrimPrefix(line, "\ufeff")
+		tag, value, ok := parseRISLine(line)
+		if !ok {
+			if entry != nil && strings.TrimSpace(line) != "" {
+				if field := risFields[lastTag]; field != "" {
+					entry.Fields[field] += " " + encodeLaTeX(strings.TrimSpace(line))
+				}
+			}
+			continue
+		}
+
+		if tag == "TY" {
+			finish()
+			entry = &Entry{Type: strings.ToLower(value), Fields: map[string]string{}}
+			lastTag = tag
+			continue
+		}
+		if entry == nil {
+			entries = append(entries, Entry{Err: fmt.Errorf("line %d: %s tag outside of a record", i+1, tag)})
+			continue
+		}
+		lastTag = tag
+
+		switch tag {
+		case "ER":
+			finish()
+		case "ID":
+			entry.Key = value
+		case "AU", "A1":
+			if name := parseName(strings.Fields(encodeLaTeX(value))); name.Last != "" {
+				authors = append(authors, name)
+			}
+		case "SP", "EP":
+			// Both ends of the page range may come in either order.
+			first, last := splitPages(entry.Fields["pages"])
+			if tag == "SP" {
+				first = value
+			} else {
+				last = value
+			}
+			entry.Fields["pages"] = encodeLaTeX(first)
+			if last != "" {
+				entry.Fields["pages"] += "--" + encodeLaTeX(last)
+			}
+		case "PY", "Y1", "DA":
+			year, month, day := parseRISDate(value)
+			if year != "" && (tag != "DA" || entry.Fields["year"] == "") {
+				entry.Fields["year"] = year
+			}
+			if month != "" {
+				entry.Fields["month"] = month
+				entry.Fields["day"] = day
+			}
+		default:
+			if field, ok := risFields[tag]; ok && entry.Fields[field] == "" {
+				entry.Fields[field] = encodeLaTeX(value)
+			}
+		}
+	}
+	if entry != nil {
+		entry.Err = fmt.Errorf("record is missing its ER tag")
+		finish()
+	}
+	return entries
+}
+
+// parseRISLine splits a "TY  - value" line. Tags are two upper-case letters
+// or digits; some writers drop the space after the dash of an empty value.
+func parseRISLine(line string) (tag, value string, ok bool) {
+	if len(line) < 5 || line[2:5] != "  -" {
+		return "", "", false
+	}
+	for _, c := range line[:2] {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return "", "", false
+		}
+	}
+	return line[:2], strings.TrimSpace(line[5:]), true
+}
+
+// parseRISDate splits a RIS date of the form YYYY/MM/DD/other, where every
+// part but the year may be empty. A plain year or an ISO date also works.
+func parseRISDate(date string) (year, month, day string) {
+	sep := "/"
+	if !strings.Contains(date, "/") {
+		sep = "-"
+	}
+	parts := strings.Split(date, sep)
+	year = strings.TrimSpace(parts[0])
+	if len(parts) > 1 {
+		month = strings.TrimSpace(parts[1])
+	}
+	if len(parts) > 2 {
+		day = strings.TrimSpace(parts[2])
+	}
+	return year, month, day
+}
+
+// joinNames writes names as a BibTeX name list.
+func joinNames(names []Name) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name.bibTeX())
+	}
+	return strings.Join(parts, " and ")
+}
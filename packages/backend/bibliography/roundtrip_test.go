@@ -0,0 +1,160 @@
+package bibliography
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// roundTripFormats export references and parse the output back as an
+// importer would.
+var roundTripFormats = []struct {
+	name  string
+	write func(w *strings.Builder, refs []Reference) error
+	parse func(src string) ([]Entry, error)
+}{
+	{
+		name:  "BibTeX",
+		write: func(w *strings.Builder, refs []Reference) error { return WriteBibTeX(w, refs) },
+		parse: func(src string) ([]Entry, error) { return ParseBibTeX(src), nil },
+	},
+	{
+		name:  "RIS",
+		write: func(w *strings.Builder, refs []Reference) error { return WriteRIS(w, refs) },
+		parse: func(src string) ([]Entry, error) { return ParseRIS(src), nil },
+	},
+	{
+		name:  "CSL-JSON",
+		write: func(w *strings.Builder, refs []Reference) error { return WriteCSLJSON(w, refs) },
+		parse: ParseCSLJSON,
+	},
+}
+
+func roundTripReferences() []Reference {
+	researcherID := 7
+	researchers := []models.Researcher{{
+		ID:       researcherID,
+		Name:     models.LocalizedString{En: "Ivan", Ru: "Иван"},
+		LastName: models.LocalizedString{En: "Petrov", Ru: "Петров"},
+	}}
+
+	pubs := []models.Publication{
+		{
+			Title: models.LocalizedString{En: "Spiking networks: 50% fewer {spikes} & $costs$", Ru: "Импульсные сети"},
+			Authors: []models.Author{
+				{Name: models.LocalizedString{En: "Ivan Petrov"}, ID: &researcherID},
+				{Name: models.LocalizedString{En: "Smith, John"}},
+				{Name: models.LocalizedString{En: "Ludwig van Beethoven"}},
+			},
+			Type:        models.PublicationTypeArticle,
+			Venue:       "Journal of Neural_Engineering",
+			Volume:      "12",
+			Issue:       "3",
+			Pages:       "101-117",
+			Publisher:   "IOP Publishing",
+			DOI:         "10.1088/1741-2552/ab1234",
+			Abstract:    "We show that spikes are cheap.",
+			PublishedAt: "2021-06-15",
+			Link:        "https://doi.org/10.1088/1741-2552/ab1234",
+		},
+		{
+			Title:       models.LocalizedString{Ru: "Нейроморфные вычисления"},
+			Authors:     []models.Author{{Name: models.LocalizedString{Ru: "Анна Сидорова"}}},
+			Type:        models.PublicationTypeConference,
+			Venue:       "Proceedings of NeuroInformatics",
+			Pages:       "5",
+			PublishedAt: "2019-01-01",
+			Link:        "https://example.org/neuro",
+		},
+		{
+			Title:       models.LocalizedString{En: "Learning rules for memristors"},
+			Authors:     []models.Author{{Name: models.LocalizedString{En: "Ivan Petrov"}, ID: &researcherID}},
+			Type:        models.PublicationTypeThesis,
+			Publisher:   "Moscow State University",
+			PublishedAt: "2020-11-01",
+			Link:        "https://example.org/thesis",
+		},
+		{
+			Title:       models.LocalizedString{En: "A memristor crossbar"},
+			Type:        models.PublicationTypeBookChapter,
+			Venue:       "Advances in Neuromorphic Hardware",
+			Publisher:   "Springer",
+			PublishedAt: "2018-03-20",
+			Link:        "https://example.org/chapter",
+		},
+	}
+
+	refs := make([]Reference, len(pubs))
+	for i, pub := range pubs {
+		refs[i] = NewReference(pub, researchers)
+	}
+	return refs
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	for _, format := range roundTripFormats {
+		t.Run(format.name, func(t *testing.T) {
+			refs := roundTripReferences()
+
+			var out strings.Builder
+			if err := format.write(&out, refs); err != nil {
+				t.Fatalf("export failed: %v", err)
+			}
+			entries, err := format.parse(out.String())
+			if err != nil {
+				t.Fatalf("parse failed: %v\n%s", err, out.String())
+			}
+			if len(entries) != len(refs) {
+				t.Fatalf("parsed %d entries, want %d\n%s", len(entries), len(refs), out.String())
+			}
+
+			for i, entry := range entries {
+				want := refs[i].Publication
+				if entry.Err != nil {
+					t.Errorf("entry %d: %v", i, entry.Err)
+					continue
+				}
+
+				got, err := entry.Publication()
+				if err != nil {
+					t.Errorf("entry %d: %v", i, err)
+					continue
+				}
+				title := localized(want.Title)
+				if got.Title != (models.LocalizedString{En: title, Ru: title}) {
+					t.Errorf("entry %d: title = %+v, want %q", i, got.Title, title)
+				}
+				for _, field := range []struct{ name, got, want string }{
+					{"type", got.Type, want.Type},
+					{"venue", got.Venue, want.Venue},
+					{"volume", got.Volume, want.Volume},
+					{"issue", got.Issue, want.Issue},
+					{"pages", got.Pages, want.Pages},
+					{"publisher", got.Publisher, want.Publisher},
+					{"doi", got.DOI, want.DOI},
+					{"abstract", got.Abstract, want.Abstract},
+					{"publishedAt", got.PublishedAt, want.PublishedAt},
+					{"link", got.Link, want.Link},
+				} {
+					if field.got != field.want {
+						t.Errorf("entry %d: %s = %q, want %q", i, field.name, field.got, field.want)
+					}
+				}
+
+				var names []Name
+				for _, name := range entry.Names("author") {
+					names = append(names, Name{First: decodeLaTeX(name.First), Last: decodeLaTeX(name.Last)})
+				}
+				wantNames := refs[i].Authors
+				if len(wantNames) == 0 {
+					wantNames = nil
+				}
+				if !reflect.DeepEqual(names, wantNames) {
+					t.Errorf("entry %d: authors = %+v, want %+v", i, names, wantNames)
+				}
+			}
+		})
+	}
+}
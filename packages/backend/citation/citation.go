@@ -0,0 +1,574 @@
+// Package citation renders publications as formatted references in the
+// GOST R 7.0.5-2008, APA (7th edition) and IEEE citation styles.
+package citation
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/damirahm/diplom/backend/bibliography"
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// Citation styles.
+const (
+	StyleGOST = "gost"
+	StyleAPA  = "apa"
+	StyleIEEE = "ieee"
+)
+
+// Output formats of a reference list.
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+	// FormatDOCX is plain text meant for pasting into a word processor: CRLF
+	// line breaks, a tab after each number and non-breaking spaces kept.
+	FormatDOCX = "docx"
+)
+
+func IsValidStyle(style string) bool {
+	switch style {
+	case StyleGOST, StyleAPA, StyleIEEE:
+		return true
+	}
+	return false
+}
+
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatText, FormatHTML, FormatDOCX:
+		return true
+	}
+	return false
+}
+
+// Options control how references are rendered.
+type Options struct {
+	Style string
+	// Lang picks the language of titles, names and the style's own words,
+	// "en" or "ru". Text missing in Russian falls back to English.
+	Lang string
+	// AccessedAt is the access date GOST gives for online resources.
+	AccessedAt time.Time
+}
+
+// Run is a piece of a reference with uniform formatting.
+type Run struct {
+	Text   string
+	Italic bool
+	Link   bool
+}
+
+// Reference is a rendered reference.
+type Reference []Run
+
+// String returns the reference as plain text.
+func (r Reference) String() string {
+	var b strings.Builder
+	for _, run := range r {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// HTML returns the reference as an HTML fragment with italics and links. Only
+// http and https URLs become links; any other URL is written as plain text.
+func (r Reference) HTML() string {
+	var b strings.Builder
+	for _, run := range r {
+		text := html.EscapeString(run.Text)
+		switch {
+		case run.Link && isWebURL(run.Text):
+			b.WriteString(`<a href="` + text + `">` + text + `</a>`)
+		case run.Italic:
+			b.WriteString("<i>" + text + "</i>")
+		default:
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+// isWebURL reports whether s is an absolute http or https URL.
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+const nbsp = "\u00a0"
+
+// terms are the words a style adds to a reference, per language.
+type terms struct {
+	and, etAl, noDate, accessed, online, available string
+	// in introduces the larger work a part was published in.
+	in string
+	// GOST labels of the volume, issue and pages.
+	gostVolume, gostIssue, gostPages string
+	// IEEE labels of the volume, issue, a single page and a page range.
+	volume, issue, page, pages string
+}
+
+var vocabulary = map[string]terms{
+	"en": {
+		and: "and", etAl: "et al.", noDate: "n.d.", accessed: "accessed", online: "[Online]", available: "Available", in: "In",
+		gostVolume: "Vol.", gostIssue: "No.", gostPages: "P.",
+		volume: "vol.", issue: "no.", page: "p.", pages: "pp.",
+	},
+	"ru": {
+		and: "и", etAl: "и др.", noDate: "б. г.", accessed: "дата обращения", online: "[Электронный ресурс]", available: "Режим доступа", in: "В",
+		gostVolume: "Т.", gostIssue: "№", gostPages: "С.",
+		volume: "т.", issue: "№", page: "с.", pages: "с.",
+	},
+}
+
+var ieeeMonths = [12]string{"Jan.", "Feb.", "Mar.", "Apr.", "May", "Jun.", "Jul.", "Aug.", "Sep.", "Oct.", "Nov.", "Dec."}
+
+// Render formats a publication. researchers are the publication's linked
+// authors as returned by PublicationRepo.GetAuthors; their names are taken in
+// the requested language from the researcher records.
+func Render(pub models.Publication, researchers []models.Researcher, opts Options) Reference {
+	t, ok := vocabulary[opts.Lang]
+	if !ok {
+		t = vocabulary["en"]
+	}
+	r := &renderer{
+		pub:     pub,
+		title:   localize(pub.Title, opts.Lang),
+		authors: people(pub, researchers, opts.Lang),
+		terms:   t,
+		opts:    opts,
+	}
+	r.year, r.month = publicationDate(pub.PublishedAt)
+	r.doi = pub.DOI
+	if r.doi == "" {
+		r.doi = bibliography.DOI(pub.Link)
+	}
+
+	switch opts.Style {
+	case StyleAPA:
+		r.apa()
+	case StyleIEEE:
+		r.ieee()
+	default:
+		r.gost()
+	}
+	return r.out
+}
+
+type renderer struct {
+	pub     models.Publication
+	title   string
+	authors []person
+	terms   terms
+	opts    Options
+	year    string
+	month   int
+	doi     string
+
+	out Reference
+}
+
+// gost renders GOST R 7.0.5-2008: up to three authors head the reference,
+// and the statement of responsibility after the slash lists at most four
+// authors, shortening longer lists to three.
+func (r *renderer) gost() {
+	if len(r.authors) > 0 && len(r.authors) <= 3 {
+		first := r.authors[0]
+		r.text(joinNonEmpty(nbsp, first.last, first.initials(nbsp)) + " ")
+	}
+	r.text(r.title)
+
+	if len(r.authors) > 0 {
+		listed, more := r.authors, ""
+		if len(listed) > 4 {
+			listed, more = listed[:3], " ["+r.terms.etAl+"]"
+		}
+		names := make([]string, 0, len(listed))
+		for _, author := range listed {
+			names = append(names, joinNonEmpty(nbsp, author.initials(nbsp), author.last))
+		}
+		r.text(" / " + strings.Join(names, ", ") + more)
+	}
+	if r.pub.Venue != "" {
+		r.text(" // " + r.pub.Venue)
+	}
+
+	// The publisher is only named for works that did not appear in a venue,
+	// such as theses.
+	publisher := ""
+	if r.pub.Venue == "" {
+		publisher = r.pub.Publisher
+	}
+	if imprint := joinNonEmpty(", ", publisher, r.year); imprint != "" {
+		r.gostArea()
+		r.text(imprint)
+	}
+	if numbering := joinNonEmpty(", ", labelled(r.terms.gostVolume, r.pub.Volume), labelled(r.terms.gostIssue, r.pub.Issue)); numbering != "" {
+		r.gostArea()
+		r.text(numbering)
+	}
+	if r.pub.Pages != "" {
+		r.gostArea()
+		r.text(labelled(r.terms.gostPages, pageRange(r.pub.Pages)))
+	}
+	if r.pub.Link != "" {
+		r.gostArea()
+		r.text("URL: ")
+		r.link(r.pub.Link)
+		r.text(" (" + r.terms.accessed + ": " + r.opts.AccessedAt.Format("02.01.2006") + ")")
+	}
+	r.endSentence()
+}
+
+// gostArea separates the areas of a GOST reference with a period and a dash.
+func (r *renderer) gostArea() {
+	r.endSentence()
+	r.text(nbsp + "– ")
+}
+
+// apa renders APA 7: up to twenty authors are listed, longer lists keep the
+// first nineteen and the last one. Without authors the title moves to the
+// front.
+func (r *renderer) apa() {
+	year := r.year
+	if year == "" {
+		year = r.terms.noDate
+	}
+
+	if len(r.authors) == 0 {
+		r.text(r.title)
+		r.endSentence()
+		r.text(" (" + year + ").")
+	} else {
+		names := make([]string, 0, len(r.authors))
+		for _, author := range r.authors {
+			if initials := author.initials(" "); initials != "" {
+				names = append(names, author.last+", "+initials)
+			} else {
+				names = append(names, author.last)
+			}
+		}
+		switch {
+		case len(names) == 1:
+			r.text(names[0])
+		case len(names) <= 20:
+			r.text(strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1])
+		default:
+			r.text(strings.Join(names[:19], ", ") + ", . . . " + names[len(names)-1])
+		}
+		r.endSentence()
+		r.text(" (" + year + "). " + r.title)
+		r.endSentence()
+	}
+
+	if r.pub.Venue != "" {
+		r.text(" ")
+		if r.inCollection() {
+			r.text(r.terms.in + " ")
+			r.italic(r.pub.Venue)
+			if r.pub.Pages != "" {
+				r.text(" (" + r.pageLabel() + " " + pageRange(r.pub.Pages) + ")")
+			}
+		} else {
+			r.italic(r.pub.Venue)
+			if r.pub.Volume != "" {
+				r.text(", ")
+				r.italic(r.pub.Volume)
+			}
+			if r.pub.Issue != "" {
+				r.text("(" + r.pub.Issue + ")")
+			}
+			if r.pub.Pages != "" {
+				r.text(", " + pageRange(r.pub.Pages))
+			}
+		}
+		r.endSentence()
+	}
+	if r.pub.Publisher != "" && r.pub.Type != models.PublicationTypeArticle {
+		r.text(" " + r.pub.Publisher)
+		r.endSentence()
+	}
+	if r.doi != "" {
+		r.text(" ")
+		r.link("https://doi.org/" + r.doi)
+	} else if r.pub.Link != "" {
+		r.text(" ")
+		r.link(r.pub.Link)
+	}
+}
+
+// ieee renders IEEE: up to six authors are listed, longer lists are cut to
+// the first author and "et al.".
+func (r *renderer) ieee() {
+	names := make([]string, 0, len(r.authors))
+	for _, author := range r.authors {
+		names = append(names, joinNonEmpty(" ", author.initials(" "), author.last))
+	}
+	switch {
+	case len(names) == 0:
+	case len(names) > 6:
+		r.text(names[0] + " " + r.terms.etAl + ", ")
+	case len(names) == 1:
+		r.text(names[0] + ", ")
+	case len(names) == 2:
+		r.text(names[0] + " " + r.terms.and + " " + names[1] + ", ")
+	default:
+		// Russian does not put a serial comma before "и".
+		last := ", " + r.terms.and + " "
+		if r.opts.Lang == "ru" {
+			last = " " + r.terms.and + " "
+		}
+		r.text(strings.Join(names[:len(names)-1], ", ") + last + names[len(names)-1] + ", ")
+	}
+
+	var date string
+	if r.year != "" {
+		date = r.year
+		if r.month > 0 && r.opts.Lang != "ru" {
+			date = ieeeMonths[r.month-1] + " " + r.year
+		}
+	}
+	// The publisher stands in for the venue of works that have none.
+	publisher := ""
+	if r.pub.Venue == "" {
+		publisher = r.pub.Publisher
+	}
+	doi := r.doi
+	more := r.pub.Venue != "" || r.pub.Volume != "" || r.pub.Issue != "" || r.pub.Pages != "" ||
+		publisher != "" || date != "" || doi != ""
+
+	// English puts the punctuation after the title inside the quotes, where a
+	// question or exclamation mark ending the title replaces it.
+	punctuation := "."
+	if more {
+		punctuation = ","
+	}
+	if strings.HasSuffix(r.title, "?") || strings.HasSuffix(r.title, "!") {
+		punctuation = ""
+	}
+	if r.opts.Lang == "ru" {
+		r.text("«" + r.title + "»")
+	} else {
+		r.text("“" + r.title + punctuation + "”")
+	}
+
+	elements := 0
+	separate := func() {
+		if elements > 0 || r.opts.Lang == "ru" {
+			r.text(",")
+		}
+		r.text(" ")
+		elements++
+	}
+	if r.pub.Venue != "" {
+		separate()
+		if r.inCollection() {
+			r.text(strings.ToLower(r.terms.in) + " ")
+		}
+		r.italic(r.pub.Venue)
+	}
+	if r.pub.Volume != "" {
+		separate()
+		r.text(r.terms.volume + " " + r.pub.Volume)
+	}
+	if r.pub.Issue != "" {
+		separate()
+		r.text(r.terms.issue + " " + r.pub.Issue)
+	}
+	if r.pub.Pages != "" {
+		separate()
+		r.text(r.pageLabel() + " " + pageRange(r.pub.Pages))
+	}
+	if publisher != "" {
+		separate()
+		r.text(publisher)
+	}
+	if date != "" {
+		separate()
+		r.text(date)
+	}
+	if doi != "" {
+		separate()
+		r.text("doi: " + doi + ".")
+		return
+	}
+
+	if more || r.opts.Lang == "ru" {
+		r.endSentence()
+	}
+	if r.pub.Link != "" {
+		r.text(" " + r.terms.online + ". " + r.terms.available + ": ")
+		r.link(r.pub.Link)
+	}
+}
+
+// inCollection reports whether the publication is part of a larger work, such
+// as conference proceedings or a book, rather than of a periodical.
+func (r *renderer) inCollection() bool {
+	return r.pub.Type == models.PublicationTypeConference || r.pub.Type == models.PublicationTypeBookChapter
+}
+
+// pageLabel returns the IEEE label of the publication's pages, which differs
+// between a single page and a range.
+func (r *renderer) pageLabel() string {
+	if strings.Contains(r.pub.Pages, "-") {
+		return r.terms.pages
+	}
+	return r.terms.page
+}
+
+func (r *renderer) text(s string) {
+	if n := len(r.out); n > 0 && !r.out[n-1].Italic && !r.out[n-1].Link {
+		r.out[n-1].Text += s
+		return
+	}
+	r.out = append(r.out, Run{Text: s})
+}
+
+func (r *renderer) italic(s string) {
+	r.out = append(r.out, Run{Text: s, Italic: true})
+}
+
+func (r *renderer) link(url string) {
+	r.out = append(r.out, Run{Text: url, Link: true})
+}
+
+// endSentence writes a period unless the text already ends with one, or with
+// a question or exclamation mark.
+func (r *renderer) endSentence() {
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimRight(r.out.String(), " "))
+	if last != '.' && last != '?' && last != '!' {
+		r.text(".")
+	}
+}
+
+// labelled prefixes a non-empty value with its label.
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + nbsp + value
+}
+
+// pageRange writes a page range with an en dash.
+func pageRange(pages string) string {
+	return strings.ReplaceAll(pages, "-", "–")
+}
+
+// person is an author's name in the language being rendered.
+type person struct {
+	first, last string
+}
+
+// initials abbreviates the first names, keeping hyphenated names hyphenated:
+// "Jean-Pierre Marie" becomes "J.-P. M.".
+func (p person) initials(sep string) string {
+	words := strings.Fields(p.first)
+	out := make([]string, 0, len(words))
+	for _, word := range words {
+		parts := strings.Split(word, "-")
+		for i, part := range parts {
+			first, _ := utf8.DecodeRuneInString(part)
+			if first == utf8.RuneError {
+				continue
+			}
+			parts[i] = string(unicode.ToUpper(first)) + "."
+		}
+		out = append(out, strings.Join(parts, "-"))
+	}
+	return strings.Join(out, sep)
+}
+
+// people resolves the publication's authors to names in lang: linked authors
+// from their researcher records, external ones by splitting the stored name.
+func people(pub models.Publication, researchers []models.Researcher, lang string) []person {
+	byID := make(map[int]models.Researcher, len(researchers))
+	for _, researcher := range researchers {
+		byID[researcher.ID] = researcher
+	}
+
+	authors := make([]person, 0, len(pub.Authors))
+	for _, author := range pub.Authors {
+		if author.ID != nil {
+			if researcher, ok := byID[*author.ID]; ok {
+				authors = append(authors, person{localize(researcher.Name, lang), localize(researcher.LastName, lang)})
+				continue
+			}
+		}
+		if full := localize(author.Name, lang); full != "" {
+			name := bibliography.SplitName(full)
+			authors = append(authors, person{name.First, name.Last})
+		}
+	}
+	return authors
+}
+
+// localize picks the text in lang, falling back to the other language.
+func localize(s models.LocalizedString, lang string) string {
+	if lang == "ru" && s.Ru != "" {
+		return s.Ru
+	}
+	if s.En != "" {
+		return s.En
+	}
+	return s.Ru
+}
+
+// publicationDate splits a YYYY-MM-DD date into its year and month. A date on
+// the first of January carries the year only, so its month is 0.
+func publicationDate(date string) (year string, month int) {
+	parts := strings.SplitN(date, "-", 3)
+	if len(parts[0]) != 4 {
+		return "", 0
+	}
+	if len(parts) == 3 && parts[1] == "01" && parts[2] == "01" {
+		return parts[0], 0
+	}
+	if len(parts) > 1 {
+		month, _ = strconv.Atoi(parts[1])
+		if month < 1 || month > 12 {
+			month = 0
+		}
+	}
+	return parts[0], month
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	nonEmpty := parts[:0:0]
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
+
+// WriteList writes references as a numbered list: "[1]" labels for IEEE and
+// "1." for the other styles. HTML is written as one paragraph per reference
+// so that the labels survive copying into a document.
+func WriteList(w io.Writer, refs []Reference, style, format string) error {
+	var b strings.Builder
+	for i, ref := range refs {
+		label := strconv.Itoa(i+1) + "."
+		if style == StyleIEEE {
+			label = "[" + strconv.Itoa(i+1) + "]"
+		}
+
+		switch format {
+		case FormatHTML:
+			b.WriteString("<p>" + label + " " + ref.HTML() + "</p>\n")
+		case FormatDOCX:
+			b.WriteString(label + "\t" + ref.String() + "\r\n")
+		default:
+			b.WriteString(label + " " + strings.ReplaceAll(ref.String(), nbsp, " ") + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
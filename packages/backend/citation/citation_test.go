@@ -0,0 +1,296 @@
+package citation
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestReferenceHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{
+			name: "web link",
+			link: "https://example.org/paper?id=1&v=2",
+			want: `Spiking networks. (2020). <a href="https://example.org/paper?id=1&amp;v=2">https://example.org/paper?id=1&amp;v=2</a>`,
+		},
+		{
+			name: "javascript link",
+			link: "javascript:alert(document.cookie)",
+			want: "Spiking networks. (2020). javascript:alert(document.cookie)",
+		},
+		{
+			name: "javascript link in capitals",
+			link: "JavaScript:alert(1)",
+			want: "Spiking networks. (2020). JavaScript:alert(1)",
+		},
+		{
+			name: "data link",
+			link: `data:text/html,<script>alert("x")</script>`,
+			want: "Spiking networks. (2020). data:text/html,&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;",
+		},
+		{
+			name: "relative link",
+			link: "/uploads/paper.pdf",
+			want: "Spiking networks. (2020). /uploads/paper.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := models.Publication{Title: models.LocalizedString{En: "Spiking networks"}, Link: tt.link, PublishedAt: "2020-01-01"}
+			if got := Render(pub, nil, Options{Style: StyleAPA, Lang: "en"}).HTML(); got != tt.want {
+				t.Errorf("HTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// petrovID links the first author of article to the researcher record in
+// researchers, whose names differ from the stored author name.
+const petrovID = 7
+
+var researchers = []models.Researcher{{
+	ID:       petrovID,
+	Name:     models.LocalizedString{En: "Ivan", Ru: "Иван"},
+	LastName: models.LocalizedString{En: "Petrov", Ru: "Петров"},
+}}
+
+// article returns a journal article by a linked researcher and an external
+// author, with every field a reference can show except the link.
+func article() models.Publication {
+	id := petrovID
+	return models.Publication{
+		Title: models.LocalizedString{En: "Spiking networks", Ru: "Импульсные сети"},
+		Authors: []models.Author{
+			{Name: models.LocalizedString{En: "I. Petrov"}, ID: &id},
+			{Name: models.LocalizedString{En: "John Smith", Ru: "Джон Смит"}},
+		},
+		Type:        models.PublicationTypeArticle,
+		Venue:       "Neural Computation",
+		Volume:      "31",
+		Issue:       "4",
+		Pages:       "101-117",
+		DOI:         "10.1162/neco",
+		PublishedAt: "2020-05-17",
+	}
+}
+
+// withAuthors replaces the authors of article with n external authors named
+// AuthorA, AuthorB and so on, or АвторА, АвторБ in Russian, and drops the DOI.
+func withAuthors(n int) models.Publication {
+	pub := article()
+	pub.DOI = ""
+	pub.Authors = nil
+	for i := 0; i < n; i++ {
+		pub.Authors = append(pub.Authors, models.Author{Name: models.LocalizedString{
+			En: fmt.Sprintf("Anna Author%c", 'A'+i),
+			Ru: fmt.Sprintf("Анна Автор%c", 'А'+i),
+		}})
+	}
+	return pub
+}
+
+// authorList returns "AuthorA, A., AuthorB, A., " and so on for the authors
+// of withAuthors from first to last, as APA lists them.
+func authorList(first, last rune) string {
+	var b strings.Builder
+	for c := first; c <= last; c++ {
+		b.WriteString("Author" + string(c) + ", A., ")
+	}
+	return b.String()
+}
+
+func TestRender(t *testing.T) {
+	thesis := article()
+	thesis.Type = models.PublicationTypeThesis
+	thesis.Venue, thesis.Volume, thesis.Issue, thesis.DOI, thesis.PublishedAt = "", "", "", "", ""
+	thesis.Pages = "42"
+	thesis.Publisher = "MIT Press"
+	thesis.Link = "https://example.org/thesis"
+
+	paper := article()
+	paper.Type = models.PublicationTypeConference
+	paper.Venue = "Proc. IJCNN"
+	paper.Volume, paper.Issue, paper.DOI = "", "", ""
+	paper.PublishedAt = "2019-01-01"
+
+	anonymous := article()
+	anonymous.Authors = nil
+	anonymous.DOI = ""
+	anonymous.Link = "https://doi.org/10.1162/neco"
+
+	question := article()
+	question.Title.En = "Do spikes matter?"
+	question.Venue, question.Volume, question.Issue, question.Pages, question.DOI, question.PublishedAt = "", "", "", "", "", ""
+
+	// In the expected references "~" stands for a non-breaking space.
+	tests := []struct {
+		name  string
+		pub   models.Publication
+		style string
+		lang  string
+		want  string
+	}{
+		// GOST R 7.0.5.
+		{
+			name: "article", pub: article(), style: StyleGOST, lang: "en",
+			want: "Petrov~I. Spiking networks / I.~Petrov, J.~Smith // Neural Computation.~– 2020.~– Vol.~31, No.~4.~– P.~101–117.",
+		},
+		{
+			name: "article", pub: article(), style: StyleGOST, lang: "ru",
+			want: "Петров~И. Импульсные сети / И.~Петров, Д.~Смит // Neural Computation.~– 2020.~– Т.~31, №~4.~– С.~101–117.",
+		},
+		{
+			name: "three authors keep the heading", pub: withAuthors(3), style: StyleGOST, lang: "en",
+			want: "AuthorA~A. Spiking networks / A.~AuthorA, A.~AuthorB, A.~AuthorC // Neural Computation.~– 2020.~– Vol.~31, No.~4.~– P.~101–117.",
+		},
+		{
+			name: "four authors drop the heading", pub: withAuthors(4), style: StyleGOST, lang: "ru",
+			want: "Импульсные сети / А.~АвторА, А.~АвторБ, А.~АвторВ, А.~АвторГ // Neural Computation.~– 2020.~– Т.~31, №~4.~– С.~101–117.",
+		},
+		{
+			name: "five authors", pub: withAuthors(5), style: StyleGOST, lang: "en",
+			want: "Spiking networks / A.~AuthorA, A.~AuthorB, A.~AuthorC [et al.] // Neural Computation.~– 2020.~– Vol.~31, No.~4.~– P.~101–117.",
+		},
+		{
+			name: "five authors", pub: withAuthors(5), style: StyleGOST, lang: "ru",
+			want: "Импульсные сети / А.~АвторА, А.~АвторБ, А.~АвторВ [и др.] // Neural Computation.~– 2020.~– Т.~31, №~4.~– С.~101–117.",
+		},
+		{
+			name: "no venue or year", pub: thesis, style: StyleGOST, lang: "ru",
+			want: "Петров~И. Импульсные сети / И.~Петров, Д.~Смит.~– MIT Press.~– С.~42.~– URL: https://example.org/thesis (дата обращения: 05.03.2024).",
+		},
+		{
+			name: "no authors", pub: anonymous, style: StyleGOST, lang: "en",
+			want: "Spiking networks // Neural Computation.~– 2020.~– Vol.~31, No.~4.~– P.~101–117.~– URL: https://doi.org/10.1162/neco (accessed: 05.03.2024).",
+		},
+
+		// APA 7.
+		{
+			name: "article", pub: article(), style: StyleAPA, lang: "en",
+			want: "Petrov, I., & Smith, J. (2020). Spiking networks. Neural Computation, 31(4), 101–117. https://doi.org/10.1162/neco",
+		},
+		{
+			name: "article", pub: article(), style: StyleAPA, lang: "ru",
+			want: "Петров, И., & Смит, Д. (2020). Импульсные сети. Neural Computation, 31(4), 101–117. https://doi.org/10.1162/neco",
+		},
+		{
+			name: "twenty authors", pub: withAuthors(20), style: StyleAPA, lang: "en",
+			want: strings.TrimSuffix(authorList('A', 'S'), ", ") + ", & AuthorT, A. (2020). Spiking networks. Neural Computation, 31(4), 101–117.",
+		},
+		{
+			name: "twenty-one authors", pub: withAuthors(21), style: StyleAPA, lang: "en",
+			want: authorList('A', 'S') + ". . . AuthorU, A. (2020). Spiking networks. Neural Computation, 31(4), 101–117.",
+		},
+		{
+			name: "conference paper", pub: paper, style: StyleAPA, lang: "ru",
+			want: "Петров, И., & Смит, Д. (2019). Импульсные сети. В Proc. IJCNN (с. 101–117).",
+		},
+		{
+			name: "no venue or year", pub: thesis, style: StyleAPA, lang: "en",
+			want: "Petrov, I., & Smith, J. (n.d.). Spiking networks. MIT Press. https://example.org/thesis",
+		},
+		{
+			name: "no venue or year", pub: thesis, style: StyleAPA, lang: "ru",
+			want: "Петров, И., & Смит, Д. (б. г.). Импульсные сети. MIT Press. https://example.org/thesis",
+		},
+		{
+			name: "no authors, DOI from the link", pub: anonymous, style: StyleAPA, lang: "en",
+			want: "Spiking networks. (2020). Neural Computation, 31(4), 101–117. https://doi.org/10.1162/neco",
+		},
+
+		// IEEE.
+		{
+			name: "article", pub: article(), style: StyleIEEE, lang: "en",
+			want: "I. Petrov and J. Smith, “Spiking networks,” Neural Computation, vol. 31, no. 4, pp. 101–117, May 2020, doi: 10.1162/neco.",
+		},
+		{
+			name: "article", pub: article(), style: StyleIEEE, lang: "ru",
+			want: "И. Петров и Д. Смит, «Импульсные сети», Neural Computation, т. 31, № 4, с. 101–117, 2020, doi: 10.1162/neco.",
+		},
+		{
+			name: "three authors", pub: withAuthors(3), style: StyleIEEE, lang: "en",
+			want: "A. AuthorA, A. AuthorB, and A. AuthorC, “Spiking networks,” Neural Computation, vol. 31, no. 4, pp. 101–117, May 2020.",
+		},
+		{
+			name: "three authors", pub: withAuthors(3), style: StyleIEEE, lang: "ru",
+			want: "А. АвторА, А. АвторБ и А. АвторВ, «Импульсные сети», Neural Computation, т. 31, № 4, с. 101–117, 2020.",
+		},
+		{
+			name: "six authors", pub: withAuthors(6), style: StyleIEEE, lang: "en",
+			want: "A. AuthorA, A. AuthorB, A. AuthorC, A. AuthorD, A. AuthorE, and A. AuthorF, “Spiking networks,” Neural Computation, vol. 31, no. 4, pp. 101–117, May 2020.",
+		},
+		{
+			name: "seven authors", pub: withAuthors(7), style: StyleIEEE, lang: "en",
+			want: "A. AuthorA et al., “Spiking networks,” Neural Computation, vol. 31, no. 4, pp. 101–117, May 2020.",
+		},
+		{
+			name: "seven authors", pub: withAuthors(7), style: StyleIEEE, lang: "ru",
+			want: "А. АвторА и др., «Импульсные сети», Neural Computation, т. 31, № 4, с. 101–117, 2020.",
+		},
+		{
+			name: "conference paper", pub: paper, style: StyleIEEE, lang: "en",
+			want: "I. Petrov and J. Smith, “Spiking networks,” in Proc. IJCNN, pp. 101–117, 2019.",
+		},
+		{
+			name: "no venue or year, single page", pub: thesis, style: StyleIEEE, lang: "en",
+			want: "I. Petrov and J. Smith, “Spiking networks,” p. 42, MIT Press. [Online]. Available: https://example.org/thesis",
+		},
+		{
+			name: "no venue or year, single page", pub: thesis, style: StyleIEEE, lang: "ru",
+			want: "И. Петров и Д. Смит, «Импульсные сети», с. 42, MIT Press. [Электронный ресурс]. Режим доступа: https://example.org/thesis",
+		},
+		{
+			name: "no authors, DOI from the link", pub: anonymous, style: StyleIEEE, lang: "en",
+			want: "“Spiking networks,” Neural Computation, vol. 31, no. 4, pp. 101–117, May 2020, doi: 10.1162/neco.",
+		},
+		{
+			name: "title ending in a question mark", pub: question, style: StyleIEEE, lang: "en",
+			want: "I. Petrov and J. Smith, “Do spikes matter?”",
+		},
+	}
+
+	accessed := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.style+"/"+tt.lang+"/"+tt.name, func(t *testing.T) {
+			opts := Options{Style: tt.style, Lang: tt.lang, AccessedAt: accessed}
+			want := strings.ReplaceAll(tt.want, "~", nbsp)
+			if got := Render(tt.pub, researchers, opts).String(); got != want {
+				t.Errorf("Render() =\n%q\nwant\n%q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteList(t *testing.T) {
+	refs := []Reference{
+		{{Text: "First"}, {Text: "Venue", Italic: true}},
+		{{Text: "Second" + nbsp + "work."}},
+	}
+
+	tests := []struct {
+		style, format, want string
+	}{
+		{StyleGOST, FormatText, "1. FirstVenue\n2. Second work.\n"},
+		{StyleIEEE, FormatText, "[1] FirstVenue\n[2] Second work.\n"},
+		{StyleAPA, FormatHTML, "<p>1. First<i>Venue</i></p>\n<p>2. Second" + nbsp + "work.</p>\n"},
+		{StyleIEEE, FormatDOCX, "[1]\tFirstVenue\r\n[2]\tSecond" + nbsp + "work.\r\n"},
+	}
+
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := WriteList(&b, refs, tt.style, tt.format); err != nil {
+			t.Fatalf("WriteList(%s, %s): %v", tt.style, tt.format, err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("WriteList(%s, %s) = %q, want %q", tt.style, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/db"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+)
+
+const cliUsage = `usage:
+  main                                      start the HTTP server
+  main user set-password [-role ROLE] NAME  set or reset a user's password (read from stdin)
+  main migrate up [-dry-run]                apply pending database migrations
+  main migrate status                       list applied and pending migrations`
+
+// runCommand executes an administrative subcommand instead of starting the server.
+func runCommand(cfg *config.Config, args []string) error {
+	switch {
+	case len(args) >= 2 && args[0] == "user" && args[1] == "set-password":
+		if err := db.InitDB(cfg.DBDriver, cfg.DBSource()); err != nil {
+			return fmt.Errorf("failed to initialize database: %v", err)
+		}
+		return runSetPassword(args[2:])
+	case len(args) >= 2 && args[0] == "migrate" && args[1] == "up":
+		if err := db.Open(cfg.DBDriver, cfg.DBSource()); err != nil {
+			return err
+		}
+		return runMigrateUp(args[2:])
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "status":
+		if err := db.Open(cfg.DBDriver, cfg.DBSource()); err != nil {
+			return err
+		}
+		return runMigrateStatus()
+	default:
+		return errors.New(cliUsage)
+	}
+}
+
+// runSetPassword sets the password of an existing user, or creates the user with
+// the given role if it does not exist yet. All sessions of the user are revoked.
+func runSetPassword(args []string) error {
+	fs := flag.NewFlagSet("set-password", flag.ContinueOnError)
+	role := fs.String("role", models.RoleAdmin, "role for a newly created user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(cliUsage)
+	}
+	username := strings.TrimSpace(fs.Arg(0))
+	if !models.IsValidRole(*role) {
+		return fmt.Errorf("invalid role %q", *role)
+	}
+
+	fmt.Fprint(os.Stderr, "New password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("failed to read password: %v", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if err := utils.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	repos := repository.New(db.Driver, db.DB)
+	userRepo := repos.Users
+	sessionRepo := repos.Sessions
+
+	user, err := userRepo.GetByUsername(username)
+	if err != nil {
+		if err.Error() != "user not found" {
+			return err
+		}
+		if _, err := userRepo.Create(models.User{Username: username, Role: *role, PasswordHash: passwordHash}); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created %s user %q\n", *role, username)
+		return nil
+	}
+
+	if err := userRepo.UpdatePassword(user.ID, passwordHash); err != nil {
+		return err
+	}
+	if err := sessionRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Password updated for user %q\n", username)
+	return nil
+}
+
+// runMigrateUp applies pending migrations, or with -dry-run reports what would
+// be applied without changing the database.
+func runMigrateUp(args []string) error {
+	fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report pending migrations without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New(cliUsage)
+	}
+
+	return db.MigrateUp(*dryRun, func(format string, args ...interface{}) {
+		fmt.Printf(format+"\n", args...)
+	})
+}
+
+func runMigrateStatus() error {
+	statuses, legacy, err := db.Status()
+	if err != nil {
+		return err
+	}
+
+	if legacy {
+		fmt.Println("Database predates versioned migrations; run \"migrate up\" to stamp it")
+	}
+	for _, s := range statuses {
+		state := "pending"
+		if s.AppliedAt != nil {
+			state = "applied " + *s.AppliedAt
+		}
+		fmt.Printf("%4d  %-28s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package coauthors builds the co-authorship graph of the lab from its
+// publications and keys the names of external co-authors.
+package coauthors
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/damirahm/diplom/backend/duplicates"
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// NameKey identifies an external author by last name and first initial,
+// whichever order the name is written in, so that "Petrov, Ivan",
+// "Petrov I. A.", "I. A. Petrov" and "Ivan Petrov" share a key. It is empty
+// for names without letters.
+func NameKey(name string) string {
+	if last, first, ok := strings.Cut(name, ","); ok {
+		name = first + " " + last
+	} else if words := strings.Fields(name); len(words) > 1 && isInitials(words[len(words)-1]) && !isInitials(words[0]) {
+		name = strings.Join(append(words[1:], words[0]), " ")
+	}
+	return duplicates.AuthorKey(name)
+}
+
+// AliasKey identifies a spelling of a name regardless of case, punctuation
+// and word order, so that "Petrov, Ivan" and "ivan petrov" share a key while
+// "I. Petrov" does not. It is empty for names without letters.
+func AliasKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-' && r != '\''
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// isInitials reports whether word is an abbreviated name, such as "I.",
+// "I.A." or "IA".
+func isInitials(word string) bool {
+	if strings.HasSuffix(word, ".") {
+		return true
+	}
+	if utf8.RuneCountInString(word) > 2 {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type node struct {
+	models.CoauthorNode
+	// spellings counts the names an external author is listed under.
+	spellings map[string]int
+}
+
+type edge struct {
+	models.CoauthorEdge
+	years map[int]bool
+}
+
+// Build returns the co-authorship graph of publications. Publications without
+// a researcher among their authors are left out, and so are those without one
+// of members when members is not nil. External authors are left out with
+// noExternal; the aliases of an external person make one node with the first
+// of them seen. Nodes and edges are sorted by publication count, highest
+// first.
+func Build(publications []models.Publication, members map[int]bool, noExternal bool) models.CoauthorGraph {
+	nodes := map[string]*node{}
+	edges := map[[2]string]*edge{}
+	people := map[int]string{}
+
+	for _, pub := range publications {
+		var ids []string
+		names := map[string]models.LocalizedString{}
+		lab := false
+		for _, author := range pub.Authors {
+			var id string
+			if author.ID != nil {
+				id = "researcher:" + strconv.Itoa(*author.ID)
+				if members == nil || members[*author.ID] {
+					lab = true
+				}
+			} else {
+				key := NameKey(author.Name.En)
+				if key == "" || noExternal {
+					continue
+				}
+				id = "external:" + key
+				if author.PersonID != nil {
+					if personNode, ok := people[*author.PersonID]; ok {
+						id = personNode
+					} else {
+						people[*author.PersonID] = id
+					}
+				}
+			}
+			if _, ok := names[id]; !ok {
+				ids = append(ids, id)
+				names[id] = author.Name
+			}
+		}
+		if !lab {
+			continue
+		}
+
+		for _, id := range ids {
+			n, ok := nodes[id]
+			if !ok {
+				n = &node{CoauthorNode: models.CoauthorNode{ID: id}, spellings: map[string]int{}}
+				if rest, ok := strings.CutPrefix(id, "researcher:"); ok {
+					researcherID, _ := strconv.Atoi(rest)
+					n.ResearcherID = &researcherID
+				} else {
+					n.External = true
+				}
+				nodes[id] = n
+			}
+			n.Publications++
+			name := names[id]
+			n.spellings[name.En]++
+			if n.Name.En == "" || preferName(name.En, n.Name.En, n.spellings) {
+				n.Name = name
+			}
+		}
+
+		year, _ := strconv.Atoi(firstN(pub.PublishedAt, 4))
+		for i := range ids {
+			for j := i + 1; j < len(ids); j++ {
+				if nodes[ids[i]].External && nodes[ids[j]].External {
+					continue
+				}
+				key := [2]string{ids[i], ids[j]}
+				if key[1] < key[0] {
+					key[0], key[1] = key[1], key[0]
+				}
+				e, ok := edges[key]
+				if !ok {
+					e = &edge{CoauthorEdge: models.CoauthorEdge{Source: key[0], Target: key[1]}, years: map[int]bool{}}
+					edges[key] = e
+				}
+				e.Publications++
+				if year != 0 {
+					e.years[year] = true
+				}
+			}
+		}
+	}
+
+	graph := models.CoauthorGraph{
+		Nodes: make([]models.CoauthorNode, 0, len(nodes)),
+		Edges: make([]models.CoauthorEdge, 0, len(edges)),
+	}
+	for _, n := range nodes {
+		graph.Nodes = append(graph.Nodes, n.CoauthorNode)
+	}
+	for _, e := range edges {
+		e.Years = make([]int, 0, len(e.years))
+		for year := range e.years {
+			e.Years = append(e.Years, year)
+		}
+		sort.Ints(e.Years)
+		graph.Edges = append(graph.Edges, e.CoauthorEdge)
+	}
+
+	sort.Slice(graph.Nodes, func(a, b int) bool {
+		if graph.Nodes[a].Publications != graph.Nodes[b].Publications {
+			return graph.Nodes[a].Publications > graph.Nodes[b].Publications
+		}
+		return graph.Nodes[a].ID < graph.Nodes[b].ID
+	})
+	sort.Slice(graph.Edges, func(a, b int) bool {
+		x, y := graph.Edges[a], graph.Edges[b]
+		if x.Publications != y.Publications {
+			return x.Publications > y.Publications
+		}
+		if x.Source != y.Source {
+			return x.Source < y.Source
+		}
+		return x.Target < y.Target
+	})
+	return graph
+}
+
+// preferName reports whether name should replace current as the label of a
+// node: the most frequent spelling wins, then the one with fewer initials,
+// then the longer one.
+func preferName(name, current string, spellings map[string]int) bool {
+	if spellings[name] != spellings[current] {
+		return spellings[name] > spellings[current]
+	}
+	if a, b := countInitials(name), countInitials(current); a != b {
+		return a < b
+	}
+	if len(name) != len(current) {
+		return len(name) > len(current)
+	}
+	return name < current
+}
+
+func countInitials(name string) int {
+	n := 0
+	for _, word := range strings.Fields(name) {
+		if isInitials(strings.TrimSuffix(word, ",")) {
+			n++
+		}
+	}
+	return n
+}
+
+func firstN(s string, n int) string {
+	if len(s) < n {
+		return s
+	}
+	return s[:n]
+}
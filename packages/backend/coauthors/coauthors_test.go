@@ -0,0 +1,161 @@
+package coauthors
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestNameKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Ivan Petrov", "petrov i"},
+		{"I. A. Petrov", "petrov i"},
+		{"Petrov, Ivan", "petrov i"},
+		{"Petrov I. A.", "petrov i"},
+		{"Petrov IA", "petrov i"},
+		{"Иван Петров", "петров и"},
+		{"Plato", "plato"},
+		{"...", ""},
+	}
+	for _, tt := range tests {
+		if got := NameKey(tt.in); got != tt.want {
+			t.Errorf("NameKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAliasKey(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Ivan Petrov", "ivan petrov"},
+		{"Petrov, Ivan", "ivan petrov"},
+		{"I. Petrov", "i petrov"},
+		{"Anna-Maria O'Neil", "anna-maria o'neil"},
+		{"…", ""},
+	}
+	for _, tt := range tests {
+		if got := AliasKey(tt.in); got != tt.want {
+			t.Errorf("AliasKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func author(name string, researcherID, personID int) models.Author {
+	a := models.Author{Name: models.LocalizedString{En: name, Ru: name}}
+	if researcherID != 0 {
+		a.ID = &researcherID
+	}
+	if personID != 0 {
+		a.PersonID = &personID
+	}
+	return a
+}
+
+func coauthorPublications() []models.Publication {
+	return []models.Publication{
+		{PublishedAt: "2020-03-01", Authors: []models.Author{
+			author("Ivan Petrov", 1, 0), author("J. Smith", 0, 0), author("Kim Lee", 0, 7),
+		}},
+		{PublishedAt: "2021-05-01", Authors: []models.Author{
+			author("Ivan Petrov", 1, 0), author("John Smith", 0, 0), author("Anna Ivanova", 2, 0),
+		}},
+		{PublishedAt: "2021-09-01", Authors: []models.Author{
+			// Another alias of the same external person.
+			author("Anna Ivanova", 2, 0), author("K. Li", 0, 7),
+		}},
+		// Nobody from the lab wrote this one.
+		{PublishedAt: "2022-01-01", Authors: []models.Author{
+			author("John Smith", 0, 0), author("K. Lee", 0, 7),
+		}},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	graph := Build(coauthorPublications(), nil, false)
+
+	type node struct {
+		id, name     string
+		publications int
+	}
+	var nodes []node
+	for _, n := range graph.Nodes {
+		nodes = append(nodes, node{n.ID, n.Name.En, n.Publications})
+		if n.External != (n.ResearcherID == nil) {
+			t.Errorf("node %s: external = %v, researcher ID = %v", n.ID, n.External, n.ResearcherID)
+		}
+	}
+	wantNodes := []node{
+		{"external:lee k", "Kim Lee", 2},
+		{"external:smith j", "John Smith", 2},
+		{"researcher:1", "Ivan Petrov", 2},
+		{"researcher:2", "Anna Ivanova", 2},
+	}
+	if !reflect.DeepEqual(nodes, wantNodes) {
+		t.Errorf("nodes = %+v, want %+v", nodes, wantNodes)
+	}
+
+	// External authors are not joined to each other.
+	wantEdges := []models.CoauthorEdge{
+		{Source: "external:smith j", Target: "researcher:1", Publications: 2, Years: []int{2020, 2021}},
+		{Source: "external:lee k", Target: "researcher:1", Publications: 1, Years: []int{2020}},
+		{Source: "external:lee k", Target: "researcher:2", Publications: 1, Years: []int{2021}},
+		{Source: "external:smith j", Target: "researcher:2", Publications: 1, Years: []int{2021}},
+		{Source: "researcher:1", Target: "researcher:2", Publications: 1, Years: []int{2021}},
+	}
+	if !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", graph.Edges, wantEdges)
+	}
+}
+
+func TestBuildFilters(t *testing.T) {
+	graph := Build(coauthorPublications(), map[int]bool{2: true}, true)
+
+	var ids []string
+	for _, n := range graph.Nodes {
+		ids = append(ids, n.ID)
+	}
+	if want := []string{"researcher:2", "researcher:1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("nodes = %v, want %v", ids, want)
+	}
+	want := []models.CoauthorEdge{{Source: "researcher:1", Target: "researcher:2", Publications: 1, Years: []int{2021}}}
+	if !reflect.DeepEqual(graph.Edges, want) {
+		t.Errorf("edges = %+v, want %+v", graph.Edges, want)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var out strings.Builder
+	if err := WriteGraphML(&out, Build(coauthorPublications(), nil, false)); err != nil {
+		t.Fatalf("WriteGraphML: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), xml.Header) {
+		t.Errorf("output does not start with the XML header:\n%s", out.String())
+	}
+
+	var doc graphML
+	if err := xml.Unmarshal([]byte(out.String()), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, out.String())
+	}
+	if doc.Graph.EdgeDefault != "undirected" || len(doc.Graph.Nodes) != 4 || len(doc.Graph.Edges) != 5 {
+		t.Fatalf("graph = %+v", doc.Graph)
+	}
+	wantNode := []graphMLData{
+		{Key: "name_en", Value: "Ivan Petrov"},
+		{Key: "name_ru", Value: "Ivan Petrov"},
+		{Key: "researcher_id", Value: "1"},
+		{Key: "external", Value: "false"},
+		{Key: "node_publications", Value: "2"},
+	}
+	if got := doc.Graph.Nodes[2]; got.ID != "researcher:1" || !reflect.DeepEqual(got.Data, wantNode) {
+		t.Errorf("node = %+v, want researcher:1 with %+v", got, wantNode)
+	}
+	wantEdge := graphMLEdge{Source: "external:smith j", Target: "researcher:1", Data: []graphMLData{
+		{Key: "edge_publications", Value: "2"},
+		{Key: "years", Value: "2020,2021"},
+	}}
+	if got := doc.Graph.Edges[0]; !reflect.DeepEqual(got, wantEdge) {
+		t.Errorf("edge = %+v, want %+v", got, wantEdge)
+	}
+}
@@ -0,0 +1,111 @@
+package coauthors
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// GraphMLContentType is the media type of GraphML documents.
+const GraphMLContentType = "application/graphml+xml"
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{ID: "name_en", For: "node", Name: "name_en", Type: "string"},
+	{ID: "name_ru", For: "node", Name: "name_ru", Type: "string"},
+	{ID: "researcher_id", For: "node", Name: "researcher_id", Type: "int"},
+	{ID: "external", For: "node", Name: "external", Type: "boolean"},
+	{ID: "node_publications", For: "node", Name: "publications", Type: "int"},
+	{ID: "edge_publications", For: "edge", Name: "publications", Type: "int"},
+	{ID: "years", For: "edge", Name: "years", Type: "string"},
+}
+
+// WriteGraphML writes graph as an undirected GraphML graph. The years of an
+// edge are written as a comma-separated list.
+func WriteGraphML(w io.Writer, graph models.CoauthorGraph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: "coauthors", EdgeDefault: "undirected"},
+	}
+
+	for _, n := range graph.Nodes {
+		data := []graphMLData{
+			{Key: "name_en", Value: n.Name.En},
+			{Key: "name_ru", Value: n.Name.Ru},
+		}
+		if n.ResearcherID != nil {
+			data = append(data, graphMLData{Key: "researcher_id", Value: strconv.Itoa(*n.ResearcherID)})
+		}
+		data = append(data,
+			graphMLData{Key: "external", Value: strconv.FormatBool(n.External)},
+			graphMLData{Key: "node_publications", Value: strconv.Itoa(n.Publications)},
+		)
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: data})
+	}
+
+	for _, e := range graph.Edges {
+		years := make([]string, len(e.Years))
+		for i, year := range e.Years {
+			years[i] = strconv.Itoa(year)
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data: []graphMLData{
+				{Key: "edge_publications", Value: strconv.Itoa(e.Publications)},
+				{Key: "years", Value: strings.Join(years, ",")},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -6,16 +6,23 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/damirahm/diplom/backend/utils"
 	"github.com/joho/godotenv"
 )
 
+// Supported values of DB_DRIVER; they double as database/sql driver names.
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
 type Config struct {
-	DBPath     string
-	Server     ServerConfig
-	Auth       AuthConfig
-	ClientHost string
-	Cron       CronConfig
+	DBDriver    string
+	DBPath      string
+	DatabaseURL string
+	Server      ServerConfig
+	Auth        AuthConfig
+	ClientHost  string
+	Cron        CronConfig
 }
 
 type ServerConfig struct {
@@ -24,15 +31,35 @@ type ServerConfig struct {
 }
 
 type AuthConfig struct {
-	AdminUsername string
-	AdminPassword string
-	CookieName    string
+	AdminUsername   string
+	AdminPassword   string
+	CookieName      string
+	CSRFCookieName  string
+	SessionDuration time.Duration
+	Login           LoginProtectionConfig
+}
+
+// LoginProtectionConfig controls the brute-force protection of the login endpoint.
+// Failures are counted per username and per client IP; each failure delays the
+// next attempt exponentially, and reaching the limit locks the key out.
+type LoginProtectionConfig struct {
+	MaxUserFailures int
+	MaxIPFailures   int
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	LockoutDuration time.Duration
+	FailureWindow   time.Duration
 }
 
 type CronConfig struct {
 	Enabled       bool
 	CrawlInterval time.Duration
 	ScopusAPIKey  string
+	// EnrichMetadata looks up the crawled publications in Crossref to fill in
+	// their missing DOIs, authors, venues, dates and abstracts.
+	EnrichMetadata bool
+	CrossrefURL    string
+	CrossrefMailto string
 }
 
 func LoadConfig() *Config {
@@ -60,26 +87,67 @@ func LoadConfig() *Config {
 		}
 	}
 
+	sessionDuration := 24 * time.Hour
+	if val := os.Getenv("SESSION_DURATION_HOURS"); val != "" {
+		hours, err := strconv.Atoi(val)
+		if err != nil || hours <= 0 {
+			log.Printf("Warning: invalid SESSION_DURATION_HOURS value, using default (24)")
+		} else {
+			sessionDuration = time.Duration(hours) * time.Hour
+		}
+	}
+
+	dbDriver := getEnv("DB_DRIVER", DriverSQLite)
+	if dbDriver != DriverSQLite && dbDriver != DriverPostgres {
+		log.Printf("Warning: invalid DB_DRIVER value, using default (%s)", DriverSQLite)
+		dbDriver = DriverSQLite
+	}
+
 	return &Config{
-		DBPath: getEnv("DB_PATH", "./data/database.db"),
+		DBDriver:    dbDriver,
+		DBPath:      getEnv("DB_PATH", "./data/database.db"),
+		DatabaseURL: getEnv("DATABASE_URL", ""),
 		Server: ServerConfig{
 			Host: getEnv("HOST", "localhost"),
 			Port: getEnv("PORT", "8080"),
 		},
 		Auth: AuthConfig{
-			AdminUsername: getEnv("ADMIN_USERNAME", "admin"),
-			AdminPassword: utils.HashPassword(getEnv("ADMIN_PASSWORD", "changeme")),
-			CookieName:    "admin_session",
+			AdminUsername:   getEnv("ADMIN_USERNAME", "admin"),
+			AdminPassword:   getEnv("ADMIN_PASSWORD", "changeme"),
+			CookieName:      "admin_session",
+			CSRFCookieName:  "csrf_token",
+			SessionDuration: sessionDuration,
+			Login: LoginProtectionConfig{
+				MaxUserFailures: getEnvInt("LOGIN_MAX_FAILURES", 5),
+				MaxIPFailures:   getEnvInt("LOGIN_MAX_IP_FAILURES", 20),
+				BackoffBase:     time.Duration(getEnvInt("LOGIN_BACKOFF_BASE_SECONDS", 1)) * time.Second,
+				BackoffMax:      time.Duration(getEnvInt("LOGIN_BACKOFF_MAX_SECONDS", 60)) * time.Second,
+				LockoutDuration: time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+				FailureWindow:   time.Duration(getEnvInt("LOGIN_FAILURE_WINDOW_MINUTES", 60)) * time.Minute,
+			},
 		},
 		ClientHost: getEnv("CLIENT_HOST", "http://localhost:3000"),
 		Cron: CronConfig{
 			Enabled:       cronEnabled,
 			CrawlInterval: crawlInterval,
 			ScopusAPIKey:  getEnv("SCOPUS_API_KEY", ""),
+
+			EnrichMetadata: getEnvBool("METADATA_ENRICHMENT", false),
+			CrossrefURL:    getEnv("CROSSREF_API_URL", "https://api.crossref.org"),
+			CrossrefMailto: getEnv("CROSSREF_MAILTO", ""),
 		},
 	}
 }
 
+// DBSource returns the data source name for the configured driver: the SQLite
+// file path or the PostgreSQL connection URL.
+func (c *Config) DBSource() string {
+	if c.DBDriver == DriverPostgres {
+		return c.DatabaseURL
+	}
+	return c.DBPath
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -87,3 +155,35 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvBool reads a boolean, falling back to defaultValue when the variable
+// is unset or invalid.
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s value, using default (%t)", key, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvInt reads a positive integer, falling back to defaultValue when the
+// variable is unset or invalid.
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: invalid %s value, using default (%d)", key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,126 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// CrawlStartedBySchedule is the StartedBy of the crawls the crawler starts on
+// its own every crawl interval.
+const CrawlStartedBySchedule = "schedule"
+
+// ErrCrawlRunning is returned by StartCrawl when a running crawl already
+// covers some of the researchers to crawl.
+var ErrCrawlRunning = errors.New("crawl already running")
+
+// crawlRun is a crawl job running in this process.
+type crawlRun struct {
+	// researcherID is the researcher the job crawls, or 0 for every one.
+	researcherID int
+	cancel       context.CancelFunc
+}
+
+// StartCrawl records a crawl job of researcher, or of every researcher when it
+// is nil, and runs it in the background. Crawls never overlap, so that two of
+// them do not save the same publications at once: a crawl of every researcher
+// only starts when nothing is being crawled, and a crawl of one researcher
+// only when neither they nor every researcher are.
+func (pc *PublicationCrawler) StartCrawl(researcher *models.Researcher, withCitations bool, startedBy string) (*models.CrawlJob, error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	scope := 0
+	if researcher != nil {
+		scope = researcher.ID
+	}
+	for _, run := range pc.runs {
+		if crawlsOverlap(run.researcherID, scope) {
+			return nil, ErrCrawlRunning
+		}
+	}
+
+	var researchers []models.Researcher
+	if researcher != nil {
+		researchers = []models.Researcher{*researcher}
+	} else {
+		all, err := pc.researcherRepo.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range all {
+			researchers = append(researchers, r.Researcher)
+		}
+	}
+
+	job := models.CrawlJob{StartedBy: startedBy, Researchers: len(researchers)}
+	if researcher != nil {
+		job.ResearcherID = &researcher.ID
+	}
+	id, err := pc.crawlJobRepo.Create(job)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(pc.ctx)
+	pc.runs[int(id)] = &crawlRun{researcherID: scope, cancel: cancel}
+	go pc.runCrawl(ctx, int(id), researchers, withCitations)
+
+	return pc.crawlJobRepo.GetByID(int(id))
+}
+
+// crawlsOverlap reports whether crawls of researcher IDs a and b, 0 standing
+// for every researcher, cover a researcher in common.
+func crawlsOverlap(a, b int) bool {
+	return a == 0 || b == 0 || a == b
+}
+
+// CancelCrawl stops a running job, keeping the publications it saved so far.
+// It reports false when the job is not running in this process.
+func (pc *PublicationCrawler) CancelCrawl(jobID int) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	run, ok := pc.runs[jobID]
+	if ok {
+		run.cancel()
+	}
+	return ok
+}
+
+func (pc *PublicationCrawler) runCrawl(ctx context.Context, jobID int, researchers []models.Researcher, withCitations bool) {
+	defer func() {
+		pc.mu.Lock()
+		pc.runs[jobID].cancel()
+		delete(pc.runs, jobID)
+		pc.mu.Unlock()
+	}()
+
+	status := models.CrawlStatusCompleted
+	for _, researcher := range researchers {
+		if ctx.Err() != nil {
+			status = models.CrawlStatusCancelled
+			break
+		}
+		if err := pc.crawlJobRepo.StartResult(jobID, researcher.ID); err != nil {
+			log.Printf("Failed to record crawl of researcher %d in job %d: %v", researcher.ID, jobID, err)
+		}
+		result := pc.crawlResearcher(ctx, researcher, withCitations)
+		if err := pc.crawlJobRepo.FinishResult(jobID, result); err != nil {
+			log.Printf("Failed to record crawl of researcher %d in job %d: %v", researcher.ID, jobID, err)
+		}
+		if result.Status == models.CrawlStatusCancelled {
+			status = models.CrawlStatusCancelled
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		status = models.CrawlStatusCancelled
+	}
+
+	if err := pc.crawlJobRepo.Finish(jobID, status, ""); err != nil {
+		log.Printf("Failed to finish crawl job %d: %v", jobID, err)
+	}
+}
@@ -0,0 +1,40 @@
+package cron
+
+import (
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestCrawlsOverlap(t *testing.T) {
+	tests := []struct {
+		running, starting int
+		want              bool
+	}{
+		{running: 0, starting: 0, want: true},
+		{running: 0, starting: 7, want: true},
+		{running: 7, starting: 0, want: true},
+		{running: 7, starting: 7, want: true},
+		{running: 7, starting: 8, want: false},
+	}
+	for _, tt := range tests {
+		if got := crawlsOverlap(tt.running, tt.starting); got != tt.want {
+			t.Errorf("crawlsOverlap(%d, %d) = %v, want %v", tt.running, tt.starting, got, tt.want)
+		}
+	}
+}
+
+func TestStartCrawlRefusesOverlap(t *testing.T) {
+	pc := &PublicationCrawler{runs: map[int]*crawlRun{1: {researcherID: 0}}}
+	if _, err := pc.StartCrawl(nil, false, CrawlStartedBySchedule); err != ErrCrawlRunning {
+		t.Errorf("second crawl of every researcher: err = %v, want ErrCrawlRunning", err)
+	}
+	if _, err := pc.StartCrawl(&models.Researcher{ID: 7}, false, "admin"); err != ErrCrawlRunning {
+		t.Errorf("crawl of a researcher during a crawl of every researcher: err = %v, want ErrCrawlRunning", err)
+	}
+
+	pc.runs = map[int]*crawlRun{1: {researcherID: 7}}
+	if _, err := pc.StartCrawl(nil, false, CrawlStartedBySchedule); err != ErrCrawlRunning {
+		t.Errorf("crawl of every researcher during a crawl of a researcher: err = %v, want ErrCrawlRunning", err)
+	}
+}
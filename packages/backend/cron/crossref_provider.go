@@ -0,0 +1,254 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/duplicates"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/venues"
+)
+
+// DefaultCrossrefURL is the base URL of the public Crossref REST API.
+const DefaultCrossrefURL = "https://api.crossref.org"
+
+// crossrefMinSimilarity is the similarity a Crossref title needs to be taken
+// for the title searched for.
+const crossrefMinSimilarity = 0.9
+
+// crossrefTypes maps Crossref work types to publication types.
+var crossrefTypes = map[string]string{
+	"journal-article":     models.PublicationTypeArticle,
+	"proceedings-article": models.PublicationTypeConference,
+	"book-chapter":        models.PublicationTypeBookChapter,
+	"book-section":        models.PublicationTypeBookChapter,
+	"book-part":           models.PublicationTypeBookChapter,
+	"dissertation":        models.PublicationTypeThesis,
+}
+
+// CrossrefProvider looks up publications in the Crossref REST API, or in any
+// service that answers the same /works requests.
+type CrossrefProvider struct {
+	client  *http.Client
+	baseURL string
+	mailto  string
+}
+
+// NewCrossrefProvider creates a provider querying baseURL. Crossref serves
+// requests that give a contact address in mailto from a faster pool.
+func NewCrossrefProvider(baseURL, mailto string) *CrossrefProvider {
+	if baseURL == "" {
+		baseURL = DefaultCrossrefURL
+	}
+	return &CrossrefProvider{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: strings.TrimRight(baseURL, "/"),
+		mailto:  mailto,
+	}
+}
+
+func (c *CrossrefProvider) Name() string {
+	return "Crossref"
+}
+
+func (c *CrossrefProvider) Lookup(ctx context.Context, pub models.Publication) (*models.Publication, error) {
+	if pub.DOI != "" {
+		var response struct {
+			Message crossrefWork `json:"message"`
+		}
+		found, err := c.get(ctx, "/works/"+url.PathEscape(pub.DOI), nil, &response)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return response.Message.publication(), nil
+		}
+	}
+
+	title := duplicates.NormalizeTitle(pub.Title.En)
+	if title == "" {
+		return nil, nil
+	}
+
+	var response struct {
+		Message struct {
+			Items []crossrefWork `json:"items"`
+		} `json:"message"`
+	}
+	query := url.Values{
+		"query.bibliographic": {pub.Title.En},
+		"rows":                {"5"},
+	}
+	if _, err := c.get(ctx, "/works", query, &response); err != nil {
+		return nil, err
+	}
+
+	year, _ := strconv.Atoi(firstN(pub.PublishedAt, 4))
+	var best *crossrefWork
+	bestScore := 0.0
+	for i, work := range response.Message.Items {
+		if len(work.Title) == 0 {
+			continue
+		}
+		if workYear := work.Issued.year(); year != 0 && workYear != 0 && abs(workYear-year) > 1 {
+			continue
+		}
+		if score := venues.Similarity(title, duplicates.NormalizeTitle(work.Title[0])); score > bestScore {
+			best, bestScore = &response.Message.Items[i], score
+		}
+	}
+	if best == nil || bestScore < crossrefMinSimilarity {
+		return nil, nil
+	}
+	return best.publication(), nil
+}
+
+// get fetches path and decodes the JSON response into v. It reports false
+// when the resource does not exist.
+func (c *CrossrefProvider) get(ctx context.Context, path string, query url.Values, v interface{}) (bool, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.mailto != "" {
+		query.Set("mailto", c.mailto)
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %s from %s", resp.Status, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return true, nil
+}
+
+type crossrefWork struct {
+	DOI            string           `json:"DOI"`
+	Type           string           `json:"type"`
+	Title          []string         `json:"title"`
+	ContainerTitle []string         `json:"container-title"`
+	Volume         string           `json:"volume"`
+	Issue          string           `json:"issue"`
+	Page           string           `json:"page"`
+	Publisher      string           `json:"publisher"`
+	Abstract       string           `json:"abstract"`
+	Author         []crossrefAuthor `json:"author"`
+	Issued         crossrefDate     `json:"issued"`
+}
+
+type crossrefAuthor struct {
+	Given  string `json:"given"`
+	Family string `json:"family"`
+	// Name is set instead of Given and Family for organizations.
+	Name string `json:"name"`
+}
+
+type crossrefDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+func (d crossrefDate) year() int {
+	if len(d.DateParts) == 0 || len(d.DateParts[0]) == 0 {
+		return 0
+	}
+	return d.DateParts[0][0]
+}
+
+// format writes the date as YYYY-MM-DD, taking the first month or day for
+// missing parts.
+func (d crossrefDate) format() string {
+	if d.year() == 0 {
+		return ""
+	}
+	parts := append(append([]int{}, d.DateParts[0]...), 1, 1)
+	month, day := max(parts[1], 1), max(parts[2], 1)
+	return fmt.Sprintf("%04d-%02d-%02d", parts[0], month, day)
+}
+
+func (w crossrefWork) publication() *models.Publication {
+	pub := &models.Publication{
+		Type:        crossrefTypes[w.Type],
+		DOI:         w.DOI,
+		Volume:      w.Volume,
+		Issue:       w.Issue,
+		Pages:       w.Page,
+		Publisher:   w.Publisher,
+		Abstract:    jatsText(w.Abstract),
+		PublishedAt: w.Issued.format(),
+		Authors:     make([]models.Author, 0, len(w.Author)),
+	}
+	if len(w.Title) > 0 {
+		pub.Title = models.LocalizedString{En: w.Title[0], Ru: w.Title[0]}
+	}
+	if len(w.ContainerTitle) > 0 {
+		pub.Venue = w.ContainerTitle[0]
+		if pub.Type == "" {
+			pub.Type = models.GuessPublicationType(pub.Venue)
+		}
+	}
+	for _, author := range w.Author {
+		name := strings.TrimSpace(author.Given + " " + author.Family)
+		if name == "" {
+			name = author.Name
+		}
+		if name != "" {
+			pub.Authors = append(pub.Authors, models.Author{Name: models.LocalizedString{En: name, Ru: name}})
+		}
+	}
+	return pub
+}
+
+var (
+	jatsTag   = regexp.MustCompile(`<[^>]*>`)
+	jatsTitle = regexp.MustCompile(`(?s)<jats:title>.*?</jats:title>`)
+)
+
+// jatsText turns a JATS XML abstract into plain text, dropping its title.
+func jatsText(abstract string) string {
+	abstract = jatsTitle.ReplaceAllString(abstract, "")
+	abstract = jatsTag.ReplaceAllString(abstract, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(abstract)), " ")
+}
+
+func firstN(s string, n int) string {
+	if len(s) < n {
+		return s
+	}
+	return s[:n]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,168 @@
+package cron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+const crossrefSpikingWork = `{
+	"DOI": "10.1000/snn",
+	"type": "journal-article",
+	"title": ["Spiking neural networks for speech recognition"],
+	"container-title": ["Neural Computation"],
+	"volume": "31",
+	"issue": "4",
+	"page": "101-117",
+	"publisher": "MIT Press",
+	"abstract": "<jats:title>Abstract</jats:title><jats:p>Spikes are &amp; stay cheap.</jats:p>",
+	"author": [
+		{"given": "Ivan", "family": "Petrov"},
+		{"given": "John", "family": "Smith"},
+		{"name": "Neuro Consortium"}
+	],
+	"issued": {"date-parts": [[2020, 5, 17]]}
+}`
+
+// newCrossrefServer serves the work above by its DOI and to title searches
+// for speech recognition, and fails every request for the DOI 10.1000/broken.
+func newCrossrefServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("mailto"); got != "admin@example.org" {
+			t.Errorf("mailto = %q, want admin@example.org", got)
+		}
+		switch r.URL.Path {
+		case "/works/10.1000/snn":
+			w.Write([]byte(`{"message": ` + crossrefSpikingWork + `}`))
+		case "/works/10.1000/broken":
+			http.Error(w, "upstream failure", http.StatusInternalServerError)
+		case "/works":
+			if r.URL.Query().Get("query.bibliographic") == "Spiking neural networks for speech recognition" {
+				w.Write([]byte(`{"message": {"items": [
+					{"title": ["Speech recognition with spiking neurons"], "issued": {"date-parts": [[2020]]}},
+					` + crossrefSpikingWork + `
+				]}}`))
+				return
+			}
+			w.Write([]byte(`{"message": {"items": [
+				{"title": ["Memristor crossbar arrays"], "issued": {"date-parts": [[2020]]}}
+			]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCrossrefLookupAndFill(t *testing.T) {
+	provider := NewCrossrefProvider(newCrossrefServer(t).URL+"/", "admin@example.org")
+	researcherID := 3
+
+	tests := []struct {
+		name string
+		pub  models.Publication
+	}{
+		{
+			name: "by DOI",
+			pub: models.Publication{
+				Title: models.LocalizedString{En: "Spiking networks"},
+				DOI:   "10.1000/snn",
+			},
+		},
+		{
+			name: "by title",
+			pub: models.Publication{
+				Title:       models.LocalizedString{En: "Spiking neural networks for speech recognition"},
+				PublishedAt: "2020-01-01",
+			},
+		},
+		{
+			name: "unknown DOI falls back to the title",
+			pub: models.Publication{
+				Title: models.LocalizedString{En: "Spiking neural networks for speech recognition"},
+				DOI:   "10.1000/unknown",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := tt.pub
+			pub.Authors = []models.Author{
+				{Name: models.LocalizedString{En: "I Petrov"}, ID: &researcherID},
+				{Name: models.LocalizedString{En: "..."}},
+			}
+
+			record, err := provider.Lookup(context.Background(), pub)
+			if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			if record == nil {
+				t.Fatal("Lookup found no record")
+			}
+			if !mergeMetadata(&pub, *record) {
+				t.Fatal("mergeMetadata reported no change")
+			}
+
+			wantDOI := "10.1000/snn"
+			if tt.pub.DOI != "" {
+				wantDOI = tt.pub.DOI
+			}
+			for _, field := range []struct{ name, got, want string }{
+				{"doi", pub.DOI, wantDOI},
+				{"type", pub.Type, models.PublicationTypeArticle},
+				{"venue", pub.Venue, "Neural Computation"},
+				{"volume", pub.Volume, "31"},
+				{"issue", pub.Issue, "4"},
+				{"pages", pub.Pages, "101-117"},
+				{"publisher", pub.Publisher, "MIT Press"},
+				{"abstract", pub.Abstract, "Spikes are & stay cheap."},
+				{"publishedAt", pub.PublishedAt, "2020-05-17"},
+				{"title", pub.Title.En, tt.pub.Title.En},
+			} {
+				if field.got != field.want {
+					t.Errorf("%s = %q, want %q", field.name, field.got, field.want)
+				}
+			}
+
+			if len(pub.Authors) != 3 {
+				t.Fatalf("authors = %+v, want the 3 Crossref authors", pub.Authors)
+			}
+			if pub.Authors[0].ID == nil || *pub.Authors[0].ID != researcherID {
+				t.Errorf("first author = %+v, want the linked researcher kept", pub.Authors[0])
+			}
+			if pub.Authors[1].Name.En != "John Smith" || pub.Authors[2].Name.En != "Neuro Consortium" {
+				t.Errorf("authors = %+v, want John Smith and Neuro Consortium after the researcher", pub.Authors)
+			}
+		})
+	}
+}
+
+func TestCrossrefLookupNoMatch(t *testing.T) {
+	provider := NewCrossrefProvider(newCrossrefServer(t).URL, "admin@example.org")
+
+	for _, pub := range []models.Publication{
+		{Title: models.LocalizedString{En: "Learning rules for memristors"}},
+		{Title: models.LocalizedString{En: "Memristor crossbar arrays"}, PublishedAt: "2015-01-01"},
+		{Title: models.LocalizedString{Ru: "Мемристоры"}},
+	} {
+		record, err := provider.Lookup(context.Background(), pub)
+		if err != nil || record != nil {
+			t.Errorf("Lookup(%+v) = %+v, %v; want no record", pub.Title, record, err)
+		}
+	}
+}
+
+func TestCrossrefLookupHTTPError(t *testing.T) {
+	provider := NewCrossrefProvider(newCrossrefServer(t).URL, "admin@example.org")
+
+	pub := models.Publication{Title: models.LocalizedString{En: "Spiking networks"}, DOI: "10.1000/broken"}
+	record, err := provider.Lookup(context.Background(), pub)
+	if err == nil {
+		t.Fatalf("Lookup = %+v, want an error", record)
+	}
+}
@@ -51,7 +51,7 @@ func (gs *GoogleScholarSource) FetchPublications(researcher models.Researcher, w
 
 	publications, publicationsToUpdateByDate, stats, err := gs.googleScholar.Scrape(url, []string{})
 	if err != nil {
-		log.Printf("error scraping Google Scholar: %w", err)
+		log.Printf("error scraping Google Scholar: %v", err)
 	}
 
 	publicationsByCitations := make([]models.Publication, 0)
@@ -68,7 +68,7 @@ func (gs *GoogleScholarSource) FetchPublications(researcher models.Researcher, w
 
 		publicationsByCitations, publicationsToUpdateByCitations, _, err = gs.googleScholar.Scrape(url, fetchedPublicationTitles)
 		if err != nil {
-			log.Printf("error scraping Google Scholar: %w", err)
+			log.Printf("error scraping Google Scholar: %v", err)
 		}
 	}
 
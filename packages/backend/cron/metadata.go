@@ -0,0 +1,194 @@
+package cron
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/duplicates"
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// MetadataProvider looks up the bibliographic record of a publication in an
+// external index, such as Crossref.
+type MetadataProvider interface {
+	Name() string
+	// Lookup returns the record of pub, found by its DOI or else by its title,
+	// or nil when the index has none.
+	Lookup(ctx context.Context, pub models.Publication) (*models.Publication, error)
+}
+
+// enrichmentInterval is how long the crawler waits before looking up a
+// publication again.
+const enrichmentInterval = 30 * 24 * time.Hour
+
+func (pc *PublicationCrawler) AddMetadataProvider(provider MetadataProvider) {
+	pc.providers = append(pc.providers, provider)
+}
+
+// enrichPublications fills in the missing metadata of a researcher's
+// publications, hidden ones included, from the metadata providers. Fields
+// that are already set are never overwritten. It stops when ctx is done.
+func (pc *PublicationCrawler) enrichPublications(ctx context.Context, researcher models.Researcher) {
+	listed, _, err := pc.publicationRepo.List(models.PublicationFilter{ResearcherID: researcher.ID})
+	if err != nil {
+		log.Printf("Failed to get publications of researcher %d for enrichment: %v", researcher.ID, err)
+		return
+	}
+
+	for _, item := range listed {
+		if ctx.Err() != nil {
+			return
+		}
+		if !pc.dueForEnrichment(item.ID) {
+			continue
+		}
+
+		pub, err := pc.publicationRepo.GetByID(item.ID)
+		if err != nil || pub == nil || !needsEnrichment(*pub) {
+			continue
+		}
+
+		failed := false
+		for _, provider := range pc.providers {
+			record, err := provider.Lookup(ctx, *pub)
+			if err != nil {
+				log.Printf("%s lookup of publication %d failed: %v", provider.Name(), pub.ID, err)
+				failed = true
+				continue
+			}
+			if record == nil {
+				continue
+			}
+
+			enriched := *pub
+			if !mergeMetadata(&enriched, *record) {
+				continue
+			}
+			if err := pc.publicationRepo.Update(enriched); err != nil {
+				log.Printf("Failed to update publication %d from %s: %v", pub.ID, provider.Name(), err)
+				failed = true
+				continue
+			}
+			pc.auditLog.Record(audit.Crawler, models.AuditEntityPublication, pub.ID, models.AuditActionUpdate, pub, enriched)
+			*pub = enriched
+		}
+
+		// Failed lookups are retried on the next crawl.
+		if !failed {
+			pc.enrichedAt.Store(pub.ID, time.Now())
+		}
+	}
+}
+
+func (pc *PublicationCrawler) dueForEnrichment(id int) bool {
+	at, ok := pc.enrichedAt.Load(id)
+	return !ok || time.Since(at.(time.Time)) > enrichmentInterval
+}
+
+// needsEnrichment reports whether pub lacks any of the fields a metadata
+// provider can fill in.
+func needsEnrichment(pub models.Publication) bool {
+	return pub.DOI == "" || pub.Venue == "" || pub.Abstract == "" ||
+		hasTruncatedAuthors(pub.Authors) || strings.HasSuffix(pub.PublishedAt, "-01-01")
+}
+
+// mergeMetadata copies to pub the fields of record that pub lacks and reports
+// whether pub changed.
+func mergeMetadata(pub *models.Publication, record models.Publication) bool {
+	changed := false
+	fill := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			changed = true
+		}
+	}
+
+	if pub.Venue == "" && record.Venue != "" {
+		// The type of a publication without a venue was only a guess.
+		if record.Type != "" {
+			pub.Type = record.Type
+		}
+		pub.VenueID = nil
+	}
+	fill(&pub.Venue, record.Venue)
+	fill(&pub.DOI, record.DOI)
+	fill(&pub.Volume, record.Volume)
+	fill(&pub.Issue, record.Issue)
+	fill(&pub.Pages, record.Pages)
+	fill(&pub.Publisher, record.Publisher)
+	fill(&pub.Abstract, record.Abstract)
+	fill(&pub.PublishedAt, record.PublishedAt)
+
+	// Scraped dates of January 1st usually stand for a bare year.
+	if strings.HasSuffix(pub.PublishedAt, "-01-01") && len(record.PublishedAt) == 10 &&
+		record.PublishedAt != pub.PublishedAt && record.PublishedAt[:4] == pub.PublishedAt[:4] {
+		pub.PublishedAt = record.PublishedAt
+		changed = true
+	}
+
+	if authors, ok := mergeAuthors(pub.Authors, record.Authors); ok {
+		pub.Authors = authors
+		changed = true
+	}
+	return changed
+}
+
+// mergeAuthors replaces a truncated or shorter author list with the one of the
+// record. Authors are matched by last name and first initial so that linked
+// researchers stay linked; researchers the record does not list are kept at
+// the end.
+func mergeAuthors(existing, found []models.Author) ([]models.Author, bool) {
+	known := make([]models.Author, 0, len(existing))
+	for _, author := range existing {
+		if !isTruncationMarker(author.Name.En) {
+			known = append(known, author)
+		}
+	}
+	if len(found) == 0 || (len(found) <= len(known) && len(known) == len(existing)) {
+		return existing, false
+	}
+
+	merged := make([]models.Author, 0, len(found)+len(known))
+	used := make([]bool, len(known))
+	for _, author := range found {
+		key := duplicates.AuthorKey(author.Name.En)
+		for i, k := range known {
+			if used[i] || key == "" || duplicates.AuthorKey(k.Name.En) != key {
+				continue
+			}
+			used[i] = true
+			if k.ID != nil {
+				author = k
+			} else if author.Role == "" {
+				author.Role = k.Role
+			}
+			break
+		}
+		merged = append(merged, author)
+	}
+	for i, k := range known {
+		if !used[i] && k.ID != nil {
+			merged = append(merged, k)
+		}
+	}
+	return merged, true
+}
+
+func hasTruncatedAuthors(authors []models.Author) bool {
+	for _, author := range authors {
+		if isTruncationMarker(author.Name.En) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTruncationMarker reports whether name is the ellipsis Google Scholar ends
+// long author lists with.
+func isTruncationMarker(name string) bool {
+	name = strings.TrimSpace(name)
+	return name == "..." || name == "…"
+}
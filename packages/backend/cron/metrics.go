@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"log"
+	"sort"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// recordPublicationCitations adds the citation count a crawl found for a
+// publication to its history.
+func (pc *PublicationCrawler) recordPublicationCitations(pub models.Publication) {
+	if err := pc.metricsRepo.RecordPublicationCitations(pub.ID, pub.CitationsCount); err != nil {
+		log.Printf("Failed to record citations of publication %d: %v", pub.ID, err)
+	}
+}
+
+// recordResearcherMetrics adds the scraped citation metrics of a researcher to
+// their history, along with the metrics computed from their publications here,
+// hidden ones included.
+func (pc *PublicationCrawler) recordResearcherMetrics(researcher models.Researcher) {
+	publications, _, err := pc.publicationRepo.List(models.PublicationFilter{ResearcherID: researcher.ID})
+	if err != nil {
+		log.Printf("Failed to get publications of researcher %d for metrics: %v", researcher.ID, err)
+		return
+	}
+
+	snapshot := models.MetricsSnapshot{
+		ResearcherID:    researcher.ID,
+		TotalCitations:  researcher.TotalCitations,
+		HIndex:          researcher.HIndex,
+		RecentCitations: researcher.RecentCitations,
+		RecentHIndex:    researcher.RecentHIndex,
+	}
+	snapshot.LocalCitations, snapshot.LocalHIndex, snapshot.LocalI10Index = citationMetrics(publications)
+
+	if err := pc.metricsRepo.RecordResearcherMetrics(snapshot); err != nil {
+		log.Printf("Failed to record metrics of researcher %d: %v", researcher.ID, err)
+	}
+}
+
+// citationMetrics returns the total citations, h-index and i10-index of
+// publications: the h-index is the largest h such that h publications have at
+// least h citations each, and the i10-index the number of publications with at
+// least 10 citations.
+func citationMetrics(publications []models.Publication) (citations, hIndex, i10Index int) {
+	counts := make([]int, len(publications))
+	for i, pub := range publications {
+		counts[i] = pub.CitationsCount
+		citations += pub.CitationsCount
+		if pub.CitationsCount >= 10 {
+			i10Index++
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	for hIndex < len(counts) && counts[hIndex] > hIndex {
+		hIndex++
+	}
+	return citations, hIndex, i10Index
+}
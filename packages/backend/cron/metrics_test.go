@@ -0,0 +1,32 @@
+package cron
+
+import (
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestCitationMetrics(t *testing.T) {
+	tests := []struct {
+		counts                      []int
+		citations, hIndex, i10Index int
+	}{
+		{nil, 0, 0, 0},
+		{[]int{0, 0}, 0, 0, 0},
+		{[]int{1}, 1, 1, 0},
+		{[]int{10, 8, 5, 4, 3}, 30, 4, 1},
+		{[]int{3, 25, 3, 3, 12}, 46, 3, 2},
+		{[]int{100, 100, 100}, 300, 3, 3},
+	}
+	for _, tt := range tests {
+		publications := make([]models.Publication, len(tt.counts))
+		for i, n := range tt.counts {
+			publications[i].CitationsCount = n
+		}
+		citations, hIndex, i10Index := citationMetrics(publications)
+		if citations != tt.citations || hIndex != tt.hIndex || i10Index != tt.i10Index {
+			t.Errorf("citationMetrics(%v) = %d, %d, %d, want %d, %d, %d",
+				tt.counts, citations, hIndex, i10Index, tt.citations, tt.hIndex, tt.i10Index)
+		}
+	}
+}
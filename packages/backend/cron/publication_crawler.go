@@ -4,20 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"sync"
 	"time"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
 )
 
 type PublicationCrawler struct {
 	db              *sql.DB
-	researcherRepo  *repository.SQLiteResearcherRepo
-	publicationRepo *repository.SQLitePublicationRepo
+	researcherRepo  repository.ResearcherRepo
+	publicationRepo repository.PublicationRepo
+	metricsRepo     repository.MetricsRepo
+	crawlJobRepo    repository.CrawlJobRepo
 	crawlInterval   time.Duration
-	lastCrawlTime   time.Time
 	sources         []PublicationSource
-	ctx             context.Context
+	providers       []MetadataProvider
+	// enrichedAt holds when each publication was last looked up by the
+	// metadata providers.
+	enrichedAt sync.Map
+	auditLog   *audit.Logger
+	ctx        context.Context
+
+	mu sync.Mutex
+	// runs holds the jobs running in this process by ID.
+	runs map[int]*crawlRun
 }
 
 type PublicationSource interface {
@@ -27,18 +40,25 @@ type PublicationSource interface {
 
 func NewPublicationCrawler(
 	db *sql.DB,
-	researcherRepo *repository.SQLiteResearcherRepo,
-	publicationRepo *repository.SQLitePublicationRepo,
+	researcherRepo repository.ResearcherRepo,
+	publicationRepo repository.PublicationRepo,
+	metricsRepo repository.MetricsRepo,
+	crawlJobRepo repository.CrawlJobRepo,
 	crawlInterval time.Duration,
+	auditLog *audit.Logger,
 	ctx context.Context,
 ) *PublicationCrawler {
 	return &PublicationCrawler{
 		db:              db,
 		researcherRepo:  researcherRepo,
 		publicationRepo: publicationRepo,
+		metricsRepo:     metricsRepo,
+		crawlJobRepo:    crawlJobRepo,
 		crawlInterval:   crawlInterval,
+		auditLog:        auditLog,
 		ctx:             ctx,
 		sources:         []PublicationSource{},
+		runs:            map[int]*crawlRun{},
 	}
 }
 
@@ -47,7 +67,7 @@ func (pc *PublicationCrawler) AddSource(source PublicationSource) {
 }
 
 func (pc *PublicationCrawler) Start() {
-	go pc.crawlAllResearchers()
+	pc.startScheduledCrawl()
 
 	ticker := time.NewTicker(pc.crawlInterval)
 	defer ticker.Stop()
@@ -55,40 +75,42 @@ func (pc *PublicationCrawler) Start() {
 	for {
 		select {
 		case <-ticker.C:
-			go pc.crawlAllResearchers()
+			pc.startScheduledCrawl()
 		case <-pc.ctx.Done():
 			return
 		}
 	}
 }
 
-func (pc *PublicationCrawler) crawlAllResearchers() {
-	researchers, err := pc.researcherRepo.GetAll()
-	if err != nil {
-		return
+// startScheduledCrawl starts a crawl of every researcher unless one is
+// already running.
+func (pc *PublicationCrawler) startScheduledCrawl() {
+	if _, err := pc.StartCrawl(nil, false, CrawlStartedBySchedule); err != nil {
+		log.Printf("Scheduled crawl not started: %v", err)
 	}
+}
 
-	successCount := 0
-	errorCount := 0
-	for _, researcherWithCount := range researchers {
-		if err := pc.CrawlResearcher(researcherWithCount.Researcher, false); err != nil {
-			errorCount++
-		} else {
-			successCount++
-		}
+// crawlResearcher fetches a researcher's publications from every source,
+// adding the new ones and updating the rest. Problems with single sources or
+// publications are listed in the result; the crawl fails when the researcher
+// cannot be read or saved, or every source fails. It stops early, as
+// cancelled, when ctx is done.
+func (pc *PublicationCrawler) crawlResearcher(ctx context.Context, researcher models.Researcher, withCitations bool) models.CrawlResult {
+	result := models.CrawlResult{ResearcherID: researcher.ID, Status: models.CrawlStatusCompleted, Errors: []string{}}
+	fail := func(format string, args ...interface{}) models.CrawlResult {
+		result.Status = models.CrawlStatusFailed
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+		return result
 	}
 
-	pc.lastCrawlTime = time.Now()
-}
-
-func (pc *PublicationCrawler) CrawlResearcher(researcher models.Researcher, withCitations bool) error {
 	if researcher.Profiles.GoogleScholar == nil || *researcher.Profiles.GoogleScholar == "" {
-		return nil
+		result.Status = models.CrawlStatusSkipped
+		return result
 	}
 
 	existingPubs, err := pc.researcherRepo.GetResearcherPublications(researcher.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get existing publications: %w", err)
+		return fail("failed to get existing publications: %v", err)
 	}
 
 	existingPubMap := make(map[string]bool)
@@ -97,36 +119,79 @@ func (pc *PublicationCrawler) CrawlResearcher(researcher models.Researcher, with
 		existingPubMap[key] = true
 	}
 
-	totalNewPubs := 0
+	var crawled *models.Researcher
+	failedSources := 0
 
 	for _, source := range pc.sources {
+		if ctx.Err() != nil {
+			result.Status = models.CrawlStatusCancelled
+			return result
+		}
+
 		publications, publicationsToUpdate, updatedResearcher, err := source.FetchPublications(researcher, withCitations)
+		if ctx.Err() != nil {
+			// The fetch cannot be interrupted; what it returned is dropped.
+			result.Status = models.CrawlStatusCancelled
+			return result
+		}
 		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source.Name(), err))
+			failedSources++
 			continue
 		}
 
-		newPubCount := 0
 		for _, pub := range publications {
+			if ctx.Err() != nil {
+				result.Status = models.CrawlStatusCancelled
+				return result
+			}
 			key := fmt.Sprintf("%s-%s", pub.Title.En, pub.PublishedAt)
 			if !existingPubMap[key] {
-				_, err := pc.publicationRepo.Create(pub)
+				id, err := pc.publicationRepo.Create(pub)
 				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("failed to add %q: %v", pub.Title.En, err))
 					continue
 				}
-				newPubCount++
+				pub.ID = int(id)
+				pc.auditLog.Record(audit.Crawler, models.AuditEntityPublication, pub.ID, models.AuditActionCreate, nil, pub)
+				pc.recordPublicationCitations(pub)
+				result.NewPublications++
 			}
 		}
 
 		for _, pub := range publicationsToUpdate {
-			pc.publicationRepo.Update(pub)
+			if ctx.Err() != nil {
+				result.Status = models.CrawlStatusCancelled
+				return result
+			}
+			before, _ := pc.publicationRepo.GetByID(pub.ID)
+			if err := pc.publicationRepo.Update(pub); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("failed to update %q: %v", pub.Title.En, err))
+				continue
+			}
+			pc.auditLog.Record(audit.Crawler, models.AuditEntityPublication, pub.ID, models.AuditActionUpdate, before, pub)
+			pc.recordPublicationCitations(pub)
+			result.UpdatedPublications++
 		}
 
-		totalNewPubs += newPubCount
-
 		if err := pc.researcherRepo.Update(*updatedResearcher); err != nil {
-			return fmt.Errorf("failed to update researcher citation stats: %w", err)
+			return fail("failed to update researcher citation stats: %v", err)
 		}
+		pc.auditLog.Record(audit.Crawler, models.AuditEntityResearcher, researcher.ID, models.AuditActionUpdate,
+			researcher.AuditState(), updatedResearcher.AuditState())
+		crawled = updatedResearcher
+	}
+
+	if len(pc.providers) > 0 {
+		pc.enrichPublications(ctx, researcher)
+	}
+
+	if crawled != nil {
+		pc.recordResearcherMetrics(*crawled)
 	}
 
-	return nil
+	if len(pc.sources) > 0 && failedSources == len(pc.sources) {
+		result.Status = models.CrawlStatusFailed
+	}
+	return result
 }
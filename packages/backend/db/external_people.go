@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// backfillExternalPeople registers a person for every spelling of an
+// external author's name and links the existing external authors to them.
+// Names that differ only in case, punctuation or word order share a person;
+// other variants are left for editors to merge.
+func backfillExternalPeople(tx *sql.Tx) error {
+	type author struct {
+		id     int
+		en, ru string
+	}
+
+	rows, err := tx.Query(`SELECT pea.id, ls.en, ls.ru
+		FROM publication_external_authors pea
+		JOIN localized_strings ls ON pea.name_id = ls.id
+		ORDER BY pea.id`)
+	if err != nil {
+		return err
+	}
+	var authors []author
+	for rows.Next() {
+		var a author
+		if err := rows.Scan(&a.id, &a.en, &a.ru); err != nil {
+			rows.Close()
+			return err
+		}
+		authors = append(authors, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ph := func(i int) string {
+		if isPostgres() {
+			return "$" + strconv.Itoa(i+1)
+		}
+		return "?"
+	}
+	insertString := "INSERT INTO localized_strings (en, ru) VALUES (" + ph(0) + ", " + ph(1) + ") RETURNING id"
+	insertPerson := "INSERT INTO external_people (name_id, affiliation_id) VALUES (" + ph(0) + ", " + ph(1) + ") RETURNING id"
+	insertAlias := "INSERT INTO external_person_aliases (person_id, alias, alias_key) VALUES (" + ph(0) + ", " + ph(1) + ", " + ph(2) + ")"
+	link := "UPDATE publication_external_authors SET person_id = " + ph(0) + " WHERE id = " + ph(1)
+
+	people := map[string]int64{}
+	for _, a := range authors {
+		var personID int64
+		for _, name := range []string{a.en, a.ru} {
+			if id, ok := people[aliasKey(name)]; ok {
+				personID = id
+				break
+			}
+		}
+
+		if personID == 0 {
+			if aliasKey(a.en) == "" && aliasKey(a.ru) == "" {
+				continue
+			}
+			var nameID, affiliationID int64
+			if err := tx.QueryRow(insertString, a.en, a.ru).Scan(&nameID); err != nil {
+				return err
+			}
+			if err := tx.QueryRow(insertString, "", "").Scan(&affiliationID); err != nil {
+				return err
+			}
+			if err := tx.QueryRow(insertPerson, nameID, affiliationID).Scan(&personID); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range []string{a.en, a.ru} {
+			key := aliasKey(name)
+			if _, ok := people[key]; ok || key == "" {
+				continue
+			}
+			if _, err := tx.Exec(insertAlias, personID, name, key); err != nil {
+				return err
+			}
+			people[key] = personID
+		}
+
+		if _, err := tx.Exec(link, personID, a.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aliasKey is coauthors.AliasKey as of migration 16, frozen so that the
+// migration groups the same names whenever it runs: the words of a name,
+// lower-cased and sorted, without punctuation.
+func aliasKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-' && r != '\''
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/coauthors"
+	"github.com/damirahm/diplom/backend/config"
+)
+
+func TestBackfillExternalPeople(t *testing.T) {
+	if err := InitDB(config.DriverSQLite, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { DB.Close() })
+
+	exec := func(query string, args ...interface{}) int64 {
+		t.Helper()
+		res, err := DB.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	titleID := exec("INSERT INTO localized_strings (en, ru) VALUES ('Title', 'Название')")
+	pubID := exec("INSERT INTO publications (title_id, venue, published_at, link) VALUES (?, '', '2020', '')", titleID)
+
+	// External authors as they were before the registry: spellings of two
+	// people, and the ellipsis of a truncated author list.
+	names := [][2]string{
+		{"Kim Lee", "Ким Ли"},
+		{"Lee, Kim", "Ли Ким"},
+		{"K. Lee", "Ким Ли"},
+		{"Olga Orlova", "Ольга Орлова"},
+		{"...", "..."},
+	}
+	var authors []int64
+	for i, name := range names {
+		nameID := exec("INSERT INTO localized_strings (en, ru) VALUES (?, ?)", name[0], name[1])
+		authors = append(authors, exec(
+			"INSERT INTO publication_external_authors (publication_id, name_id, author_order) VALUES (?, ?, ?)",
+			pubID, nameID, i,
+		))
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillExternalPeople(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("backfillExternalPeople: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var persons []int64
+	for _, id := range authors {
+		var personID *int64
+		if err := DB.QueryRow("SELECT person_id FROM publication_external_authors WHERE id = ?", id).Scan(&personID); err != nil {
+			t.Fatal(err)
+		}
+		if personID == nil {
+			persons = append(persons, 0)
+		} else {
+			persons = append(persons, *personID)
+		}
+	}
+	// "K. Lee" shares its Russian spelling with Kim Lee, so it joins them.
+	if want := []int64{1, 1, 1, 2, 0}; !reflect.DeepEqual(persons, want) {
+		t.Errorf("people of the external authors = %v, want %v", persons, want)
+	}
+
+	rows, err := DB.Query("SELECT person_id, alias FROM external_person_aliases ORDER BY person_id, alias")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	aliases := map[int64][]string{}
+	for rows.Next() {
+		var personID int64
+		var alias string
+		if err := rows.Scan(&personID, &alias); err != nil {
+			t.Fatal(err)
+		}
+		aliases[personID] = append(aliases[personID], alias)
+	}
+	want := map[int64][]string{
+		1: {"K. Lee", "Kim Lee", "Ким Ли"},
+		2: {"Olga Orlova", "Ольга Орлова"},
+	}
+	if !reflect.DeepEqual(aliases, want) {
+		t.Errorf("aliases = %v, want %v", aliases, want)
+	}
+}
+
+// The registry looks aliases up with coauthors.AliasKey. A change to it that
+// makes this test fail needs a migration recomputing external_person_aliases.
+func TestAliasKeyMatchesCoauthors(t *testing.T) {
+	for _, name := range []string{"Kim Lee", "Lee, Kim", "K. Lee", "O'Neil-Smith, Anna", "Ким Ли", "...", ""} {
+		if got, want := aliasKey(name), coauthors.AliasKey(name); got != want {
+			t.Errorf("aliasKey(%q) = %q, coauthors.AliasKey = %q", name, got, want)
+		}
+	}
+}
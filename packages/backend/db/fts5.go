@@ -0,0 +1,17 @@
+//go:build sqlite_fts5 || fts5
+
+package db
+
+// sqliteFTS5 reports whether the SQLite driver was compiled with FTS5, which
+// the ranked search index needs.
+const sqliteFTS5 = true
+
+const createSearchIndex = `CREATE VIRTUAL TABLE search_index USING fts5(
+	entity UNINDEXED,
+	entity_id UNINDEXED,
+	title_en,
+	title_ru,
+	body_en,
+	body_ru,
+	tokenize = 'unicode61 remove_diacritics 2'
+)`
@@ -0,0 +1,16 @@
+//go:build !sqlite_fts5 && !fts5
+
+package db
+
+const sqliteFTS5 = false
+
+// Without FTS5 the search index is a plain table with the same columns, which
+// SQLiteSearchRepo scans instead of querying with MATCH.
+const createSearchIndex = `CREATE TABLE search_index (
+	entity TEXT NOT NULL,
+	entity_id INTEGER NOT NULL,
+	title_en TEXT,
+	title_ru TEXT,
+	body_en TEXT,
+	body_ru TEXT
+)`
@@ -0,0 +1,321 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Migration is one versioned schema change. Its statements for the current
+// driver and then Up run inside a single transaction, and the version is
+// recorded in schema_migrations so the migration is applied exactly once.
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      []string
+	Postgres []string
+	Up       func(tx *sql.Tx) error
+
+	// Detect reports whether a database created before schema_migrations
+	// existed already contains this change, so it is stamped rather than
+	// applied again. Migrations added after the framework need no Detect.
+	Detect func(q querier) (bool, error)
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *string
+}
+
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Logf receives progress messages from MigrateUp.
+type Logf func(format string, args ...interface{})
+
+// MigrateUp applies all pending migrations in order. A database that predates
+// schema_migrations is first stamped with the migrations it already contains.
+// With dryRun, pending migrations run in a transaction that is rolled back and
+// nothing is recorded.
+func MigrateUp(dryRun bool, logf Logf) error {
+	if err := validateMigrations(); err != nil {
+		return err
+	}
+
+	legacy, err := isLegacyDatabase()
+	if err != nil {
+		return err
+	}
+
+	applied := map[int]bool{}
+	if legacy {
+		stamped, err := stampLegacyDatabase(dryRun, logf)
+		if err != nil {
+			return err
+		}
+		for _, version := range stamped {
+			applied[version] = true
+		}
+	} else if !dryRun {
+		if err := createMigrationsTable(); err != nil {
+			return err
+		}
+	}
+
+	versions, err := appliedVersions()
+	if err != nil {
+		return err
+	}
+	for version := range versions {
+		applied[version] = true
+	}
+
+	pending := []Migration{}
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+
+	if dryRun {
+		return dryRunMigrations(pending, logf)
+	}
+
+	for _, m := range pending {
+		if err := applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %v", m.Version, m.Name, err)
+		}
+		logf("Applied migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// Status lists every known migration with the time it was applied, or nil if
+// it is pending. legacy is true for a database that has not been stamped yet.
+func Status() (statuses []MigrationStatus, legacy bool, err error) {
+	legacy, err = isLegacyDatabase()
+	if err != nil {
+		return nil, false, err
+	}
+
+	versions, err := appliedVersions()
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, m := range migrations {
+		status := MigrationStatus{Migration: m}
+		if appliedAt, ok := versions[m.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, legacy, nil
+}
+
+func validateMigrations() error {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("migration %q has version %d, expected %d", m.Name, m.Version, i+1)
+		}
+	}
+	return nil
+}
+
+func createMigrationsTable() error {
+	now := "CURRENT_TIMESTAMP"
+	if isPostgres() {
+		now = pgNow
+	}
+
+	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL DEFAULT ` + now + `
+		)
+	`)
+	return err
+}
+
+func recordMigration(exec func(query string, args ...interface{}) (sql.Result, error), m Migration) error {
+	query := "INSERT INTO schema_migrations (version, name) VALUES (?, ?)"
+	if isPostgres() {
+		query = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+	}
+
+	_, err := exec(query, m.Version, m.Name)
+	return err
+}
+
+// appliedVersions maps applied migration versions to their application time.
+func appliedVersions() (map[int]string, error) {
+	versions := map[int]string{}
+
+	exists, err := tableExists(DB, "schema_migrations")
+	if err != nil || !exists {
+		return versions, err
+	}
+
+	rows, err := DB.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// isLegacyDatabase reports whether the database was created by a release that
+// predates schema_migrations. Only SQLite databases can be that old.
+func isLegacyDatabase() (bool, error) {
+	if isPostgres() {
+		return false, nil
+	}
+
+	hasMigrations, err := tableExists(DB, "schema_migrations")
+	if err != nil || hasMigrations {
+		return false, err
+	}
+	return tableExists(DB, "researchers")
+}
+
+// stampLegacyDatabase records the migrations a legacy database already
+// contains, upgrading it to the baseline schema first if it is older than
+// that. It returns the stamped versions.
+func stampLegacyDatabase(dryRun bool, logf Logf) ([]int, error) {
+	baseline, err := isBaselineSchema(DB)
+	if err != nil {
+		return nil, err
+	}
+	if !baseline {
+		if dryRun {
+			logf("Would upgrade legacy schema to the baseline")
+		} else {
+			if err := upgradeLegacySchema(); err != nil {
+				return nil, fmt.Errorf("legacy schema upgrade failed: %v", err)
+			}
+			logf("Upgraded legacy schema to the baseline")
+		}
+	}
+
+	if !dryRun {
+		if err := createMigrationsTable(); err != nil {
+			return nil, err
+		}
+	}
+
+	stamped := []int{}
+	for _, m := range migrations {
+		if m.Version > 1 {
+			if m.Detect == nil {
+				break
+			}
+			detected, err := m.Detect(DB)
+			if err != nil {
+				return nil, err
+			}
+			if !detected {
+				continue
+			}
+		}
+
+		if dryRun {
+			logf("Would stamp migration %d: %s", m.Version, m.Name)
+		} else {
+			if err := recordMigration(DB.Exec, m); err != nil {
+				return nil, err
+			}
+			logf("Stamped migration %d: %s", m.Version, m.Name)
+		}
+		stamped = append(stamped, m.Version)
+	}
+	return stamped, nil
+}
+
+func applyMigration(m Migration) (err error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = runMigration(tx, m); err != nil {
+		return err
+	}
+
+	if err = recordMigration(tx.Exec, m); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func runMigration(tx *sql.Tx, m Migration) error {
+	statements := m.SQL
+	if isPostgres() {
+		statements = m.Postgres
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if m.Up != nil {
+		return m.Up(tx)
+	}
+	return nil
+}
+
+// dryRunMigrations applies the pending migrations in one transaction and
+// rolls it back, reporting which would be applied and whether they succeed.
+func dryRunMigrations(pending []Migration, logf Logf) error {
+	if len(pending) == 0 {
+		logf("No pending migrations")
+		return nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, m := range pending {
+		if err := runMigration(tx, m); err != nil {
+			return fmt.Errorf("migration %d (%s) would fail: %v", m.Version, m.Name, err)
+		}
+		logf("Would apply migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+func tableExists(q querier, table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?`
+	if isPostgres() {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1`
+	}
+
+	var count int
+	err := q.QueryRow(query, table).Scan(&count)
+	return count > 0, err
+}
+
+func columnExists(q querier, table, column string) (bool, error) {
+	var count int
+	err := q.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?`, table, column).Scan(&count)
+	return count > 0, err
+}
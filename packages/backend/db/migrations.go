@@ -0,0 +1,871 @@
+package db
+
+// pgNow is the PostgreSQL default for timestamp columns. Timestamps are stored
+// as text in the layout of SQLite's CURRENT_TIMESTAMP so both databases compare
+// them the same way.
+const pgNow = "to_char(timezone('UTC', now()), 'YYYY-MM-DD HH24:MI:SS')"
+
+// migrations lists every schema change in the order it is applied, with the
+// statements for SQLite in SQL and for PostgreSQL in Postgres. Versions must be
+// consecutive starting at 1; append new migrations to the end and never edit
+// one that has been released.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Detect:  isBaselineSchema,
+		SQL: []string{
+			`CREATE TABLE localized_strings (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				en TEXT NOT NULL,
+				ru TEXT NOT NULL
+			)`,
+			`CREATE TABLE partners (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				logo TEXT NOT NULL,
+				url TEXT NOT NULL,
+				type TEXT NOT NULL CHECK(type IN ('university', 'enterprise'))
+			)`,
+			`CREATE TABLE projects (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title_id INTEGER NOT NULL,
+				description_id INTEGER,
+				github_link TEXT,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (description_id) REFERENCES localized_strings(id)
+			)`,
+			`CREATE TABLE project_publications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title_id INTEGER NOT NULL,
+				link TEXT NOT NULL,
+				project_id INTEGER,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (project_id) REFERENCES projects(id)
+			)`,
+			`CREATE TABLE project_videos (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title_id INTEGER NOT NULL,
+				embed_url TEXT NOT NULL,
+				project_id INTEGER,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (project_id) REFERENCES projects(id)
+			)`,
+			`CREATE TABLE project_images (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				project_id INTEGER NOT NULL,
+				url TEXT NOT NULL,
+				image_order INTEGER NOT NULL,
+				FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE publications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title_id INTEGER NOT NULL,
+				journal TEXT NOT NULL,
+				published_at TEXT NOT NULL,
+				citations_count INTEGER DEFAULT 0,
+				link TEXT NOT NULL,
+				visible BOOLEAN DEFAULT 0,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id),
+				UNIQUE(title_id)
+			)`,
+			`CREATE TABLE researchers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name_id INTEGER NOT NULL,
+				last_name_id INTEGER NOT NULL,
+				position_id INTEGER NOT NULL,
+				photo TEXT NOT NULL,
+				bio_id INTEGER,
+				research_gate TEXT,
+				google_scholar TEXT,
+				scopus TEXT,
+				publons TEXT,
+				orcid TEXT,
+				total_citations INTEGER DEFAULT 0,
+				h_index INTEGER DEFAULT 0,
+				recent_citations INTEGER DEFAULT 0,
+				recent_h_index INTEGER DEFAULT 0,
+				FOREIGN KEY (name_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (last_name_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (position_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (bio_id) REFERENCES localized_strings(id)
+			)`,
+			`CREATE TABLE researcher_publications (
+				researcher_id INTEGER NOT NULL,
+				publication_id INTEGER NOT NULL,
+				PRIMARY KEY (researcher_id, publication_id),
+				FOREIGN KEY (researcher_id) REFERENCES researchers(id),
+				FOREIGN KEY (publication_id) REFERENCES publications(id)
+			)`,
+			`CREATE TABLE publication_authors (
+				publication_id INTEGER NOT NULL,
+				researcher_id INTEGER NOT NULL,
+				PRIMARY KEY (publication_id, researcher_id),
+				FOREIGN KEY (publication_id) REFERENCES publications(id),
+				FOREIGN KEY (researcher_id) REFERENCES researchers(id)
+			)`,
+			`CREATE TABLE publication_external_authors (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				publication_id INTEGER NOT NULL,
+				name_id INTEGER NOT NULL,
+				FOREIGN KEY (publication_id) REFERENCES publications(id),
+				FOREIGN KEY (name_id) REFERENCES localized_strings(id)
+			)`,
+			`CREATE TABLE training_materials (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title_id INTEGER NOT NULL,
+				description_id INTEGER NOT NULL,
+				url TEXT NOT NULL,
+				image TEXT NOT NULL,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (description_id) REFERENCES localized_strings(id)
+			)`,
+			`CREATE TABLE disciplines (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title_id INTEGER NOT NULL,
+				description_id INTEGER NOT NULL,
+				image TEXT NOT NULL,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (description_id) REFERENCES localized_strings(id)
+			)`,
+			`CREATE TABLE discipline_researchers (
+				discipline_id INTEGER NOT NULL,
+				researcher_id INTEGER NOT NULL,
+				PRIMARY KEY (discipline_id, researcher_id),
+				FOREIGN KEY (discipline_id) REFERENCES disciplines(id) ON DELETE CASCADE,
+				FOREIGN KEY (researcher_id) REFERENCES researchers(id) ON DELETE CASCADE
+			)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE localized_strings (
+				id SERIAL PRIMARY KEY,
+				en TEXT NOT NULL,
+				ru TEXT NOT NULL
+			)`,
+			`CREATE TABLE partners (
+				id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL,
+				logo TEXT NOT NULL,
+				url TEXT NOT NULL,
+				type TEXT NOT NULL CHECK(type IN ('university', 'enterprise'))
+			)`,
+			`CREATE TABLE projects (
+				id SERIAL PRIMARY KEY,
+				title_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				description_id INTEGER REFERENCES localized_strings(id),
+				github_link TEXT
+			)`,
+			`CREATE TABLE project_publications (
+				id SERIAL PRIMARY KEY,
+				title_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				link TEXT NOT NULL,
+				project_id INTEGER REFERENCES projects(id)
+			)`,
+			`CREATE TABLE project_videos (
+				id SERIAL PRIMARY KEY,
+				title_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				embed_url TEXT NOT NULL,
+				project_id INTEGER REFERENCES projects(id)
+			)`,
+			`CREATE TABLE project_images (
+				id SERIAL PRIMARY KEY,
+				project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				url TEXT NOT NULL,
+				image_order INTEGER NOT NULL
+			)`,
+			`CREATE TABLE publications (
+				id SERIAL PRIMARY KEY,
+				title_id INTEGER NOT NULL UNIQUE REFERENCES localized_strings(id),
+				journal TEXT NOT NULL,
+				published_at TEXT NOT NULL,
+				citations_count INTEGER DEFAULT 0,
+				link TEXT NOT NULL,
+				visible BOOLEAN DEFAULT FALSE
+			)`,
+			`CREATE TABLE researchers (
+				id SERIAL PRIMARY KEY,
+				name_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				last_name_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				position_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				photo TEXT NOT NULL,
+				bio_id INTEGER REFERENCES localized_strings(id),
+				research_gate TEXT,
+				google_scholar TEXT,
+				scopus TEXT,
+				publons TEXT,
+				orcid TEXT,
+				total_citations INTEGER DEFAULT 0,
+				h_index INTEGER DEFAULT 0,
+				recent_citations INTEGER DEFAULT 0,
+				recent_h_index INTEGER DEFAULT 0
+			)`,
+			`CREATE TABLE researcher_publications (
+				researcher_id INTEGER NOT NULL REFERENCES researchers(id),
+				publication_id INTEGER NOT NULL REFERENCES publications(id),
+				PRIMARY KEY (researcher_id, publication_id)
+			)`,
+			`CREATE TABLE publication_authors (
+				publication_id INTEGER NOT NULL REFERENCES publications(id),
+				researcher_id INTEGER NOT NULL REFERENCES researchers(id),
+				PRIMARY KEY (publication_id, researcher_id)
+			)`,
+			`CREATE TABLE publication_external_authors (
+				id SERIAL PRIMARY KEY,
+				publication_id INTEGER NOT NULL REFERENCES publications(id),
+				name_id INTEGER NOT NULL REFERENCES localized_strings(id)
+			)`,
+			`CREATE TABLE training_materials (
+				id SERIAL PRIMARY KEY,
+				title_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				description_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				url TEXT NOT NULL,
+				image TEXT NOT NULL
+			)`,
+			`CREATE TABLE disciplines (
+				id SERIAL PRIMARY KEY,
+				title_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				description_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				image TEXT NOT NULL
+			)`,
+			`CREATE TABLE discipline_researchers (
+				discipline_id INTEGER NOT NULL REFERENCES disciplines(id) ON DELETE CASCADE,
+				researcher_id INTEGER NOT NULL REFERENCES researchers(id) ON DELETE CASCADE,
+				PRIMARY KEY (discipline_id, researcher_id)
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_users",
+		Detect:  detectTable("users"),
+		SQL: []string{
+			`CREATE TABLE users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				username TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				role TEXT NOT NULL CHECK(role IN ('admin', 'editor', 'researcher')),
+				created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE users (
+				id SERIAL PRIMARY KEY,
+				username TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				role TEXT NOT NULL CHECK(role IN ('admin', 'editor', 'researcher')),
+				created_at TEXT NOT NULL DEFAULT ` + pgNow + `
+			)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_sessions",
+		Detect:  detectTable("sessions"),
+		SQL: []string{
+			`CREATE TABLE sessions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				token_hash TEXT NOT NULL UNIQUE,
+				user_id INTEGER NOT NULL,
+				created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TEXT NOT NULL,
+				last_seen_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				ip TEXT NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+			)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE sessions (
+				id SERIAL PRIMARY KEY,
+				token_hash TEXT NOT NULL UNIQUE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TEXT NOT NULL DEFAULT ` + pgNow + `,
+				expires_at TEXT NOT NULL,
+				last_seen_at TEXT NOT NULL DEFAULT ` + pgNow + `,
+				ip TEXT NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT ''
+			)`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_researcher_to_users",
+		Detect:  detectColumn("users", "researcher_id"),
+		SQL: []string{
+			`ALTER TABLE users ADD COLUMN researcher_id INTEGER REFERENCES researchers(id) ON DELETE SET NULL`,
+		},
+		Postgres: []string{
+			`ALTER TABLE users ADD COLUMN researcher_id INTEGER REFERENCES researchers(id) ON DELETE SET NULL`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_api_tokens",
+		Detect:  detectTable("api_tokens"),
+		SQL: []string{
+			`CREATE TABLE api_tokens (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL DEFAULT '',
+				created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TEXT,
+				last_used_at TEXT,
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+			)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE api_tokens (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name TEXT NOT NULL,
+				token_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL DEFAULT '',
+				created_at TEXT NOT NULL DEFAULT ` + pgNow + `,
+				expires_at TEXT,
+				last_used_at TEXT
+			)`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_audit_log",
+		Detect:  detectTable("audit_log"),
+		SQL: []string{
+			`CREATE TABLE audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				actor_id INTEGER,
+				actor TEXT NOT NULL,
+				entity TEXT NOT NULL,
+				entity_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				before TEXT,
+				after TEXT,
+				diff TEXT,
+				created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id)`,
+			`CREATE INDEX idx_audit_log_created_at ON audit_log(created_at)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE audit_log (
+				id SERIAL PRIMARY KEY,
+				actor_id INTEGER,
+				actor TEXT NOT NULL,
+				entity TEXT NOT NULL,
+				entity_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				before TEXT,
+				after TEXT,
+				diff TEXT,
+				created_at TEXT NOT NULL DEFAULT ` + pgNow + `
+			)`,
+			`CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id)`,
+			`CREATE INDEX idx_audit_log_created_at ON audit_log(created_at)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_login_attempts",
+		Detect:  detectTable("login_attempts"),
+		SQL: []string{
+			`CREATE TABLE login_attempts (
+				key TEXT PRIMARY KEY,
+				failures INTEGER NOT NULL DEFAULT 0,
+				last_failure_at TEXT NOT NULL,
+				blocked_until TEXT
+			)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE login_attempts (
+				key TEXT PRIMARY KEY,
+				failures INTEGER NOT NULL DEFAULT 0,
+				last_failure_at TEXT NOT NULL,
+				blocked_until TEXT
+			)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_csrf_to_sessions",
+		Detect:  detectColumn("sessions", "csrf_token_hash"),
+		SQL: []string{
+			`ALTER TABLE sessions ADD COLUMN csrf_token_hash TEXT NOT NULL DEFAULT ''`,
+		},
+		Postgres: []string{
+			`ALTER TABLE sessions ADD COLUMN csrf_token_hash TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add_publication_listing_indexes",
+		SQL: []string{
+			`CREATE INDEX idx_publications_published_at ON publications(published_at)`,
+			`CREATE INDEX idx_publications_citations_count ON publications(citations_count)`,
+			`CREATE INDEX idx_publication_authors_researcher ON publication_authors(researcher_id)`,
+		},
+		Postgres: []string{
+			`CREATE INDEX idx_publications_published_at ON publications(published_at)`,
+			`CREATE INDEX idx_publications_citations_count ON publications(citations_count)`,
+			`CREATE INDEX idx_publication_authors_researcher ON publication_authors(researcher_id)`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "add_search_index",
+		SQL: []string{
+			createSearchIndex,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'publication', p.id, t.en, t.ru, p.journal, p.journal
+			FROM publications p
+			JOIN localized_strings t ON p.title_id = t.id`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'researcher', r.id, n.en || ' ' || ln.en, n.ru || ' ' || ln.ru,
+				pos.en || ' ' || COALESCE(b.en, ''), pos.ru || ' ' || COALESCE(b.ru, '')
+			FROM researchers r
+			JOIN localized_strings n ON r.name_id = n.id
+			JOIN localized_strings ln ON r.last_name_id = ln.id
+			JOIN localized_strings pos ON r.position_id = pos.id
+			LEFT JOIN localized_strings b ON r.bio_id = b.id`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'project', p.id, t.en, t.ru, COALESCE(d.en, ''), COALESCE(d.ru, '')
+			FROM projects p
+			JOIN localized_strings t ON p.title_id = t.id
+			LEFT JOIN localized_strings d ON p.description_id = d.id`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'training', m.id, t.en, t.ru, d.en, d.ru
+			FROM training_materials m
+			JOIN localized_strings t ON m.title_id = t.id
+			JOIN localized_strings d ON m.description_id = d.id`,
+		},
+		Postgres: []string{
+			`CREATE TABLE search_index (
+				entity TEXT NOT NULL,
+				entity_id INTEGER NOT NULL,
+				title_en TEXT NOT NULL,
+				title_ru TEXT NOT NULL,
+				body_en TEXT NOT NULL,
+				body_ru TEXT NOT NULL,
+				document TSVECTOR GENERATED ALWAYS AS (
+					setweight(to_tsvector('simple', title_en || ' ' || title_ru), 'A') ||
+					setweight(to_tsvector('simple', body_en || ' ' || body_ru), 'B')
+				) STORED,
+				PRIMARY KEY (entity, entity_id)
+			)`,
+			`CREATE INDEX idx_search_index_document ON search_index USING GIN (document)`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'publication', p.id, t.en, t.ru, p.journal, p.journal
+			FROM publications p
+			JOIN localized_strings t ON p.title_id = t.id`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'researcher', r.id, n.en || ' ' || ln.en, n.ru || ' ' || ln.ru,
+				pos.en || ' ' || COALESCE(b.en, ''), pos.ru || ' ' || COALESCE(b.ru, '')
+			FROM researchers r
+			JOIN localized_strings n ON r.name_id = n.id
+			JOIN localized_strings ln ON r.last_name_id = ln.id
+			JOIN localized_strings pos ON r.position_id = pos.id
+			LEFT JOIN localized_strings b ON r.bio_id = b.id`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'project', p.id, t.en, t.ru, COALESCE(d.en, ''), COALESCE(d.ru, '')
+			FROM projects p
+			JOIN localized_strings t ON p.title_id = t.id
+			LEFT JOIN localized_strings d ON p.description_id = d.id`,
+			`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+			SELECT 'training', m.id, t.en, t.ru, d.en, d.ru
+			FROM training_materials m
+			JOIN localized_strings t ON m.title_id = t.id
+			JOIN localized_strings d ON m.description_id = d.id`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add_structured_publication_metadata",
+		SQL: []string{
+			`ALTER TABLE publications RENAME COLUMN journal TO venue`,
+			`ALTER TABLE publications ADD COLUMN type TEXT NOT NULL DEFAULT 'article'`,
+			`ALTER TABLE publications ADD COLUMN volume TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN issue TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN pages TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN publisher TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN doi TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN abstract TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_publications_venue ON publications(venue)`,
+			`CREATE INDEX idx_publications_type ON publications(type)`,
+		},
+		Postgres: []string{
+			`ALTER TABLE publications RENAME COLUMN journal TO venue`,
+			`ALTER TABLE publications ADD COLUMN type TEXT NOT NULL DEFAULT 'article'`,
+			`ALTER TABLE publications ADD COLUMN volume TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN issue TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN pages TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN publisher TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN doi TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publications ADD COLUMN abstract TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_publications_venue ON publications(venue)`,
+			`CREATE INDEX idx_publications_type ON publications(type)`,
+		},
+		Up: backfillPublicationMetadata,
+	},
+	{
+		Version: 12,
+		Name:    "add_venues",
+		SQL: []string{
+			`CREATE TABLE venues (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				issn TEXT NOT NULL DEFAULT '',
+				eissn TEXT NOT NULL DEFAULT '',
+				publisher TEXT NOT NULL DEFAULT '',
+				scopus BOOLEAN NOT NULL DEFAULT 0,
+				wos BOOLEAN NOT NULL DEFAULT 0,
+				rsci BOOLEAN NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX idx_venues_issn ON venues(issn)`,
+			`CREATE INDEX idx_venues_eissn ON venues(eissn)`,
+			`CREATE TABLE venue_aliases (
+				venue_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				PRIMARY KEY (venue_id, name),
+				FOREIGN KEY (venue_id) REFERENCES venues(id)
+			)`,
+			`CREATE TABLE venue_quartiles (
+				venue_id INTEGER NOT NULL,
+				year INTEGER NOT NULL,
+				quartile INTEGER NOT NULL CHECK (quartile BETWEEN 1 AND 4),
+				PRIMARY KEY (venue_id, year),
+				FOREIGN KEY (venue_id) REFERENCES venues(id)
+			)`,
+			`ALTER TABLE publications ADD COLUMN venue_id INTEGER REFERENCES venues(id)`,
+			`CREATE INDEX idx_publications_venue_id ON publications(venue_id)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE venues (
+				id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL,
+				issn TEXT NOT NULL DEFAULT '',
+				eissn TEXT NOT NULL DEFAULT '',
+				publisher TEXT NOT NULL DEFAULT '',
+				scopus BOOLEAN NOT NULL DEFAULT FALSE,
+				wos BOOLEAN NOT NULL DEFAULT FALSE,
+				rsci BOOLEAN NOT NULL DEFAULT FALSE
+			)`,
+			`CREATE INDEX idx_venues_issn ON venues(issn)`,
+			`CREATE INDEX idx_venues_eissn ON venues(eissn)`,
+			`CREATE TABLE venue_aliases (
+				venue_id INTEGER NOT NULL REFERENCES venues(id),
+				name TEXT NOT NULL,
+				PRIMARY KEY (venue_id, name)
+			)`,
+			`CREATE TABLE venue_quartiles (
+				venue_id INTEGER NOT NULL REFERENCES venues(id),
+				year INTEGER NOT NULL,
+				quartile INTEGER NOT NULL CHECK (quartile BETWEEN 1 AND 4),
+				PRIMARY KEY (venue_id, year)
+			)`,
+			`ALTER TABLE publications ADD COLUMN venue_id INTEGER REFERENCES venues(id)`,
+			`CREATE INDEX idx_publications_venue_id ON publications(venue_id)`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_author_order_and_roles",
+		// Existing authors keep the order they were listed in: researchers by
+		// ID, then external authors.
+		SQL: []string{
+			`ALTER TABLE publication_authors ADD COLUMN author_order INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE publication_authors ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publication_external_authors ADD COLUMN author_order INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE publication_external_authors ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+			`UPDATE publication_authors SET author_order = (
+				SELECT COUNT(*) FROM publication_authors pa
+				WHERE pa.publication_id = publication_authors.publication_id
+				AND pa.researcher_id < publication_authors.researcher_id
+			)`,
+			`UPDATE publication_external_authors SET author_order = (
+				SELECT COUNT(*) FROM publication_authors pa
+				WHERE pa.publication_id = publication_external_authors.publication_id
+			) + (
+				SELECT COUNT(*) FROM publication_external_authors pea
+				WHERE pea.publication_id = publication_external_authors.publication_id
+				AND pea.id < publication_external_authors.id
+			)`,
+		},
+		Postgres: []string{
+			`ALTER TABLE publication_authors ADD COLUMN author_order INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE publication_authors ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE publication_external_authors ADD COLUMN author_order INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE publication_external_authors ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+			`UPDATE publication_authors SET author_order = (
+				SELECT COUNT(*) FROM publication_authors pa
+				WHERE pa.publication_id = publication_authors.publication_id
+				AND pa.researcher_id < publication_authors.researcher_id
+			)`,
+			`UPDATE publication_external_authors SET author_order = (
+				SELECT COUNT(*) FROM publication_authors pa
+				WHERE pa.publication_id = publication_external_authors.publication_id
+			) + (
+				SELECT COUNT(*) FROM publication_external_authors pea
+				WHERE pea.publication_id = publication_external_authors.publication_id
+				AND pea.id < publication_external_authors.id
+			)`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "add_metric_snapshots",
+		SQL: []string{
+			`CREATE TABLE publication_citation_snapshots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				publication_id INTEGER NOT NULL,
+				citations_count INTEGER NOT NULL,
+				recorded_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_publication_citation_snapshots ON publication_citation_snapshots(publication_id, recorded_at)`,
+			`CREATE TABLE researcher_metric_snapshots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				researcher_id INTEGER NOT NULL,
+				total_citations INTEGER NOT NULL,
+				h_index INTEGER NOT NULL,
+				recent_citations INTEGER NOT NULL,
+				recent_h_index INTEGER NOT NULL,
+				local_citations INTEGER NOT NULL,
+				local_h_index INTEGER NOT NULL,
+				local_i10_index INTEGER NOT NULL,
+				recorded_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (researcher_id) REFERENCES researchers(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_researcher_metric_snapshots ON researcher_metric_snapshots(researcher_id, recorded_at)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE publication_citation_snapshots (
+				id SERIAL PRIMARY KEY,
+				publication_id INTEGER NOT NULL REFERENCES publications(id) ON DELETE CASCADE,
+				citations_count INTEGER NOT NULL,
+				recorded_at TEXT NOT NULL DEFAULT ` + pgNow + `
+			)`,
+			`CREATE INDEX idx_publication_citation_snapshots ON publication_citation_snapshots(publication_id, recorded_at)`,
+			`CREATE TABLE researcher_metric_snapshots (
+				id SERIAL PRIMARY KEY,
+				researcher_id INTEGER NOT NULL REFERENCES researchers(id) ON DELETE CASCADE,
+				total_citations INTEGER NOT NULL,
+				h_index INTEGER NOT NULL,
+				recent_citations INTEGER NOT NULL,
+				recent_h_index INTEGER NOT NULL,
+				local_citations INTEGER NOT NULL,
+				local_h_index INTEGER NOT NULL,
+				local_i10_index INTEGER NOT NULL,
+				recorded_at TEXT NOT NULL DEFAULT ` + pgNow + `
+			)`,
+			`CREATE INDEX idx_researcher_metric_snapshots ON researcher_metric_snapshots(researcher_id, recorded_at)`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "add_publication_attachments",
+		SQL: []string{
+			`CREATE TABLE publication_attachments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				publication_id INTEGER NOT NULL,
+				type TEXT NOT NULL,
+				title_id INTEGER NOT NULL,
+				url TEXT NOT NULL DEFAULT '',
+				file TEXT NOT NULL DEFAULT '',
+				file_name TEXT NOT NULL DEFAULT '',
+				content_type TEXT NOT NULL DEFAULT '',
+				size INTEGER NOT NULL DEFAULT 0,
+				attachment_order INTEGER NOT NULL DEFAULT 0,
+				created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE,
+				FOREIGN KEY (title_id) REFERENCES localized_strings(id)
+			)`,
+			`CREATE INDEX idx_publication_attachments ON publication_attachments(publication_id, attachment_order)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE publication_attachments (
+				id SERIAL PRIMARY KEY,
+				publication_id INTEGER NOT NULL REFERENCES publications(id) ON DELETE CASCADE,
+				type TEXT NOT NULL,
+				title_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				url TEXT NOT NULL DEFAULT '',
+				file TEXT NOT NULL DEFAULT '',
+				file_name TEXT NOT NULL DEFAULT '',
+				content_type TEXT NOT NULL DEFAULT '',
+				size BIGINT NOT NULL DEFAULT 0,
+				attachment_order INTEGER NOT NULL DEFAULT 0,
+				created_at TEXT NOT NULL DEFAULT ` + pgNow + `
+			)`,
+			`CREATE INDEX idx_publication_attachments ON publication_attachments(publication_id, attachment_order)`,
+		},
+	},
+	{
+		Version: 16,
+		Name:    "add_external_people",
+		SQL: []string{
+			`CREATE TABLE external_people (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name_id INTEGER NOT NULL,
+				orcid TEXT NOT NULL DEFAULT '',
+				affiliation_id INTEGER NOT NULL,
+				researcher_id INTEGER,
+				created_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (name_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (affiliation_id) REFERENCES localized_strings(id),
+				FOREIGN KEY (researcher_id) REFERENCES researchers(id) ON DELETE SET NULL
+			)`,
+			`CREATE TABLE external_person_aliases (
+				person_id INTEGER NOT NULL,
+				alias TEXT NOT NULL,
+				alias_key TEXT NOT NULL PRIMARY KEY,
+				FOREIGN KEY (person_id) REFERENCES external_people(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_external_person_aliases ON external_person_aliases(person_id)`,
+			`ALTER TABLE publication_external_authors ADD COLUMN person_id INTEGER REFERENCES external_people(id)`,
+			`CREATE INDEX idx_publication_external_authors_person ON publication_external_authors(person_id)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE external_people (
+				id SERIAL PRIMARY KEY,
+				name_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				orcid TEXT NOT NULL DEFAULT '',
+				affiliation_id INTEGER NOT NULL REFERENCES localized_strings(id),
+				researcher_id INTEGER REFERENCES researchers(id) ON DELETE SET NULL,
+				created_at TEXT NOT NULL DEFAULT ` + pgNow + `
+			)`,
+			`CREATE TABLE external_person_aliases (
+				person_id INTEGER NOT NULL REFERENCES external_people(id) ON DELETE CASCADE,
+				alias TEXT NOT NULL,
+				alias_key TEXT NOT NULL PRIMARY KEY
+			)`,
+			`CREATE INDEX idx_external_person_aliases ON external_person_aliases(person_id)`,
+			`ALTER TABLE publication_external_authors ADD COLUMN person_id INTEGER REFERENCES external_people(id)`,
+			`CREATE INDEX idx_publication_external_authors_person ON publication_external_authors(person_id)`,
+		},
+		Up: backfillExternalPeople,
+	},
+	{
+		Version: 17,
+		Name:    "add_crawl_jobs",
+		// Jobs keep the ID of the researcher they crawled after the researcher
+		// is deleted, so that a researcher's job never reads as a full crawl.
+		SQL: []string{
+			`CREATE TABLE crawl_jobs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				researcher_id INTEGER,
+				status TEXT NOT NULL,
+				started_by TEXT NOT NULL DEFAULT '',
+				researchers INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				started_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				finished_at TEXT
+			)`,
+			`CREATE INDEX idx_crawl_jobs_started_at ON crawl_jobs(started_at)`,
+			`CREATE TABLE crawl_job_results (
+				job_id INTEGER NOT NULL,
+				researcher_id INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				new_publications INTEGER NOT NULL DEFAULT 0,
+				updated_publications INTEGER NOT NULL DEFAULT 0,
+				errors TEXT NOT NULL DEFAULT '',
+				started_at TEXT NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				finished_at TEXT,
+				PRIMARY KEY (job_id, researcher_id),
+				FOREIGN KEY (job_id) REFERENCES crawl_jobs(id) ON DELETE CASCADE
+			)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE crawl_jobs (
+				id SERIAL PRIMARY KEY,
+				researcher_id INTEGER,
+				status TEXT NOT NULL,
+				started_by TEXT NOT NULL DEFAULT '',
+				researchers INTEGER NOT NULL DEFAULT 0,
+				error TEXT NOT NULL DEFAULT '',
+				started_at TEXT NOT NULL DEFAULT ` + pgNow + `,
+				finished_at TEXT
+			)`,
+			`CREATE INDEX idx_crawl_jobs_started_at ON crawl_jobs(started_at)`,
+			`CREATE TABLE crawl_job_results (
+				job_id INTEGER NOT NULL REFERENCES crawl_jobs(id) ON DELETE CASCADE,
+				researcher_id INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				new_publications INTEGER NOT NULL DEFAULT 0,
+				updated_publications INTEGER NOT NULL DEFAULT 0,
+				errors TEXT NOT NULL DEFAULT '',
+				started_at TEXT NOT NULL DEFAULT ` + pgNow + `,
+				finished_at TEXT,
+				PRIMARY KEY (job_id, researcher_id)
+			)`,
+		},
+	},
+	{
+		Version: 18,
+		Name:    "add_publication_title_words",
+		SQL: []string{
+			`CREATE TABLE publication_title_words (
+				publication_id INTEGER NOT NULL,
+				word TEXT NOT NULL,
+				PRIMARY KEY (publication_id, word),
+				FOREIGN KEY (publication_id) REFERENCES publications(id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_publication_title_words_word ON publication_title_words(word)`,
+		},
+		Postgres: []string{
+			`CREATE TABLE publication_title_words (
+				publication_id INTEGER NOT NULL REFERENCES publications(id) ON DELETE CASCADE,
+				word TEXT NOT NULL,
+				PRIMARY KEY (publication_id, word)
+			)`,
+			`CREATE INDEX idx_publication_title_words_word ON publication_title_words(word)`,
+		},
+		Up: backfillTitleWords,
+	},
+}
+
+// isBaselineSchema reports whether a legacy database already has every change
+// made by the old start-up upgrades, i.e. matches migration 1.
+func isBaselineSchema(q querier) (bool, error) {
+	for _, table := range []string{"project_images", "publication_external_authors", "disciplines", "discipline_researchers"} {
+		exists, err := tableExists(q, table)
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+
+	columns := [][2]string{
+		{"researchers", "last_name_id"},
+		{"researchers", "position_id"},
+		{"researchers", "total_citations"},
+		{"publications", "published_at"},
+		{"publications", "visible"},
+		{"publication_external_authors", "name_id"},
+	}
+	for _, c := range columns {
+		exists, err := columnExists(q, c[0], c[1])
+		if err != nil || !exists {
+			return false, err
+		}
+	}
+
+	hasLevel, err := columnExists(q, "disciplines", "level_id")
+	if err != nil || hasLevel {
+		return false, err
+	}
+
+	var uniqueIndexes int
+	err = q.QueryRow(`SELECT COUNT(*) FROM pragma_index_list('publications') WHERE "unique" = 1`).Scan(&uniqueIndexes)
+	return uniqueIndexes > 0, err
+}
+
+func detectTable(table string) func(q querier) (bool, error) {
+	return func(q querier) (bool, error) {
+		return tableExists(q, table)
+	}
+}
+
+func detectColumn(table, column string) func(q querier) (bool, error) {
+	return func(q querier) (bool, error) {
+		return columnExists(q, table, column)
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// legacyVenue holds the parts of a venue string written by the old Google
+// Scholar crawler.
+type legacyVenue struct {
+	venue, volume, issue, pages, publisher string
+}
+
+// splitLegacyVenue splits "Journal, Vol. 3, No. 2, pp. 10-20, Publisher" into
+// its parts. Whatever follows the labelled parts is the publisher; without
+// labelled parts the publisher cannot be told apart from the venue, so the
+// string is kept whole.
+func splitLegacyVenue(s string) legacyVenue {
+	parts := strings.Split(s, ", ")
+	first := len(parts)
+	for i, part := range parts {
+		if strings.HasPrefix(part, "Vol. ") || strings.HasPrefix(part, "No. ") || strings.HasPrefix(part, "pp. ") {
+			first = i
+			break
+		}
+	}
+
+	v := legacyVenue{venue: strings.Join(parts[:first], ", ")}
+	var publisher []string
+	for _, part := range parts[first:] {
+		switch {
+		case strings.HasPrefix(part, "Vol. ") && v.volume == "":
+			v.volume = strings.TrimPrefix(part, "Vol. ")
+		case strings.HasPrefix(part, "No. ") && v.issue == "":
+			v.issue = strings.TrimPrefix(part, "No. ")
+		case strings.HasPrefix(part, "pp. ") && v.pages == "":
+			v.pages = strings.TrimPrefix(part, "pp. ")
+		default:
+			publisher = append(publisher, part)
+		}
+	}
+	v.publisher = strings.Join(publisher, ", ")
+	return v
+}
+
+// backfillPublicationMetadata fills the structured fields of existing
+// publications: it splits their venue strings, guesses their type from the
+// venue and takes DOIs from doi.org links. The search documents of
+// publications are then rebuilt from the shorter venues.
+func backfillPublicationMetadata(tx *sql.Tx) error {
+	type publication struct {
+		id          int
+		venue, link string
+	}
+
+	rows, err := tx.Query("SELECT id, venue, link FROM publications")
+	if err != nil {
+		return err
+	}
+	var publications []publication
+	for rows.Next() {
+		var p publication
+		if err := rows.Scan(&p.id, &p.venue, &p.link); err != nil {
+			rows.Close()
+			return err
+		}
+		publications = append(publications, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	update := "UPDATE publications SET type = ?, venue = ?, volume = ?, issue = ?, pages = ?, publisher = ?, doi = ? WHERE id = ?"
+	if isPostgres() {
+		update = "UPDATE publications SET type = $1, venue = $2, volume = $3, issue = $4, pages = $5, publisher = $6, doi = $7 WHERE id = $8"
+	}
+	for _, p := range publications {
+		v := splitLegacyVenue(p.venue)
+		var doi string
+		for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/"} {
+			if strings.HasPrefix(p.link, prefix) {
+				doi = strings.TrimPrefix(p.link, prefix)
+			}
+		}
+
+		_, err := tx.Exec(update, models.GuessPublicationType(v.venue), v.venue, v.volume, v.issue, v.pages, v.publisher, doi, p.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM search_index WHERE entity = 'publication'"); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru)
+		SELECT 'publication', p.id, t.en, t.ru, p.venue || ' ' || p.abstract, p.venue || ' ' || p.abstract
+		FROM publications p
+		JOIN localized_strings t ON p.title_id = t.id`)
+	return err
+}
@@ -0,0 +1,70 @@
+package db
+
+import "testing"
+
+func TestSplitLegacyVenue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want legacyVenue
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: legacyVenue{},
+		},
+		{
+			name: "venue only",
+			in:   "Neural Computation",
+			want: legacyVenue{venue: "Neural Computation"},
+		},
+		{
+			name: "unlabelled publisher stays in the venue",
+			in:   "Neural Computation, MIT Press",
+			want: legacyVenue{venue: "Neural Computation, MIT Press"},
+		},
+		{
+			name: "all parts",
+			in:   "Neural Computation, Vol. 31, No. 4, pp. 10-20, MIT Press",
+			want: legacyVenue{venue: "Neural Computation", volume: "31", issue: "4", pages: "10-20", publisher: "MIT Press"},
+		},
+		{
+			name: "venue with commas",
+			in:   "Physics, Chemistry and Biology, Vol. 2",
+			want: legacyVenue{venue: "Physics, Chemistry and Biology", volume: "2"},
+		},
+		{
+			name: "pages only",
+			in:   "Proceedings of IJCNN, pp. 1-8",
+			want: legacyVenue{venue: "Proceedings of IJCNN", pages: "1-8"},
+		},
+		{
+			name: "publisher with commas",
+			in:   "Journal, No. 3, Springer, Berlin, Heidelberg",
+			want: legacyVenue{venue: "Journal", issue: "3", publisher: "Springer, Berlin, Heidelberg"},
+		},
+		{
+			name: "repeated label goes to the publisher",
+			in:   "Journal, Vol. 1, Vol. 2",
+			want: legacyVenue{venue: "Journal", volume: "1", publisher: "Vol. 2"},
+		},
+		{
+			name: "labelled part first",
+			in:   "Vol. 5, No. 1",
+			want: legacyVenue{volume: "5", issue: "1"},
+		},
+		{
+			name: "label without the separating space",
+			in:   "Journal, Vol.5",
+			want: legacyVenue{venue: "Journal, Vol.5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitLegacyVenue(tt.in); got != tt.want {
+				t.Errorf("splitLegacyVenue(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// backfillTitleWords indexes the title words of every existing publication for
+// the duplicate search. The repositories keep the index current from then on.
+func backfillTitleWords(tx *sql.Tx) error {
+	type title struct {
+		id     int
+		en, ru string
+	}
+
+	rows, err := tx.Query(`SELECT p.id, ls.en, ls.ru
+		FROM publications p
+		JOIN localized_strings ls ON p.title_id = ls.id
+		ORDER BY p.id`)
+	if err != nil {
+		return err
+	}
+	var titles []title
+	for rows.Next() {
+		var t title
+		if err := rows.Scan(&t.id, &t.en, &t.ru); err != nil {
+			rows.Close()
+			return err
+		}
+		titles = append(titles, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	insert := "INSERT INTO publication_title_words (publication_id, word) VALUES (?, ?)"
+	if isPostgres() {
+		insert = "INSERT INTO publication_title_words (publication_id, word) VALUES ($1, $2)"
+	}
+	for _, t := range titles {
+		for _, word := range titleWords(t.en, t.ru) {
+			if _, err := tx.Exec(insert, t.id, word); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// titleWords is duplicates.TitleWords as of migration 18, frozen so that the
+// migration indexes the same words whenever it runs. A change to
+// duplicates.TitleWords needs a migration that rebuilds the index.
+func titleWords(en, ru string) []string {
+	title := normalizeTitle(en)
+	if title == "" {
+		title = normalizeTitle(ru)
+	}
+
+	var words []string
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(title) {
+		if utf8.RuneCountInString(word) >= 3 && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// normalizeTitle is duplicates.NormalizeTitle as of migration 18: the title
+// lower-cased, without markup, and reduced to its words.
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range title {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+			b.WriteRune(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.FieldsFunc(strings.ToLower(b.String()), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
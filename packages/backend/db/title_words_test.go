@@ -0,0 +1,85 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/duplicates"
+	"github.com/damirahm/diplom/backend/models"
+)
+
+var titleWordsTitles = []models.LocalizedString{
+	{En: "The “Liquid” State Machine: a <i>survey</i> of state machines", Ru: "Машина"},
+	{En: "", Ru: "СЕТИ эхо-состояний"},
+	{En: "AI in 3D", Ru: "ИИ в 3D"},
+	{En: "", Ru: ""},
+}
+
+func TestBackfillTitleWords(t *testing.T) {
+	if err := InitDB(config.DriverSQLite, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { DB.Close() })
+
+	exec := func(query string, args ...interface{}) int64 {
+		t.Helper()
+		res, err := DB.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	var ids []int64
+	for _, title := range titleWordsTitles {
+		titleID := exec("INSERT INTO localized_strings (en, ru) VALUES (?, ?)", title.En, title.Ru)
+		ids = append(ids, exec("INSERT INTO publications (title_id, venue, published_at, link) VALUES (?, '', '2020', '')", titleID))
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillTitleWords(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("backfillTitleWords: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := DB.Query("SELECT publication_id, word FROM publication_title_words ORDER BY publication_id, word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	words := map[int64][]string{}
+	for rows.Next() {
+		var id int64
+		var word string
+		if err := rows.Scan(&id, &word); err != nil {
+			t.Fatal(err)
+		}
+		words[id] = append(words[id], word)
+	}
+	want := map[int64][]string{
+		ids[0]: {"liquid", "machine", "machines", "state", "survey", "the"},
+		ids[1]: {"сети", "состояний", "эхо"},
+	}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("title words = %v, want %v", words, want)
+	}
+}
+
+// The repositories index titles with duplicates.TitleWords. A change to it
+// that makes this test fail needs a migration rebuilding publication_title_words.
+func TestTitleWordsMatchDuplicates(t *testing.T) {
+	for _, title := range titleWordsTitles {
+		got := titleWords(title.En, title.Ru)
+		if want := duplicates.TitleWords(title); !reflect.DeepEqual(got, want) {
+			t.Errorf("titleWords(%q, %q) = %v, duplicates.TitleWords = %v", title.En, title.Ru, got, want)
+		}
+	}
+}
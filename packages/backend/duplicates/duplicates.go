@@ -0,0 +1,244 @@
+// Package duplicates finds publications that are likely the same work and
+// merges them.
+package duplicates
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/venues"
+)
+
+// MinScore is the default score a pair needs to be reported.
+const MinScore = 0.8
+
+// minTitleSimilarity is the title similarity below which a pair is never a
+// duplicate, whatever its year and authors.
+const minTitleSimilarity = 0.8
+
+// Weights of the title, year and author scores in the score of a pair.
+const (
+	titleWeight  = 0.6
+	yearWeight   = 0.2
+	authorWeight = 0.2
+)
+
+// NormalizeTitle lower-cases a title and keeps only its words, so that titles
+// differing in punctuation, case and markup compare equal.
+func NormalizeTitle(title string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range title {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+			b.WriteRune(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.FieldsFunc(strings.ToLower(b.String()), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// AuthorKey identifies an author by last name and first initial, so that
+// "I. A. Petrov", "IA Petrov" and "Ivan Petrov" agree. It is empty for names
+// without letters, such as the ellipsis ending a truncated author list.
+func AuthorKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-' && r != '\''
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	initial, _ := utf8.DecodeRuneInString(words[0])
+	return words[len(words)-1] + " " + string(initial)
+}
+
+// entry is a publication prepared for comparison.
+type entry struct {
+	pub     models.Publication
+	title   string
+	words   []string
+	year    string
+	authors map[string]bool
+}
+
+// newEntry compares publications by their English title and author names,
+// falling back to the Russian ones for publications that only have those.
+func newEntry(pub models.Publication) entry {
+	e := entry{pub: pub, title: comparedTitle(pub.Title), words: TitleWords(pub.Title), authors: map[string]bool{}}
+	if len(pub.PublishedAt) >= 4 {
+		e.year = pub.PublishedAt[:4]
+	}
+	for _, author := range pub.Authors {
+		if key := AuthorKey(authorName(author)); key != "" {
+			e.authors[key] = true
+		}
+	}
+	return e
+}
+
+// comparedTitle is the normalized English title, or the Russian one when the
+// English title is empty.
+func comparedTitle(title models.LocalizedString) string {
+	if normalized := NormalizeTitle(title.En); normalized != "" {
+		return normalized
+	}
+	return NormalizeTitle(title.Ru)
+}
+
+// TitleWords returns the distinct words of at least three letters of the title
+// Find compares, in order. Find only scores pairs sharing a DOI or at least
+// half of the words of the shorter list.
+func TitleWords(title models.LocalizedString) []string {
+	var words []string
+	for _, word := range strings.Fields(comparedTitle(title)) {
+		if utf8.RuneCountInString(word) >= 3 && !slices.Contains(words, word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// authorName is the English name of an author, or the Russian one when it has
+// no English name.
+func authorName(author models.Author) string {
+	if author.Name.En != "" {
+		return author.Name.En
+	}
+	return author.Name.Ru
+}
+
+// Find returns the pairs of publications scoring at least minScore, best
+// first. Only pairs sharing a DOI or at least half of the TitleWords of the
+// shorter title are scored.
+func Find(publications []models.Publication, minScore float64) []models.DuplicateCandidate {
+	entries := make([]entry, len(publications))
+	byWord := map[string][]int{}
+	byDOI := map[string][]int{}
+	for i, pub := range publications {
+		entries[i] = newEntry(pub)
+		for _, word := range entries[i].words {
+			byWord[word] = append(byWord[word], i)
+		}
+		if pub.DOI != "" {
+			doi := strings.ToLower(pub.DOI)
+			byDOI[doi] = append(byDOI[doi], i)
+		}
+	}
+
+	candidates := []models.DuplicateCandidate{}
+	for i := range entries {
+		shared := map[int]int{}
+		for _, word := range entries[i].words {
+			for _, j := range byWord[word] {
+				if j > i {
+					shared[j]++
+				}
+			}
+		}
+		if doi := strings.ToLower(entries[i].pub.DOI); doi != "" {
+			for _, j := range byDOI[doi] {
+				if j > i {
+					shared[j] += len(entries[i].words) + len(entries[j].words)
+				}
+			}
+		}
+
+		for j, n := range shared {
+			if 2*n < min(len(entries[i].words), len(entries[j].words)) {
+				continue
+			}
+			candidate, ok := score(entries[i], entries[j])
+			if ok && candidate.Score >= minScore {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].Score != candidates[b].Score {
+			return candidates[a].Score > candidates[b].Score
+		}
+		if candidates[a].First.ID != candidates[b].First.ID {
+			return candidates[a].First.ID < candidates[b].First.ID
+		}
+		return candidates[a].Second.ID < candidates[b].Second.ID
+	})
+	return candidates
+}
+
+// Score compares two publications. It reports false when they cannot be the
+// same work: their titles differ too much or they have different DOIs.
+func Score(a, b models.Publication) (models.DuplicateCandidate, bool) {
+	return score(newEntry(a), newEntry(b))
+}
+
+func score(a, b entry) (models.DuplicateCandidate, bool) {
+	first, second := a, b
+	if second.pub.ID < first.pub.ID {
+		first, second = second, first
+	}
+	candidate := models.DuplicateCandidate{First: first.pub, Second: second.pub}
+
+	sameDOI := false
+	if a.pub.DOI != "" && b.pub.DOI != "" {
+		if !strings.EqualFold(a.pub.DOI, b.pub.DOI) {
+			return candidate, false
+		}
+		sameDOI = true
+	}
+
+	candidate.TitleScore = venues.Similarity(a.title, b.title)
+	if candidate.TitleScore < minTitleSimilarity && !sameDOI {
+		return candidate, false
+	}
+
+	switch {
+	case a.year == "" || b.year == "":
+		candidate.YearScore = 0.5
+	case a.year == b.year:
+		candidate.YearScore = 1
+	case yearsApart(a.year, b.year) == 1:
+		candidate.YearScore = 0.5
+	}
+
+	if len(a.authors) == 0 || len(b.authors) == 0 {
+		candidate.AuthorScore = 0.5
+	} else {
+		common := 0
+		for key := range a.authors {
+			if b.authors[key] {
+				common++
+			}
+		}
+		candidate.AuthorScore = float64(common) / float64(min(len(a.authors), len(b.authors)))
+	}
+
+	candidate.Score = titleWeight*candidate.TitleScore + yearWeight*candidate.YearScore + authorWeight*candidate.AuthorScore
+	if sameDOI {
+		candidate.Score = 1
+	}
+	return candidate, true
+}
+
+func yearsApart(a, b string) int {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	if x > y {
+		return x - y
+	}
+	return y - x
+}
@@ -0,0 +1,64 @@
+package duplicates
+
+import (
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestFindRussianTitles(t *testing.T) {
+	publications := []models.Publication{
+		{
+			ID:          1,
+			Title:       models.LocalizedString{Ru: "Импульсные нейронные сети для распознавания речи"},
+			Authors:     []models.Author{{Name: models.LocalizedString{Ru: "Иван Петров"}}},
+			PublishedAt: "2020-05-01",
+		},
+		{
+			ID:          2,
+			Title:       models.LocalizedString{Ru: "Импульсные нейронные сети для распознавания речи."},
+			Authors:     []models.Author{{Name: models.LocalizedString{Ru: "И. Петров"}}},
+			PublishedAt: "2020-01-01",
+		},
+		{
+			ID:          3,
+			Title:       models.LocalizedString{Ru: "Мемристорные кроссбары"},
+			PublishedAt: "2020-01-01",
+		},
+	}
+
+	candidates := Find(publications, MinScore)
+	if len(candidates) != 1 {
+		t.Fatalf("Find returned %d pairs, want 1: %+v", len(candidates), candidates)
+	}
+	got := candidates[0]
+	if got.First.ID != 1 || got.Second.ID != 2 {
+		t.Errorf("pair = %d, %d, want 1, 2", got.First.ID, got.Second.ID)
+	}
+	if got.TitleScore != 1 || got.YearScore != 1 || got.AuthorScore != 1 {
+		t.Errorf("scores = title %v, year %v, authors %v, want 1 each", got.TitleScore, got.YearScore, got.AuthorScore)
+	}
+}
+
+func TestMergeRussianAuthors(t *testing.T) {
+	survivor := models.Publication{
+		ID:      1,
+		Title:   models.LocalizedString{Ru: "Импульсные нейронные сети"},
+		Authors: []models.Author{{Name: models.LocalizedString{Ru: "Иван Петров"}}},
+	}
+	duplicate := models.Publication{
+		ID: 2,
+		Authors: []models.Author{
+			{Name: models.LocalizedString{Ru: "И. Петров"}},
+			{Name: models.LocalizedString{Ru: "Анна Сидорова"}},
+		},
+	}
+
+	merged := Merge(survivor, duplicate)
+	if len(merged.Authors) != 2 {
+		t.Fatalf("merged authors = %+v, want Петров and Сидорова", merged.Authors)
+	}
+	if merged.Authors[0].Name.Ru != "Иван Петров" || merged.Authors[1].Name.Ru != "Анна Сидорова" {
+		t.Errorf("merged authors = %+v, want Иван Петров, Анна Сидорова", merged.Authors)
+	}
+}
@@ -0,0 +1,82 @@
+package duplicates
+
+import "github.com/damirahm/diplom/backend/models"
+
+// Merge returns survivor completed with what duplicate adds to it. Fields the
+// survivor lacks are taken from the duplicate, the citation count is the
+// higher of the two, since both count the same work, and the merged record is
+// visible if either was. The authors of the duplicate missing from the
+// survivor are added, and a researcher replaces the external author with the
+// same name, keeping its role.
+func Merge(survivor, duplicate models.Publication) models.Publication {
+	merged := survivor
+
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&merged.Link, duplicate.Link)
+	fill(&merged.PublishedAt, duplicate.PublishedAt)
+	fill(&merged.Type, duplicate.Type)
+	if merged.Venue == "" {
+		merged.Venue, merged.VenueID = duplicate.Venue, duplicate.VenueID
+	}
+	if merged.VenueID == nil && merged.Venue == duplicate.Venue {
+		merged.VenueID = duplicate.VenueID
+	}
+	fill(&merged.Volume, duplicate.Volume)
+	fill(&merged.Issue, duplicate.Issue)
+	fill(&merged.Pages, duplicate.Pages)
+	fill(&merged.Publisher, duplicate.Publisher)
+	fill(&merged.DOI, duplicate.DOI)
+	fill(&merged.Abstract, duplicate.Abstract)
+
+	merged.CitationsCount = max(survivor.CitationsCount, duplicate.CitationsCount)
+	merged.Visible = survivor.Visible || duplicate.Visible
+	merged.Authors = mergeAuthors(survivor.Authors, duplicate.Authors)
+	return merged
+}
+
+func mergeAuthors(survivor, duplicate []models.Author) []models.Author {
+	authors := make([]models.Author, 0, len(survivor)+len(duplicate))
+	keys := map[string]int{}
+	researchers := map[int]bool{}
+	for _, author := range survivor {
+		if key := AuthorKey(authorName(author)); key != "" {
+			keys[key] = len(authors)
+		}
+		if author.ID != nil {
+			researchers[*author.ID] = true
+		}
+		authors = append(authors, author)
+	}
+
+	for _, author := range duplicate {
+		if author.ID != nil && researchers[*author.ID] {
+			continue
+		}
+		key := AuthorKey(authorName(author))
+		if key == "" {
+			continue
+		}
+		if i, ok := keys[key]; ok {
+			if author.ID != nil && authors[i].ID == nil {
+				if author.Role == "" {
+					author.Role = authors[i].Role
+				}
+				authors[i] = author
+				researchers[*author.ID] = true
+			} else if authors[i].Role == "" {
+				authors[i].Role = author.Role
+			}
+			continue
+		}
+		keys[key] = len(authors)
+		if author.ID != nil {
+			researchers[*author.ID] = true
+		}
+		authors = append(authors, author)
+	}
+	return authors
+}
@@ -12,6 +12,7 @@ require (
 	github.com/go-openapi/swag v0.23.0 // indirect
 	github.com/gorilla/mux v1.8.1
 	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
 	github.com/mattn/go-sqlite3 v1.14.24
@@ -24,6 +25,7 @@ require (
 require (
 	github.com/PuerkitoBio/goquery v1.10.2
 	github.com/swaggo/http-swagger v1.3.4
+	golang.org/x/crypto v0.36.0
 )
 
 require (
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxAttachmentUpload matches the request size nginx lets through.
+const maxAttachmentUpload = 32 << 20
+
+type AttachmentHandler struct {
+	attachmentRepo  repository.AttachmentRepo
+	publicationRepo repository.PublicationRepo
+	files           *FileHandler
+	auditLog        *audit.Logger
+}
+
+func NewAttachmentHandler(ar repository.AttachmentRepo, pr repository.PublicationRepo, fh *FileHandler, al *audit.Logger) *AttachmentHandler {
+	return &AttachmentHandler{attachmentRepo: ar, publicationRepo: pr, files: fh, auditLog: al}
+}
+
+// GetAttachments godoc
+// @Summary Get the attachments of a publication
+// @Description Get the files and external resources attached to a publication, hidden or not, in display order
+// @Tags publications
+// @Produce json
+// @Param id path int true "Publication ID"
+// @Success 200 {array} models.PublicationAttachment
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Publication not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/attachments [get]
+func (h *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid publication ID", err)
+		return
+	}
+	if _, ok := h.fetchPublication(w, id, false); !ok {
+		return
+	}
+	h.writeAttachments(w, id)
+}
+
+// GetPublicAttachments godoc
+// @Summary Get the attachments of a visible publication
+// @Description Get the files and external resources attached to a visible publication, in display order
+// @Tags publications
+// @Produce json
+// @Param id path int true "Publication ID"
+// @Success 200 {array} models.PublicationAttachment
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Publication not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/attachments/public [get]
+func (h *AttachmentHandler) GetPublicAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid publication ID", err)
+		return
+	}
+	if _, ok := h.fetchPublication(w, id, true); !ok {
+		return
+	}
+	h.writeAttachments(w, id)
+}
+
+func (h *AttachmentHandler) writeAttachments(w http.ResponseWriter, publicationID int) {
+	attachments, err := h.attachmentRepo.GetByPublication(publicationID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch attachments", err)
+		return
+	}
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// DownloadAttachment godoc
+// @Summary Download an attachment
+// @Description Download an uploaded attachment of a visible publication, or get redirected to an external resource
+// @Tags publications
+// @Produce octet-stream
+// @Param id path int true "Publication ID"
+// @Param attachmentId path int true "Attachment ID"
+// @Success 200 {file} file "Attachment file"
+// @Success 302 "Redirect to the external resource"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/attachments/{attachmentId}/download [get]
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, attachmentID, err := attachmentIDsFromPath(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if _, ok := h.fetchPublication(w, id, true); !ok {
+		return
+	}
+	attachment, ok := h.fetchAttachment(w, id, attachmentID)
+	if !ok {
+		return
+	}
+
+	if attachment.File == "" {
+		http.Redirect(w, r, attachment.URL, http.StatusFound)
+		return
+	}
+	h.files.ServeAttachment(w, r, attachment.File, attachment.FileName, attachment.ContentType)
+}
+
+// CreateAttachment godoc
+// @Summary Attach a file or external resource to a publication
+// @Description Upload a file as multipart/form-data, or post JSON with the url of an external resource.
+// @Description The title of an uploaded file defaults to its name.
+// @Tags publications
+// @Accept multipart/form-data,json
+// @Produce json
+// @Param id path int true "Publication ID"
+// @Param file formData file false "File to upload"
+// @Param type formData string false "Attachment type" Enums(preprint, slides, code, dataset, supplementary, other)
+// @Param titleEn formData string false "English title"
+// @Param titleRu formData string false "Russian title"
+// @Param order formData int false "Position among the attachments"
+// @Param attachment body models.PublicationAttachment false "External resource"
+// @Success 201 {object} models.PublicationAttachment
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Publication not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/attachments [post]
+func (h *AttachmentHandler) CreateAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid publication ID", err)
+		return
+	}
+	if _, ok := h.fetchPublication(w, id, false); !ok {
+		return
+	}
+
+	var attachment models.PublicationAttachment
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentUpload)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "A file is required in the file field", err)
+			return
+		}
+		defer file.Close()
+
+		attachment.Type = r.FormValue("type")
+		attachment.Title = models.LocalizedString{En: r.FormValue("titleEn"), Ru: r.FormValue("titleRu")}
+		if val := r.FormValue("order"); val != "" {
+			if attachment.Order, err = strconv.Atoi(val); err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid order", err)
+				return
+			}
+		}
+		attachment.FileName = filepath.Base(header.Filename)
+		if err := normalizeAttachment(&attachment); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+
+		attachment.ContentType = header.Header.Get("Content-Type")
+		if attachment.ContentType == "" || attachment.ContentType == "application/octet-stream" {
+			attachment.ContentType = mime.TypeByExtension(filepath.Ext(attachment.FileName))
+		}
+		attachment.File, attachment.Size, err = h.files.SaveAttachment(file, attachment.FileName)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to save file", err)
+			return
+		}
+	} else {
+		if err := json.NewDecoder(r.Body).Decode(&attachment); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+		attachment.FileName, attachment.ContentType, attachment.Size = "", "", 0
+		if err := normalizeAttachment(&attachment); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	attachment.PublicationID = id
+
+	attachmentID, err := h.attachmentRepo.Create(attachment)
+	if err != nil {
+		if attachment.File != "" {
+			os.Remove(attachment.File)
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create attachment", err)
+		return
+	}
+	created, err := h.attachmentRepo.GetByID(int(attachmentID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch attachment", err)
+		return
+	}
+
+	h.auditLog.Record(auditActor(r), models.AuditEntityAttachment, created.ID, models.AuditActionCreate, nil, created)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdateAttachment godoc
+// @Summary Update an attachment
+// @Description Update the type, title and position of an attachment, and the url of an external resource.
+// @Description The file of an uploaded attachment cannot be replaced; delete it and upload a new one instead.
+// @Tags publications
+// @Accept json
+// @Produce json
+// @Param id path int true "Publication ID"
+// @Param attachmentId path int true "Attachment ID"
+// @Param attachment body models.PublicationAttachment true "Attachment"
+// @Success 200 {object} models.PublicationAttachment
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/attachments/{attachmentId} [put]
+func (h *AttachmentHandler) UpdateAttachment(w http.ResponseWriter, r *http.Request) {
+	id, attachmentID, err := attachmentIDsFromPath(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	before, ok := h.fetchAttachment(w, id, attachmentID)
+	if !ok {
+		return
+	}
+
+	var attachment models.PublicationAttachment
+	if err := json.NewDecoder(r.Body).Decode(&attachment); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	attachment.ID, attachment.PublicationID, attachment.CreatedAt = before.ID, before.PublicationID, before.CreatedAt
+	attachment.File, attachment.FileName, attachment.ContentType, attachment.Size = before.File, before.FileName, before.ContentType, before.Size
+	if attachment.File != "" {
+		attachment.URL = ""
+	}
+	if err := normalizeAttachment(&attachment); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.attachmentRepo.Update(attachment); err != nil {
+		if err.Error() == "attachment not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Attachment not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update attachment", err)
+		return
+	}
+
+	h.auditLog.Record(auditActor(r), models.AuditEntityAttachment, attachment.ID, models.AuditActionUpdate, before, attachment)
+
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DeleteAttachment godoc
+// @Summary Delete an attachment
+// @Description Delete an attachment of a publication along with its uploaded file
+// @Tags publications
+// @Param id path int true "Publication ID"
+// @Param attachmentId path int true "Attachment ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/attachments/{attachmentId} [delete]
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, attachmentID, err := attachmentIDsFromPath(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	before, ok := h.fetchAttachment(w, id, attachmentID)
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentRepo.Delete(attachmentID); err != nil {
+		if err.Error() == "attachment not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Attachment not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete attachment", err)
+		return
+	}
+
+	h.auditLog.Record(auditActor(r), models.AuditEntityAttachment, attachmentID, models.AuditActionDelete, before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fetchPublication gets a publication, responding with an error and reporting
+// false when it cannot. With visibleOnly, hidden publications are not found
+// either.
+func (h *AttachmentHandler) fetchPublication(w http.ResponseWriter, id int, visibleOnly bool) (*models.Publication, bool) {
+	pub, err := h.publicationRepo.GetByID(id)
+	if err != nil && err != sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publication", err)
+		return nil, false
+	}
+	if pub == nil || (visibleOnly && !pub.Visible) {
+		utils.RespondWithError(w, http.StatusNotFound, "Publication not found", err)
+		return nil, false
+	}
+	return pub, true
+}
+
+// fetchAttachment gets an attachment of a publication, responding with an
+// error and reporting false when it cannot.
+func (h *AttachmentHandler) fetchAttachment(w http.ResponseWriter, publicationID, id int) (*models.PublicationAttachment, bool) {
+	attachment, err := h.attachmentRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "attachment not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Attachment not found", err)
+			return nil, false
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch attachment", err)
+		return nil, false
+	}
+	if attachment.PublicationID != publicationID {
+		utils.RespondWithError(w, http.StatusNotFound, "Attachment not found", nil)
+		return nil, false
+	}
+	return attachment, true
+}
+
+func attachmentIDsFromPath(r *http.Request) (int, int, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid publication ID")
+	}
+	attachmentID, err := strconv.Atoi(vars["attachmentId"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid attachment ID")
+	}
+	return id, attachmentID, nil
+}
+
+// normalizeAttachment validates the type of an attachment and the URL of an
+// external resource, and defaults its title to the file name or URL.
+func normalizeAttachment(attachment *models.PublicationAttachment) error {
+	if attachment.Type == "" {
+		attachment.Type = models.AttachmentTypeOther
+	}
+	if !models.IsValidAttachmentType(attachment.Type) {
+		return fmt.Errorf("invalid attachment type %q", attachment.Type)
+	}
+
+	if attachment.FileName == "" {
+		attachment.URL = strings.TrimSpace(attachment.URL)
+		u, err := url.Parse(attachment.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https address")
+		}
+	}
+
+	attachment.Title.En = strings.TrimSpace(attachment.Title.En)
+	attachment.Title.Ru = strings.TrimSpace(attachment.Title.Ru)
+	name := attachment.FileName
+	if name == "" {
+		name = attachment.URL
+	}
+	if attachment.Title.En == "" {
+		attachment.Title.En = name
+	}
+	if attachment.Title.Ru == "" {
+		attachment.Title.Ru = attachment.Title.En
+	}
+	return nil
+}
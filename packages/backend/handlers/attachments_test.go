@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/db"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/gorilla/mux"
+)
+
+func TestDownloadAttachment(t *testing.T) {
+	dir := t.TempDir()
+	if err := db.InitDB(config.DriverSQLite, filepath.Join(dir, "test.db")); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	repos := repository.New(config.DriverSQLite, db.DB)
+
+	createPublication := func(title string, visible bool) int {
+		pub := models.Publication{Title: models.LocalizedString{En: title, Ru: title + " ru"}}
+		id, err := repos.Publications.Create(pub)
+		if err != nil {
+			t.Fatalf("failed to create publication: %v", err)
+		}
+		pub.ID, pub.Visible = int(id), visible
+		if err := repos.Publications.Update(pub); err != nil {
+			t.Fatalf("failed to update publication: %v", err)
+		}
+		return pub.ID
+	}
+	visible := createPublication("Visible", true)
+	hidden := createPublication("Hidden", false)
+
+	file := filepath.Join(dir, "stored.pdf")
+	if err := os.WriteFile(file, []byte("%PDF preprint"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	createAttachment := func(a models.PublicationAttachment) int {
+		id, err := repos.Attachments.Create(a)
+		if err != nil {
+			t.Fatalf("failed to create attachment: %v", err)
+		}
+		return int(id)
+	}
+	preprint := createAttachment(models.PublicationAttachment{PublicationID: visible, Type: models.AttachmentTypePreprint,
+		File: file, FileName: "preprint.pdf", ContentType: "application/pdf", Size: 13})
+	code := createAttachment(models.PublicationAttachment{PublicationID: visible, Type: models.AttachmentTypeCode,
+		URL: "https://example.org/code"})
+	secret := createAttachment(models.PublicationAttachment{PublicationID: hidden, Type: models.AttachmentTypePreprint,
+		File: file, FileName: "secret.pdf", ContentType: "application/pdf", Size: 13})
+
+	h := NewAttachmentHandler(repos.Attachments, repos.Publications, &FileHandler{uploadsDir: dir, attachmentsDir: dir}, nil)
+	router := mux.NewRouter()
+	router.HandleFunc("/publications/{id}/attachments/public", h.GetPublicAttachments)
+	router.HandleFunc("/publications/{id}/attachments/{attachmentId}/download", h.DownloadAttachment)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		header string
+		value  string
+	}{
+		{"file", fmt.Sprintf("/publications/%d/attachments/%d/download", visible, preprint),
+			http.StatusOK, "Content-Disposition", `attachment; filename=preprint.pdf`},
+		{"external resource", fmt.Sprintf("/publications/%d/attachments/%d/download", visible, code),
+			http.StatusFound, "Location", "https://example.org/code"},
+		{"hidden publication", fmt.Sprintf("/publications/%d/attachments/%d/download", hidden, secret),
+			http.StatusNotFound, "", ""},
+		{"attachment of another publication", fmt.Sprintf("/publications/%d/attachments/%d/download", visible, secret),
+			http.StatusNotFound, "", ""},
+		{"unknown attachment", fmt.Sprintf("/publications/%d/attachments/999/download", visible),
+			http.StatusNotFound, "", ""},
+		{"invalid attachment ID", fmt.Sprintf("/publications/%d/attachments/x/download", visible),
+			http.StatusBadRequest, "", ""},
+		{"list of a hidden publication", fmt.Sprintf("/publications/%d/attachments/public", hidden),
+			http.StatusNotFound, "", ""},
+		{"list of a visible publication", fmt.Sprintf("/publications/%d/attachments/public", visible),
+			http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.header != "" && rec.Header().Get(tt.header) != tt.value {
+				t.Errorf("%s = %q, want %q", tt.header, rec.Header().Get(tt.header), tt.value)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/publications/%d/attachments/%d/download", visible, preprint), nil))
+	if body, _ := io.ReadAll(rec.Body); string(body) != "%PDF preprint" || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Errorf("download = %q as %q, want the stored PDF", body, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestNormalizeAttachment(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      models.PublicationAttachment
+		want    models.PublicationAttachment
+		wantErr bool
+	}{
+		{
+			name: "file defaults",
+			in:   models.PublicationAttachment{FileName: "slides.pdf"},
+			want: models.PublicationAttachment{FileName: "slides.pdf", Type: models.AttachmentTypeOther,
+				Title: models.LocalizedString{En: "slides.pdf", Ru: "slides.pdf"}},
+		},
+		{
+			name: "external resource",
+			in: models.PublicationAttachment{Type: models.AttachmentTypeDataset, URL: " https://example.org/data ",
+				Title: models.LocalizedString{En: " Data "}},
+			want: models.PublicationAttachment{Type: models.AttachmentTypeDataset, URL: "https://example.org/data",
+				Title: models.LocalizedString{En: "Data", Ru: "Data"}},
+		},
+		{name: "unknown type", in: models.PublicationAttachment{Type: "poster", URL: "https://example.org"}, wantErr: true},
+		{name: "no URL", in: models.PublicationAttachment{}, wantErr: true},
+		{name: "not HTTP", in: models.PublicationAttachment{URL: "javascript:alert(1)"}, wantErr: true},
+		{name: "relative URL", in: models.PublicationAttachment{URL: "/files/a.pdf"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in
+			err := normalizeAttachment(&got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeAttachment(%+v) error = %v, want error: %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("normalizeAttachment(%+v) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+)
+
+// Default and maximum number of audit log entries returned at once.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditHandler struct {
+	auditRepo repository.AuditRepo
+}
+
+func NewAuditHandler(ar repository.AuditRepo) *AuditHandler {
+	return &AuditHandler{auditRepo: ar}
+}
+
+// auditActor returns the audit actor for the authenticated user of the request.
+func auditActor(r *http.Request) audit.Actor {
+	user := UserFromContext(r.Context())
+	if user == nil {
+		return audit.Actor{Name: "anonymous"}
+	}
+
+	actor := audit.Actor{UserID: &user.ID, Name: user.Username}
+	if token := APITokenFromContext(r.Context()); token != nil {
+		actor.Name += " (token: " + token.Name + ")"
+	}
+	return actor
+}
+
+// GetAuditLog godoc
+// @Summary Get the audit log
+// @Description Get recorded content changes, newest first
+// @Tags audit
+// @Produce json
+// @Param entity query string false "Entity type, e.g. publication"
+// @Param entityId query int false "Entity ID"
+// @Param actorId query int false "ID of the user who made the change"
+// @Param actor query string false "Actor name, e.g. crawler"
+// @Param action query string false "create, update or delete"
+// @Param from query string false "Earliest time, YYYY-MM-DD HH:MM:SS (UTC)"
+// @Param to query string false "Latest time, YYYY-MM-DD HH:MM:SS (UTC)"
+// @Param limit query int false "Maximum number of entries, at least 1 (default 100; larger values are capped at 1000)"
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /audit [get]
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Entity: query.Get("entity"),
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Limit:  defaultAuditLimit,
+	}
+
+	intParams := map[string]*int{
+		"entityId": &filter.EntityID,
+		"actorId":  &filter.ActorID,
+		"limit":    &filter.Limit,
+		"offset":   &filter.Offset,
+	}
+	for name, target := range intParams {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid "+name, err)
+			return
+		}
+		*target = n
+	}
+	// The default limit is positive, so a zero limit was asked for.
+	if filter.Limit == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit", nil)
+		return
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	entries, err := h.auditRepo.GetAll(filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch audit log", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/db"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+)
+
+func TestGetAuditLogLimit(t *testing.T) {
+	if err := db.InitDB(config.DriverSQLite, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	repos := repository.New(config.DriverSQLite, db.DB)
+
+	for i := 1; i <= 3; i++ {
+		_, err := repos.Audit.Create(models.AuditEntry{Actor: "crawler", Entity: "publication", EntityID: i, Action: "create"})
+		if err != nil {
+			t.Fatalf("failed to create audit entry: %v", err)
+		}
+	}
+	h := NewAuditHandler(repos.Audit)
+
+	tests := []struct {
+		query   string
+		status  int
+		entries int
+	}{
+		{"", http.StatusOK, 3},
+		{"?limit=2", http.StatusOK, 2},
+		{"?limit=5000", http.StatusOK, 3},
+		{"?limit=0", http.StatusBadRequest, 0},
+		{"?limit=-1", http.StatusBadRequest, 0},
+		{"?limit=ten", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.GetAuditLog(rec, httptest.NewRequest(http.MethodGet, "/audit"+tt.query, nil))
+		if rec.Code != tt.status {
+			t.Errorf("GET /audit%s: status = %d, want %d", tt.query, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var entries []models.AuditEntry
+		if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+			t.Fatalf("GET /audit%s: %v", tt.query, err)
+		}
+		if len(entries) != tt.entries {
+			t.Errorf("GET /audit%s returned %d entries, want %d", tt.query, len(entries), tt.entries)
+		}
+	}
+}
@@ -4,31 +4,96 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
 )
 
 type contextKey string
 
-const IsAdminKey contextKey = "isAdmin"
+const (
+	UserKey    contextKey = "user"
+	RoleKey    contextKey = "role"
+	SessionKey contextKey = "session"
+	TokenKey   contextKey = "api_token"
+)
+
+const sessionTokenBytes = 32
+
+// CSRFHeaderName is the header in which clients echo the CSRF token issued at login.
+const CSRFHeaderName = "X-CSRF-Token"
 
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
 type AuthHandler struct {
-	config *config.Config
+	config      *config.Config
+	userRepo    repository.UserRepo
+	sessionRepo repository.SessionRepo
+	loginGuard  *loginGuard
+	auditLog    *audit.Logger
 }
 
-func NewAuthHandler(config *config.Config) *AuthHandler {
+func NewAuthHandler(
+	config *config.Config,
+	userRepo repository.UserRepo,
+	sessionRepo repository.SessionRepo,
+	loginAttemptRepo repository.LoginAttemptRepo,
+	auditLog *audit.Logger,
+) *AuthHandler {
 	return &AuthHandler{
-		config: config,
+		config:      config,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		loginGuard:  &loginGuard{config: config.Auth.Login, repo: loginAttemptRepo},
+		auditLog:    auditLog,
 	}
 }
 
+// UserFromContext returns the authenticated user stored by AuthMiddleware, or nil.
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(UserKey).(*models.User)
+	return user
+}
+
+// SessionFromContext returns the session the request was authenticated with, or nil.
+func SessionFromContext(ctx context.Context) *models.Session {
+	session, _ := ctx.Value(SessionKey).(*models.Session)
+	return session
+}
+
+// APITokenFromContext returns the API token the request was authenticated with, or nil.
+func APITokenFromContext(ctx context.Context) *models.APIToken {
+	token, _ := ctx.Value(TokenKey).(*models.APIToken)
+	return token
+}
+
+// RoleFromContext returns the role of the authenticated user, or an empty string.
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role
+}
+
+// dummyPasswordHash is a bcrypt hash at the default cost. Login checks the
+// password given for an unknown username against it and fails either way, so
+// that the response time does not reveal which usernames exist.
+const dummyPasswordHash = "$2a$10$leRWVm/mUuUyRxapdjGLZeF5rsHQcPUsl7dOl474UN1y6UHJZ.a3K"
+
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -36,27 +101,137 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	usernameMatch := subtle.ConstantTimeCompare([]byte(req.Username), []byte(h.config.Auth.AdminUsername)) == 1
-	passwordMatch := subtle.ConstantTimeCompare([]byte(req.Password), []byte(h.config.Auth.AdminPassword)) == 1
+	ip := utils.ClientIP(r)
+	userKey, ipKey := loginKeys(req.Username, ip)
+	defer h.loginGuard.lock(userKey, ipKey)()
+
+	wait, err := h.loginGuard.retryAfter(userKey, ipKey)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check login attempts", err)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	user, err := h.userRepo.GetByUsername(req.Username)
+	if err != nil {
+		if err.Error() != "user not found" {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch user", err)
+			return
+		}
+		utils.CheckPassword(dummyPasswordHash, req.Password)
+		h.loginFailed(w, r, req.Username, nil)
+		return
+	}
+
+	if !utils.CheckPassword(user.PasswordHash, req.Password) {
+		h.loginFailed(w, r, req.Username, user)
+		return
+	}
+
+	h.loginGuard.reset(userKey, ipKey)
+
+	if err := h.sessionRepo.DeleteExpired(); err != nil {
+		log.Printf("Failed to delete expired sessions: %v", err)
+	}
+
+	token, err := utils.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create session", err)
+		return
+	}
+
+	csrfToken, err := utils.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create session", err)
+		return
+	}
 
-	if !usernameMatch || !passwordMatch {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	expiresAt := time.Now().UTC().Add(h.config.Auth.SessionDuration)
+	_, err = h.sessionRepo.Create(models.Session{
+		UserID:        user.ID,
+		ExpiresAt:     expiresAt.Format(models.TimestampLayout),
+		IP:            ip,
+		UserAgent:     r.UserAgent(),
+		CSRFTokenHash: utils.HashToken(csrfToken),
+	}, utils.HashToken(token))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create session", err)
 		return
 	}
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     h.config.Auth.CookieName,
-		Value:    "true",
+		Value:    token,
 		Path:     "/",
-		MaxAge:   int(24 * time.Hour.Seconds()),
+		Expires:  expiresAt,
+		MaxAge:   int(h.config.Auth.SessionDuration.Seconds()),
 		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// The CSRF cookie is readable by scripts so the frontend can echo it in
+	// the CSRFHeaderName header; a cross-site page cannot read it.
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.config.Auth.CSRFCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   int(h.config.Auth.SessionDuration.Seconds()),
+		SameSite: http.SameSiteLaxMode,
 	})
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Login successful"})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Login successful",
+		"user":      user,
+		"csrfToken": csrfToken,
+	})
+}
+
+// loginFailed counts a failed login against the username and client IP,
+// records it in the log and the audit log and rejects the request.
+func (h *AuthHandler) loginFailed(w http.ResponseWriter, r *http.Request, username string, user *models.User) {
+	ip := utils.ClientIP(r)
+	userKey, ipKey := loginKeys(username, ip)
+
+	failures, err := h.loginGuard.fail(userKey, h.config.Auth.Login.MaxUserFailures)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+		return
+	}
+	if _, err := h.loginGuard.fail(ipKey, h.config.Auth.Login.MaxIPFailures); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to record login attempt", err)
+		return
+	}
+
+	log.Printf("Failed login for %q from %s (%d consecutive failures)", username, ip, failures)
+
+	entityID := 0
+	if user != nil {
+		entityID = user.ID
+	}
+	h.auditLog.Record(audit.Actor{Name: ip}, models.AuditEntityLogin, entityID, models.AuditActionLoginFailed, nil, map[string]interface{}{
+		"username":  username,
+		"ip":        ip,
+		"userAgent": r.UserAgent(),
+		"failures":  failures,
+	})
+
+	http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 }
 
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(h.config.Auth.CookieName); err == nil && cookie.Value != "" {
+		if err := h.sessionRepo.DeleteByTokenHash(utils.HashToken(cookie.Value)); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke session", err)
+			return
+		}
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:  h.config.Auth.CookieName,
 		Value: "",
@@ -65,12 +240,96 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		MaxAge:   -1,
 		HttpOnly: true,
 	})
+	http.SetCookie(w, &http.Cookie{
+		Name:   h.config.Auth.CSRFCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Logout successful"})
 }
 
-func AuthMiddleware(config *config.Config) func(http.Handler) http.Handler {
+// Me godoc
+// @Summary Get the current user
+// @Description Get the account of the authenticated user
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 401 {string} string "Authentication required"
+// @Router /auth/me [get]
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ChangePassword godoc
+// @Summary Change the current user's password
+// @Description Change the password of the authenticated user and revoke their other sessions
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Invalid current password"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /auth/password [post]
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request format", err)
+		return
+	}
+
+	if !utils.CheckPassword(user.PasswordHash, req.CurrentPassword) {
+		http.Error(w, "Invalid current password", http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to hash password", err)
+		return
+	}
+
+	if err := h.userRepo.UpdatePassword(user.ID, passwordHash); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update password", err)
+		return
+	}
+
+	if session := SessionFromContext(r.Context()); session != nil {
+		if err := h.sessionRepo.DeleteOthers(user.ID, session.ID); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke other sessions", err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed"})
+}
+
+// AuthMiddleware authenticates requests by an "Authorization: Bearer" API token
+// or, failing that, by the session cookie. API tokens are limited to their scopes.
+func AuthMiddleware(config *config.Config, userRepo repository.UserRepo, sessionRepo repository.SessionRepo, tokenRepo repository.APITokenRepo) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == "OPTIONS" {
@@ -78,15 +337,135 @@ func AuthMiddleware(config *config.Config) func(http.Handler) http.Handler {
 				return
 			}
 
-			_, err := r.Cookie(config.Auth.CookieName)
+			if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+				token, err := tokenRepo.GetByTokenHash(utils.HashToken(strings.TrimSpace(bearer)))
+				if err != nil {
+					if err.Error() != "api token not found" {
+						utils.RespondWithError(w, http.StatusInternalServerError, "Failed to validate API token", err)
+						return
+					}
+					http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+					return
+				}
+
+				if scope := requiredScope(r); !token.HasScope(scope) {
+					http.Error(w, "API token lacks scope "+scope, http.StatusForbidden)
+					return
+				}
+
+				user, err := userRepo.GetByID(token.UserID)
+				if err != nil {
+					http.Error(w, "Authentication required", http.StatusUnauthorized)
+					return
+				}
+
+				if err := tokenRepo.Touch(token.ID); err != nil {
+					log.Printf("Failed to update API token %d: %v", token.ID, err)
+				}
+
+				ctx := context.WithValue(r.Context(), UserKey, user)
+				ctx = context.WithValue(ctx, RoleKey, user.Role)
+				ctx = context.WithValue(ctx, TokenKey, token)
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			cookie, err := r.Cookie(config.Auth.CookieName)
+			if err != nil || cookie.Value == "" {
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			session, err := sessionRepo.GetByTokenHash(utils.HashToken(cookie.Value))
+			if err != nil {
+				if err.Error() != "session not found" {
+					utils.RespondWithError(w, http.StatusInternalServerError, "Failed to validate session", err)
+					return
+				}
+				http.Error(w, "Session expired or revoked", http.StatusUnauthorized)
+				return
+			}
+
+			user, err := userRepo.GetByID(session.UserID)
 			if err != nil {
 				http.Error(w, "Authentication required", http.StatusUnauthorized)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), IsAdminKey, true)
+			if err := sessionRepo.Touch(session.ID); err != nil {
+				log.Printf("Failed to update session %d: %v", session.ID, err)
+			}
+
+			ctx := context.WithValue(r.Context(), UserKey, user)
+			ctx = context.WithValue(ctx, RoleKey, user.Role)
+			ctx = context.WithValue(ctx, SessionKey, session)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// CSRFMiddleware requires state-changing requests authenticated by a session
+// cookie to carry the session's CSRF token in the CSRFHeaderName header.
+// Requests authenticated by an API token are exempt, since browsers never
+// attach those automatically. It must be used behind AuthMiddleware.
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if APITokenFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session := SessionFromContext(r.Context())
+		if session == nil {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if session.CSRFTokenHash == "" {
+			http.Error(w, "Session has no CSRF token, please log in again", http.StatusForbidden)
+			return
+		}
+
+		token := r.Header.Get(CSRFHeaderName)
+		if token == "" {
+			http.Error(w, "Missing CSRF token", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(utils.HashToken(token)), []byte(session.CSRFTokenHash)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole rejects requests whose authenticated user has none of the given roles.
+// It must be used behind AuthMiddleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			role := RoleFromContext(r.Context())
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A malformed hash or a lower cost would make CheckPassword return early for
+// unknown usernames, which is what dummyPasswordHash is there to prevent.
+func TestDummyPasswordHashCost(t *testing.T) {
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatalf("dummyPasswordHash is not a bcrypt hash: %v", err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("dummyPasswordHash cost = %d, want bcrypt.DefaultCost (%d), the cost of HashPassword", cost, bcrypt.DefaultCost)
+	}
+}
@@ -0,0 +1,541 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/bibliography"
+	"github.com/damirahm/diplom/backend/citation"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+const maxBibliographyUpload = 10 << 20
+
+type BibliographyHandler struct {
+	publicationRepo repository.PublicationRepo
+	researcherRepo  repository.ResearcherRepo
+	projectRepo     repository.ProjectRepo
+	auditLog        *audit.Logger
+}
+
+func NewBibliographyHandler(pr repository.PublicationRepo, rr repository.ResearcherRepo, pjr repository.ProjectRepo, al *audit.Logger) *BibliographyHandler {
+	return &BibliographyHandler{publicationRepo: pr, researcherRepo: rr, projectRepo: pjr, auditLog: al}
+}
+
+// ExportPublications godoc
+// @Summary Export publications
+// @Description Download publications as BibTeX, RIS or CSL-JSON, newest first. The format is taken
+// @Description from the format parameter, the .bib extension of the path or the Accept header, and
+// @Description defaults to BibTeX.
+// @Tags publications
+// @Produce application/x-bibtex,application/x-research-info-systems,application/vnd.citationstyles.csl+json
+// @Param format query string false "Export format" Enums(bibtex, ris, csl-json)
+// @Param researcherId query int false "ID of an author"
+// @Param year query int false "Publication year"
+// @Param visible query bool false "Only visible (true) or only hidden (false) publications"
+// @Success 200 {string} string "Exported publications"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/export [get]
+// @Router /publications/export.bib [get]
+func (h *BibliographyHandler) ExportPublications(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	filter, err := exportFilterFromQuery(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	publications, _, err := h.publicationRepo.List(filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publications", err)
+		return
+	}
+
+	h.writeExport(w, format, "publications", publications)
+}
+
+// ExportPublication godoc
+// @Summary Export a publication
+// @Description Download a visible publication as BibTeX, RIS or CSL-JSON, chosen by the format
+// @Description parameter or the Accept header
+// @Tags publications
+// @Produce application/x-bibtex,application/x-research-info-systems,application/vnd.citationstyles.csl+json
+// @Param id path int true "Publication ID"
+// @Param format query string false "Export format" Enums(bibtex, ris, csl-json)
+// @Success 200 {string} string "Exported publication"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Router /publications/{id}/export [get]
+func (h *BibliographyHandler) ExportPublication(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid publication ID", http.StatusBadRequest)
+		return
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	publication, err := h.publicationRepo.GetByID(id)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == sql.ErrNoRows || !publication.Visible {
+		http.Error(w, "Publication not found", http.StatusNotFound)
+		return
+	}
+
+	h.writeExport(w, format, "publication-"+strconv.Itoa(id), []models.Publication{*publication})
+}
+
+// ExportResearcherPublications godoc
+// @Summary Export a researcher's publications
+// @Description Download the visible publications of a researcher as BibTeX, RIS or CSL-JSON,
+// @Description newest first
+// @Tags researchers
+// @Produce application/x-bibtex,application/x-research-info-systems,application/vnd.citationstyles.csl+json
+// @Param id path int true "Researcher ID"
+// @Param format query string false "Export format" Enums(bibtex, ris, csl-json)
+// @Success 200 {string} string "Exported publications"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Router /researchers/{id}/publications/export [get]
+func (h *BibliographyHandler) ExportResearcherPublications(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid researcher ID", http.StatusBadRequest)
+		return
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if _, err := h.researcherRepo.GetByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Researcher not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	visible := true
+	publications, _, err := h.publicationRepo.List(models.PublicationFilter{
+		Visible:      &visible,
+		ResearcherID: id,
+		Sort:         models.PublicationSortDate,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publications", err)
+		return
+	}
+
+	h.writeExport(w, format, "researcher-"+strconv.Itoa(id), publications)
+}
+
+// ExportProjectPublications godoc
+// @Summary Export a project's publications
+// @Description Download the publications listed on a project as BibTeX, RIS or CSL-JSON. Entries
+// @Description matching a visible publication by title are exported in full, the rest with their
+// @Description title and link only.
+// @Tags projects
+// @Produce application/x-bibtex,application/x-research-info-systems,application/vnd.citationstyles.csl+json
+// @Param id path int true "Project ID"
+// @Param format query string false "Export format" Enums(bibtex, ris, csl-json)
+// @Success 200 {string} string "Exported publications"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Router /projects/{id}/publications/export [get]
+func (h *BibliographyHandler) ExportProjectPublications(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid project ID", err)
+		return
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	project, err := h.projectRepo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, "Project not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch project", err)
+		return
+	}
+
+	// Project publications are stored as a title and a link; the full record
+	// is looked up by title.
+	publications := make([]models.Publication, 0, len(project.Publications))
+	for _, projectPub := range project.Publications {
+		publication := models.Publication{Title: projectPub.Title, Link: projectPub.Link}
+		match, err := h.publicationRepo.GetByTitle(projectPub.Title.En)
+		if err == nil && match != nil && match.Visible {
+			if full, err := h.publicationRepo.GetByID(match.ID); err == nil && full != nil {
+				publication = *full
+			}
+		}
+		publications = append(publications, publication)
+	}
+
+	h.writeExport(w, format, "project-"+strconv.Itoa(id), publications)
+}
+
+// GetResearcherBibliography godoc
+// @Summary Get a researcher's reference list
+// @Description Render the visible publications of a researcher, newest first, as a numbered reference
+// @Description list in the GOST R 7.0.5, APA or IEEE style. The docx format is plain text for pasting
+// @Description into a word processor, with a tab after each number and non-breaking spaces kept.
+// @Tags researchers
+// @Produce plain,html
+// @Param id path int true "Researcher ID"
+// @Param style query string false "Citation style" Enums(gost, apa, ieee) default(gost)
+// @Param lang query string false "Language of titles and names" Enums(en, ru) default(en)
+// @Param format query string false "Output format" Enums(text, html, docx) default(text)
+// @Param from query int false "First publication year"
+// @Param to query int false "Last publication year"
+// @Success 200 {string} string "Reference list"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Router /researchers/{id}/bibliography [get]
+func (h *BibliographyHandler) GetResearcherBibliography(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid researcher ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := citation.Options{
+		Style:      query.Get("style"),
+		Lang:       query.Get("lang"),
+		AccessedAt: time.Now(),
+	}
+	if opts.Style == "" {
+		opts.Style = citation.StyleGOST
+	} else if !citation.IsValidStyle(opts.Style) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid style", nil)
+		return
+	}
+	if opts.Lang == "" {
+		opts.Lang = "en"
+	} else if opts.Lang != "en" && opts.Lang != "ru" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid lang", nil)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = citation.FormatText
+	} else if !citation.IsValidFormat(format) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid format", nil)
+		return
+	}
+
+	visible := true
+	filter := models.PublicationFilter{
+		Visible:      &visible,
+		ResearcherID: id,
+		Sort:         models.PublicationSortDate,
+	}
+	years := map[string]*int{"from": &filter.FromYear, "to": &filter.ToYear}
+	for name, target := range years {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid "+name, nil)
+			return
+		}
+		*target = n
+	}
+
+	if _, err := h.researcherRepo.GetByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Researcher not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	publications, _, err := h.publicationRepo.List(filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publications", err)
+		return
+	}
+
+	refs := make([]citation.Reference, 0, len(publications))
+	for _, pub := range publications {
+		researchers, err := h.publicationRepo.GetAuthors(pub.ID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publication authors", err)
+			return
+		}
+		refs = append(refs, citation.Render(pub, researchers, opts))
+	}
+
+	if format == citation.FormatHTML {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	citation.WriteList(w, refs, opts.Style, format)
+}
+
+// writeExport writes publications as an attachment named name plus the
+// format's extension, resolving linked authors to their researcher records.
+func (h *BibliographyHandler) writeExport(w http.ResponseWriter, format bibliography.Format, name string, publications []models.Publication) {
+	refs := make([]bibliography.Reference, 0, len(publications))
+	for _, pub := range publications {
+		var researchers []models.Researcher
+		if pub.ID != 0 {
+			var err error
+			researchers, err = h.publicationRepo.GetAuthors(pub.ID)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publication authors", err)
+				return
+			}
+		}
+		refs = append(refs, bibliography.NewReference(pub, researchers))
+	}
+
+	w.Header().Set("Content-Type", format.ContentType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, name, format.Extension))
+	format.Write(w, refs)
+}
+
+// ImportPublications godoc
+// @Summary Import publications
+// @Description Create publications from an uploaded BibTeX, RIS or CSL-JSON file; the format is
+// @Description taken from the format field or the file extension and defaults to BibTeX. Authors
+// @Description are linked to researchers by full name, or by last name and first initial when
+// @Description that is unambiguous; the rest are stored as external authors. Entries whose title
+// @Description matches an existing publication are skipped.
+// @Tags publications
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Bibliography file"
+// @Param format formData string false "File format" Enums(bibtex, ris, csl-json)
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {string} string "Bad Request"
+// @Router /publications/import [post]
+func (h *BibliographyHandler) ImportPublications(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBibliographyUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "A bibliography file is required in the file field", err)
+		return
+	}
+	defer file.Close()
+
+	format, ok := bibliography.FormatByFilename(header.Filename)
+	if name := r.FormValue("format"); name != "" {
+		format, ok = bibliography.FormatByName(name)
+		if !ok {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format %q", name), nil)
+			return
+		}
+	} else if !ok {
+		format = bibliography.BibTeX
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Failed to read the file", err)
+		return
+	}
+
+	entries, err := format.Parse(string(data))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	report := models.ImportReport{
+		Created: []models.ImportResult{},
+		Skipped: []models.ImportResult{},
+		Failed:  []models.ImportResult{},
+	}
+	for _, entry := range entries {
+		result := models.ImportResult{Key: entry.Key}
+		if entry.Err != nil {
+			result.Error = entry.Err.Error()
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+
+		pub, err := entry.Publication()
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+		result.Title = pub.Title.En
+
+		existing, err := h.publicationRepo.GetByTitle(pub.Title.En)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+		if existing != nil {
+			result.PublicationID = existing.ID
+			report.Skipped = append(report.Skipped, result)
+			continue
+		}
+
+		pub.Authors = h.matchAuthors(entry.Names("author"))
+		id, err := h.publicationRepo.Create(pub)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed = append(report.Failed, result)
+			continue
+		}
+		pub.ID = int(id)
+
+		h.auditLog.Record(auditActor(r), models.AuditEntityPublication, pub.ID, models.AuditActionCreate, nil, pub)
+
+		result.PublicationID = pub.ID
+		report.Created = append(report.Created, result)
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
+// matchAuthors links names to researchers where possible and keeps the rest
+// as external authors.
+func (h *BibliographyHandler) matchAuthors(names []bibliography.Name) []models.Author {
+	authors := make([]models.Author, 0, len(names))
+	seen := map[int]bool{}
+	for _, name := range names {
+		author := models.Author{Name: models.LocalizedString{En: name.String(), Ru: name.String()}}
+		if id, ok := h.findResearcher(name); ok && !seen[id] {
+			seen[id] = true
+			author.ID = &id
+		}
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// findResearcher matches a name by first and last name, then by last name and
+// first initial when exactly one researcher fits. Middle names and
+// patronymics are ignored.
+func (h *BibliographyHandler) findResearcher(name bibliography.Name) (int, bool) {
+	firstNames := strings.Fields(name.First)
+	if len(firstNames) == 0 || name.Last == "" {
+		return 0, false
+	}
+
+	if first := firstNames[0]; len([]rune(strings.TrimSuffix(first, "."))) > 1 {
+		researcher, err := h.researcherRepo.FindByFullName(first + " " + name.Last)
+		if err == nil && researcher != nil {
+			return researcher.ID, true
+		}
+	}
+
+	candidates, err := h.researcherRepo.FindByLastName(name.Last)
+	if err != nil {
+		return 0, false
+	}
+
+	initial := firstRune(firstNames[0])
+	match, matches := 0, 0
+	for _, candidate := range candidates {
+		if firstRune(candidate.Name.En) == initial || firstRune(candidate.Name.Ru) == initial {
+			match = candidate.ID
+			matches++
+		}
+	}
+	return match, matches == 1
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return unicode.ToUpper(r)
+	}
+	return 0
+}
+
+// exportFormat picks the export format from the format parameter, the
+// extension of the requested path or the Accept header, defaulting to BibTeX.
+func exportFormat(r *http.Request) (bibliography.Format, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format, ok := bibliography.FormatByName(name)
+		if !ok {
+			return format, fmt.Errorf("unsupported format %q", name)
+		}
+		return format, nil
+	}
+	if format, ok := bibliography.FormatByFilename(r.URL.Path); ok {
+		return format, nil
+	}
+	if format, ok := bibliography.NegotiateFormat(r.Header.Get("Accept")); ok {
+		return format, nil
+	}
+	return bibliography.BibTeX, nil
+}
+
+// exportFilterFromQuery reads the filters of a bibliography export, which is
+// never paged.
+func exportFilterFromQuery(r *http.Request) (models.PublicationFilter, error) {
+	query := r.URL.Query()
+	filter := models.PublicationFilter{Sort: models.PublicationSortDate}
+
+	intParams := map[string]*int{
+		"year":         &filter.Year,
+		"researcherId": &filter.ResearcherID,
+	}
+	for name, target := range intParams {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		*target = n
+	}
+
+	if val := query.Get("visible"); val != "" {
+		visible, err := strconv.ParseBool(val)
+		if err != nil {
+			return filter, fmt.Errorf("invalid visible")
+		}
+		filter.Visible = &visible
+	}
+
+	return filter, nil
+}
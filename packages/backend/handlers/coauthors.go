@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/coauthors"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+)
+
+type CoauthorHandler struct {
+	publicationRepo repository.PublicationRepo
+	disciplineRepo  repository.DisciplineRepo
+}
+
+func NewCoauthorHandler(pr repository.PublicationRepo, dr repository.DisciplineRepo) *CoauthorHandler {
+	return &CoauthorHandler{publicationRepo: pr, disciplineRepo: dr}
+}
+
+// GetCoauthorGraph godoc
+// @Summary Get the co-authorship graph
+// @Description Get the network of authors of the lab's visible publications as JSON or GraphML. Nodes are researchers
+// @Description and external authors, whose name variants collapse into one node; edges join co-authors, at least one of
+// @Description them a researcher, with the number and years of their joint publications.
+// @Tags researchers
+// @Produce json,application/graphml+xml
+// @Param fromYear query int false "First publication year"
+// @Param toYear query int false "Last publication year"
+// @Param disciplineId query int false "Keep publications with an author teaching the discipline"
+// @Param external query bool false "Include external authors" default(true)
+// @Param format query string false "Response format" Enums(json, graphml) default(json)
+// @Success 200 {object} models.CoauthorGraph
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Discipline not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /coauthorship [get]
+func (h *CoauthorHandler) GetCoauthorGraph(w http.ResponseWriter, r *http.Request) {
+	filter, err := coauthorGraphFilterFromQuery(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "graphml" {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("unsupported format %q", format), nil)
+		return
+	}
+
+	var members map[int]bool
+	if filter.DisciplineID != 0 {
+		discipline, err := h.disciplineRepo.GetByID(filter.DisciplineID)
+		if err != nil {
+			if err.Error() == "discipline not found" {
+				utils.RespondWithError(w, http.StatusNotFound, "Discipline not found", err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch discipline", err)
+			return
+		}
+		members = map[int]bool{}
+		for _, researcher := range discipline.Researchers {
+			members[researcher.ID] = true
+		}
+	}
+
+	visible := true
+	publications, _, err := h.publicationRepo.List(models.PublicationFilter{
+		Visible:  &visible,
+		FromYear: filter.FromYear,
+		ToYear:   filter.ToYear,
+	})
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publications", err)
+		return
+	}
+
+	graph := coauthors.Build(publications, members, filter.NoExternal)
+	if format == "graphml" {
+		w.Header().Set("Content-Type", coauthors.GraphMLContentType+"; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="coauthors.graphml"`)
+		coauthors.WriteGraphML(w, graph)
+		return
+	}
+	json.NewEncoder(w).Encode(graph)
+}
+
+func coauthorGraphFilterFromQuery(r *http.Request) (models.CoauthorGraphFilter, error) {
+	query := r.URL.Query()
+	var filter models.CoauthorGraphFilter
+	for name, target := range map[string]*int{
+		"fromYear":     &filter.FromYear,
+		"toYear":       &filter.ToYear,
+		"disciplineId": &filter.DisciplineID,
+	} {
+		val := query.Get(name)
+		if val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		*target = n
+	}
+	if val := query.Get("external"); val != "" {
+		external, err := strconv.ParseBool(val)
+		if err != nil {
+			return filter, fmt.Errorf("invalid external")
+		}
+		filter.NoExternal = !external
+	}
+	return filter, nil
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/cron"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultCrawlJobLimit = 20
+	maxCrawlJobLimit     = 100
+)
+
+type CrawlJobHandler struct {
+	crawler        *cron.PublicationCrawler
+	crawlJobRepo   repository.CrawlJobRepo
+	researcherRepo repository.ResearcherRepo
+}
+
+func NewCrawlJobHandler(pc *cron.PublicationCrawler, cr repository.CrawlJobRepo, rr repository.ResearcherRepo) *CrawlJobHandler {
+	return &CrawlJobHandler{crawler: pc, crawlJobRepo: cr, researcherRepo: rr}
+}
+
+type StartCrawlJobRequest struct {
+	// ResearcherID limits the crawl to one researcher; every researcher is
+	// crawled when it is omitted.
+	ResearcherID  *int `json:"researcherId"`
+	WithCitations bool `json:"withCitations"`
+}
+
+// GetCrawlJobs godoc
+// @Summary Get crawl jobs
+// @Description Get the most recent publication crawl jobs, newest first, with their publication counts
+// @Tags crawl-jobs
+// @Produce json
+// @Param status query string false "running, completed, failed or cancelled"
+// @Param limit query int false "Maximum number of jobs (default 20, max 100)"
+// @Success 200 {array} models.CrawlJob
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /crawl-jobs [get]
+func (h *CrawlJobHandler) GetCrawlJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	status := query.Get("status")
+	switch status {
+	case "", models.CrawlStatusRunning, models.CrawlStatusCompleted, models.CrawlStatusFailed, models.CrawlStatusCancelled:
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid status", nil)
+		return
+	}
+
+	limit := defaultCrawlJobLimit
+	if val := query.Get("limit"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		limit = n
+	}
+	if limit == 0 || limit > maxCrawlJobLimit {
+		limit = maxCrawlJobLimit
+	}
+
+	jobs, err := h.crawlJobRepo.List(status, limit)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch crawl jobs", err)
+		return
+	}
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetCrawlJob godoc
+// @Summary Get a crawl job
+// @Description Get a crawl job by ID with the result of each researcher it crawled so far
+// @Tags crawl-jobs
+// @Produce json
+// @Param id path int true "Crawl job ID"
+// @Success 200 {object} models.CrawlJob
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Crawl job not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /crawl-jobs/{id} [get]
+func (h *CrawlJobHandler) GetCrawlJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid crawl job ID", err)
+		return
+	}
+	job, ok := h.fetchJob(w, id)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(job)
+}
+
+// StartCrawlJob godoc
+// @Summary Start a crawl job
+// @Description Start a background crawl of every researcher, or of one researcher, from their Google Scholar profiles. Crawls never overlap: a crawl of every researcher needs no crawl to be running, and a crawl of one researcher needs neither them nor every researcher to be crawled.
+// @Tags crawl-jobs
+// @Accept json
+// @Produce json
+// @Param request body StartCrawlJobRequest false "Researcher to crawl and whether to fetch citations"
+// @Success 202 {object} models.CrawlJob
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Researcher not found"
+// @Failure 409 {string} string "A crawl covering the same researchers is already running"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /crawl-jobs [post]
+func (h *CrawlJobHandler) StartCrawlJob(w http.ResponseWriter, r *http.Request) {
+	var req StartCrawlJobRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload", err)
+			return
+		}
+	}
+
+	var researcher *models.Researcher
+	if req.ResearcherID != nil {
+		found, err := h.researcherRepo.GetByID(*req.ResearcherID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				utils.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Researcher %d not found", *req.ResearcherID), err)
+				return
+			}
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch researcher", err)
+			return
+		}
+		if found.Profiles.GoogleScholar == nil || *found.Profiles.GoogleScholar == "" {
+			utils.RespondWithError(w, http.StatusBadRequest, "Researcher has no Google Scholar profile", nil)
+			return
+		}
+		researcher = &found.Researcher
+	}
+
+	job, err := h.crawler.StartCrawl(researcher, req.WithCitations, auditActor(r).Name)
+	if err != nil {
+		if err == cron.ErrCrawlRunning {
+			utils.RespondWithError(w, http.StatusConflict, "A crawl covering the same researchers is already running", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start crawl", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// CancelCrawlJob godoc
+// @Summary Cancel a crawl job
+// @Description Stop a running crawl job after the publication it is saving. Publications saved so far are kept.
+// @Tags crawl-jobs
+// @Produce json
+// @Param id path int true "Crawl job ID"
+// @Success 202 {object} models.CrawlJob
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Crawl job not found"
+// @Failure 409 {string} string "Crawl job is not running"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /crawl-jobs/{id}/cancel [post]
+func (h *CrawlJobHandler) CancelCrawlJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid crawl job ID", err)
+		return
+	}
+	job, ok := h.fetchJob(w, id)
+	if !ok {
+		return
+	}
+
+	if job.Status != models.CrawlStatusRunning || !h.crawler.CancelCrawl(id) {
+		utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Crawl job %d is not running", id), nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// fetchJob gets a crawl job, responding with an error and reporting false
+// when it cannot.
+func (h *CrawlJobHandler) fetchJob(w http.ResponseWriter, id int) (*models.CrawlJob, bool) {
+	job, err := h.crawlJobRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "crawl job not found" {
+			utils.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Crawl job %d not found", id), err)
+			return nil, false
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch crawl job", err)
+		return nil, false
+	}
+	return job, true
+}
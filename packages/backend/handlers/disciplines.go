@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
 	"github.com/gorilla/mux"
@@ -12,10 +13,11 @@ import (
 
 type DisciplineHandler struct {
 	disciplineRepo repository.DisciplineRepo
+	auditLog       *audit.Logger
 }
 
-func NewDisciplineHandler(dr repository.DisciplineRepo) *DisciplineHandler {
-	return &DisciplineHandler{disciplineRepo: dr}
+func NewDisciplineHandler(dr repository.DisciplineRepo, al *audit.Logger) *DisciplineHandler {
+	return &DisciplineHandler{disciplineRepo: dr, auditLog: al}
 }
 
 // GetDisciplines godoc
@@ -94,6 +96,8 @@ func (h *DisciplineHandler) CreateDiscipline(w http.ResponseWriter, r *http.Requ
 	}
 	discipline.ID = int(id)
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityDiscipline, discipline.ID, models.AuditActionCreate, nil, discipline)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(discipline)
 }
@@ -126,6 +130,8 @@ func (h *DisciplineHandler) UpdateDiscipline(w http.ResponseWriter, r *http.Requ
 	}
 	discipline.ID = id
 
+	before, _ := h.disciplineRepo.GetByID(id)
+
 	err = h.disciplineRepo.Update(discipline)
 	if err != nil {
 		if err.Error() == "discipline not found" {
@@ -136,6 +142,8 @@ func (h *DisciplineHandler) UpdateDiscipline(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityDiscipline, id, models.AuditActionUpdate, before, discipline)
+
 	json.NewEncoder(w).Encode(discipline)
 }
 
@@ -158,6 +166,8 @@ func (h *DisciplineHandler) DeleteDiscipline(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	before, _ := h.disciplineRepo.GetByID(id)
+
 	err = h.disciplineRepo.Delete(id)
 	if err != nil {
 		if err.Error() == "discipline not found" {
@@ -168,5 +178,7 @@ func (h *DisciplineHandler) DeleteDiscipline(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityDiscipline, id, models.AuditActionDelete, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
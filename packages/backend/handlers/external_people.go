@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/coauthors"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+var orcidPattern = regexp.MustCompile(`^\d{4}-\d{4}-\d{4}-\d{3}[\dX]$`)
+
+type ExternalPersonHandler struct {
+	personRepo     repository.ExternalPersonRepo
+	researcherRepo repository.ResearcherRepo
+	auditLog       *audit.Logger
+}
+
+func NewExternalPersonHandler(pr repository.ExternalPersonRepo, rr repository.ResearcherRepo, al *audit.Logger) *ExternalPersonHandler {
+	return &ExternalPersonHandler{personRepo: pr, researcherRepo: rr, auditLog: al}
+}
+
+type MergeExternalPeopleRequest struct {
+	DuplicateIDs []int `json:"duplicateIds"`
+}
+
+type PromoteExternalPersonRequest struct {
+	ResearcherID int `json:"researcherId"`
+}
+
+// GetExternalPeople godoc
+// @Summary Get external people
+// @Description Get the registry of co-authors from outside the lab, with the spellings of their names they are listed under
+// @Tags external-people
+// @Produce json
+// @Success 200 {array} models.ExternalPerson
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /external-people [get]
+func (h *ExternalPersonHandler) GetExternalPeople(w http.ResponseWriter, r *http.Request) {
+	people, err := h.personRepo.GetAll()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch external people", err)
+		return
+	}
+	json.NewEncoder(w).Encode(people)
+}
+
+// GetExternalPerson godoc
+// @Summary Get an external person
+// @Description Get an external co-author by ID
+// @Tags external-people
+// @Produce json
+// @Param id path int true "External person ID"
+// @Success 200 {object} models.ExternalPerson
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "External person not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /external-people/{id} [get]
+func (h *ExternalPersonHandler) GetExternalPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid external person ID", err)
+		return
+	}
+	person, ok := h.fetchPerson(w, id)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(person)
+}
+
+// UpdateExternalPerson godoc
+// @Summary Update an external person
+// @Description Replace the name, ORCID, affiliation and aliases of an external co-author. Publications added later under
+// @Description an alias are linked to the person. An alias of another person is rejected; merge the two people instead.
+// @Tags external-people
+// @Accept json
+// @Produce json
+// @Param id path int true "External person ID"
+// @Param person body models.ExternalPerson true "External person object"
+// @Success 200 {object} models.ExternalPerson
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "External person not found"
+// @Failure 409 {string} string "Alias of another person"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /external-people/{id} [put]
+func (h *ExternalPersonHandler) UpdateExternalPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid external person ID", err)
+		return
+	}
+
+	var person models.ExternalPerson
+	if err := json.NewDecoder(r.Body).Decode(&person); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid external person data", err)
+		return
+	}
+	if err := normalizeExternalPerson(&person); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	person.ID = id
+
+	before, ok := h.fetchPerson(w, id)
+	if !ok {
+		return
+	}
+
+	for _, alias := range person.Aliases {
+		owner, err := h.personRepo.FindByAlias(alias)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check aliases", err)
+			return
+		}
+		if owner != nil && *owner != id {
+			utils.RespondWithError(w, http.StatusConflict,
+				fmt.Sprintf("%q is an alias of external person %d; merge them instead", alias, *owner), nil)
+			return
+		}
+	}
+
+	if err := h.personRepo.Update(person); err != nil {
+		if err.Error() == "external person not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "External person not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update external person", err)
+		return
+	}
+
+	updated, err := h.personRepo.GetByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch updated external person", err)
+		return
+	}
+	h.auditLog.Record(auditActor(r), models.AuditEntityPerson, id, models.AuditActionUpdate, before, updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// MergeExternalPeople godoc
+// @Summary Merge duplicates into an external person
+// @Description Move the aliases and publications of other external people to the person and delete them, in one
+// @Description transaction. People promoted to different researchers cannot be merged; when one of them was promoted,
+// @Description the merged person's publications move to the researcher.
+// @Tags external-people
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the surviving external person"
+// @Param request body MergeExternalPeopleRequest true "IDs of the duplicates"
+// @Success 200 {object} models.ExternalPerson
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "External person not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /external-people/{id}/merge [post]
+func (h *ExternalPersonHandler) MergeExternalPeople(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid external person ID", err)
+		return
+	}
+
+	var req MergeExternalPeopleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if len(req.DuplicateIDs) == 0 || slices.Contains(req.DuplicateIDs, id) {
+		utils.RespondWithError(w, http.StatusBadRequest, "duplicateIds must list other external people", nil)
+		return
+	}
+
+	survivor, ok := h.fetchPerson(w, id)
+	if !ok {
+		return
+	}
+	researcherID := survivor.ResearcherID
+	var duplicates []*models.ExternalPerson
+	for _, duplicateID := range req.DuplicateIDs {
+		duplicate, ok := h.fetchPerson(w, duplicateID)
+		if !ok {
+			return
+		}
+		if duplicate.ResearcherID != nil {
+			if researcherID != nil && *researcherID != *duplicate.ResearcherID {
+				utils.RespondWithError(w, http.StatusBadRequest, "External people promoted to different researchers cannot be merged", nil)
+				return
+			}
+			researcherID = duplicate.ResearcherID
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	if err := h.personRepo.Merge(id, req.DuplicateIDs); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to merge external people", err)
+		return
+	}
+	for _, duplicate := range duplicates {
+		h.auditLog.Record(auditActor(r), models.AuditEntityPerson, duplicate.ID, models.AuditActionDelete, duplicate, nil)
+	}
+
+	merged, err := h.personRepo.GetByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch external person", err)
+		return
+	}
+	h.auditLog.Record(auditActor(r), models.AuditEntityPerson, id, models.AuditActionUpdate, survivor, merged)
+
+	json.NewEncoder(w).Encode(merged)
+}
+
+// PromoteExternalPerson godoc
+// @Summary Promote an external person to a researcher
+// @Description Replace an external co-author with a researcher of the lab in the bylines of their publications, keeping
+// @Description their places and roles. Publications added later under the person's aliases list the researcher.
+// @Tags external-people
+// @Accept json
+// @Produce json
+// @Param id path int true "External person ID"
+// @Param request body PromoteExternalPersonRequest true "ID of the researcher"
+// @Success 200 {object} models.ExternalPerson
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /external-people/{id}/promote [post]
+func (h *ExternalPersonHandler) PromoteExternalPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid external person ID", err)
+		return
+	}
+
+	var req PromoteExternalPersonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.ResearcherID <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "researcherId is required", nil)
+		return
+	}
+
+	before, ok := h.fetchPerson(w, id)
+	if !ok {
+		return
+	}
+	if before.ResearcherID != nil && *before.ResearcherID != req.ResearcherID {
+		utils.RespondWithError(w, http.StatusBadRequest,
+			fmt.Sprintf("External person is already promoted to researcher %d", *before.ResearcherID), nil)
+		return
+	}
+	if _, err := h.researcherRepo.GetByID(req.ResearcherID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, "Researcher not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch researcher", err)
+		return
+	}
+
+	if err := h.personRepo.Promote(id, req.ResearcherID); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to promote external person", err)
+		return
+	}
+
+	promoted, err := h.personRepo.GetByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch external person", err)
+		return
+	}
+	h.auditLog.Record(auditActor(r), models.AuditEntityPerson, id, models.AuditActionUpdate, before, promoted)
+
+	json.NewEncoder(w).Encode(promoted)
+}
+
+// fetchPerson gets an external person, responding with an error and reporting
+// false when it cannot.
+func (h *ExternalPersonHandler) fetchPerson(w http.ResponseWriter, id int) (*models.ExternalPerson, bool) {
+	person, err := h.personRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "external person not found" {
+			utils.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("External person %d not found", id), err)
+			return nil, false
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch external person", err)
+		return nil, false
+	}
+	return person, true
+}
+
+func normalizeExternalPerson(person *models.ExternalPerson) error {
+	person.Name.En = strings.TrimSpace(person.Name.En)
+	person.Name.Ru = strings.TrimSpace(person.Name.Ru)
+	if person.Name.En == "" && person.Name.Ru == "" {
+		return fmt.Errorf("name is required")
+	}
+	person.Affiliation.En = strings.TrimSpace(person.Affiliation.En)
+	person.Affiliation.Ru = strings.TrimSpace(person.Affiliation.Ru)
+
+	orcid := strings.TrimSpace(person.Orcid)
+	for _, prefix := range []string{"https://orcid.org/", "http://orcid.org/", "orcid.org/"} {
+		orcid = strings.TrimPrefix(orcid, prefix)
+	}
+	orcid = strings.ToUpper(orcid)
+	if orcid != "" && !orcidPattern.MatchString(orcid) {
+		return fmt.Errorf("invalid ORCID %q", person.Orcid)
+	}
+	person.Orcid = orcid
+
+	aliases := []string{}
+	keys := map[string]bool{}
+	for _, alias := range person.Aliases {
+		alias = strings.TrimSpace(alias)
+		key := coauthors.AliasKey(alias)
+		if key == "" {
+			if alias != "" {
+				return fmt.Errorf("invalid alias %q", alias)
+			}
+			continue
+		}
+		if !keys[key] {
+			keys[key] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	person.Aliases = aliases
+	return nil
+}
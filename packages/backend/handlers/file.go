@@ -3,18 +3,27 @@ package handlers
 import (
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/utils"
 )
 
 type FileHandler struct {
 	uploadsDir string
+	// attachmentsDir holds publication attachments. ServeFile only serves the
+	// top level of uploadsDir, so they can only be downloaded through their
+	// publication, which checks its visibility.
+	attachmentsDir string
 }
 
 func NewFileHandler() *FileHandler {
 	return &FileHandler{
-		uploadsDir: "uploads",
+		uploadsDir:     "uploads",
+		attachmentsDir: filepath.Join("uploads", "attachments"),
 	}
 }
 
@@ -61,3 +70,53 @@ func (h *FileHandler) ServeFile(w http.ResponseWriter, r *http.Request) {
 
 	http.ServeFile(w, r, filePath)
 }
+
+// SaveAttachment stores an uploaded attachment under a random name with the
+// extension of filename and returns its path and size.
+func (h *FileHandler) SaveAttachment(file io.Reader, filename string) (string, int64, error) {
+	if err := os.MkdirAll(h.attachmentsDir, 0755); err != nil {
+		return "", 0, err
+	}
+	name, err := utils.GenerateToken(16)
+	if err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(h.attachmentsDir, name+strings.ToLower(filepath.Ext(filename)))
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	size, err := io.Copy(dst, file)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", 0, err
+	}
+	return path, size, nil
+}
+
+// ServeAttachment sends the attachment stored at path as a download named
+// filename.
+func (h *FileHandler) ServeAttachment(w http.ResponseWriter, r *http.Request, path, filename, contentType string) {
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read file", err)
+		return
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	http.ServeContent(w, r, filename, info.ModTime(), file)
+}
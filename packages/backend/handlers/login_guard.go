@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+)
+
+// loginGuard throttles failed logins per username and per client IP. Its state
+// lives in the login_attempts table so lockouts survive a restart.
+type loginGuard struct {
+	config config.LoginProtectionConfig
+	repo   repository.LoginAttemptRepo
+
+	mu sync.Mutex
+	// locks holds a lock for every key with a login in progress.
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	// refs counts the logins holding or waiting for the lock.
+	refs int
+}
+
+func loginKeys(username, ip string) (userKey, ipKey string) {
+	return "user:" + strings.ToLower(username), "ip:" + ip
+}
+
+// lock serializes the logins that share any of the keys, so that concurrent
+// attempts cannot all pass retryAfter before the failures of the others are
+// recorded. It returns the function that releases the keys.
+func (g *loginGuard) lock(keys ...string) func() {
+	// Taking the locks in a fixed order keeps two logins from deadlocking.
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+
+	g.mu.Lock()
+	if g.locks == nil {
+		g.locks = map[string]*keyLock{}
+	}
+	held := make([]*keyLock, len(keys))
+	for i, key := range keys {
+		l, ok := g.locks[key]
+		if !ok {
+			l = &keyLock{}
+			g.locks[key] = l
+		}
+		l.refs++
+		held[i] = l
+	}
+	g.mu.Unlock()
+
+	for _, l := range held {
+		l.Lock()
+	}
+
+	return func() {
+		for _, l := range held {
+			l.Unlock()
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for i, key := range keys {
+			if held[i].refs--; held[i].refs == 0 {
+				delete(g.locks, key)
+			}
+		}
+	}
+}
+
+// retryAfter returns how long the caller must wait before the next attempt for
+// any of the keys, or zero if a login may be attempted now.
+func (g *loginGuard) retryAfter(keys ...string) (time.Duration, error) {
+	now := time.Now().UTC()
+	var wait time.Duration
+
+	for _, key := range keys {
+		attempt, err := g.repo.Get(key)
+		if err != nil {
+			if err.Error() == "login attempt not found" {
+				continue
+			}
+			return 0, err
+		}
+		if attempt.BlockedUntil == nil {
+			continue
+		}
+
+		blockedUntil, err := time.Parse(models.TimestampLayout, *attempt.BlockedUntil)
+		if err != nil {
+			return 0, err
+		}
+		if d := blockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	return wait, nil
+}
+
+// fail records a failed attempt for the key and blocks it for an exponentially
+// growing delay, or for the full lockout once limit failures are reached. It
+// returns the number of consecutive failures.
+func (g *loginGuard) fail(key string, limit int) (int, error) {
+	now := time.Now().UTC()
+	windowStart := now.Add(-g.config.FailureWindow).Format(models.TimestampLayout)
+
+	failures, err := g.repo.Fail(key, now.Format(models.TimestampLayout), windowStart)
+	if err != nil {
+		return 0, err
+	}
+
+	block := g.config.LockoutDuration
+	if failures < limit {
+		block = g.config.BackoffBase << (failures - 1)
+		if block > g.config.BackoffMax || block <= 0 {
+			block = g.config.BackoffMax
+		}
+	}
+	if err := g.repo.Block(key, now.Add(block).Format(models.TimestampLayout)); err != nil {
+		return 0, err
+	}
+
+	if failures == limit {
+		log.Printf("Login locked out for %s after %d failed attempts", key, failures)
+	}
+	return failures, nil
+}
+
+// reset forgets the failures of the keys after a successful login and prunes
+// attempts that fell out of the failure window.
+func (g *loginGuard) reset(keys ...string) {
+	for _, key := range keys {
+		if err := g.repo.Delete(key); err != nil {
+			log.Printf("Failed to reset login attempts for %s: %v", key, err)
+		}
+	}
+
+	stale := time.Now().UTC().Add(-g.config.FailureWindow).Format(models.TimestampLayout)
+	if err := g.repo.DeleteStale(stale); err != nil {
+		log.Printf("Failed to prune login attempts: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type MetricsHandler struct {
+	metricsRepo     repository.MetricsRepo
+	researcherRepo  repository.ResearcherRepo
+	publicationRepo repository.PublicationRepo
+}
+
+func NewMetricsHandler(mr repository.MetricsRepo, rr repository.ResearcherRepo, pr repository.PublicationRepo) *MetricsHandler {
+	return &MetricsHandler{metricsRepo: mr, researcherRepo: rr, publicationRepo: pr}
+}
+
+// GetResearcherMetricsHistory godoc
+// @Summary Get a researcher's metrics history
+// @Description Get the citation metrics of a researcher recorded by the crawler, the last of each day or month, oldest first.
+// @Description Scraped metrics come from Google Scholar; local ones are computed from the publications stored here.
+// @Tags researchers
+// @Produce json
+// @Param id path int true "Researcher ID"
+// @Param interval query string false "Aggregation interval" Enums(day, month) default(day)
+// @Param from query string false "First date, YYYY-MM-DD"
+// @Param to query string false "Last date, YYYY-MM-DD"
+// @Success 200 {array} models.MetricsSnapshot
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Researcher not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /researchers/{id}/metrics/history [get]
+func (h *MetricsHandler) GetResearcherMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid researcher ID", err)
+		return
+	}
+	filter, err := metricsHistoryFilterFromQuery(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if _, err := h.researcherRepo.GetByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, "Researcher not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch researcher", err)
+		return
+	}
+
+	history, err := h.metricsRepo.GetResearcherMetricsHistory(id, filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch metrics history", err)
+		return
+	}
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetPublicationCitationHistory godoc
+// @Summary Get a publication's citation history
+// @Description Get the citation counts of a visible publication recorded by the crawler, the last of each day or month, oldest first
+// @Tags publications
+// @Produce json
+// @Param id path int true "Publication ID"
+// @Param interval query string false "Aggregation interval" Enums(day, month) default(day)
+// @Param from query string false "First date, YYYY-MM-DD"
+// @Param to query string false "Last date, YYYY-MM-DD"
+// @Success 200 {array} models.CitationSnapshot
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Publication not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/citations/history [get]
+func (h *MetricsHandler) GetPublicationCitationHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid publication ID", err)
+		return
+	}
+	filter, err := metricsHistoryFilterFromQuery(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	publication, err := h.publicationRepo.GetByID(id)
+	if err != nil && err != sql.ErrNoRows {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publication", err)
+		return
+	}
+	if publication == nil || !publication.Visible {
+		utils.RespondWithError(w, http.StatusNotFound, "Publication not found", err)
+		return
+	}
+
+	history, err := h.metricsRepo.GetPublicationCitationHistory(id, filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch citation history", err)
+		return
+	}
+	json.NewEncoder(w).Encode(history)
+}
+
+// metricsHistoryFilterFromQuery reads the interval, from and to parameters,
+// defaulting to daily snapshots.
+func metricsHistoryFilterFromQuery(r *http.Request) (models.MetricsHistoryFilter, error) {
+	query := r.URL.Query()
+	filter := models.MetricsHistoryFilter{
+		Interval: query.Get("interval"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+	}
+
+	switch filter.Interval {
+	case "":
+		filter.Interval = models.MetricsIntervalDay
+	case models.MetricsIntervalDay, models.MetricsIntervalMonth:
+	default:
+		return filter, fmt.Errorf("invalid interval %q, must be day or month", filter.Interval)
+	}
+
+	for name, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return filter, fmt.Errorf("invalid %s, must be a date in YYYY-MM-DD", name)
+		}
+	}
+	return filter, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
 	"github.com/gorilla/mux"
@@ -12,11 +13,13 @@ import (
 
 type PartnerHandler struct {
 	partnerRepo repository.PartnerRepo
+	auditLog    *audit.Logger
 }
 
-func NewPartnerHandler(pr repository.PartnerRepo) *PartnerHandler {
+func NewPartnerHandler(pr repository.PartnerRepo, al *audit.Logger) *PartnerHandler {
 	return &PartnerHandler{
 		partnerRepo: pr,
+		auditLog:    al,
 	}
 }
 
@@ -122,6 +125,8 @@ func (h *PartnerHandler) CreatePartner(w http.ResponseWriter, r *http.Request) {
 	}
 	partner.ID = int(id)
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPartner, partner.ID, models.AuditActionCreate, nil, partner)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(partner)
 }
@@ -157,6 +162,8 @@ func (h *PartnerHandler) UpdatePartner(w http.ResponseWriter, r *http.Request) {
 	}
 	partner.ID = idInt
 
+	before, _ := h.partnerRepo.GetByID(idInt)
+
 	err = h.partnerRepo.Update(partner)
 	if err != nil {
 		if err.Error() == "partner not found" {
@@ -167,6 +174,8 @@ func (h *PartnerHandler) UpdatePartner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPartner, idInt, models.AuditActionUpdate, before, partner)
+
 	json.NewEncoder(w).Encode(partner)
 }
 
@@ -192,6 +201,8 @@ func (h *PartnerHandler) DeletePartner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.partnerRepo.GetByID(idInt)
+
 	err = h.partnerRepo.Delete(idInt)
 	if err != nil {
 		if err.Error() == "partner not found" {
@@ -202,5 +213,7 @@ func (h *PartnerHandler) DeletePartner(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPartner, idInt, models.AuditActionDelete, before, nil)
+
 	w.WriteHeader(http.StatusOK)
 }
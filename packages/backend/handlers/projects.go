@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
 	"github.com/damirahm/diplom/backend/utils"
@@ -13,10 +14,11 @@ import (
 
 type ProjectHandler struct {
 	projectRepo repository.ProjectRepo
+	auditLog    *audit.Logger
 }
 
-func NewProjectHandler(pr repository.ProjectRepo) *ProjectHandler {
-	return &ProjectHandler{projectRepo: pr}
+func NewProjectHandler(pr repository.ProjectRepo, al *audit.Logger) *ProjectHandler {
+	return &ProjectHandler{projectRepo: pr, auditLog: al}
 }
 
 // GetProjects godoc
@@ -101,6 +103,8 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityProject, int(id), models.AuditActionCreate, nil, createdProject)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdProject)
 }
@@ -133,6 +137,8 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	}
 	project.ID = id
 
+	before, _ := h.projectRepo.GetByID(id)
+
 	err = h.projectRepo.Update(project)
 	if err != nil {
 		if err.Error() == "project not found" {
@@ -150,6 +156,8 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityProject, id, models.AuditActionUpdate, before, updatedProject)
+
 	json.NewEncoder(w).Encode(updatedProject)
 }
 
@@ -172,6 +180,8 @@ func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.projectRepo.GetByID(id)
+
 	err = h.projectRepo.Delete(id)
 	if err != nil {
 		if err.Error() == "project not found" {
@@ -182,5 +192,7 @@ func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityProject, id, models.AuditActionDelete, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -1,22 +1,34 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/duplicates"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
 	"github.com/gorilla/mux"
 )
 
+const (
+	defaultPublicationPageSize = 20
+	maxPublicationPageSize     = 1000
+	defaultDuplicateLimit      = 100
+)
+
 type PublicationHandler struct {
 	publicationRepo repository.PublicationRepo
+	auditLog        *audit.Logger
 }
 
-func NewPublicationHandler(pr repository.PublicationRepo) *PublicationHandler {
-	return &PublicationHandler{publicationRepo: pr}
+func NewPublicationHandler(pr repository.PublicationRepo, al *audit.Logger) *PublicationHandler {
+	return &PublicationHandler{publicationRepo: pr, auditLog: al}
 }
 
 type PublicationWithAuthors struct {
@@ -24,22 +36,37 @@ type PublicationWithAuthors struct {
 	Authors []models.Researcher `json:"authors"`
 }
 
+type MergePublicationRequest struct {
+	DuplicateID int `json:"duplicateId"`
+}
+
 // GetPublications godoc
-// @Summary Get all publications
-// @Description Get a list of all publications
+// @Summary Get publications
+// @Description Get a page of publications, including hidden ones
 // @Tags publications
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Publication
+// @Param page query int false "Page number, starting at 1"
+// @Param pageSize query int false "Publications per page (default 20, max 1000)"
+// @Param sort query string false "Sort order: date (default), citations or title"
+// @Param year query int false "Publication year"
+// @Param researcherId query int false "ID of an author"
+// @Param venue query string false "Part of the venue name"
+// @Param journal query string false "Deprecated alias of venue"
+// @Param venueId query int false "ID of a registry venue"
+// @Param type query string false "Publication type: article, conference, book_chapter, thesis or patent"
+// @Param q query string false "Text to search for in titles and venues"
+// @Success 200 {object} models.PublicationPage
+// @Failure 400 {object} string "Bad Request"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /publications [get]
 func (h *PublicationHandler) GetPublications(w http.ResponseWriter, r *http.Request) {
-	publications, err := h.publicationRepo.GetAll()
+	filter, err := publicationFilterFromQuery(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	json.NewEncoder(w).Encode(publications)
+	h.listPublications(w, filter)
 }
 
 // GetPublication godoc
@@ -92,6 +119,10 @@ func (h *PublicationHandler) CreatePublication(w http.ResponseWriter, r *http.Re
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := normalizePublication(&publication); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	id, err := h.publicationRepo.Create(publication)
 	if err != nil {
@@ -100,10 +131,28 @@ func (h *PublicationHandler) CreatePublication(w http.ResponseWriter, r *http.Re
 	}
 	publication.ID = int(id)
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPublication, publication.ID, models.AuditActionCreate, nil, publication)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(publication)
 }
 
+// normalizePublication defaults an empty publication type to an article and
+// rejects unknown types and author roles.
+func normalizePublication(publication *models.Publication) error {
+	if publication.Type == "" {
+		publication.Type = models.PublicationTypeArticle
+	} else if !models.IsValidPublicationType(publication.Type) {
+		return fmt.Errorf("invalid publication type %q", publication.Type)
+	}
+	for _, author := range publication.Authors {
+		if !models.IsValidAuthorRole(author.Role) {
+			return fmt.Errorf("invalid role %q of author %q", author.Role, author.Name.En)
+		}
+	}
+	return nil
+}
+
 // UpdatePublication godoc
 // @Summary Update a publication
 // @Description Update an existing publication's information
@@ -130,8 +179,14 @@ func (h *PublicationHandler) UpdatePublication(w http.ResponseWriter, r *http.Re
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := normalizePublication(&publication); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	publication.ID = id
 
+	before, _ := h.publicationRepo.GetByID(id)
+
 	err = h.publicationRepo.Update(publication)
 	if err != nil {
 		if err.Error() == "publication not found" {
@@ -142,6 +197,8 @@ func (h *PublicationHandler) UpdatePublication(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPublication, id, models.AuditActionUpdate, before, publication)
+
 	json.NewEncoder(w).Encode(publication)
 }
 
@@ -164,6 +221,8 @@ func (h *PublicationHandler) DeletePublication(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	before, _ := h.publicationRepo.GetByID(id)
+
 	err = h.publicationRepo.Delete(id)
 	if err != nil {
 		if err.Error() == "publication not found" {
@@ -174,9 +233,125 @@ func (h *PublicationHandler) DeletePublication(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPublication, id, models.AuditActionDelete, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetDuplicates godoc
+// @Summary Find duplicate publications
+// @Description Get pairs of publications that are likely the same work, scored by title, year and author similarity, best first. Only publications sharing a DOI or at least half of their title words with another publication are compared.
+// @Tags publications
+// @Produce json
+// @Param minScore query number false "Lowest score of a reported pair, from 0 to 1 (default 0.8)"
+// @Param limit query int false "Maximum number of pairs (default 100)"
+// @Success 200 {array} models.DuplicateCandidate
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/duplicates [get]
+func (h *PublicationHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	minScore := duplicates.MinScore
+	if v := r.URL.Query().Get("minScore"); v != "" {
+		score, err := strconv.ParseFloat(v, 64)
+		if err != nil || score < 0 || score > 1 {
+			utils.RespondWithError(w, http.StatusBadRequest, "minScore must be a number from 0 to 1", err)
+			return
+		}
+		minScore = score
+	}
+	limit := defaultDuplicateLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			utils.RespondWithError(w, http.StatusBadRequest, "limit must be a positive integer", err)
+			return
+		}
+		limit = n
+	}
+
+	publications, err := h.publicationRepo.GetPossibleDuplicates()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publications", err)
+		return
+	}
+
+	candidates := duplicates.Find(publications, minScore)
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// MergePublication godoc
+// @Summary Merge a duplicate into a publication
+// @Description Merge the authors, links, citation count, metadata and project references of a duplicate into the publication and delete the duplicate, in one transaction. Fields the publication already has are kept.
+// @Tags publications
+// @Accept json
+// @Produce json
+// @Param id path int true "ID of the surviving publication"
+// @Param request body MergePublicationRequest true "ID of the duplicate"
+// @Success 200 {object} models.Publication
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Publication not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /publications/{id}/merge [post]
+func (h *PublicationHandler) MergePublication(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid publication ID", err)
+		return
+	}
+
+	var req MergePublicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if req.DuplicateID == 0 || req.DuplicateID == id {
+		utils.RespondWithError(w, http.StatusBadRequest, "duplicateId must be the ID of another publication", nil)
+		return
+	}
+
+	survivor, ok := h.fetchPublication(w, id)
+	if !ok {
+		return
+	}
+	duplicate, ok := h.fetchPublication(w, req.DuplicateID)
+	if !ok {
+		return
+	}
+
+	if err := h.publicationRepo.Merge(duplicates.Merge(*survivor, *duplicate), duplicate.ID); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to merge publications", err)
+		return
+	}
+	h.auditLog.Record(auditActor(r), models.AuditEntityPublication, duplicate.ID, models.AuditActionDelete, duplicate, nil)
+
+	merged, err := h.publicationRepo.GetByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publication", err)
+		return
+	}
+	h.auditLog.Record(auditActor(r), models.AuditEntityPublication, id, models.AuditActionUpdate, survivor, merged)
+
+	json.NewEncoder(w).Encode(merged)
+}
+
+// fetchPublication gets a publication, responding with an error and reporting
+// false when it cannot.
+func (h *PublicationHandler) fetchPublication(w http.ResponseWriter, id int) (*models.Publication, bool) {
+	pub, err := h.publicationRepo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Publication %d not found", id), err)
+			return nil, false
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch publication", err)
+		return nil, false
+	}
+	return pub, true
+}
+
 // GetPublicationAuthors godoc
 // @Summary Get authors of a publication
 // @Description Get all researchers who are authors of a specific publication
@@ -212,13 +387,14 @@ func (h *PublicationHandler) GetPublicationAuthors(w http.ResponseWriter, r *htt
 
 // TogglePublicationVisibility godoc
 // @Summary Toggle publication visibility
-// @Description Toggle the visibility of a publication
+// @Description Toggle the visibility of a publication. Researchers may only toggle publications they authored.
 // @Tags publications
 // @Accept json
 // @Produce json
 // @Param id path int true "Publication ID"
 // @Success 200 {object} models.Publication
 // @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Insufficient permissions"
 // @Failure 404 {string} string "Not Found"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /publications/{id}/toggle-visibility [put]
@@ -232,49 +408,146 @@ func (h *PublicationHandler) TogglePublicationVisibility(w http.ResponseWriter,
 
 	publication, err := h.publicationRepo.GetByID(id)
 	if err != nil {
-		if err.Error() == "publication not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Publication not found", http.StatusNotFound)
 			return
 		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	user := UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if user.Role == models.RoleResearcher {
+		authors, err := h.publicationRepo.GetAuthors(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !isAuthor(user, authors) {
+			http.Error(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+	}
+
+	before := *publication
 	publication.Visible = !publication.Visible
 
-	err = h.publicationRepo.Update(*publication)
+	err = h.publicationRepo.SetVisibility(id, publication.Visible)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Publication not found", http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityPublication, id, models.AuditActionUpdate, before, publication)
+
 	json.NewEncoder(w).Encode(publication)
 }
 
 // GetPublicPublications godoc
-// @Summary Get all visible publications
-// @Description Get a list of all visible publications
+// @Summary Get visible publications
+// @Description Get a page of visible publications
 // @Tags publications
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.Publication
+// @Param page query int false "Page number, starting at 1"
+// @Param pageSize query int false "Publications per page (default 20, max 1000)"
+// @Param sort query string false "Sort order: date (default), citations or title"
+// @Param year query int false "Publication year"
+// @Param researcherId query int false "ID of an author"
+// @Param venue query string false "Part of the venue name"
+// @Param journal query string false "Deprecated alias of venue"
+// @Param venueId query int false "ID of a registry venue"
+// @Param type query string false "Publication type: article, conference, book_chapter, thesis or patent"
+// @Param q query string false "Text to search for in titles and venues"
+// @Success 200 {object} models.PublicationPage
+// @Failure 400 {object} string "Bad Request"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /publications/public [get]
 func (h *PublicationHandler) GetPublicPublications(w http.ResponseWriter, r *http.Request) {
-	publications, err := h.publicationRepo.GetAll()
+	filter, err := publicationFilterFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	visible := true
+	filter.Visible = &visible
+	h.listPublications(w, filter)
+}
+
+func (h *PublicationHandler) listPublications(w http.ResponseWriter, filter models.PublicationFilter) {
+	publications, total, err := h.publicationRepo.List(filter)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	visiblePublications := make([]models.Publication, 0)
-	for _, pub := range publications {
-		if pub.Visible {
-			visiblePublications = append(visiblePublications, pub)
+	json.NewEncoder(w).Encode(models.PublicationPage{
+		Items:    publications,
+		Total:    total,
+		Page:     filter.Page,
+		PageSize: filter.PageSize,
+	})
+}
+
+// publicationFilterFromQuery reads the paging, sorting and filtering parameters
+// of a publication listing.
+func publicationFilterFromQuery(r *http.Request) (models.PublicationFilter, error) {
+	query := r.URL.Query()
+	filter := models.PublicationFilter{
+		Venue:    query.Get("venue"),
+		Type:     query.Get("type"),
+		Query:    query.Get("q"),
+		Sort:     query.Get("sort"),
+		Page:     1,
+		PageSize: defaultPublicationPageSize,
+	}
+
+	// journal is the name venue had before publications got structured
+	// metadata.
+	if filter.Venue == "" {
+		filter.Venue = query.Get("journal")
+	}
+	if filter.Type != "" && !models.IsValidPublicationType(filter.Type) {
+		return filter, fmt.Errorf("invalid type %q", filter.Type)
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = models.PublicationSortDate
+	} else if !models.IsValidPublicationSort(filter.Sort) {
+		return filter, fmt.Errorf("invalid sort %q", filter.Sort)
+	}
+
+	intParams := map[string]*int{
+		"page":         &filter.Page,
+		"pageSize":     &filter.PageSize,
+		"year":         &filter.Year,
+		"researcherId": &filter.ResearcherID,
+		"venueId":      &filter.VenueID,
+	}
+	for name, target := range intParams {
+		val := query.Get(name)
+		if val == "" {
+			continue
 		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		*target = n
+	}
+	if filter.PageSize > maxPublicationPageSize {
+		filter.PageSize = maxPublicationPageSize
 	}
 
-	json.NewEncoder(w).Encode(visiblePublications)
+	return filter, nil
 }
 
 // GetTotalCount godoc
@@ -294,3 +567,13 @@ func (h *PublicationHandler) GetTotalCount(w http.ResponseWriter, r *http.Reques
 	}
 	json.NewEncoder(w).Encode(map[string]int{"count": count})
 }
+
+// isAuthor reports whether the user's linked researcher is among authors.
+func isAuthor(user *models.User, authors []models.Researcher) bool {
+	for _, author := range authors {
+		if user.CanEditResearcher(author.ID) {
+			return true
+		}
+	}
+	return false
+}
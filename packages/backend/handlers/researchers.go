@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/cron"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
@@ -14,12 +17,14 @@ import (
 type ResearcherHandler struct {
 	researcherRepo     repository.ResearcherRepo
 	publicationCrawler *cron.PublicationCrawler
+	auditLog           *audit.Logger
 }
 
-func NewResearcherHandler(rr repository.ResearcherRepo, pc *cron.PublicationCrawler) *ResearcherHandler {
+func NewResearcherHandler(rr repository.ResearcherRepo, pc *cron.PublicationCrawler, al *audit.Logger) *ResearcherHandler {
 	return &ResearcherHandler{
 		researcherRepo:     rr,
 		publicationCrawler: pc,
+		auditLog:           al,
 	}
 }
 
@@ -99,8 +104,10 @@ func (h *ResearcherHandler) CreateResearcher(w http.ResponseWriter, r *http.Requ
 	}
 	researcher.ID = int(id)
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityResearcher, researcher.ID, models.AuditActionCreate, nil, researcher.AuditState())
+
 	if researcher.Profiles.GoogleScholar != nil && *researcher.Profiles.GoogleScholar != "" {
-		go h.publicationCrawler.CrawlResearcher(researcher, true)
+		h.startCrawl(r, researcher, true)
 	}
 
 	w.WriteHeader(http.StatusCreated)
@@ -109,7 +116,7 @@ func (h *ResearcherHandler) CreateResearcher(w http.ResponseWriter, r *http.Requ
 
 // UpdateResearcher godoc
 // @Summary Update a researcher
-// @Description Update an existing researcher's information
+// @Description Update an existing researcher's information. Researchers may only update the profile fields of their own linked profile.
 // @Tags researchers
 // @Accept json
 // @Produce json
@@ -117,6 +124,7 @@ func (h *ResearcherHandler) CreateResearcher(w http.ResponseWriter, r *http.Requ
 // @Param researcher body models.Researcher true "Researcher object"
 // @Success 200 {object} models.Researcher
 // @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Insufficient permissions"
 // @Failure 404 {string} string "Not Found"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /researchers/{id} [put]
@@ -128,6 +136,12 @@ func (h *ResearcherHandler) UpdateResearcher(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	user := UserFromContext(r.Context())
+	if user == nil || !user.CanEditResearcher(id) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
 	var researcher models.Researcher
 	if err := json.NewDecoder(r.Body).Decode(&researcher); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -135,6 +149,20 @@ func (h *ResearcherHandler) UpdateResearcher(w http.ResponseWriter, r *http.Requ
 	}
 	researcher.ID = id
 
+	existing, err := h.researcherRepo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "researcher not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if user.Role == models.RoleResearcher {
+		researcher = applyProfileFields(existing.Researcher, researcher)
+	}
+
 	err = h.researcherRepo.Update(researcher)
 	if err != nil {
 		if err.Error() == "researcher not found" {
@@ -145,13 +173,80 @@ func (h *ResearcherHandler) UpdateResearcher(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityResearcher, id, models.AuditActionUpdate,
+		existing.Researcher.AuditState(), researcher.AuditState())
+
 	if researcher.Profiles.GoogleScholar != nil && *researcher.Profiles.GoogleScholar != "" {
-		go h.publicationCrawler.CrawlResearcher(researcher, false)
+		h.startCrawl(r, researcher, false)
 	}
 
 	json.NewEncoder(w).Encode(researcher)
 }
 
+// CrawlResearcher godoc
+// @Summary Re-crawl a researcher's publications
+// @Description Start a background crawl job of the researcher's Google Scholar profile
+// @Tags researchers
+// @Produce json
+// @Param id path int true "Researcher ID"
+// @Success 202 {object} models.CrawlJob
+// @Failure 400 {string} string "Bad Request"
+// @Failure 403 {string} string "Insufficient permissions"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "A crawl covering the researcher is already running"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /researchers/{id}/crawl [post]
+func (h *ResearcherHandler) CrawlResearcher(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid researcher ID", http.StatusBadRequest)
+		return
+	}
+
+	user := UserFromContext(r.Context())
+	if user == nil || !user.CanEditResearcher(id) {
+		http.Error(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	researcher, err := h.researcherRepo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Researcher not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if researcher.Profiles.GoogleScholar == nil || *researcher.Profiles.GoogleScholar == "" {
+		http.Error(w, "Researcher has no Google Scholar profile", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.publicationCrawler.StartCrawl(&researcher.Researcher, true, auditActor(r).Name)
+	if err != nil {
+		if err == cron.ErrCrawlRunning {
+			http.Error(w, "A crawl covering the researcher is already running", http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// startCrawl starts a crawl job of a researcher whose profile was just
+// saved. A running crawl covering them is left to finish instead.
+func (h *ResearcherHandler) startCrawl(r *http.Request, researcher models.Researcher, withCitations bool) {
+	if _, err := h.publicationCrawler.StartCrawl(&researcher, withCitations, auditActor(r).Name); err != nil && err != cron.ErrCrawlRunning {
+		log.Printf("Failed to start crawl of researcher %d: %v", researcher.ID, err)
+	}
+}
+
 // DeleteResearcher godoc
 // @Summary Delete a researcher
 // @Description Delete a researcher by ID
@@ -171,6 +266,8 @@ func (h *ResearcherHandler) DeleteResearcher(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	before, _ := h.researcherRepo.GetByID(id)
+
 	err = h.researcherRepo.Delete(id)
 	if err != nil {
 		if err.Error() == "researcher not found" {
@@ -181,5 +278,19 @@ func (h *ResearcherHandler) DeleteResearcher(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if before != nil {
+		h.auditLog.Record(auditActor(r), models.AuditEntityResearcher, id, models.AuditActionDelete, before.Researcher.AuditState(), nil)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// applyProfileFields copies the fields a researcher may edit on their own
+// profile from update onto existing; names and citation stats are kept.
+func applyProfileFields(existing, update models.Researcher) models.Researcher {
+	existing.Position = update.Position
+	existing.Photo = update.Photo
+	existing.Bio = update.Bio
+	existing.Profiles = update.Profiles
+	return existing
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+)
+
+type SearchHandler struct {
+	searchRepo repository.SearchRepo
+}
+
+func NewSearchHandler(sr repository.SearchRepo) *SearchHandler {
+	return &SearchHandler{searchRepo: sr}
+}
+
+// Search godoc
+// @Summary Full-text search
+// @Description Search publications, researchers, projects and training materials in both languages.
+// @Description Every word is matched as a prefix. Hits are grouped by entity type, best first;
+// @Description titles and snippets are HTML-escaped with matches wrapped in <mark> tags.
+// @Description Hidden publications are never returned.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search text"
+// @Param types query string false "Comma-separated entity types: publication, researcher, project, training (default all)"
+// @Param lang query string false "Language of titles and snippets: en (default) or ru"
+// @Param limit query int false "Maximum number of hits per type (default 10, max 50)"
+// @Success 200 {object} models.SearchResults
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /search [get]
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := models.SearchQuery{
+		Query: strings.TrimSpace(params.Get("q")),
+		Types: models.SearchTypes,
+		Lang:  params.Get("lang"),
+		Limit: defaultSearchLimit,
+	}
+
+	if query.Query == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Search text (q) is required", nil)
+		return
+	}
+
+	if types := params.Get("types"); types != "" {
+		query.Types = nil
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if !isSearchType(t) {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid type: "+t, nil)
+				return
+			}
+			query.Types = append(query.Types, t)
+		}
+	}
+
+	switch query.Lang {
+	case "":
+		query.Lang = "en"
+	case "en", "ru":
+	default:
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid lang", nil)
+		return
+	}
+
+	if val := params.Get("limit"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		query.Limit = min(n, maxSearchLimit)
+	}
+
+	results, err := h.searchRepo.Search(query)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Search failed", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+func isSearchType(t string) bool {
+	for _, searchType := range models.SearchTypes {
+		if searchType == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type SessionHandler struct {
+	sessionRepo repository.SessionRepo
+}
+
+func NewSessionHandler(sr repository.SessionRepo) *SessionHandler {
+	return &SessionHandler{sessionRepo: sr}
+}
+
+// GetSessions godoc
+// @Summary Get active sessions
+// @Description Get all unexpired login sessions, optionally for a single user
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param userId query int false "User ID"
+// @Success 200 {array} models.Session
+// @Failure 400 {string} string "Invalid user ID"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /sessions [get]
+func (h *SessionHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID := 0
+	if val := r.URL.Query().Get("userId"); val != "" {
+		id, err := strconv.Atoi(val)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+			return
+		}
+		userID = id
+	}
+
+	sessions, err := h.sessionRepo.GetAll(userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch sessions", err)
+		return
+	}
+
+	if current := SessionFromContext(r.Context()); current != nil {
+		for i := range sessions {
+			sessions[i].Current = sessions[i].ID == current.ID
+		}
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Revoke a login session so its cookie stops working
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid session ID"
+// @Failure 404 {string} string "Session not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	err = h.sessionRepo.Delete(id)
+	if err != nil {
+		if err.Error() == "session not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Session not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	apiTokenBytes  = 32
+	apiTokenPrefix = "dpl_"
+)
+
+type APITokenHandler struct {
+	tokenRepo repository.APITokenRepo
+}
+
+func NewAPITokenHandler(tr repository.APITokenRepo) *APITokenHandler {
+	return &APITokenHandler{tokenRepo: tr}
+}
+
+type APITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty"`
+}
+
+type CreatedAPIToken struct {
+	models.APIToken
+	Token string `json:"token"`
+}
+
+// requiredScope maps a request on /api/<resource>/... to the API token scope
+// it needs: "<resource>:read" for safe methods and "<resource>:write" otherwise.
+func requiredScope(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, "/api/")
+	resource, _, _ := strings.Cut(path, "/")
+	if resource == "upload" {
+		resource = "files"
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// GetAPITokens godoc
+// @Summary Get API tokens
+// @Description Get the current user's API tokens. Admins may pass all=true to list every user's tokens.
+// @Tags tokens
+// @Produce json
+// @Param all query bool false "List tokens of all users (admin only)"
+// @Success 200 {array} models.APIToken
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /tokens [get]
+func (h *APITokenHandler) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	userID := user.ID
+	if user.Role == models.RoleAdmin && r.URL.Query().Get("all") == "true" {
+		userID = 0
+	}
+
+	tokens, err := h.tokenRepo.GetAll(userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch API tokens", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateAPIToken godoc
+// @Summary Create an API token
+// @Description Create a personal API token. The token value is only returned once.
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param token body APITokenRequest true "Token name, scopes and lifetime"
+// @Success 201 {object} CreatedAPIToken
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /tokens [post]
+func (h *APITokenHandler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req APITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid token data", err)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Token name is required", nil)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "At least one scope is required", nil)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !models.IsValidScope(scope) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid scope: "+scope, nil)
+			return
+		}
+	}
+	if req.ExpiresInDays < 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Expiry must not be negative", nil)
+		return
+	}
+
+	secret, err := utils.GenerateToken(apiTokenBytes)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create API token", err)
+		return
+	}
+	secret = apiTokenPrefix + secret
+
+	token := models.APIToken{
+		UserID: user.ID,
+		Name:   req.Name,
+		Scopes: req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().UTC().AddDate(0, 0, req.ExpiresInDays).Format(models.TimestampLayout)
+		token.ExpiresAt = &expiresAt
+	}
+
+	id, err := h.tokenRepo.Create(token, utils.HashToken(secret))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create API token", err)
+		return
+	}
+
+	created, err := h.tokenRepo.GetByID(int(id))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch created API token", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreatedAPIToken{APIToken: *created, Token: secret})
+}
+
+// RevokeAPIToken godoc
+// @Summary Revoke an API token
+// @Description Revoke one of the current user's API tokens. Admins may revoke any token.
+// @Tags tokens
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Invalid token ID"
+// @Failure 404 {string} string "API token not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /tokens/{id} [delete]
+func (h *APITokenHandler) RevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := UserFromContext(r.Context())
+	if user == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid token ID", err)
+		return
+	}
+
+	token, err := h.tokenRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "api token not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "API token not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch API token", err)
+		return
+	}
+	if token.UserID != user.ID && user.Role != models.RoleAdmin {
+		utils.RespondWithError(w, http.StatusNotFound, "API token not found", nil)
+		return
+	}
+
+	if err := h.tokenRepo.Delete(id); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke API token", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
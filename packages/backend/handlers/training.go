@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
 	"github.com/gorilla/mux"
@@ -12,10 +13,11 @@ import (
 
 type TrainingHandler struct {
 	trainingMaterialRepo repository.TrainingMaterialRepo
+	auditLog             *audit.Logger
 }
 
-func NewTrainingHandler(tr repository.TrainingMaterialRepo) *TrainingHandler {
-	return &TrainingHandler{trainingMaterialRepo: tr}
+func NewTrainingHandler(tr repository.TrainingMaterialRepo, al *audit.Logger) *TrainingHandler {
+	return &TrainingHandler{trainingMaterialRepo: tr, auditLog: al}
 }
 
 // GetTrainingMaterials godoc
@@ -94,6 +96,8 @@ func (h *TrainingHandler) CreateTrainingMaterial(w http.ResponseWriter, r *http.
 	}
 	material.ID = int(id)
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityTraining, material.ID, models.AuditActionCreate, nil, material)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(material)
 }
@@ -126,6 +130,8 @@ func (h *TrainingHandler) UpdateTrainingMaterial(w http.ResponseWriter, r *http.
 	}
 	material.ID = id
 
+	before, _ := h.trainingMaterialRepo.GetByID(id)
+
 	err = h.trainingMaterialRepo.Update(material)
 	if err != nil {
 		if err.Error() == "training material not found" {
@@ -136,6 +142,8 @@ func (h *TrainingHandler) UpdateTrainingMaterial(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityTraining, id, models.AuditActionUpdate, before, material)
+
 	json.NewEncoder(w).Encode(material)
 }
 
@@ -158,6 +166,8 @@ func (h *TrainingHandler) DeleteTrainingMaterial(w http.ResponseWriter, r *http.
 		return
 	}
 
+	before, _ := h.trainingMaterialRepo.GetByID(id)
+
 	err = h.trainingMaterialRepo.Delete(id)
 	if err != nil {
 		if err.Error() == "training material not found" {
@@ -168,5 +178,7 @@ func (h *TrainingHandler) DeleteTrainingMaterial(w http.ResponseWriter, r *http.
 		return
 	}
 
+	h.auditLog.Record(auditActor(r), models.AuditEntityTraining, id, models.AuditActionDelete, before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
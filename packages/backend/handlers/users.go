@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type UserHandler struct {
+	userRepo       repository.UserRepo
+	sessionRepo    repository.SessionRepo
+	researcherRepo repository.ResearcherRepo
+}
+
+func NewUserHandler(ur repository.UserRepo, sr repository.SessionRepo, rr repository.ResearcherRepo) *UserHandler {
+	return &UserHandler{userRepo: ur, sessionRepo: sr, researcherRepo: rr}
+}
+
+type UserRequest struct {
+	Username     string `json:"username"`
+	Password     string `json:"password,omitempty"`
+	Role         string `json:"role"`
+	ResearcherID *int   `json:"researcherId,omitempty"`
+}
+
+// validateResearcherLink checks that the researcher a user is linked to exists.
+func (h *UserHandler) validateResearcherLink(w http.ResponseWriter, researcherID *int) bool {
+	if researcherID == nil {
+		return true
+	}
+
+	_, err := h.researcherRepo.GetByID(*researcherID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.RespondWithError(w, http.StatusBadRequest, "Linked researcher does not exist", nil)
+			return false
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch researcher", err)
+		return false
+	}
+	return true
+}
+
+// validateUsername checks that no other user than userID has the username;
+// pass 0 for a new user.
+func (h *UserHandler) validateUsername(w http.ResponseWriter, username string, userID int) bool {
+	existing, err := h.userRepo.GetByUsername(username)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return true
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check username", err)
+		return false
+	}
+	if existing.ID != userID {
+		utils.RespondWithError(w, http.StatusConflict, "Username is already taken", nil)
+		return false
+	}
+	return true
+}
+
+// GetUsers godoc
+// @Summary Get all users
+// @Description Get a list of all user accounts
+// @Tags users
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.User
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /users [get]
+func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userRepo.GetAll()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch users", err)
+		return
+	}
+	json.NewEncoder(w).Encode(users)
+}
+
+// GetUser godoc
+// @Summary Get a user by ID
+// @Description Get a single user account by its ID
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {string} string "Invalid user ID"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch user", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(user)
+}
+
+// CreateUser godoc
+// @Summary Create a new user
+// @Description Create a new user account with a role
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body UserRequest true "User object"
+// @Success 201 {object} models.User
+// @Failure 400 {string} string "Bad Request"
+// @Failure 409 {string} string "Username is already taken"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /users [post]
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user data", err)
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" || req.Password == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Username and password are required", nil)
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid role", nil)
+		return
+	}
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if !h.validateResearcherLink(w, req.ResearcherID) || !h.validateUsername(w, req.Username, 0) {
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.Password)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to hash password", err)
+		return
+	}
+
+	user := models.User{
+		Username:     req.Username,
+		Role:         req.Role,
+		ResearcherID: req.ResearcherID,
+		PasswordHash: passwordHash,
+	}
+
+	id, err := h.userRepo.Create(user)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create user", err)
+		return
+	}
+
+	createdUser, err := h.userRepo.GetByID(int(id))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch created user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdUser)
+}
+
+// UpdateUser godoc
+// @Summary Update a user
+// @Description Update a user's username, role, linked researcher and, optionally, password
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body UserRequest true "User object"
+// @Success 200 {object} models.User
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 409 {string} string "Username is already taken"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /users/{id} [put]
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var req UserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user data", err)
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Username is required", nil)
+		return
+	}
+	if !models.IsValidRole(req.Role) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid role", nil)
+		return
+	}
+	if req.Password != "" {
+		if err := utils.ValidatePassword(req.Password); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	if !h.validateResearcherLink(w, req.ResearcherID) || !h.validateUsername(w, req.Username, id) {
+		return
+	}
+
+	current := UserFromContext(r.Context())
+	if current != nil && current.ID == id && req.Role != models.RoleAdmin {
+		utils.RespondWithError(w, http.StatusBadRequest, "You cannot remove your own admin role", nil)
+		return
+	}
+
+	err = h.userRepo.Update(models.User{
+		ID:           id,
+		Username:     req.Username,
+		Role:         req.Role,
+		ResearcherID: req.ResearcherID,
+	})
+	if err != nil {
+		if err.Error() == "user not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update user", err)
+		return
+	}
+
+	if req.Password != "" {
+		passwordHash, err := utils.HashPassword(req.Password)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to hash password", err)
+			return
+		}
+		if err := h.userRepo.UpdatePassword(id, passwordHash); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update password", err)
+			return
+		}
+		if err := h.sessionRepo.DeleteByUserID(id); err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+			return
+		}
+	}
+
+	updatedUser, err := h.userRepo.GetByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch updated user", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(updatedUser)
+}
+
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description Delete a user account by ID
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /users/{id} [delete]
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	current := UserFromContext(r.Context())
+	if current != nil && current.ID == id {
+		utils.RespondWithError(w, http.StatusBadRequest, "You cannot delete your own account", nil)
+		return
+	}
+
+	err = h.userRepo.Delete(id)
+	if err != nil {
+		if err.Error() == "user not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete user", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/db"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/gorilla/mux"
+)
+
+func TestUserUsernameConflict(t *testing.T) {
+	if err := db.InitDB(config.DriverSQLite, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	repos := repository.New(config.DriverSQLite, db.DB)
+
+	createUser := func(username string) int {
+		id, err := repos.Users.Create(models.User{Username: username, PasswordHash: "hash", Role: models.RoleEditor})
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		return int(id)
+	}
+	alice := createUser("alice")
+	createUser("bob")
+
+	h := NewUserHandler(repos.Users, repos.Sessions, repos.Researchers)
+	router := mux.NewRouter()
+	router.HandleFunc("/users", h.CreateUser).Methods(http.MethodPost)
+	router.HandleFunc("/users/{id}", h.UpdateUser).Methods(http.MethodPut)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"create with a taken username", http.MethodPost, "/users",
+			`{"username": " bob ", "password": "long enough password", "role": "editor"}`, http.StatusConflict},
+		{"create with a free username", http.MethodPost, "/users",
+			`{"username": "carol", "password": "long enough password", "role": "editor"}`, http.StatusCreated},
+		{"rename to a taken username", http.MethodPut, fmt.Sprintf("/users/%d", alice),
+			`{"username": "bob", "role": "editor"}`, http.StatusConflict},
+		{"keep the own username", http.MethodPut, fmt.Sprintf("/users/%d", alice),
+			`{"username": "alice", "role": "admin"}`, http.StatusOK},
+		{"rename to a free username", http.MethodPut, fmt.Sprintf("/users/%d", alice),
+			`{"username": "alicia", "role": "admin"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	user, err := repos.Users.GetByID(alice)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.Username != "alicia" {
+		t.Errorf("username = %q, want alicia", user.Username)
+	}
+}
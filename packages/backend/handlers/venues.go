@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/audit"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
+	"github.com/damirahm/diplom/backend/venues"
+	"github.com/gorilla/mux"
+)
+
+const maxQuartileUpload = 20 << 20
+
+type VenueHandler struct {
+	venueRepo repository.VenueRepo
+	auditLog  *audit.Logger
+}
+
+func NewVenueHandler(vr repository.VenueRepo, al *audit.Logger) *VenueHandler {
+	return &VenueHandler{venueRepo: vr, auditLog: al}
+}
+
+// GetVenues godoc
+// @Summary Get all venues
+// @Description Get the journals and conference series of the venue registry
+// @Tags venues
+// @Produce json
+// @Success 200 {array} models.Venue
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues [get]
+func (h *VenueHandler) GetVenues(w http.ResponseWriter, r *http.Request) {
+	list, err := h.venueRepo.GetAll()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch venues", err)
+		return
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetVenue godoc
+// @Summary Get a venue by ID
+// @Tags venues
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Success 200 {object} models.Venue
+// @Failure 400 {string} string "Invalid venue ID"
+// @Failure 404 {string} string "Venue not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues/{id} [get]
+func (h *VenueHandler) GetVenue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid venue ID", err)
+		return
+	}
+
+	venue, err := h.venueRepo.GetByID(id)
+	if err != nil {
+		if err.Error() == "venue not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Venue not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch venue", err)
+		return
+	}
+	json.NewEncoder(w).Encode(venue)
+}
+
+// CreateVenue godoc
+// @Summary Create a venue
+// @Description Add a venue to the registry and link the publications whose venue names match it
+// @Tags venues
+// @Accept json
+// @Produce json
+// @Param venue body models.Venue true "Venue object"
+// @Success 201 {object} models.Venue
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues [post]
+func (h *VenueHandler) CreateVenue(w http.ResponseWriter, r *http.Request) {
+	venue := models.NewVenue()
+	if err := json.NewDecoder(r.Body).Decode(&venue); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid venue data", err)
+		return
+	}
+	if err := normalizeVenue(&venue); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	id, err := h.venueRepo.Create(venue)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create venue", err)
+		return
+	}
+
+	created, err := h.venueRepo.GetByID(int(id))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch created venue", err)
+		return
+	}
+
+	h.auditLog.Record(auditActor(r), models.AuditEntityVenue, int(id), models.AuditActionCreate, nil, created)
+	h.linkPublications()
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// UpdateVenue godoc
+// @Summary Update a venue
+// @Description Replace a venue, including its aliases and quartiles
+// @Tags venues
+// @Accept json
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Param venue body models.Venue true "Venue object"
+// @Success 200 {object} models.Venue
+// @Failure 400 {string} string "Bad Request"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues/{id} [put]
+func (h *VenueHandler) UpdateVenue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid venue ID", err)
+		return
+	}
+
+	venue := models.NewVenue()
+	if err := json.NewDecoder(r.Body).Decode(&venue); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid venue data", err)
+		return
+	}
+	if err := normalizeVenue(&venue); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	venue.ID = id
+
+	before, _ := h.venueRepo.GetByID(id)
+
+	if err := h.venueRepo.Update(venue); err != nil {
+		if err.Error() == "venue not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Venue not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update venue", err)
+		return
+	}
+
+	updated, err := h.venueRepo.GetByID(id)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch updated venue", err)
+		return
+	}
+
+	h.auditLog.Record(auditActor(r), models.AuditEntityVenue, id, models.AuditActionUpdate, before, updated)
+	h.linkPublications()
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteVenue godoc
+// @Summary Delete a venue
+// @Description Remove a venue from the registry; its publications keep their venue names
+// @Tags venues
+// @Param id path int true "Venue ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "Not Found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues/{id} [delete]
+func (h *VenueHandler) DeleteVenue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid venue ID", err)
+		return
+	}
+
+	before, _ := h.venueRepo.GetByID(id)
+
+	if err := h.venueRepo.Delete(id); err != nil {
+		if err.Error() == "venue not found" {
+			utils.RespondWithError(w, http.StatusNotFound, "Venue not found", err)
+			return
+		}
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete venue", err)
+		return
+	}
+
+	h.auditLog.Record(auditActor(r), models.AuditEntityVenue, id, models.AuditActionDelete, before, nil)
+	// The publications of the venue may match another one.
+	h.linkPublications()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LinkPublications godoc
+// @Summary Link publications to venues
+// @Description Link the publications without a venue to the registry venue their venue name matches
+// @Tags venues
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues/link [post]
+func (h *VenueHandler) LinkPublications(w http.ResponseWriter, r *http.Request) {
+	linked, err := h.venueRepo.LinkPublications()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to link publications", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]int{"linked": linked})
+}
+
+// ImportQuartiles godoc
+// @Summary Import venue quartiles
+// @Description Read venue quartiles and indexing flags from a CSV file with a header row, such as
+// @Description a SCImago Journal Rank export. Rows are matched to venues by ISSN, then by name.
+// @Description Files without a year column take the year field. Unless create is set, rows
+// @Description matching no venue are reported as unmatched.
+// @Tags venues
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV file"
+// @Param year formData int false "Year of the quartiles when the file has no year column"
+// @Param create formData bool false "Create the venues missing from the registry"
+// @Success 200 {object} models.QuartileImportReport
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /venues/quartiles/import [post]
+func (h *VenueHandler) ImportQuartiles(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxQuartileUpload)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "A CSV file is required in the file field", err)
+		return
+	}
+	defer file.Close()
+
+	year := 0
+	if val := r.FormValue("year"); val != "" {
+		year, err = strconv.Atoi(val)
+		if err != nil || year < 1000 || year > 9999 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid year", err)
+			return
+		}
+	}
+	create := false
+	if val := r.FormValue("create"); val != "" {
+		create, err = strconv.ParseBool(val)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid create", err)
+			return
+		}
+	}
+
+	rows, failed, err := venues.ParseQuartileCSV(file, year)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	list, err := h.venueRepo.GetAll()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch venues", err)
+		return
+	}
+	matcher := venues.NewMatcher(list)
+	byID := map[int]*models.Venue{}
+	for i := range list {
+		byID[list[i].ID] = &list[i]
+	}
+
+	report := models.QuartileImportReport{
+		Unmatched: []models.QuartileImportRow{},
+		Failed:    append([]models.QuartileImportRow{}, failed...),
+	}
+	// Changes are collected per venue and saved once every row is read, so
+	// that a venue listed on several rows is updated once.
+	quartiles := map[int][]models.VenueQuartile{}
+	flagged := map[int]bool{}
+	var order []int
+
+	for _, row := range rows {
+		id, ok := 0, false
+		for _, issn := range row.ISSNs {
+			if id, ok = matcher.MatchISSN(issn); ok {
+				break
+			}
+		}
+		if !ok {
+			id, ok = matcher.Match(row.Title)
+		}
+
+		if !ok {
+			if !create || row.Title == "" {
+				report.Unmatched = append(report.Unmatched, models.QuartileImportRow{
+					Line: row.Line, Title: row.Title, ISSN: strings.Join(row.ISSNs, ", "),
+				})
+				continue
+			}
+			venue, err := h.createImportedVenue(r, row)
+			if err != nil {
+				report.Failed = append(report.Failed, models.QuartileImportRow{
+					Line: row.Line, Title: row.Title, ISSN: strings.Join(row.ISSNs, ", "), Error: err.Error(),
+				})
+				continue
+			}
+			matcher.Add(*venue)
+			byID[venue.ID] = venue
+			report.Created++
+			continue
+		}
+
+		if _, seen := quartiles[id]; !seen {
+			quartiles[id] = []models.VenueQuartile{}
+			order = append(order, id)
+		}
+		if row.Quartile != 0 {
+			quartiles[id] = setQuartile(quartiles[id], models.VenueQuartile{Year: row.Year, Quartile: row.Quartile})
+		}
+		if applyVenueFlags(byID[id], row) {
+			flagged[id] = true
+		}
+	}
+
+	for _, id := range order {
+		venue := byID[id]
+		before, _ := h.venueRepo.GetByID(id)
+
+		if flagged[id] {
+			for _, q := range quartiles[id] {
+				venue.Quartiles = setQuartile(venue.Quartiles, q)
+			}
+			err = h.venueRepo.Update(*venue)
+		} else {
+			err = h.venueRepo.SetQuartiles(id, quartiles[id])
+		}
+		if err != nil {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update venue", err)
+			return
+		}
+
+		after, _ := h.venueRepo.GetByID(id)
+		h.auditLog.Record(auditActor(r), models.AuditEntityVenue, id, models.AuditActionUpdate, before, after)
+		report.Updated++
+	}
+
+	if report.Created > 0 {
+		h.linkPublications()
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
+// createImportedVenue adds the venue of an unmatched quartile row.
+func (h *VenueHandler) createImportedVenue(r *http.Request, row venues.QuartileRow) (*models.Venue, error) {
+	venue := models.NewVenue()
+	venue.Name = row.Title
+	venue.Publisher = row.Publisher
+	if len(row.ISSNs) > 0 {
+		venue.ISSN = row.ISSNs[0]
+	}
+	if len(row.ISSNs) > 1 {
+		venue.EISSN = row.ISSNs[1]
+	}
+	if row.Quartile != 0 {
+		venue.Quartiles = []models.VenueQuartile{{Year: row.Year, Quartile: row.Quartile}}
+	}
+	applyVenueFlags(&venue, row)
+
+	id, err := h.venueRepo.Create(venue)
+	if err != nil {
+		return nil, err
+	}
+	created, err := h.venueRepo.GetByID(int(id))
+	if err != nil {
+		return nil, err
+	}
+	h.auditLog.Record(auditActor(r), models.AuditEntityVenue, created.ID, models.AuditActionCreate, nil, created)
+	return created, nil
+}
+
+// GetVenueYearReport godoc
+// @Summary Publications by venue quartile per year
+// @Description Count publications by the quartile and indexing of their venue for each year
+// @Tags reports
+// @Produce json
+// @Param fromYear query int false "First publication year"
+// @Param toYear query int false "Last publication year"
+// @Success 200 {array} models.VenueYearStats
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /reports/venues/years [get]
+func (h *VenueHandler) GetVenueYearReport(w http.ResponseWriter, r *http.Request) {
+	filter, err := venueReportFilterFromQuery(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	stats, err := h.venueRepo.YearStats(filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to build the report", err)
+		return
+	}
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetVenueResearcherReport godoc
+// @Summary Publications by venue quartile per researcher
+// @Description Count the publications of each researcher by the quartile and indexing of their venue
+// @Tags reports
+// @Produce json
+// @Param fromYear query int false "First publication year"
+// @Param toYear query int false "Last publication year"
+// @Success 200 {array} models.VenueResearcherStats
+// @Failure 400 {string} string "Bad Request"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /reports/venues/researchers [get]
+func (h *VenueHandler) GetVenueResearcherReport(w http.ResponseWriter, r *http.Request) {
+	filter, err := venueReportFilterFromQuery(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	stats, err := h.venueRepo.ResearcherStats(filter)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to build the report", err)
+		return
+	}
+	json.NewEncoder(w).Encode(stats)
+}
+
+// linkPublications links unlinked publications after the registry changed.
+// Failing to do so does not fail the change itself.
+func (h *VenueHandler) linkPublications() {
+	if _, err := h.venueRepo.LinkPublications(); err != nil {
+		log.Printf("Failed to link publications to venues: %v", err)
+	}
+}
+
+// normalizeVenue checks a venue sent by a client and tidies up its names and
+// ISSNs.
+func normalizeVenue(venue *models.Venue) error {
+	venue.Name = strings.TrimSpace(venue.Name)
+	if venue.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	venue.Publisher = strings.TrimSpace(venue.Publisher)
+
+	for _, issn := range []*string{&venue.ISSN, &venue.EISSN} {
+		if *issn == "" {
+			continue
+		}
+		normalized := venues.NormalizeISSN(*issn)
+		if normalized == "" {
+			return fmt.Errorf("invalid ISSN %q", *issn)
+		}
+		*issn = normalized
+	}
+
+	aliases := []string{}
+	for _, alias := range venue.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias != "" && alias != venue.Name && !slices.Contains(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	venue.Aliases = aliases
+
+	years := map[int]bool{}
+	for _, q := range venue.Quartiles {
+		if q.Year < 1000 || q.Year > 9999 {
+			return fmt.Errorf("invalid quartile year %d", q.Year)
+		}
+		if q.Quartile < 1 || q.Quartile > 4 {
+			return fmt.Errorf("invalid quartile %d", q.Quartile)
+		}
+		if years[q.Year] {
+			return fmt.Errorf("duplicate quartile for %d", q.Year)
+		}
+		years[q.Year] = true
+	}
+	if venue.Quartiles == nil {
+		venue.Quartiles = []models.VenueQuartile{}
+	}
+	return nil
+}
+
+// applyVenueFlags copies the indexing flags of a quartile row to venue and
+// reports whether any of them changed.
+func applyVenueFlags(venue *models.Venue, row venues.QuartileRow) bool {
+	changed := false
+	for _, flag := range []struct {
+		target *bool
+		value  *bool
+	}{
+		{&venue.Scopus, row.Scopus},
+		{&venue.WoS, row.WoS},
+		{&venue.RSCI, row.RSCI},
+	} {
+		if flag.value != nil && *flag.target != *flag.value {
+			*flag.target = *flag.value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// setQuartile sets the quartile of q.Year in list.
+func setQuartile(list []models.VenueQuartile, q models.VenueQuartile) []models.VenueQuartile {
+	for i := range list {
+		if list[i].Year == q.Year {
+			list[i].Quartile = q.Quartile
+			return list
+		}
+	}
+	return append(list, q)
+}
+
+func venueReportFilterFromQuery(r *http.Request) (models.VenueReportFilter, error) {
+	var filter models.VenueReportFilter
+	for name, target := range map[string]*int{
+		"fromYear": &filter.FromYear,
+		"toYear":   &filter.ToYear,
+	} {
+		val := r.URL.Query().Get(name)
+		if val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n <= 0 {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		*target = n
+	}
+	return filter, nil
+}
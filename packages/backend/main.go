@@ -11,13 +11,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/damirahm/diplom/backend/audit"
 	"github.com/damirahm/diplom/backend/config"
 	"github.com/damirahm/diplom/backend/cron"
 	"github.com/damirahm/diplom/backend/db"
 	"github.com/damirahm/diplom/backend/docs"
 	"github.com/damirahm/diplom/backend/handlers"
 	"github.com/damirahm/diplom/backend/middleware"
+	"github.com/damirahm/diplom/backend/models"
 	"github.com/damirahm/diplom/backend/repository"
+	"github.com/damirahm/diplom/backend/utils"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -39,12 +42,21 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	dataDir := filepath.Dir(cfg.DBPath)
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		log.Fatal(err)
+	if cfg.DBDriver == config.DriverSQLite {
+		dataDir := filepath.Dir(cfg.DBPath)
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	if err := db.InitDB(cfg.DBPath); err != nil {
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := db.InitDB(cfg.DBDriver, cfg.DBSource()); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
@@ -58,22 +70,42 @@ func main() {
 		log.Fatal("Failed to create static directory:", err)
 	}
 
-	localizedStringRepo := repository.NewSQLiteLocalizedStringRepo(db.DB)
-	partnerRepo := repository.NewSQLitePartnerRepo(db.DB)
-	researcherRepo := repository.NewSQLiteResearcherRepo(db.DB, localizedStringRepo)
-	publicationRepo := repository.NewSQLitePublicationRepo(db.DB, localizedStringRepo, researcherRepo)
-	projectRepo := repository.NewSQLiteProjectRepo(db.DB, localizedStringRepo)
-	trainingMaterialRepo := repository.NewSQLiteTrainingMaterialRepo(db.DB, localizedStringRepo)
-	disciplineRepo := repository.NewSQLiteDisciplineRepo(db.DB, localizedStringRepo, researcherRepo)
+	repos := repository.New(cfg.DBDriver, db.DB)
+	partnerRepo := repos.Partners
+	researcherRepo := repos.Researchers
+	publicationRepo := repos.Publications
+	projectRepo := repos.Projects
+	trainingMaterialRepo := repos.TrainingMaterials
+	disciplineRepo := repos.Disciplines
+	userRepo := repos.Users
+	sessionRepo := repos.Sessions
+	apiTokenRepo := repos.APITokens
+	auditRepo := repos.Audit
+	venueRepo := repos.Venues
+	auditLog := audit.NewLogger(auditRepo)
+	loginAttemptRepo := repos.LoginAttempts
+
+	if err := ensureAdminUser(userRepo, cfg); err != nil {
+		log.Fatal("Failed to create initial admin user:", err)
+	}
 
 	publicationCrawler := cron.NewPublicationCrawler(
 		db.DB,
 		researcherRepo,
 		publicationRepo,
+		repos.Metrics,
+		repos.CrawlJobs,
 		cfg.Cron.CrawlInterval,
+		auditLog,
 		ctx,
 	)
 
+	if n, err := repos.CrawlJobs.FailRunning("interrupted by a server restart"); err != nil {
+		log.Printf("Failed to close interrupted crawl jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("Marked %d crawl jobs interrupted by the restart as failed", n)
+	}
+
 	googleScholarRepo := repository.NewGoogleScholar(researcherRepo, publicationRepo)
 	log.Println("Initializing Google Scholar repository")
 
@@ -84,14 +116,31 @@ func main() {
 	publicationCrawler.AddSource(cron.NewGoogleScholarSourceWithRepo(googleScholarRepo))
 	log.Println("Added Google Scholar source with repository to the crawler")
 
-	partnersHandler := handlers.NewPartnerHandler(partnerRepo)
-	projectsHandler := handlers.NewProjectHandler(projectRepo)
-	researchersHandler := handlers.NewResearcherHandler(researcherRepo, publicationCrawler) // Now publicationCrawler is defined
-	publicationsHandler := handlers.NewPublicationHandler(publicationRepo)
-	trainingHandler := handlers.NewTrainingHandler(trainingMaterialRepo)
-	disciplineHandler := handlers.NewDisciplineHandler(disciplineRepo)
-	authHandler := handlers.NewAuthHandler(cfg)
+	if cfg.Cron.EnrichMetadata {
+		publicationCrawler.AddMetadataProvider(cron.NewCrossrefProvider(cfg.Cron.CrossrefURL, cfg.Cron.CrossrefMailto))
+		log.Printf("Added Crossref metadata provider (%s) to the crawler", cfg.Cron.CrossrefURL)
+	}
+
+	partnersHandler := handlers.NewPartnerHandler(partnerRepo, auditLog)
+	projectsHandler := handlers.NewProjectHandler(projectRepo, auditLog)
+	researchersHandler := handlers.NewResearcherHandler(researcherRepo, publicationCrawler, auditLog) // Now publicationCrawler is defined
+	publicationsHandler := handlers.NewPublicationHandler(publicationRepo, auditLog)
+	trainingHandler := handlers.NewTrainingHandler(trainingMaterialRepo, auditLog)
+	disciplineHandler := handlers.NewDisciplineHandler(disciplineRepo, auditLog)
+	authHandler := handlers.NewAuthHandler(cfg, userRepo, sessionRepo, loginAttemptRepo, auditLog)
+	usersHandler := handlers.NewUserHandler(userRepo, sessionRepo, researcherRepo)
+	sessionsHandler := handlers.NewSessionHandler(sessionRepo)
+	apiTokensHandler := handlers.NewAPITokenHandler(apiTokenRepo)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	searchHandler := handlers.NewSearchHandler(repos.Search)
+	bibliographyHandler := handlers.NewBibliographyHandler(publicationRepo, researcherRepo, projectRepo, auditLog)
+	venuesHandler := handlers.NewVenueHandler(venueRepo, auditLog)
+	metricsHandler := handlers.NewMetricsHandler(repos.Metrics, researcherRepo, publicationRepo)
+	coauthorHandler := handlers.NewCoauthorHandler(publicationRepo, disciplineRepo)
+	externalPeopleHandler := handlers.NewExternalPersonHandler(repos.ExternalPeople, researcherRepo, auditLog)
 	fileHandler := handlers.NewFileHandler()
+	crawlJobsHandler := handlers.NewCrawlJobHandler(publicationCrawler, repos.CrawlJobs, researcherRepo)
+	attachmentsHandler := handlers.NewAttachmentHandler(repos.Attachments, publicationRepo, fileHandler, auditLog)
 
 	// Создание обработчика для алгоритма разбиения изображения
 	imageProcessingHandler := handlers.NewImageProcessingHandler("./uploads")
@@ -121,49 +170,118 @@ func main() {
 	api.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST", "OPTIONS")
 	api.HandleFunc("/publications/public", publicationsHandler.GetPublicPublications).Methods("GET")
 	api.HandleFunc("/publications/count", publicationsHandler.GetTotalCount).Methods("GET")
+	api.HandleFunc("/publications/{id}/export", bibliographyHandler.ExportPublication).Methods("GET")
+	api.HandleFunc("/publications/{id}/citations/history", metricsHandler.GetPublicationCitationHistory).Methods("GET")
+	api.HandleFunc("/publications/{id}/attachments/public", attachmentsHandler.GetPublicAttachments).Methods("GET")
+	api.HandleFunc("/publications/{id}/attachments/{attachmentId}/download", attachmentsHandler.DownloadAttachment).Methods("GET")
+	api.HandleFunc("/search", searchHandler.Search).Methods("GET")
+	api.HandleFunc("/coauthorship", coauthorHandler.GetCoauthorGraph).Methods("GET")
 
 	protected := api.PathPrefix("").Subrouter()
-	protected.Use(handlers.AuthMiddleware(cfg))
+	protected.Use(handlers.AuthMiddleware(cfg, userRepo, sessionRepo, apiTokenRepo))
+	protected.Use(handlers.CSRFMiddleware)
+
+	// Контент могут изменять редакторы и администраторы
+	editor := protected.PathPrefix("").Subrouter()
+	editor.Use(handlers.RequireRole(models.RoleAdmin, models.RoleEditor))
+
+	// Управление пользователями доступно только администраторам
+	admin := protected.PathPrefix("").Subrouter()
+	admin.Use(handlers.RequireRole(models.RoleAdmin))
+
+	protected.HandleFunc("/auth/me", authHandler.Me).Methods("GET")
+	protected.HandleFunc("/auth/password", authHandler.ChangePassword).Methods("POST")
 
 	api.HandleFunc("/partners", partnersHandler.GetAllPartners).Methods("GET")
 	api.HandleFunc("/partners/{id}", partnersHandler.GetPartnerByID).Methods("GET")
-	protected.HandleFunc("/partners", partnersHandler.CreatePartner).Methods("POST")
-	protected.HandleFunc("/partners/{id}", partnersHandler.UpdatePartner).Methods("PUT")
-	protected.HandleFunc("/partners/{id}", partnersHandler.DeletePartner).Methods("DELETE")
+	editor.HandleFunc("/partners", partnersHandler.CreatePartner).Methods("POST")
+	editor.HandleFunc("/partners/{id}", partnersHandler.UpdatePartner).Methods("PUT")
+	editor.HandleFunc("/partners/{id}", partnersHandler.DeletePartner).Methods("DELETE")
 
 	api.HandleFunc("/projects", projectsHandler.GetProjects).Methods("GET")
 	api.HandleFunc("/projects/{id}", projectsHandler.GetProject).Methods("GET")
-	protected.HandleFunc("/projects", projectsHandler.CreateProject).Methods("POST")
-	protected.HandleFunc("/projects/{id}", projectsHandler.UpdateProject).Methods("PUT")
-	protected.HandleFunc("/projects/{id}", projectsHandler.DeleteProject).Methods("DELETE")
+	api.HandleFunc("/projects/{id}/publications/export", bibliographyHandler.ExportProjectPublications).Methods("GET")
+	editor.HandleFunc("/projects", projectsHandler.CreateProject).Methods("POST")
+	editor.HandleFunc("/projects/{id}", projectsHandler.UpdateProject).Methods("PUT")
+	editor.HandleFunc("/projects/{id}", projectsHandler.DeleteProject).Methods("DELETE")
 
 	api.HandleFunc("/researchers", researchersHandler.GetResearchers).Methods("GET")
 	api.HandleFunc("/researchers/{id}", researchersHandler.GetResearcher).Methods("GET")
-	protected.HandleFunc("/researchers", researchersHandler.CreateResearcher).Methods("POST")
+	api.HandleFunc("/researchers/{id}/publications/export", bibliographyHandler.ExportResearcherPublications).Methods("GET")
+	api.HandleFunc("/researchers/{id}/bibliography", bibliographyHandler.GetResearcherBibliography).Methods("GET")
+	api.HandleFunc("/researchers/{id}/metrics/history", metricsHandler.GetResearcherMetricsHistory).Methods("GET")
+	editor.HandleFunc("/researchers", researchersHandler.CreateResearcher).Methods("POST")
 	protected.HandleFunc("/researchers/{id}", researchersHandler.UpdateResearcher).Methods("PUT")
-	protected.HandleFunc("/researchers/{id}", researchersHandler.DeleteResearcher).Methods("DELETE")
-
-	protected.HandleFunc("/publications", publicationsHandler.GetPublications).Methods("GET")
-	protected.HandleFunc("/publications", publicationsHandler.CreatePublication).Methods("POST")
-	protected.HandleFunc("/publications/{id}", publicationsHandler.GetPublication).Methods("GET")
-	protected.HandleFunc("/publications/{id}", publicationsHandler.UpdatePublication).Methods("PUT")
-	protected.HandleFunc("/publications/{id}", publicationsHandler.DeletePublication).Methods("DELETE")
+	protected.HandleFunc("/researchers/{id}/crawl", researchersHandler.CrawlResearcher).Methods("POST")
+	editor.HandleFunc("/researchers/{id}", researchersHandler.DeleteResearcher).Methods("DELETE")
+
+	editor.HandleFunc("/publications", publicationsHandler.GetPublications).Methods("GET")
+	editor.HandleFunc("/publications", publicationsHandler.CreatePublication).Methods("POST")
+	editor.HandleFunc("/publications/export", bibliographyHandler.ExportPublications).Methods("GET")
+	editor.HandleFunc("/publications/export.bib", bibliographyHandler.ExportPublications).Methods("GET")
+	editor.HandleFunc("/publications/import", bibliographyHandler.ImportPublications).Methods("POST")
+	editor.HandleFunc("/publications/duplicates", publicationsHandler.GetDuplicates).Methods("GET")
+	editor.HandleFunc("/publications/{id}/merge", publicationsHandler.MergePublication).Methods("POST")
+	editor.HandleFunc("/publications/{id}", publicationsHandler.GetPublication).Methods("GET")
+	editor.HandleFunc("/publications/{id}", publicationsHandler.UpdatePublication).Methods("PUT")
+	editor.HandleFunc("/publications/{id}", publicationsHandler.DeletePublication).Methods("DELETE")
 	protected.HandleFunc("/publications/{id}/toggle-visibility", publicationsHandler.TogglePublicationVisibility).Methods("PUT")
-	protected.HandleFunc("/publications/{id}/authors", publicationsHandler.GetPublicationAuthors).Methods("GET")
+	editor.HandleFunc("/publications/{id}/authors", publicationsHandler.GetPublicationAuthors).Methods("GET")
+	editor.HandleFunc("/publications/{id}/attachments", attachmentsHandler.GetAttachments).Methods("GET")
+	editor.HandleFunc("/publications/{id}/attachments", attachmentsHandler.CreateAttachment).Methods("POST")
+	editor.HandleFunc("/publications/{id}/attachments/{attachmentId}", attachmentsHandler.UpdateAttachment).Methods("PUT")
+	editor.HandleFunc("/publications/{id}/attachments/{attachmentId}", attachmentsHandler.DeleteAttachment).Methods("DELETE")
 
 	api.HandleFunc("/training", trainingHandler.GetTrainingMaterials).Methods("GET")
 	api.HandleFunc("/training/{id}", trainingHandler.GetTrainingMaterial).Methods("GET")
-	protected.HandleFunc("/training", trainingHandler.CreateTrainingMaterial).Methods("POST")
-	protected.HandleFunc("/training/{id}", trainingHandler.UpdateTrainingMaterial).Methods("PUT")
-	protected.HandleFunc("/training/{id}", trainingHandler.DeleteTrainingMaterial).Methods("DELETE")
+	editor.HandleFunc("/training", trainingHandler.CreateTrainingMaterial).Methods("POST")
+	editor.HandleFunc("/training/{id}", trainingHandler.UpdateTrainingMaterial).Methods("PUT")
+	editor.HandleFunc("/training/{id}", trainingHandler.DeleteTrainingMaterial).Methods("DELETE")
 
 	api.HandleFunc("/disciplines", disciplineHandler.GetDisciplines).Methods("GET")
 	api.HandleFunc("/disciplines/{id}", disciplineHandler.GetDiscipline).Methods("GET")
-	protected.HandleFunc("/disciplines", disciplineHandler.CreateDiscipline).Methods("POST")
-	protected.HandleFunc("/disciplines/{id}", disciplineHandler.UpdateDiscipline).Methods("PUT")
-	protected.HandleFunc("/disciplines/{id}", disciplineHandler.DeleteDiscipline).Methods("DELETE")
+	editor.HandleFunc("/disciplines", disciplineHandler.CreateDiscipline).Methods("POST")
+	editor.HandleFunc("/disciplines/{id}", disciplineHandler.UpdateDiscipline).Methods("PUT")
+	editor.HandleFunc("/disciplines/{id}", disciplineHandler.DeleteDiscipline).Methods("DELETE")
+
+	editor.HandleFunc("/venues", venuesHandler.GetVenues).Methods("GET")
+	admin.HandleFunc("/venues", venuesHandler.CreateVenue).Methods("POST")
+	admin.HandleFunc("/venues/link", venuesHandler.LinkPublications).Methods("POST")
+	admin.HandleFunc("/venues/quartiles/import", venuesHandler.ImportQuartiles).Methods("POST")
+	editor.HandleFunc("/venues/{id}", venuesHandler.GetVenue).Methods("GET")
+	admin.HandleFunc("/venues/{id}", venuesHandler.UpdateVenue).Methods("PUT")
+	admin.HandleFunc("/venues/{id}", venuesHandler.DeleteVenue).Methods("DELETE")
+
+	editor.HandleFunc("/external-people", externalPeopleHandler.GetExternalPeople).Methods("GET")
+	editor.HandleFunc("/external-people/{id}", externalPeopleHandler.GetExternalPerson).Methods("GET")
+	editor.HandleFunc("/external-people/{id}", externalPeopleHandler.UpdateExternalPerson).Methods("PUT")
+	editor.HandleFunc("/external-people/{id}/merge", externalPeopleHandler.MergeExternalPeople).Methods("POST")
+	editor.HandleFunc("/external-people/{id}/promote", externalPeopleHandler.PromoteExternalPerson).Methods("POST")
+
+	editor.HandleFunc("/crawl-jobs", crawlJobsHandler.GetCrawlJobs).Methods("GET")
+	editor.HandleFunc("/crawl-jobs", crawlJobsHandler.StartCrawlJob).Methods("POST")
+	editor.HandleFunc("/crawl-jobs/{id}", crawlJobsHandler.GetCrawlJob).Methods("GET")
+	editor.HandleFunc("/crawl-jobs/{id}/cancel", crawlJobsHandler.CancelCrawlJob).Methods("POST")
+
+	editor.HandleFunc("/reports/venues/years", venuesHandler.GetVenueYearReport).Methods("GET")
+	editor.HandleFunc("/reports/venues/researchers", venuesHandler.GetVenueResearcherReport).Methods("GET")
+
+	editor.HandleFunc("/upload", fileHandler.UploadFile).Methods("POST")
+
+	admin.HandleFunc("/users", usersHandler.GetUsers).Methods("GET")
+	admin.HandleFunc("/users", usersHandler.CreateUser).Methods("POST")
+	admin.HandleFunc("/users/{id}", usersHandler.GetUser).Methods("GET")
+	admin.HandleFunc("/users/{id}", usersHandler.UpdateUser).Methods("PUT")
+	admin.HandleFunc("/users/{id}", usersHandler.DeleteUser).Methods("DELETE")
 
-	protected.HandleFunc("/upload", fileHandler.UploadFile).Methods("POST")
+	admin.HandleFunc("/sessions", sessionsHandler.GetSessions).Methods("GET")
+	admin.HandleFunc("/sessions/{id}", sessionsHandler.RevokeSession).Methods("DELETE")
+
+	admin.HandleFunc("/audit", auditHandler.GetAuditLog).Methods("GET")
+
+	protected.HandleFunc("/tokens", apiTokensHandler.GetAPITokens).Methods("GET")
+	protected.HandleFunc("/tokens", apiTokensHandler.CreateAPIToken).Methods("POST")
+	protected.HandleFunc("/tokens/{id}", apiTokensHandler.RevokeAPIToken).Methods("DELETE")
 
 	// Новый маршрут для обработки изображений с модифицированным алгоритмом SLIC
 	api.HandleFunc("/image/superpixels", imageProcessingHandler.ProcessSuperpixels).Methods("POST")
@@ -176,7 +294,7 @@ func main() {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{cfg.ClientHost},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization", "Cookie"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Cookie", handlers.CSRFHeaderName},
 		ExposedHeaders:   []string{"Set-Cookie"},
 		AllowCredentials: true,
 		MaxAge:           86400,
@@ -236,3 +354,50 @@ func main() {
 
 	log.Println("Server exited properly")
 }
+
+// ensureAdminUser creates the first administrator from ADMIN_USERNAME/ADMIN_PASSWORD
+// when the users table is empty, so existing deployments keep their login. An admin
+// account still carrying a legacy SHA-256 hash is re-hashed from the env password.
+func ensureAdminUser(userRepo repository.UserRepo, cfg *config.Config) error {
+	count, err := userRepo.Count()
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := utils.HashPassword(cfg.Auth.AdminPassword)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		_, err = userRepo.Create(models.User{
+			Username:     cfg.Auth.AdminUsername,
+			Role:         models.RoleAdmin,
+			PasswordHash: passwordHash,
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Created initial admin user %q", cfg.Auth.AdminUsername)
+		return nil
+	}
+
+	admin, err := userRepo.GetByUsername(cfg.Auth.AdminUsername)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+	if utils.IsPasswordHash(admin.PasswordHash) {
+		return nil
+	}
+
+	if err := userRepo.UpdatePassword(admin.ID, passwordHash); err != nil {
+		return err
+	}
+
+	log.Printf("Migrated admin user %q to a bcrypt password hash", admin.Username)
+	return nil
+}
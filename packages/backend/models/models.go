@@ -1,5 +1,19 @@
 package models
 
+import (
+	"encoding/json"
+	"strings"
+)
+
+// TimestampLayout matches SQLite's CURRENT_TIMESTAMP so stored times compare as strings.
+const TimestampLayout = "2006-01-02 15:04:05"
+
+const (
+	RoleAdmin      = "admin"
+	RoleEditor     = "editor"
+	RoleResearcher = "researcher"
+)
+
 type LocalizedString struct {
 	En string `json:"en"`
 	Ru string `json:"ru"`
@@ -18,22 +32,172 @@ type PartnersData struct {
 	Enterprises  []Partner `json:"enterprises"`
 }
 
+// Author is an author of a publication: a researcher when ID is set and an
+// external author otherwise, linked to their entry in the external people
+// registry by PersonID. Publications list their authors in byline order.
 type Author struct {
-	Name LocalizedString `json:"name"`
-	ID   *int            `json:"id,omitempty"`
+	Name     LocalizedString `json:"name"`
+	ID       *int            `json:"id,omitempty"`
+	PersonID *int            `json:"personId,omitempty"`
+	Role     string          `json:"role,omitempty"`
 }
 
+// Author roles. Most authors have none.
+const (
+	AuthorRoleCorresponding = "corresponding"
+	AuthorRoleSupervisor    = "supervisor"
+)
+
+// Publication types.
+const (
+	PublicationTypeArticle     = "article"
+	PublicationTypeConference  = "conference"
+	PublicationTypeBookChapter = "book_chapter"
+	PublicationTypeThesis      = "thesis"
+	PublicationTypePatent      = "patent"
+)
+
+// Publication is a publication with its bibliographic metadata. Venue is the
+// journal, conference, book or patent office the work appeared in, as named by
+// the source; VenueID links it to the venue registry. For theses Publisher
+// holds the institution.
 type Publication struct {
 	ID             int             `json:"id"`
 	Title          LocalizedString `json:"title"`
 	Authors        []Author        `json:"authors"`
-	Journal        string          `json:"journal"`
+	Type           string          `json:"type"`
+	Venue          string          `json:"venue"`
+	VenueID        *int            `json:"venueId,omitempty"`
+	Volume         string          `json:"volume"`
+	Issue          string          `json:"issue"`
+	Pages          string          `json:"pages"`
+	Publisher      string          `json:"publisher"`
+	DOI            string          `json:"doi"`
+	Abstract       string          `json:"abstract"`
 	PublishedAt    string          `json:"publishedAt"`
 	CitationsCount int             `json:"citationsCount"`
 	Link           string          `json:"link"`
 	Visible        bool            `json:"visible"`
 }
 
+// publicationFields has the fields of Publication without its JSON methods.
+type publicationFields Publication
+
+// publicationJSON adds journal, the name venue had before publications got
+// structured metadata, so that older clients keep working.
+type publicationJSON struct {
+	publicationFields
+	Journal string `json:"journal"`
+}
+
+// MarshalJSON writes the venue under both venue and the deprecated journal.
+func (p Publication) MarshalJSON() ([]byte, error) {
+	return json.Marshal(publicationJSON{publicationFields(p), p.Venue})
+}
+
+// UnmarshalJSON reads the deprecated journal as the venue when venue is unset.
+func (p *Publication) UnmarshalJSON(data []byte) error {
+	var v publicationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = Publication(v.publicationFields)
+	if p.Venue == "" {
+		p.Venue = v.Journal
+	}
+	return nil
+}
+
+// Attachment types.
+const (
+	AttachmentTypePreprint      = "preprint"
+	AttachmentTypeSlides        = "slides"
+	AttachmentTypeCode          = "code"
+	AttachmentTypeDataset       = "dataset"
+	AttachmentTypeSupplementary = "supplementary"
+	AttachmentTypeOther         = "other"
+)
+
+// PublicationAttachment is a file uploaded for a publication, such as its
+// preprint or slides, or a link to an external resource, such as its code or
+// dataset. Uploaded files have a FileName and are stored at File, which is
+// only served through the download endpoint of a visible publication; links
+// have a URL instead.
+type PublicationAttachment struct {
+	ID            int             `json:"id"`
+	PublicationID int             `json:"publicationId"`
+	Type          string          `json:"type"`
+	Title         LocalizedString `json:"title"`
+	URL           string          `json:"url,omitempty"`
+	File          string          `json:"-"`
+	FileName      string          `json:"fileName,omitempty"`
+	ContentType   string          `json:"contentType,omitempty"`
+	Size          int64           `json:"size,omitempty"`
+	Order         int             `json:"order"`
+	CreatedAt     string          `json:"createdAt"`
+}
+
+// ImportResult is the outcome of importing one bibliography entry.
+type ImportResult struct {
+	Key           string `json:"key"`
+	Title         string `json:"title,omitempty"`
+	PublicationID int    `json:"publicationId,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ImportReport groups imported entries by outcome. Skipped entries duplicate
+// an existing publication, whose ID they carry.
+type ImportReport struct {
+	Created []ImportResult `json:"created"`
+	Skipped []ImportResult `json:"skipped"`
+	Failed  []ImportResult `json:"failed"`
+}
+
+// Sort orders of publication listings.
+const (
+	PublicationSortDate      = "date"
+	PublicationSortCitations = "citations"
+	PublicationSortTitle     = "title"
+)
+
+// PublicationFilter narrows down publication listings; zero values match everything.
+type PublicationFilter struct {
+	Visible      *bool
+	Year         int
+	FromYear     int
+	ToYear       int
+	ResearcherID int
+	Venue        string
+	VenueID      int
+	Type         string
+	Query        string
+	Sort         string
+	Page         int
+	PageSize     int
+}
+
+// DuplicateCandidate is a pair of publications that may be the same work,
+// First being the older record. Score weighs the similarity of their titles,
+// the closeness of their years and the share of common authors, each from 0
+// to 1; publications with the same DOI score 1.
+type DuplicateCandidate struct {
+	First       Publication `json:"first"`
+	Second      Publication `json:"second"`
+	Score       float64     `json:"score"`
+	TitleScore  float64     `json:"titleScore"`
+	YearScore   float64     `json:"yearScore"`
+	AuthorScore float64     `json:"authorScore"`
+}
+
+// PublicationPage is one page of a publication listing along with the number
+// of publications matching the filter.
+type PublicationPage struct {
+	Items    []Publication `json:"items"`
+	Total    int           `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"pageSize"`
+}
+
 type Researcher struct {
 	ID              int                `json:"id"`
 	Name            LocalizedString    `json:"name"`
@@ -49,6 +213,13 @@ type Researcher struct {
 	RecentHIndex    int                `json:"recentHIndex"`
 }
 
+// AuditState returns the researcher without its publication list, which is
+// audited separately and would otherwise swamp the diff.
+func (r Researcher) AuditState() Researcher {
+	r.Publications = nil
+	return r
+}
+
 type ResearcherWithPublicationsCount struct {
 	Researcher
 	PublicationsCount int `json:"publicationsCount"`
@@ -113,6 +284,461 @@ type Discipline struct {
 	Image       string                 `json:"image"`
 }
 
+type User struct {
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	ResearcherID *int   `json:"researcherId,omitempty"`
+	PasswordHash string `json:"-"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// CanEditResearcher reports whether the user may edit the researcher's profile:
+// admins and editors may edit anyone, researchers only their linked profile.
+func (u *User) CanEditResearcher(researcherID int) bool {
+	switch u.Role {
+	case RoleAdmin, RoleEditor:
+		return true
+	case RoleResearcher:
+		return u.ResearcherID != nil && *u.ResearcherID == researcherID
+	}
+	return false
+}
+
+type Session struct {
+	ID         int    `json:"id"`
+	UserID     int    `json:"userId"`
+	Username   string `json:"username"`
+	CreatedAt  string `json:"createdAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	Current    bool   `json:"current"`
+
+	CSRFTokenHash string `json:"-"`
+}
+
+type APIToken struct {
+	ID         int      `json:"id"`
+	UserID     int      `json:"userId"`
+	Username   string   `json:"username"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"createdAt"`
+	ExpiresAt  *string  `json:"expiresAt,omitempty"`
+	LastUsedAt *string  `json:"lastUsedAt,omitempty"`
+}
+
+// APIScopeResources lists the API resources an API token can be granted
+// access to, each as "<resource>:read" or "<resource>:write".
+var APIScopeResources = []string{
+	"partners", "projects", "researchers", "publications",
+	"training", "disciplines", "files", "users", "sessions", "audit",
+	"venues", "reports", "external-people", "crawl-jobs",
+}
+
+func IsValidScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok || (action != "read" && action != "write") {
+		return false
+	}
+	for _, r := range APIScopeResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token was granted scope. A write scope
+// implies read access to the same resource.
+func (t *APIToken) HasScope(scope string) bool {
+	resource, _, _ := strings.Cut(scope, ":")
+	for _, granted := range t.Scopes {
+		if granted == scope || granted == resource+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+
+	AuditActionLoginFailed = "login_failed"
+)
+
+const (
+	AuditEntityPartner     = "partner"
+	AuditEntityProject     = "project"
+	AuditEntityResearcher  = "researcher"
+	AuditEntityPublication = "publication"
+	AuditEntityTraining    = "training"
+	AuditEntityDiscipline  = "discipline"
+	AuditEntityVenue       = "venue"
+	AuditEntityAttachment  = "attachment"
+	AuditEntityPerson      = "external_person"
+	AuditEntityLogin       = "login"
+)
+
+type AuditChange struct {
+	Before json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After  json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+}
+
+type AuditEntry struct {
+	ID        int                    `json:"id"`
+	ActorID   *int                   `json:"actorId,omitempty"`
+	Actor     string                 `json:"actor"`
+	Entity    string                 `json:"entity"`
+	EntityID  int                    `json:"entityId"`
+	Action    string                 `json:"action"`
+	Before    json.RawMessage        `json:"before,omitempty" swaggertype:"object"`
+	After     json.RawMessage        `json:"after,omitempty" swaggertype:"object"`
+	Diff      map[string]AuditChange `json:"diff,omitempty"`
+	CreatedAt string                 `json:"createdAt"`
+}
+
+// AuditFilter narrows down audit log queries; zero values match everything.
+type AuditFilter struct {
+	Entity   string
+	EntityID int
+	ActorID  int
+	Actor    string
+	Action   string
+	From     string
+	To       string
+	Limit    int
+	Offset   int
+}
+
+// Venue is a journal or conference series in the venue registry. Aliases are
+// further names the venue is known by, used when matching publications.
+type Venue struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Aliases   []string        `json:"aliases"`
+	ISSN      string          `json:"issn"`
+	EISSN     string          `json:"eissn"`
+	Publisher string          `json:"publisher"`
+	Scopus    bool            `json:"scopus"`
+	WoS       bool            `json:"wos"`
+	RSCI      bool            `json:"rsci"`
+	Quartiles []VenueQuartile `json:"quartiles"`
+}
+
+// VenueQuartile is the quartile, 1 to 4, a venue was ranked in for a year.
+type VenueQuartile struct {
+	Year     int `json:"year"`
+	Quartile int `json:"quartile"`
+}
+
+func NewVenue() Venue {
+	return Venue{
+		Aliases:   []string{},
+		Quartiles: []VenueQuartile{},
+	}
+}
+
+// QuartileImportReport is the outcome of importing venue quartiles from a
+// CSV file. Unmatched rows name venues missing from the registry.
+type QuartileImportReport struct {
+	Updated   int                 `json:"updated"`
+	Created   int                 `json:"created"`
+	Unmatched []QuartileImportRow `json:"unmatched"`
+	Failed    []QuartileImportRow `json:"failed"`
+}
+
+// QuartileImportRow identifies a row of a quartile CSV file.
+type QuartileImportRow struct {
+	Line  int    `json:"line"`
+	Title string `json:"title,omitempty"`
+	ISSN  string `json:"issn,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// VenueReportFilter narrows down venue reports to publication years; zero
+// values match everything.
+type VenueReportFilter struct {
+	FromYear int
+	ToYear   int
+}
+
+// VenueStats counts publications by the quartile and indexing of their venue
+// in the year of publication. A venue ranked in no quartile that year falls
+// back to its latest earlier quartile. Q1 to Q4 and NoQuartile add up to
+// Total.
+type VenueStats struct {
+	Total      int `json:"total"`
+	Q1         int `json:"q1"`
+	Q2         int `json:"q2"`
+	Q3         int `json:"q3"`
+	Q4         int `json:"q4"`
+	NoQuartile int `json:"noQuartile"`
+	Scopus     int `json:"scopus"`
+	WoS        int `json:"wos"`
+	RSCI       int `json:"rsci"`
+	// NoVenue counts publications not linked to the venue registry.
+	NoVenue int `json:"noVenue"`
+}
+
+type VenueYearStats struct {
+	Year int `json:"year"`
+	VenueStats
+}
+
+type VenueResearcherStats struct {
+	ResearcherID int             `json:"researcherId"`
+	Name         LocalizedString `json:"name"`
+	LastName     LocalizedString `json:"lastName"`
+	VenueStats
+}
+
+// ExternalPerson is a co-author from outside the lab. Their publications list
+// them under one of their aliases, the spellings of their name. A person
+// promoted to a researcher keeps their aliases, so that publications added
+// later under them are linked to the researcher.
+type ExternalPerson struct {
+	ID           int             `json:"id"`
+	Name         LocalizedString `json:"name"`
+	Aliases      []string        `json:"aliases"`
+	Orcid        string          `json:"orcid"`
+	Affiliation  LocalizedString `json:"affiliation"`
+	ResearcherID *int            `json:"researcherId,omitempty"`
+	Publications int             `json:"publications"`
+	CreatedAt    string          `json:"createdAt"`
+}
+
+// CoauthorGraphFilter narrows down the publications a co-authorship graph is
+// built from; zero values match everything. DisciplineID keeps publications
+// with an author teaching the discipline, and NoExternal leaves out authors
+// from outside the lab.
+type CoauthorGraphFilter struct {
+	FromYear     int
+	ToYear       int
+	DisciplineID int
+	NoExternal   bool
+}
+
+// CoauthorGraph is the network of authors of the lab's publications. An
+// edge joins two authors of the same publication, at least one of them a
+// researcher of the lab.
+type CoauthorGraph struct {
+	Nodes []CoauthorNode `json:"nodes"`
+	Edges []CoauthorEdge `json:"edges"`
+}
+
+// CoauthorNode is a researcher or an external author, whose name variants
+// collapse into one node. Publications counts the publications of the graph
+// the author is on.
+type CoauthorNode struct {
+	ID           string          `json:"id"`
+	ResearcherID *int            `json:"researcherId,omitempty"`
+	Name         LocalizedString `json:"name"`
+	External     bool            `json:"external"`
+	Publications int             `json:"publications"`
+}
+
+// CoauthorEdge counts the publications two authors wrote together and lists
+// the years they appeared in.
+type CoauthorEdge struct {
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	Publications int    `json:"publications"`
+	Years        []int  `json:"years"`
+}
+
+// Statuses of crawl jobs and of the researchers they crawl. Researchers
+// without a Google Scholar profile are skipped.
+const (
+	CrawlStatusRunning   = "running"
+	CrawlStatusCompleted = "completed"
+	CrawlStatusFailed    = "failed"
+	CrawlStatusCancelled = "cancelled"
+	CrawlStatusSkipped   = "skipped"
+)
+
+// CrawlJob is a crawl of every researcher or, with ResearcherID, of one.
+// StartedBy names the user who started it, or "schedule". The counts add up
+// the results of the researchers crawled so far.
+type CrawlJob struct {
+	ID                  int           `json:"id"`
+	ResearcherID        *int          `json:"researcherId,omitempty"`
+	Status              string        `json:"status"`
+	StartedBy           string        `json:"startedBy"`
+	Researchers         int           `json:"researchers"`
+	Crawled             int           `json:"crawled"`
+	Failed              int           `json:"failed"`
+	NewPublications     int           `json:"newPublications"`
+	UpdatedPublications int           `json:"updatedPublications"`
+	Error               string        `json:"error,omitempty"`
+	StartedAt           string        `json:"startedAt"`
+	FinishedAt          *string       `json:"finishedAt,omitempty"`
+	Results             []CrawlResult `json:"results,omitempty"`
+}
+
+// CrawlResult is what a crawl job did for one researcher. Errors lists the
+// problems met along the way; a researcher whose crawl could not finish has
+// failed.
+type CrawlResult struct {
+	ResearcherID        int      `json:"researcherId"`
+	Status              string   `json:"status"`
+	NewPublications     int      `json:"newPublications"`
+	UpdatedPublications int      `json:"updatedPublications"`
+	Errors              []string `json:"errors"`
+	StartedAt           string   `json:"startedAt"`
+	FinishedAt          *string  `json:"finishedAt,omitempty"`
+}
+
+// Intervals of a metrics history.
+const (
+	MetricsIntervalDay   = "day"
+	MetricsIntervalMonth = "month"
+)
+
+// MetricsHistoryFilter selects the snapshots of a metrics history. Interval
+// keeps the last snapshot of each day or month; From and To are dates in
+// YYYY-MM-DD bounding the snapshots, empty for no bound.
+type MetricsHistoryFilter struct {
+	Interval string
+	From     string
+	To       string
+}
+
+// CitationSnapshot is the citation count of a publication at the end of a day
+// or month, as last recorded by the crawler at RecordedAt.
+type CitationSnapshot struct {
+	Period         string `json:"period"`
+	RecordedAt     string `json:"recordedAt"`
+	CitationsCount int    `json:"citationsCount"`
+}
+
+// MetricsSnapshot holds the citation metrics of a researcher at the end of a
+// day or month, as last recorded by the crawler at RecordedAt. The scraped
+// metrics come from the researcher's Google Scholar profile; the local ones are
+// computed from the publications stored here.
+type MetricsSnapshot struct {
+	ResearcherID    int    `json:"researcherId"`
+	Period          string `json:"period"`
+	RecordedAt      string `json:"recordedAt"`
+	TotalCitations  int    `json:"totalCitations"`
+	HIndex          int    `json:"hIndex"`
+	RecentCitations int    `json:"recentCitations"`
+	RecentHIndex    int    `json:"recentHIndex"`
+	LocalCitations  int    `json:"localCitations"`
+	LocalHIndex     int    `json:"localHIndex"`
+	LocalI10Index   int    `json:"localI10Index"`
+}
+
+// Entity types covered by full-text search.
+const (
+	SearchTypePublication = "publication"
+	SearchTypeResearcher  = "researcher"
+	SearchTypeProject     = "project"
+	SearchTypeTraining    = "training"
+)
+
+var SearchTypes = []string{SearchTypePublication, SearchTypeResearcher, SearchTypeProject, SearchTypeTraining}
+
+// SearchQuery describes a full-text search. Lang picks the language of the
+// returned titles and snippets; Limit applies to each entity type.
+type SearchQuery struct {
+	Query string
+	Types []string
+	Lang  string
+	Limit int
+}
+
+// SearchHit is one matching entity. Title and Snippet are HTML-escaped, with
+// the matched terms wrapped in <mark> tags.
+type SearchHit struct {
+	ID      int     `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// SearchResults maps each searched entity type to its hits, best first.
+type SearchResults map[string][]SearchHit
+
+// LoginAttempt tracks failed logins for one key, either "user:<name>" or "ip:<addr>".
+type LoginAttempt struct {
+	Key           string  `json:"key"`
+	Failures      int     `json:"failures"`
+	LastFailureAt string  `json:"lastFailureAt"`
+	BlockedUntil  *string `json:"blockedUntil,omitempty"`
+}
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleEditor, RoleResearcher:
+		return true
+	}
+	return false
+}
+
+func IsValidPublicationType(publicationType string) bool {
+	switch publicationType {
+	case PublicationTypeArticle, PublicationTypeConference, PublicationTypeBookChapter,
+		PublicationTypeThesis, PublicationTypePatent:
+		return true
+	}
+	return false
+}
+
+func IsValidAttachmentType(attachmentType string) bool {
+	switch attachmentType {
+	case AttachmentTypePreprint, AttachmentTypeSlides, AttachmentTypeCode,
+		AttachmentTypeDataset, AttachmentTypeSupplementary, AttachmentTypeOther:
+		return true
+	}
+	return false
+}
+
+func IsValidAuthorRole(role string) bool {
+	switch role {
+	case "", AuthorRoleCorresponding, AuthorRoleSupervisor:
+		return true
+	}
+	return false
+}
+
+// GuessPublicationType infers the type of a publication from the name of its
+// venue, defaulting to an article.
+func GuessPublicationType(venue string) string {
+	venue = strings.ToLower(venue)
+	containsAny := func(words ...string) bool {
+		for _, word := range words {
+			if strings.Contains(venue, word) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case containsAny("patent", "патент"):
+		return PublicationTypePatent
+	case containsAny("thesis", "dissertation", "диссертац"):
+		return PublicationTypeThesis
+	case containsAny("conference", "proceedings", "symposium", "workshop", "congress",
+		"конференц", "симпозиум", "семинар", "конгресс"):
+		return PublicationTypeConference
+	}
+	return PublicationTypeArticle
+}
+
+func IsValidPublicationSort(sort string) bool {
+	switch sort {
+	case PublicationSortDate, PublicationSortCitations, PublicationSortTitle:
+		return true
+	}
+	return false
+}
+
 func NewLocalizedString() LocalizedString {
 	return LocalizedString{}
 }
@@ -131,6 +757,7 @@ func NewPartnersData() PartnersData {
 func NewPublication() Publication {
 	return Publication{
 		Authors:        []Author{},
+		Type:           PublicationTypeArticle,
 		CitationsCount: 0,
 	}
 }
@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestCanEditResearcher(t *testing.T) {
+	own, other := 3, 4
+	tests := []struct {
+		user User
+		want bool
+	}{
+		{User{Role: RoleAdmin}, true},
+		{User{Role: RoleEditor}, true},
+		{User{Role: RoleResearcher, ResearcherID: &own}, true},
+		{User{Role: RoleResearcher, ResearcherID: &other}, false},
+		{User{Role: RoleResearcher}, false},
+		{User{Role: "guest", ResearcherID: &own}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.user.CanEditResearcher(own); got != tt.want {
+			t.Errorf("%+v.CanEditResearcher(%d) = %v, want %v", tt.user, own, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteAPITokenRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteAPITokenRepo(db *sql.DB) *SQLiteAPITokenRepo {
+	return &SQLiteAPITokenRepo{db: db}
+}
+
+const apiTokenColumns = `t.id, t.user_id, u.username, t.name, t.scopes, t.created_at, t.expires_at, t.last_used_at`
+
+func scanAPIToken(scanner interface{ Scan(...interface{}) error }) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	if err := scanner.Scan(
+		&token.ID, &token.UserID, &token.Username, &token.Name, &scopes,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	token.Scopes = []string{}
+	if scopes != "" {
+		token.Scopes = strings.Split(scopes, " ")
+	}
+	return &token, nil
+}
+
+func (r *SQLiteAPITokenRepo) Create(token models.APIToken, tokenHash string) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at) VALUES (?, ?, ?, ?, ?)",
+		token.UserID, token.Name, tokenHash, strings.Join(token.Scopes, " "), token.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *SQLiteAPITokenRepo) GetByID(id int) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(
+		`SELECT `+apiTokenColumns+`
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id
+		WHERE t.id = ?`,
+		id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("api token not found")
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// GetByTokenHash returns the unexpired token stored under tokenHash.
+func (r *SQLiteAPITokenRepo) GetByTokenHash(tokenHash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(
+		`SELECT `+apiTokenColumns+`
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id
+		WHERE t.token_hash = ? AND (t.expires_at IS NULL OR t.expires_at > ?)`,
+		tokenHash, time.Now().UTC().Format(models.TimestampLayout),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("api token not found")
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// GetAll returns all tokens, limited to one user when userID is non-zero.
+func (r *SQLiteAPITokenRepo) GetAll(userID int) ([]models.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + `
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id`
+	args := []interface{}{}
+
+	if userID != 0 {
+		query += " WHERE t.user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY t.id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, nil
+}
+
+func (r *SQLiteAPITokenRepo) Touch(id int) error {
+	_, err := r.db.Exec(
+		"UPDATE api_tokens SET last_used_at = ? WHERE id = ?",
+		time.Now().UTC().Format(models.TimestampLayout), id,
+	)
+	return err
+}
+
+func (r *SQLiteAPITokenRepo) Delete(id int) error {
+	res, err := r.db.Exec("DELETE FROM api_tokens WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("api token not found")
+	}
+	return nil
+}
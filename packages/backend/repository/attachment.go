@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteAttachmentRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewSQLiteAttachmentRepo(db *sql.DB, lsRepo LocalizedStringRepo) *SQLiteAttachmentRepo {
+	return &SQLiteAttachmentRepo{db: db, localizedStringRepo: lsRepo}
+}
+
+func (r *SQLiteAttachmentRepo) Create(attachment models.PublicationAttachment) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	titleID, err := r.localizedStringRepo.CreateTx(tx, attachment.Title)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(
+		`INSERT INTO publication_attachments
+		(publication_id, type, title_id, url, file, file_name, content_type, size, attachment_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		attachment.PublicationID, attachment.Type, titleID, attachment.URL, attachment.File,
+		attachment.FileName, attachment.ContentType, attachment.Size, attachment.Order,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *SQLiteAttachmentRepo) GetByID(id int) (*models.PublicationAttachment, error) {
+	var attachment models.PublicationAttachment
+	err := scanAttachment(r.db.QueryRow(attachmentQuery+" WHERE a.id = ?", id), &attachment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *SQLiteAttachmentRepo) GetByPublication(publicationID int) ([]models.PublicationAttachment, error) {
+	return scanAttachments(r.db.Query(
+		attachmentQuery+" WHERE a.publication_id = ? ORDER BY a.attachment_order, a.id",
+		publicationID,
+	))
+}
+
+// Update saves the type, title, URL and position of an attachment. The file of
+// an uploaded attachment cannot be replaced.
+func (r *SQLiteAttachmentRepo) Update(attachment models.PublicationAttachment) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID int64
+	err = tx.QueryRow("SELECT title_id FROM publication_attachments WHERE id = ?", attachment.ID).Scan(&titleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("attachment not found")
+		}
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, titleID, attachment.Title); err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"UPDATE publication_attachments SET type = ?, url = ?, attachment_order = ? WHERE id = ?",
+		attachment.Type, attachment.URL, attachment.Order, attachment.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes an attachment along with its uploaded file.
+func (r *SQLiteAttachmentRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID int64
+	var file string
+	err = tx.QueryRow("SELECT title_id, file FROM publication_attachments WHERE id = ?", id).Scan(&titleID, &file)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("attachment not found")
+		}
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM publication_attachments WHERE id = ?", id); err != nil {
+		return err
+	}
+	if err = r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	removeAttachmentFiles([]string{file})
+	return nil
+}
+
+const attachmentQuery = `SELECT a.id, a.publication_id, a.type, ls.en, ls.ru, a.url, a.file, a.file_name,
+	a.content_type, a.size, a.attachment_order, a.created_at
+	FROM publication_attachments a
+	JOIN localized_strings ls ON a.title_id = ls.id`
+
+func scanAttachment(scanner interface{ Scan(...interface{}) error }, attachment *models.PublicationAttachment) error {
+	return scanner.Scan(
+		&attachment.ID, &attachment.PublicationID, &attachment.Type, &attachment.Title.En, &attachment.Title.Ru,
+		&attachment.URL, &attachment.File, &attachment.FileName, &attachment.ContentType, &attachment.Size,
+		&attachment.Order, &attachment.CreatedAt,
+	)
+}
+
+func scanAttachments(rows *sql.Rows, err error) ([]models.PublicationAttachment, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.PublicationAttachment{}
+	for rows.Next() {
+		var attachment models.PublicationAttachment
+		if err := scanAttachment(rows, &attachment); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// deletePublicationAttachments deletes the attachments of a publication and
+// their titles, returning the uploaded files to remove once the transaction
+// commits. placeholder returns the placeholder of the i-th parameter, counting
+// from 0.
+func deletePublicationAttachments(tx *sql.Tx, publicationID int, placeholder func(i int) string) ([]string, error) {
+	rows, err := tx.Query(
+		"SELECT title_id, file FROM publication_attachments WHERE publication_id = "+placeholder(0),
+		publicationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var titleIDs []int64
+	var files []string
+	for rows.Next() {
+		var titleID int64
+		var file string
+		if err := rows.Scan(&titleID, &file); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		titleIDs = append(titleIDs, titleID)
+		files = append(files, file)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM publication_attachments WHERE publication_id = "+placeholder(0), publicationID); err != nil {
+		return nil, err
+	}
+	for _, titleID := range titleIDs {
+		if _, err := tx.Exec("DELETE FROM localized_strings WHERE id = "+placeholder(0), titleID); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// removeAttachmentFiles removes the uploaded files of deleted attachments.
+// Their rows are gone already, so a file that cannot be removed is only
+// logged.
+func removeAttachmentFiles(files []string) {
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove attachment file %s: %v", file, err)
+		}
+	}
+}
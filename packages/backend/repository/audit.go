@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteAuditRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteAuditRepo(db *sql.DB) *SQLiteAuditRepo {
+	return &SQLiteAuditRepo{db: db}
+}
+
+func (r *SQLiteAuditRepo) Create(entry models.AuditEntry) (int64, error) {
+	var diff []byte
+	if len(entry.Diff) > 0 {
+		var err error
+		if diff, err = json.Marshal(entry.Diff); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := r.db.Exec(
+		`INSERT INTO audit_log (actor_id, actor, entity, entity_id, action, before, after, diff)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ActorID, entry.Actor, entry.Entity, entry.EntityID, entry.Action,
+		nullableJSON(entry.Before), nullableJSON(entry.After), nullableJSON(diff),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// GetAll returns audit entries matching filter, newest first.
+func (r *SQLiteAuditRepo) GetAll(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := `SELECT id, actor_id, actor, entity, entity_id, action, before, after, diff, created_at
+		FROM audit_log WHERE 1 = 1`
+	args := []interface{}{}
+
+	if filter.Entity != "" {
+		query += " AND entity = ?"
+		args = append(args, filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		query += " AND entity_id = ?"
+		args = append(args, filter.EntityID)
+	}
+	if filter.ActorID != 0 {
+		query += " AND actor_id = ?"
+		args = append(args, filter.ActorID)
+	}
+	if filter.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		query += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.From != "" {
+		query += " AND created_at >= ?"
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += " AND created_at <= ?"
+		args = append(args, filter.To)
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after, diff sql.NullString
+		if err := rows.Scan(
+			&entry.ID, &entry.ActorID, &entry.Actor, &entry.Entity, &entry.EntityID,
+			&entry.Action, &before, &after, &diff, &entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		if diff.Valid {
+			if err := json.Unmarshal([]byte(diff.String), &entry.Diff); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func nullableJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,1227 @@
+package repository_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/damirahm/diplom/backend/config"
+	"github.com/damirahm/diplom/backend/db"
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/repository"
+)
+
+// The tests in this file make up a conformance suite: each runs against a
+// fresh SQLite database and, when DATABASE_URL points at a PostgreSQL server,
+// against a fresh schema there, so both backends are held to the same
+// behaviour.
+
+// forEachBackend runs test once per available backend, each time on an empty,
+// fully migrated database.
+func forEachBackend(t *testing.T, test func(t *testing.T, repos *repository.Repositories)) {
+	t.Run("sqlite", func(t *testing.T) {
+		openDB(t, config.DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+		test(t, repository.New(config.DriverSQLite, db.DB))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dataSource := os.Getenv("DATABASE_URL")
+		if dataSource == "" {
+			t.Skip("DATABASE_URL is not set")
+		}
+		openDB(t, config.DriverPostgres, postgresSchema(t, dataSource))
+		test(t, repository.New(config.DriverPostgres, db.DB))
+	})
+}
+
+func openDB(t *testing.T, driver, dataSource string) {
+	t.Helper()
+	if err := db.InitDB(driver, dataSource); err != nil {
+		t.Fatalf("failed to open %s database: %v", driver, err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+}
+
+// postgresSchema creates a schema of its own for the test, dropped when it
+// ends, and returns dataSource with the schema as its search path.
+func postgresSchema(t *testing.T, dataSource string) string {
+	t.Helper()
+
+	admin, err := sql.Open(config.DriverPostgres, dataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to PostgreSQL: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(dataSource)
+	if err != nil {
+		t.Fatalf("DATABASE_URL must be a URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func ls(en, ru string) models.LocalizedString {
+	return models.LocalizedString{En: en, Ru: ru}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// mustCreate returns a function that checks the result of a Create call and
+// returns the new ID, as in mustCreate(t)(repo.Create(v)).
+func mustCreate(t *testing.T) func(id int64, err error) int {
+	t.Helper()
+	return func(id int64, err error) int {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+		return int(id)
+	}
+}
+
+func newResearcher(t *testing.T, repos *repository.Repositories, name, lastName string) models.Researcher {
+	t.Helper()
+	researcher := models.Researcher{
+		Name:     ls(name, name+" ru"),
+		LastName: ls(lastName, lastName+" ru"),
+		Position: ls("Professor", "Профессор"),
+		Bio:      ls("Works on neural networks", "Нейронные сети"),
+		Profiles: models.ResearcherProfiles{Orcid: ptr("0000-0002-1825-0097")},
+	}
+	researcher.ID = mustCreate(t)(repos.Researchers.Create(researcher))
+	return researcher
+}
+
+func TestLocalizedStringRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		id, err := repos.LocalizedStrings.Create(ls("Neuron", "Нейрон"))
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		got, err := repos.LocalizedStrings.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if *got != ls("Neuron", "Нейрон") {
+			t.Errorf("Get = %+v", *got)
+		}
+
+		if err := repos.LocalizedStrings.Update(id, ls("Synapse", "Синапс")); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, _ := repos.LocalizedStrings.Get(id); got == nil || *got != ls("Synapse", "Синапс") {
+			t.Errorf("Get after Update = %+v", got)
+		}
+
+		if err := repos.LocalizedStrings.Delete(id); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.LocalizedStrings.Get(id); err == nil {
+			t.Error("Get after Delete succeeded")
+		}
+	})
+}
+
+func TestPartnerRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		university := models.Partner{Name: "MSU", Logo: "/msu.png", URL: "https://msu.ru", Type: "university"}
+		university.ID = mustCreate(t)(repos.Partners.Create(university))
+		company := models.Partner{Name: "Acme", Logo: "/acme.png", URL: "https://acme.com", Type: "enterprise"}
+		company.ID = mustCreate(t)(repos.Partners.Create(company))
+
+		got, err := repos.Partners.GetByID(university.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if *got != university {
+			t.Errorf("GetByID = %+v, want %+v", *got, university)
+		}
+
+		universities, err := repos.Partners.GetAll("university")
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if !reflect.DeepEqual(universities, []models.Partner{university}) {
+			t.Errorf("GetAll(university) = %+v", universities)
+		}
+		enterprises, err := repos.Partners.GetAll("enterprise")
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if !reflect.DeepEqual(enterprises, []models.Partner{company}) {
+			t.Errorf("GetAll(enterprise) = %+v", enterprises)
+		}
+
+		university.Name = "Moscow State University"
+		if err := repos.Partners.Update(university); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got, _ := repos.Partners.GetByID(university.ID); got == nil || got.Name != university.Name {
+			t.Errorf("GetByID after Update = %+v", got)
+		}
+
+		if err := repos.Partners.Delete(company.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Partners.GetByID(company.ID); err == nil {
+			t.Error("GetByID after Delete succeeded")
+		}
+	})
+}
+
+func TestResearcherRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		researcher := newResearcher(t, repos, "Ivan", "Petrov")
+		newResearcher(t, repos, "Anna", "Sidorova")
+
+		got, err := repos.Researchers.GetByID(researcher.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Name != researcher.Name || got.LastName != researcher.LastName ||
+			got.Position != researcher.Position || got.Bio != researcher.Bio {
+			t.Errorf("GetByID = %+v, want %+v", got.Researcher, researcher)
+		}
+		if got.Profiles.Orcid == nil || *got.Profiles.Orcid != *researcher.Profiles.Orcid {
+			t.Errorf("GetByID profiles = %+v", got.Profiles)
+		}
+
+		all, err := repos.Researchers.GetAll()
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		if len(all) != 2 {
+			t.Errorf("GetAll returned %d researchers, want 2", len(all))
+		}
+
+		found, err := repos.Researchers.FindByFullName("Ivan Petrov")
+		if err != nil || found == nil || found.ID != researcher.ID {
+			t.Errorf("FindByFullName = %+v, %v", found, err)
+		}
+		byLastName, err := repos.Researchers.FindByLastName("Petrov")
+		if err != nil || len(byLastName) != 1 || byLastName[0].ID != researcher.ID {
+			t.Errorf("FindByLastName = %+v, %v", byLastName, err)
+		}
+
+		researcher.Position = ls("Head of the lab", "Заведующий лабораторией")
+		researcher.HIndex = 12
+		if err := repos.Researchers.Update(researcher); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err = repos.Researchers.GetByID(researcher.ID)
+		if err != nil {
+			t.Fatalf("GetByID after Update: %v", err)
+		}
+		if got.Position != researcher.Position || got.HIndex != 12 {
+			t.Errorf("GetByID after Update = %+v", got.Researcher)
+		}
+
+		if err := repos.Researchers.Delete(researcher.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Researchers.GetByID(researcher.ID); err != sql.ErrNoRows {
+			t.Errorf("GetByID after Delete: err = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestPublicationRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		researcher := newResearcher(t, repos, "Ivan", "Petrov")
+
+		pub := models.Publication{
+			Title: ls("Spiking neural networks", "Импульсные нейронные сети"),
+			Authors: []models.Author{
+				{Name: ls("J. Smith", "Дж. Смит")},
+				{Name: researcher.Name, ID: &researcher.ID, Role: models.AuthorRoleCorresponding},
+			},
+			Type:           models.PublicationTypeArticle,
+			Venue:          "Neural Computation",
+			Volume:         "31",
+			Issue:          "4",
+			Pages:          "1-20",
+			Publisher:      "MIT Press",
+			DOI:            "10.1162/neco_a_01000",
+			Abstract:       "About spikes.",
+			PublishedAt:    "2019-04-01",
+			CitationsCount: 7,
+			Link:           "https://example.org/snn",
+		}
+		pub.ID = mustCreate(t)(repos.Publications.Create(pub))
+
+		// New publications are hidden until reviewed; Update publishes them.
+		if got, err := repos.Publications.GetByID(pub.ID); err != nil || got.Visible {
+			t.Fatalf("GetByID after Create = %+v, %v; want a hidden publication", got, err)
+		}
+		pub.Visible = true
+		if err := repos.Publications.Update(pub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		got, err := repos.Publications.GetByID(pub.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Title != pub.Title || got.Type != pub.Type || got.Venue != pub.Venue || got.Volume != pub.Volume ||
+			got.Issue != pub.Issue || got.Pages != pub.Pages || got.Publisher != pub.Publisher || got.DOI != pub.DOI ||
+			got.Abstract != pub.Abstract || got.PublishedAt != pub.PublishedAt ||
+			got.CitationsCount != pub.CitationsCount || got.Link != pub.Link || got.Visible != pub.Visible {
+			t.Errorf("GetByID = %+v, want %+v", *got, pub)
+		}
+		if len(got.Authors) != 2 {
+			t.Fatalf("GetByID returned %d authors, want 2", len(got.Authors))
+		}
+		if got.Authors[0].ID != nil || got.Authors[0].Name != pub.Authors[0].Name {
+			t.Errorf("first author = %+v, want the external author", got.Authors[0])
+		}
+		if got.Authors[1].ID == nil || *got.Authors[1].ID != researcher.ID || got.Authors[1].Role != models.AuthorRoleCorresponding {
+			t.Errorf("second author = %+v, want the corresponding researcher", got.Authors[1])
+		}
+
+		authors, err := repos.Publications.GetAuthors(pub.ID)
+		if err != nil || len(authors) != 1 || authors[0].ID != researcher.ID {
+			t.Errorf("GetAuthors = %+v, %v", authors, err)
+		}
+		byTitle, err := repos.Publications.GetByTitle(pub.Title.En)
+		if err != nil || byTitle == nil || byTitle.ID != pub.ID {
+			t.Errorf("GetByTitle = %+v, %v", byTitle, err)
+		}
+
+		hidden := models.Publication{
+			Title:       ls("A hidden report", "Скрытый отчёт"),
+			Type:        models.PublicationTypeThesis,
+			PublishedAt: "2021-01-01",
+		}
+		hidden.ID = mustCreate(t)(repos.Publications.Create(hidden))
+
+		for name, tc := range map[string]struct {
+			filter models.PublicationFilter
+			want   []int
+		}{
+			"visible":    {models.PublicationFilter{Visible: ptr(true)}, []int{pub.ID}},
+			"year":       {models.PublicationFilter{Year: 2021}, []int{hidden.ID}},
+			"researcher": {models.PublicationFilter{ResearcherID: researcher.ID}, []int{pub.ID}},
+			"type":       {models.PublicationFilter{Type: models.PublicationTypeThesis}, []int{hidden.ID}},
+		} {
+			tc.filter.Page, tc.filter.PageSize = 1, 10
+			pubs, total, err := repos.Publications.List(tc.filter)
+			if err != nil {
+				t.Errorf("List(%s): %v", name, err)
+				continue
+			}
+			var ids []int
+			for _, p := range pubs {
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.want) || total != len(tc.want) {
+				t.Errorf("List(%s) = %v (total %d), want %v", name, ids, total, tc.want)
+			}
+		}
+
+		count, err := repos.Publications.GetTotalCount()
+		if err != nil || count != 2 {
+			t.Errorf("GetTotalCount = %d, %v; want 2", count, err)
+		}
+		withCount, err := repos.Researchers.GetByID(researcher.ID)
+		if err != nil || withCount.PublicationsCount != 1 {
+			t.Errorf("researcher publications count = %+v, %v; want 1", withCount, err)
+		}
+
+		pub.Title = ls("Spiking neural networks, revised", "Импульсные нейронные сети, ред.")
+		pub.Authors = pub.Authors[1:]
+		pub.CitationsCount = 9
+		if err := repos.Publications.Update(pub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err = repos.Publications.GetByID(pub.ID)
+		if err != nil {
+			t.Fatalf("GetByID after Update: %v", err)
+		}
+		if got.Title != pub.Title || got.CitationsCount != 9 || len(got.Authors) != 1 {
+			t.Errorf("GetByID after Update = %+v", *got)
+		}
+
+		if err := repos.Publications.Delete(pub.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Publications.GetByID(pub.ID); err != sql.ErrNoRows {
+			t.Errorf("GetByID after Delete: err = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestPublicationAuthorOrder(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		petrov := newResearcher(t, repos, "Ivan", "Petrov")
+		ivanova := newResearcher(t, repos, "Anna", "Ivanova")
+
+		pub := models.Publication{
+			Title: ls("Ordered authors", "Упорядоченные авторы"),
+			Authors: []models.Author{
+				{Name: ls("J. Smith", "Дж. Смит")},
+				{Name: petrov.Name, ID: &petrov.ID, Role: models.AuthorRoleCorresponding},
+				{Name: ls("K. Lee", "К. Ли")},
+				{Name: ivanova.Name, ID: &ivanova.ID, Role: models.AuthorRoleSupervisor},
+			},
+			PublishedAt: "2021-01-01",
+		}
+		pub.ID = mustCreate(t)(repos.Publications.Create(pub))
+		pub.Visible = true
+		if err := repos.Publications.Update(pub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		type byline struct {
+			name, role string
+			id         int
+		}
+		bylineOf := func(authors []models.Author) []byline {
+			var out []byline
+			for _, a := range authors {
+				b := byline{name: a.Name.En, role: a.Role}
+				if a.ID != nil {
+					b.id = *a.ID
+				}
+				out = append(out, b)
+			}
+			return out
+		}
+		check := func(how string, authors []models.Author, want []byline) {
+			t.Helper()
+			if got := bylineOf(authors); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: authors = %+v, want %+v", how, got, want)
+			}
+		}
+
+		want := []byline{
+			{name: "J. Smith"},
+			{name: "Ivan Petrov", role: models.AuthorRoleCorresponding, id: petrov.ID},
+			{name: "K. Lee"},
+			{name: "Anna Ivanova", role: models.AuthorRoleSupervisor, id: ivanova.ID},
+		}
+		got, err := repos.Publications.GetByID(pub.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		check("GetByID", got.Authors, want)
+		byIDs, err := repos.Publications.GetByIDs([]int{pub.ID})
+		if err != nil || len(byIDs) != 1 {
+			t.Fatalf("GetByIDs = %d publications, %v", len(byIDs), err)
+		}
+		check("GetByIDs", byIDs[0].Authors, want)
+		all, err := repos.Publications.GetAll()
+		if err != nil || len(all) != 1 {
+			t.Fatalf("GetAll = %d publications, %v", len(all), err)
+		}
+		check("GetAll", all[0].Authors, want)
+
+		pub.Authors = []models.Author{pub.Authors[3], pub.Authors[0], pub.Authors[1]}
+		pub.Authors[2].Role = ""
+		if err := repos.Publications.Update(pub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err = repos.Publications.GetByID(pub.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		want = []byline{
+			{name: "Anna Ivanova", role: models.AuthorRoleSupervisor, id: ivanova.ID},
+			{name: "J. Smith"},
+			{name: "Ivan Petrov", id: petrov.ID},
+		}
+		check("GetByID after reordering", got.Authors, want)
+
+		// A deleted researcher stays in the byline as an external author.
+		if err := repos.Researchers.Delete(ivanova.ID); err != nil {
+			t.Fatalf("Delete researcher: %v", err)
+		}
+		got, err = repos.Publications.GetByID(pub.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		want[0].id = 0
+		check("GetByID after deleting a researcher", got.Authors, want)
+	})
+}
+
+func TestPublicationSetVisibility(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		petrov := newResearcher(t, repos, "Ivan", "Petrov")
+		pub := models.Publication{
+			Title: ls("Hidden work", "Скрытая работа"),
+			Authors: []models.Author{
+				{Name: ls("J. Smith", "Дж. Смит"), Role: models.AuthorRoleCorresponding},
+				{Name: petrov.Name, ID: &petrov.ID, Role: models.AuthorRoleSupervisor},
+			},
+			Venue:       "Neural Computation",
+			PublishedAt: "2021-01-01",
+		}
+		pub.ID = mustCreate(t)(repos.Publications.Create(pub))
+		before, err := repos.Publications.GetByID(pub.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+
+		for _, visible := range []bool{true, false} {
+			if err := repos.Publications.SetVisibility(pub.ID, visible); err != nil {
+				t.Fatalf("SetVisibility(%v): %v", visible, err)
+			}
+			got, err := repos.Publications.GetByID(pub.ID)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			want := *before
+			want.Visible = visible
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("after SetVisibility(%v) got %+v, want %+v", visible, *got, want)
+			}
+		}
+
+		if err := repos.Publications.SetVisibility(pub.ID+100, true); err != sql.ErrNoRows {
+			t.Errorf("SetVisibility of a missing publication: err = %v, want sql.ErrNoRows", err)
+		}
+	})
+}
+
+func TestPublicationPossibleDuplicates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		create := func(pub models.Publication) int {
+			return mustCreate(t)(repos.Publications.Create(pub))
+		}
+		spiking := create(models.Publication{Title: ls("Spiking neural networks", "Импульсные сети")})
+		spikingAgain := create(models.Publication{Title: ls("SPIKING NEURAL NETS", "Импульсные нейросети")})
+		byDOI := create(models.Publication{Title: ls("Memristor crossbars", "Кроссбары"), DOI: "10.1000/XYZ"})
+		byDOIAgain := create(models.Publication{Title: ls("Crossbar arrays", "Массивы"), DOI: "10.1000/xyz"})
+		// Create refuses a second publication with an empty English title, so
+		// the upper-case title is matched against the Russian one with its
+		// English one.
+		echo := create(models.Publication{Title: ls("", "Сети эхо-состояний")})
+		echoUpperCase := create(models.Publication{Title: ls("СЕТИ ЭХО-СОСТОЯНИЙ", "Резервуарные вычисления")})
+		liquid := create(models.Publication{Title: ls("Liquid state machines", "Жидкостные машины")})
+		liquidWithArticle := create(models.Publication{Title: ls("The “liquid state machine”", "Машина жидких состояний")})
+		dendrites := create(models.Publication{Title: ls("Dendritic computation in pyramidal neurons", "Дендритные вычисления")})
+		dendritesTypo := create(models.Publication{Title: ls("Dendritc computation in pyramidal neurons", "Дендриты")})
+		hardware := create(models.Publication{Title: ls("Neuromorphic hardware", "Нейроморфное железо"), DOI: "10.1000/other"})
+
+		check := func(when string, want []int) {
+			t.Helper()
+			pubs, err := repos.Publications.GetPossibleDuplicates()
+			if err != nil {
+				t.Fatalf("GetPossibleDuplicates: %v", err)
+			}
+			var ids []int
+			for _, pub := range pubs {
+				ids = append(ids, pub.ID)
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Errorf("GetPossibleDuplicates %s returned %v, want %v", when, ids, want)
+			}
+		}
+		check("after Create", []int{
+			spiking, spikingAgain, byDOI, byDOIAgain,
+			echo, echoUpperCase, liquid, liquidWithArticle, dendrites, dendritesTypo,
+		})
+
+		// Renaming a publication and deleting another update the title words.
+		pub, err := repos.Publications.GetByID(hardware)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		pub.Title = ls("Spiking neural hardware", "Импульсное железо")
+		if err := repos.Publications.Update(*pub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := repos.Publications.Delete(echoUpperCase); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		check("after Update and Delete", []int{
+			spiking, spikingAgain, byDOI, byDOIAgain,
+			liquid, liquidWithArticle, dendrites, dendritesTypo, hardware,
+		})
+	})
+}
+
+func TestPublicationMerge(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		survivor := models.Publication{Title: ls("Spiking neural networks", "Импульсные сети"), Link: "https://example.org/snn"}
+		survivor.ID = mustCreate(t)(repos.Publications.Create(survivor))
+		duplicate := models.Publication{Title: ls("Spiking Neural Networks.", "Импульсные нейронные сети"), Link: "https://example.org/snn-2"}
+		duplicate.ID = mustCreate(t)(repos.Publications.Create(duplicate))
+
+		if err := repos.Metrics.RecordPublicationCitations(duplicate.ID, 8); err != nil {
+			t.Fatalf("RecordPublicationCitations: %v", err)
+		}
+
+		survivor.CitationsCount = 8
+		if err := repos.Publications.Merge(survivor, duplicate.ID); err != nil {
+			t.Fatalf("Merge: %v", err)
+		}
+
+		if _, err := repos.Publications.GetByID(duplicate.ID); err != sql.ErrNoRows {
+			t.Errorf("GetByID of the duplicate: err = %v, want sql.ErrNoRows", err)
+		}
+		history, err := repos.Metrics.GetPublicationCitationHistory(survivor.ID, models.MetricsHistoryFilter{})
+		if err != nil {
+			t.Fatalf("GetPublicationCitationHistory: %v", err)
+		}
+		if len(history) != 1 || history[0].CitationsCount != 8 {
+			t.Errorf("citation history of the survivor = %+v, want the duplicate's snapshot", history)
+		}
+	})
+}
+
+func TestVenueRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		venue := models.NewVenue()
+		venue.Name = "Journal of Applied Physics"
+		venue.Aliases = []string{"JAP"}
+		venue.ISSN = "0021-8979"
+		venue.Publisher = "AIP"
+		venue.Scopus = true
+		venue.Quartiles = []models.VenueQuartile{{Year: 2019, Quartile: 2}}
+		venue.ID = mustCreate(t)(repos.Venues.Create(venue))
+
+		if err := repos.Venues.SetQuartiles(venue.ID, []models.VenueQuartile{{Year: 2019, Quartile: 1}, {Year: 2021, Quartile: 3}}); err != nil {
+			t.Fatalf("SetQuartiles: %v", err)
+		}
+		got, err := repos.Venues.GetByID(venue.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		wantQuartiles := []models.VenueQuartile{{Year: 2019, Quartile: 1}, {Year: 2021, Quartile: 3}}
+		if got.Name != venue.Name || !reflect.DeepEqual(got.Aliases, venue.Aliases) || got.ISSN != venue.ISSN ||
+			!got.Scopus || got.WoS || !reflect.DeepEqual(got.Quartiles, wantQuartiles) {
+			t.Errorf("GetByID = %+v", *got)
+		}
+
+		for name, want := range map[string]bool{"J. Appl. Phys.": true, "jap": true, "Nature": false} {
+			id, err := repos.Venues.Match(name)
+			if err != nil {
+				t.Fatalf("Match(%q): %v", name, err)
+			}
+			if (id != nil && *id == venue.ID) != want {
+				t.Errorf("Match(%q) = %v, want a match: %v", name, id, want)
+			}
+		}
+
+		// Publications are linked to the venue their venue name matches, and
+		// counted under the latest quartile up to their year.
+		for _, pub := range []models.Publication{
+			{Title: ls("First", "Первая"), Venue: "J. Appl. Phys.", PublishedAt: "2019-03-01"},
+			{Title: ls("Second", "Вторая"), Venue: "JAP", PublishedAt: "2020-03-01"},
+			{Title: ls("Third", "Третья"), Venue: "Nature", PublishedAt: "2020-06-01"},
+			{Title: ls("Fourth", "Четвёртая"), Venue: "JAP", PublishedAt: "2018-01-01"},
+		} {
+			mustCreate(t)(repos.Publications.Create(pub))
+		}
+
+		stats, err := repos.Venues.YearStats(models.VenueReportFilter{FromYear: 2019, ToYear: 2020})
+		if err != nil {
+			t.Fatalf("YearStats: %v", err)
+		}
+		want := []models.VenueYearStats{
+			{Year: 2019, VenueStats: models.VenueStats{Total: 1, Q1: 1, Scopus: 1}},
+			{Year: 2020, VenueStats: models.VenueStats{Total: 2, Q1: 1, NoQuartile: 1, Scopus: 1, NoVenue: 1}},
+		}
+		if !reflect.DeepEqual(stats, want) {
+			t.Errorf("YearStats = %+v, want %+v", stats, want)
+		}
+
+		if err := repos.Venues.Delete(venue.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if got, err := repos.Venues.GetByID(venue.ID); err == nil && got != nil {
+			t.Errorf("GetByID after Delete = %+v", *got)
+		}
+	})
+}
+
+func TestMetricsRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		researcher := newResearcher(t, repos, "Ivan", "Petrov")
+		recordedAt := []string{"2024-01-05 10:00:00", "2024-01-20 10:00:00", "2024-02-03 10:00:00"}
+		for i, at := range recordedAt {
+			snapshot := models.MetricsSnapshot{
+				ResearcherID: researcher.ID, TotalCitations: 10 * (i + 1), HIndex: i + 1,
+				RecentCitations: 5, RecentHIndex: 1, LocalCitations: 8, LocalHIndex: 2, LocalI10Index: i,
+			}
+			if err := repos.Metrics.RecordResearcherMetrics(snapshot); err != nil {
+				t.Fatalf("RecordResearcherMetrics: %v", err)
+			}
+			// Snapshots are stamped with the current time; move them into
+			// the past to get several periods.
+			if _, err := db.DB.Exec(fmt.Sprintf(
+				"UPDATE researcher_metric_snapshots SET recorded_at = '%s' WHERE id = %d", at, i+1,
+			)); err != nil {
+				t.Fatalf("failed to backdate snapshot: %v", err)
+			}
+		}
+
+		tests := []struct {
+			filter  models.MetricsHistoryFilter
+			periods []string
+			hIndex  []int
+		}{
+			{models.MetricsHistoryFilter{}, []string{"2024-01-05", "2024-01-20", "2024-02-03"}, []int{1, 2, 3}},
+			{models.MetricsHistoryFilter{Interval: models.MetricsIntervalMonth}, []string{"2024-01", "2024-02"}, []int{2, 3}},
+			{models.MetricsHistoryFilter{From: "2024-01-10", To: "2024-01-31"}, []string{"2024-01-20"}, []int{2}},
+			{models.MetricsHistoryFilter{To: "2024-01-05"}, []string{"2024-01-05"}, []int{1}},
+		}
+		for _, tt := range tests {
+			history, err := repos.Metrics.GetResearcherMetricsHistory(researcher.ID, tt.filter)
+			if err != nil {
+				t.Fatalf("GetResearcherMetricsHistory(%+v): %v", tt.filter, err)
+			}
+			var periods []string
+			var hIndex []int
+			for _, s := range history {
+				periods = append(periods, s.Period)
+				hIndex = append(hIndex, s.HIndex)
+				if s.ResearcherID != researcher.ID || s.LocalCitations != 8 || s.LocalI10Index != s.HIndex-1 {
+					t.Errorf("GetResearcherMetricsHistory(%+v) returned %+v", tt.filter, s)
+				}
+			}
+			if !reflect.DeepEqual(periods, tt.periods) || !reflect.DeepEqual(hIndex, tt.hIndex) {
+				t.Errorf("GetResearcherMetricsHistory(%+v) = periods %v, h-index %v; want %v, %v",
+					tt.filter, periods, hIndex, tt.periods, tt.hIndex)
+			}
+		}
+
+		pubID := mustCreate(t)(repos.Publications.Create(models.Publication{Title: ls("Cited", "Цитируемая")}))
+		for _, citations := range []int{3, 5} {
+			if err := repos.Metrics.RecordPublicationCitations(pubID, citations); err != nil {
+				t.Fatalf("RecordPublicationCitations: %v", err)
+			}
+		}
+		citations, err := repos.Metrics.GetPublicationCitationHistory(pubID, models.MetricsHistoryFilter{})
+		if err != nil {
+			t.Fatalf("GetPublicationCitationHistory: %v", err)
+		}
+		if len(citations) != 1 || citations[0].CitationsCount != 5 {
+			t.Errorf("GetPublicationCitationHistory = %+v, want the last snapshot of today", citations)
+		}
+	})
+}
+
+func TestAttachmentRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		pubID := mustCreate(t)(repos.Publications.Create(models.Publication{Title: ls("Attached", "Приложения")}))
+
+		slides := models.PublicationAttachment{
+			PublicationID: pubID, Type: models.AttachmentTypeSlides, Title: ls("Slides", "Слайды"),
+			File: "uploads/attachments/a.pdf", FileName: "slides.pdf", ContentType: "application/pdf", Size: 42, Order: 2,
+		}
+		slides.ID = mustCreate(t)(repos.Attachments.Create(slides))
+		code := models.PublicationAttachment{
+			PublicationID: pubID, Type: models.AttachmentTypeCode, Title: ls("Code", "Код"),
+			URL: "https://example.org/code", Order: 1,
+		}
+		code.ID = mustCreate(t)(repos.Attachments.Create(code))
+
+		got, err := repos.Attachments.GetByID(slides.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		got.CreatedAt = ""
+		if *got != slides {
+			t.Errorf("GetByID = %+v, want %+v", *got, slides)
+		}
+
+		ids := func() []int {
+			t.Helper()
+			list, err := repos.Attachments.GetByPublication(pubID)
+			if err != nil {
+				t.Fatalf("GetByPublication: %v", err)
+			}
+			var ids []int
+			for _, a := range list {
+				ids = append(ids, a.ID)
+			}
+			return ids
+		}
+		if got := ids(); !reflect.DeepEqual(got, []int{code.ID, slides.ID}) {
+			t.Errorf("GetByPublication = %v, want the code before the slides", got)
+		}
+
+		code.Order = 3
+		code.Title = ls("Source code", "Исходный код")
+		if err := repos.Attachments.Update(code); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if got := ids(); !reflect.DeepEqual(got, []int{slides.ID, code.ID}) {
+			t.Errorf("GetByPublication after Update = %v, want the slides first", got)
+		}
+
+		if err := repos.Attachments.Delete(slides.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Attachments.GetByID(slides.ID); err == nil {
+			t.Error("GetByID after Delete succeeded")
+		}
+		if got := ids(); !reflect.DeepEqual(got, []int{code.ID}) {
+			t.Errorf("GetByPublication after Delete = %v, want only the code", got)
+		}
+	})
+}
+
+func TestExternalPersonRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		petrov := newResearcher(t, repos, "Ivan", "Petrov")
+		lab := models.Author{Name: petrov.Name, ID: &petrov.ID}
+		createPublication := func(title string, coauthor models.LocalizedString) int {
+			t.Helper()
+			pub := models.Publication{Title: ls(title, title+" ru"), Authors: []models.Author{{Name: coauthor}, lab}}
+			return mustCreate(t)(repos.Publications.Create(pub))
+		}
+		first := createPublication("First", ls("Kim Lee", "Ким Ли"))
+		second := createPublication("Second", ls("Lee, Kim", "Ли Ким"))
+		third := createPublication("Third", ls("K. Lee", "К. Ли"))
+		createPublication("Fourth", ls("Olga Orlova", "Ольга Орлова"))
+
+		// Spellings differing in case, punctuation or word order are one
+		// person; "K. Lee" is someone else until merged.
+		kim, err := repos.ExternalPeople.FindByAlias("lee KIM")
+		if err != nil || kim == nil {
+			t.Fatalf("FindByAlias = %v, %v; want Kim Lee", kim, err)
+		}
+		k, err := repos.ExternalPeople.FindByAlias("K. Lee")
+		if err != nil || k == nil || *k == *kim {
+			t.Fatalf("FindByAlias(K. Lee) = %v, %v; want another person", k, err)
+		}
+		if id, err := repos.ExternalPeople.FindByAlias("Nobody"); err != nil || id != nil {
+			t.Errorf("FindByAlias(Nobody) = %v, %v; want nil", id, err)
+		}
+		people, err := repos.ExternalPeople.GetAll()
+		if err != nil || len(people) != 3 {
+			t.Fatalf("GetAll = %d people, %v; want 3", len(people), err)
+		}
+
+		person, err := repos.ExternalPeople.GetByID(*kim)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if person.Name != ls("Kim Lee", "Ким Ли") || person.Publications != 2 || person.ResearcherID != nil {
+			t.Errorf("GetByID = %+v, want Kim Lee with 2 publications", *person)
+		}
+
+		person.Orcid = "0000-0001-2345-6789"
+		person.Affiliation = ls("KAIST", "КАИСТ")
+		person.Aliases = append(person.Aliases, "Kimberly Lee", "K. Lee")
+		if err := repos.ExternalPeople.Update(*person); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		person, err = repos.ExternalPeople.GetByID(*kim)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if person.Orcid != "0000-0001-2345-6789" || person.Affiliation != ls("KAIST", "КАИСТ") {
+			t.Errorf("GetByID after Update = %+v", *person)
+		}
+		if id, _ := repos.ExternalPeople.FindByAlias("Kimberly Lee"); id == nil || *id != *kim {
+			t.Errorf("FindByAlias(Kimberly Lee) = %v, want the alias added by Update", id)
+		}
+		if id, _ := repos.ExternalPeople.FindByAlias("K. Lee"); id == nil || *id != *k {
+			t.Errorf("FindByAlias(K. Lee) = %v, want it left to its owner", id)
+		}
+
+		if err := repos.ExternalPeople.Merge(*kim, []int{*k}); err != nil {
+			t.Fatalf("Merge: %v", err)
+		}
+		if _, err := repos.ExternalPeople.GetByID(*k); err == nil {
+			t.Error("GetByID of a merged person succeeded")
+		}
+		if id, _ := repos.ExternalPeople.FindByAlias("K. Lee"); id == nil || *id != *kim {
+			t.Errorf("FindByAlias(K. Lee) after Merge = %v, want Kim Lee", id)
+		}
+		if person, _ := repos.ExternalPeople.GetByID(*kim); person == nil || person.Publications != 3 {
+			t.Errorf("GetByID after Merge = %+v, want 3 publications", person)
+		}
+
+		// Creating a researcher whose name is an alias promotes the person:
+		// the researcher takes their place in every byline.
+		lee := newResearcher(t, repos, "Kim", "Lee")
+		if person, _ := repos.ExternalPeople.GetByID(*kim); person == nil || person.ResearcherID == nil || *person.ResearcherID != lee.ID {
+			t.Errorf("GetByID after creating the researcher = %+v, want them promoted", person)
+		}
+		for _, id := range []int{first, second, third} {
+			pub, err := repos.Publications.GetByID(id)
+			if err != nil {
+				t.Fatalf("GetByID: %v", err)
+			}
+			if len(pub.Authors) != 2 || pub.Authors[0].ID == nil || *pub.Authors[0].ID != lee.ID {
+				t.Errorf("authors of publication %d = %+v, want the researcher first", id, pub.Authors)
+			}
+		}
+		later := createPublication("Later", ls("Lee, Kimberly", ""))
+		if pub, _ := repos.Publications.GetByID(later); pub == nil || pub.Authors[0].ID == nil || *pub.Authors[0].ID != lee.ID {
+			t.Errorf("authors of a publication added later = %+v, want the researcher", pub)
+		}
+
+		orlova, _ := repos.ExternalPeople.FindByAlias("Olga Orlova")
+		smirnova := newResearcher(t, repos, "Olga", "Smirnova")
+		if err := repos.ExternalPeople.Promote(*orlova, smirnova.ID); err != nil {
+			t.Fatalf("Promote: %v", err)
+		}
+		if person, _ := repos.ExternalPeople.GetByID(*orlova); person == nil || person.ResearcherID == nil || *person.ResearcherID != smirnova.ID {
+			t.Errorf("GetByID after Promote = %+v, want them linked to the researcher", person)
+		}
+	})
+}
+
+func TestUserRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		researcher := newResearcher(t, repos, "Ivan", "Petrov")
+
+		admin := models.User{Username: "admin", Role: models.RoleAdmin, PasswordHash: "hash-1"}
+		admin.ID = mustCreate(t)(repos.Users.Create(admin))
+		petrov := models.User{Username: "petrov", Role: models.RoleResearcher, ResearcherID: &researcher.ID, PasswordHash: "hash-2"}
+		petrov.ID = mustCreate(t)(repos.Users.Create(petrov))
+
+		if _, err := repos.Users.Create(models.User{Username: "admin", Role: models.RoleEditor}); err == nil {
+			t.Error("Create with a taken username succeeded")
+		}
+		if n, err := repos.Users.Count(); err != nil || n != 2 {
+			t.Errorf("Count = %d, %v; want 2", n, err)
+		}
+
+		got, err := repos.Users.GetByUsername("petrov")
+		if err != nil {
+			t.Fatalf("GetByUsername: %v", err)
+		}
+		if got.ID != petrov.ID || got.Role != models.RoleResearcher || got.PasswordHash != "hash-2" ||
+			got.ResearcherID == nil || *got.ResearcherID != researcher.ID || got.CreatedAt == "" {
+			t.Errorf("GetByUsername = %+v, want %+v", *got, petrov)
+		}
+		if _, err := repos.Users.GetByUsername("nobody"); err == nil {
+			t.Error("GetByUsername of an unknown user succeeded")
+		}
+
+		petrov.Role = models.RoleEditor
+		petrov.ResearcherID = nil
+		if err := repos.Users.Update(petrov); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := repos.Users.UpdatePassword(petrov.ID, "hash-3"); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		got, err = repos.Users.GetByID(petrov.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Role != models.RoleEditor || got.ResearcherID != nil || got.PasswordHash != "hash-3" {
+			t.Errorf("GetByID after Update = %+v", *got)
+		}
+
+		all, err := repos.Users.GetAll()
+		if err != nil || len(all) != 2 {
+			t.Fatalf("GetAll = %d users, %v; want 2", len(all), err)
+		}
+
+		if err := repos.Users.Delete(petrov.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Users.GetByID(petrov.ID); err == nil {
+			t.Error("GetByID after Delete succeeded")
+		}
+	})
+}
+
+func TestSessionRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		userID := mustCreate(t)(repos.Users.Create(models.User{Username: "editor", Role: models.RoleEditor}))
+		otherID := mustCreate(t)(repos.Users.Create(models.User{Username: "other", Role: models.RoleEditor}))
+
+		now := time.Now().UTC()
+		create := func(userID int, tokenHash string, expiresAt time.Time) int {
+			t.Helper()
+			session := models.Session{
+				UserID: userID, ExpiresAt: expiresAt.Format(models.TimestampLayout),
+				IP: "10.0.0.1", UserAgent: "curl", CSRFTokenHash: "csrf-" + tokenHash,
+			}
+			return mustCreate(t)(repos.Sessions.Create(session, tokenHash))
+		}
+		current := create(userID, "current", now.Add(time.Hour))
+		second := create(userID, "second", now.Add(time.Hour))
+		create(userID, "expired", now.Add(-time.Minute))
+		create(otherID, "other", now.Add(time.Hour))
+
+		got, err := repos.Sessions.GetByTokenHash("current")
+		if err != nil {
+			t.Fatalf("GetByTokenHash: %v", err)
+		}
+		if got.ID != current || got.UserID != userID || got.Username != "editor" || got.IP != "10.0.0.1" ||
+			got.UserAgent != "curl" || got.CSRFTokenHash != "csrf-current" {
+			t.Errorf("GetByTokenHash = %+v", *got)
+		}
+		if _, err := repos.Sessions.GetByTokenHash("expired"); err == nil {
+			t.Error("GetByTokenHash of an expired session succeeded")
+		}
+
+		sessions, err := repos.Sessions.GetAll(userID)
+		if err != nil || len(sessions) != 2 {
+			t.Fatalf("GetAll(user) = %d sessions, %v; want the 2 unexpired ones", len(sessions), err)
+		}
+		if all, err := repos.Sessions.GetAll(0); err != nil || len(all) != 3 {
+			t.Errorf("GetAll(0) = %d sessions, %v; want 3", len(all), err)
+		}
+		if err := repos.Sessions.Touch(current); err != nil {
+			t.Fatalf("Touch: %v", err)
+		}
+
+		if err := repos.Sessions.DeleteOthers(userID, current); err != nil {
+			t.Fatalf("DeleteOthers: %v", err)
+		}
+		if _, err := repos.Sessions.GetByTokenHash("second"); err == nil {
+			t.Errorf("session %d survived DeleteOthers", second)
+		}
+		if _, err := repos.Sessions.GetByTokenHash("current"); err != nil {
+			t.Errorf("DeleteOthers removed the kept session: %v", err)
+		}
+		if _, err := repos.Sessions.GetByTokenHash("other"); err != nil {
+			t.Errorf("DeleteOthers removed a session of another user: %v", err)
+		}
+
+		if err := repos.Sessions.DeleteByTokenHash("current"); err != nil {
+			t.Fatalf("DeleteByTokenHash: %v", err)
+		}
+		if err := repos.Sessions.DeleteByUserID(otherID); err != nil {
+			t.Fatalf("DeleteByUserID: %v", err)
+		}
+		if all, err := repos.Sessions.GetAll(0); err != nil || len(all) != 0 {
+			t.Errorf("GetAll(0) after deleting = %+v, %v; want none", all, err)
+		}
+		if err := repos.Sessions.Delete(current); err == nil {
+			t.Error("Delete of a deleted session succeeded")
+		}
+		if err := repos.Sessions.DeleteExpired(); err != nil {
+			t.Fatalf("DeleteExpired: %v", err)
+		}
+	})
+}
+
+func TestProjectRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		project := models.NewProject()
+		project.Title = ls("Neuromorphic chips", "Нейроморфные чипы")
+		project.Description = ls("Hardware for spiking networks", "Железо для импульсных сетей")
+		project.GithubLink = "https://github.com/lab/chips"
+		project.Images = []models.ProjectImage{{URL: "/uploads/chip.png", Order: 1}}
+		project.ID = mustCreate(t)(repos.Projects.Create(project))
+
+		if err := repos.Projects.AddPublication(project.ID, models.ProjectPublication{
+			Title: ls("A chip", "Чип"), Link: "https://example.org/chip",
+		}); err != nil {
+			t.Fatalf("AddPublication: %v", err)
+		}
+		if err := repos.Projects.AddVideo(project.ID, models.ProjectVideo{
+			Title: ls("Demo", "Демо"), EmbedURL: "https://youtube.com/embed/x",
+		}); err != nil {
+			t.Fatalf("AddVideo: %v", err)
+		}
+
+		got, err := repos.Projects.GetByID(project.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Title != project.Title || got.Description != project.Description || got.GithubLink != project.GithubLink {
+			t.Errorf("GetByID = %+v, want %+v", *got, project)
+		}
+		if len(got.Images) != 1 || got.Images[0].URL != "/uploads/chip.png" {
+			t.Errorf("GetByID images = %+v", got.Images)
+		}
+		if len(got.Publications) != 1 || got.Publications[0].Title != ls("A chip", "Чип") {
+			t.Errorf("GetByID publications = %+v", got.Publications)
+		}
+		if len(got.Videos) != 1 || got.Videos[0].EmbedURL != "https://youtube.com/embed/x" {
+			t.Errorf("GetByID videos = %+v", got.Videos)
+		}
+
+		got.GithubLink = "https://github.com/lab/chips2"
+		got.Videos = nil
+		if err := repos.Projects.Update(*got); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		updated, err := repos.Projects.GetByID(project.ID)
+		if err != nil {
+			t.Fatalf("GetByID after Update: %v", err)
+		}
+		if updated.GithubLink != got.GithubLink || len(updated.Videos) != 0 || len(updated.Publications) != 1 {
+			t.Errorf("GetByID after Update = %+v", *updated)
+		}
+
+		all, err := repos.Projects.GetAll()
+		if err != nil || len(all) != 1 {
+			t.Errorf("GetAll = %+v, %v", all, err)
+		}
+
+		if err := repos.Projects.Delete(project.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Projects.GetByID(project.ID); err == nil {
+			t.Error("GetByID after Delete succeeded")
+		}
+	})
+}
+
+func TestTrainingMaterialRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		material := models.TrainingMaterial{
+			Title:       ls("Intro to SNN", "Введение в ИНС"),
+			Description: ls("A lecture", "Лекция"),
+			URL:         "https://example.org/lecture",
+			Image:       "/uploads/lecture.png",
+		}
+		material.ID = mustCreate(t)(repos.TrainingMaterials.Create(material))
+
+		got, err := repos.TrainingMaterials.GetByID(material.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !reflect.DeepEqual(*got, material) {
+			t.Errorf("GetByID = %+v, want %+v", *got, material)
+		}
+
+		material.URL = "https://example.org/lecture-2"
+		if err := repos.TrainingMaterials.Update(material); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err = repos.TrainingMaterials.GetByID(material.ID)
+		if err != nil || !reflect.DeepEqual(*got, material) {
+			t.Errorf("GetByID after Update = %+v, %v; want %+v", got, err, material)
+		}
+
+		all, err := repos.TrainingMaterials.GetAll()
+		if err != nil || len(all) != 1 {
+			t.Errorf("GetAll = %+v, %v", all, err)
+		}
+
+		if err := repos.TrainingMaterials.Delete(material.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.TrainingMaterials.GetByID(material.ID); err == nil {
+			t.Error("GetByID after Delete succeeded")
+		}
+	})
+}
+
+func TestDisciplineRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		researcher := newResearcher(t, repos, "Ivan", "Petrov")
+
+		discipline := models.Discipline{
+			Title:       ls("Machine learning", "Машинное обучение"),
+			Description: ls("A course", "Курс"),
+			Image:       "/uploads/ml.png",
+			Researchers: []models.DisciplineResearcher{{ID: researcher.ID}},
+		}
+		discipline.ID = mustCreate(t)(repos.Disciplines.Create(discipline))
+
+		got, err := repos.Disciplines.GetByID(discipline.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Title != discipline.Title || got.Description != discipline.Description || got.Image != discipline.Image {
+			t.Errorf("GetByID = %+v, want %+v", *got, discipline)
+		}
+		want := []models.DisciplineResearcher{{ID: researcher.ID, Name: researcher.Name, LastName: researcher.LastName}}
+		if !reflect.DeepEqual(got.Researchers, want) {
+			t.Errorf("GetByID researchers = %+v, want %+v", got.Researchers, want)
+		}
+
+		discipline.Title = ls("Deep learning", "Глубокое обучение")
+		discipline.Researchers = nil
+		if err := repos.Disciplines.Update(discipline); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err = repos.Disciplines.GetByID(discipline.ID)
+		if err != nil {
+			t.Fatalf("GetByID after Update: %v", err)
+		}
+		if got.Title != discipline.Title || len(got.Researchers) != 0 {
+			t.Errorf("GetByID after Update = %+v", *got)
+		}
+
+		all, err := repos.Disciplines.GetAll()
+		if err != nil || len(all) != 1 {
+			t.Errorf("GetAll = %+v, %v", all, err)
+		}
+
+		if err := repos.Disciplines.Delete(discipline.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repos.Disciplines.GetByID(discipline.ID); err == nil || err.Error() != "discipline not found" {
+			t.Errorf("GetByID after Delete: err = %v, want discipline not found", err)
+		}
+	})
+}
+
+func TestSearchRepo(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *repository.Repositories) {
+		researcher := newResearcher(t, repos, "Ivan", "Petrov")
+		pub := models.Publication{
+			Title:       ls("Spiking neural networks", "Импульсные нейронные сети"),
+			Type:        models.PublicationTypeArticle,
+			PublishedAt: "2019-04-01",
+		}
+		pub.ID = mustCreate(t)(repos.Publications.Create(pub))
+		pub.Visible = true
+		if err := repos.Publications.Update(pub); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		mustCreate(t)(repos.Publications.Create(models.Publication{
+			Title:       ls("Hidden neural notes", "Скрытые заметки"),
+			Type:        models.PublicationTypeArticle,
+			PublishedAt: "2020-01-01",
+		}))
+
+		results, err := repos.Search.Search(models.SearchQuery{
+			Query: "НЕЙРОН",
+			Types: []string{models.SearchTypePublication, models.SearchTypeResearcher},
+			Lang:  "ru",
+			Limit: 10,
+		})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if hits := results[models.SearchTypePublication]; len(hits) != 1 || hits[0].ID != pub.ID {
+			t.Errorf("publication hits = %+v, want only the visible publication", hits)
+		}
+		if hits := results[models.SearchTypeResearcher]; len(hits) != 1 || hits[0].ID != researcher.ID {
+			t.Errorf("researcher hits = %+v, want the researcher by their bio", hits)
+		}
+
+		results, err = repos.Search.Search(models.SearchQuery{
+			Query: "spik chips",
+			Types: []string{models.SearchTypePublication},
+			Lang:  "en",
+			Limit: 10,
+		})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if hits := results[models.SearchTypePublication]; len(hits) != 0 {
+			t.Errorf("hits = %+v, want none as not every term matches", hits)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteCrawlJobRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteCrawlJobRepo(db *sql.DB) *SQLiteCrawlJobRepo {
+	return &SQLiteCrawlJobRepo{db: db}
+}
+
+func (r *SQLiteCrawlJobRepo) Create(job models.CrawlJob) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO crawl_jobs (researcher_id, status, started_by, researchers) VALUES (?, ?, ?, ?)",
+		job.ResearcherID, models.CrawlStatusRunning, job.StartedBy, job.Researchers,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *SQLiteCrawlJobRepo) GetByID(id int) (*models.CrawlJob, error) {
+	job, err := scanCrawlJob(r.db.QueryRow(crawlJobQuery+" WHERE j.id = ? GROUP BY j.id", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("crawl job not found")
+		}
+		return nil, err
+	}
+	job.Results, err = crawlResults(r.db.Query(crawlResultQuery+" WHERE job_id = ? ORDER BY started_at, researcher_id", id))
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *SQLiteCrawlJobRepo) List(status string, limit int) ([]models.CrawlJob, error) {
+	where, args := "", []interface{}{}
+	if status != "" {
+		where, args = " WHERE j.status = ?", append(args, status)
+	}
+	return scanCrawlJobs(r.db.Query(
+		crawlJobQuery+where+" GROUP BY j.id ORDER BY j.id DESC LIMIT ?",
+		append(args, limit)...,
+	))
+}
+
+func (r *SQLiteCrawlJobRepo) StartResult(jobID, researcherID int) error {
+	_, err := r.db.Exec(
+		"INSERT INTO crawl_job_results (job_id, researcher_id, status) VALUES (?, ?, ?)",
+		jobID, researcherID, models.CrawlStatusRunning,
+	)
+	return err
+}
+
+func (r *SQLiteCrawlJobRepo) FinishResult(jobID int, result models.CrawlResult) error {
+	_, err := r.db.Exec(
+		`UPDATE crawl_job_results SET status = ?, new_publications = ?, updated_publications = ?, errors = ?, finished_at = ?
+		WHERE job_id = ? AND researcher_id = ?`,
+		result.Status, result.NewPublications, result.UpdatedPublications, strings.Join(result.Errors, "\n"),
+		time.Now().UTC().Format(models.TimestampLayout), jobID, result.ResearcherID,
+	)
+	return err
+}
+
+func (r *SQLiteCrawlJobRepo) Finish(id int, status, message string) error {
+	_, err := r.db.Exec(
+		"UPDATE crawl_jobs SET status = ?, error = ?, finished_at = ? WHERE id = ?",
+		status, message, time.Now().UTC().Format(models.TimestampLayout), id,
+	)
+	return err
+}
+
+func (r *SQLiteCrawlJobRepo) FailRunning(message string) (int, error) {
+	now := time.Now().UTC().Format(models.TimestampLayout)
+	_, err := r.db.Exec(
+		"UPDATE crawl_job_results SET status = ?, finished_at = ? WHERE status = ?",
+		models.CrawlStatusFailed, now, models.CrawlStatusRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.db.Exec(
+		"UPDATE crawl_jobs SET status = ?, error = ?, finished_at = ? WHERE status = ?",
+		models.CrawlStatusFailed, message, now, models.CrawlStatusRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// The queries below do not depend on the SQL dialect and are shared with
+// PostgresCrawlJobRepo. The counts of a job are summed from its results.
+
+const crawlJobQuery = `SELECT j.id, j.researcher_id, j.status, j.started_by, j.researchers, j.error,
+		j.started_at, j.finished_at,
+		COALESCE(SUM(CASE WHEN r.finished_at IS NOT NULL THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN r.status = 'failed' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(r.new_publications), 0),
+		COALESCE(SUM(r.updated_publications), 0)
+	FROM crawl_jobs j
+	LEFT JOIN crawl_job_results r ON r.job_id = j.id`
+
+const crawlResultQuery = `SELECT researcher_id, status, new_publications, updated_publications, errors, started_at, finished_at
+	FROM crawl_job_results`
+
+func scanCrawlJob(scanner interface{ Scan(...interface{}) error }) (*models.CrawlJob, error) {
+	var job models.CrawlJob
+	var researcherID sql.NullInt64
+	var finishedAt sql.NullString
+	err := scanner.Scan(
+		&job.ID, &researcherID, &job.Status, &job.StartedBy, &job.Researchers, &job.Error,
+		&job.StartedAt, &finishedAt,
+		&job.Crawled, &job.Failed, &job.NewPublications, &job.UpdatedPublications,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if researcherID.Valid {
+		id := int(researcherID.Int64)
+		job.ResearcherID = &id
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.String
+	}
+	return &job, nil
+}
+
+func scanCrawlJobs(rows *sql.Rows, err error) ([]models.CrawlJob, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.CrawlJob{}
+	for rows.Next() {
+		job, err := scanCrawlJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+func crawlResults(rows *sql.Rows, err error) ([]models.CrawlResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.CrawlResult{}
+	for rows.Next() {
+		var result models.CrawlResult
+		var errs string
+		var finishedAt sql.NullString
+		err := rows.Scan(
+			&result.ResearcherID, &result.Status, &result.NewPublications, &result.UpdatedPublications,
+			&errs, &result.StartedAt, &finishedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Errors = []string{}
+		if errs != "" {
+			result.Errors = strings.Split(errs, "\n")
+		}
+		if finishedAt.Valid {
+			result.FinishedAt = &finishedAt.String
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,491 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/damirahm/diplom/backend/coauthors"
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteExternalPersonRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewSQLiteExternalPersonRepo(db *sql.DB, lsRepo LocalizedStringRepo) *SQLiteExternalPersonRepo {
+	return &SQLiteExternalPersonRepo{db: db, localizedStringRepo: lsRepo}
+}
+
+func (r *SQLiteExternalPersonRepo) GetByID(id int) (*models.ExternalPerson, error) {
+	return getExternalPerson(r.db, func(int) string { return "?" }, id)
+}
+
+func (r *SQLiteExternalPersonRepo) GetAll() ([]models.ExternalPerson, error) {
+	return getExternalPeople(r.db, "")
+}
+
+func (r *SQLiteExternalPersonRepo) FindByAlias(name string) (*int, error) {
+	return findPersonByAlias(r.db, func(int) string { return "?" }, name)
+}
+
+func (r *SQLiteExternalPersonRepo) Update(person models.ExternalPerson) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = updateExternalPerson(tx, r.localizedStringRepo, func(int) string { return "?" }, person); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteExternalPersonRepo) Merge(survivorID int, duplicateIDs []int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = mergeExternalPeople(tx, r.localizedStringRepo, func(int) string { return "?" }, survivorID, duplicateIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteExternalPersonRepo) Promote(personID, researcherID int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = promoteExternalPerson(tx, r.localizedStringRepo, func(int) string { return "?" }, personID, researcherID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// The queries below are shared with PostgresExternalPersonRepo and with the
+// publication and researcher repositories, which link bylines to the
+// registry; placeholder returns the placeholder of the i-th parameter,
+// counting from 0. SQLite supports RETURNING since 3.35, so inserts read back
+// IDs the same way in both dialects.
+
+const externalPersonQuery = `SELECT ep.id, n.en, n.ru, ep.orcid, a.en, a.ru, ep.researcher_id, ep.created_at,
+		(SELECT COUNT(*) FROM publication_external_authors pea WHERE pea.person_id = ep.id)
+	FROM external_people ep
+	JOIN localized_strings n ON ep.name_id = n.id
+	JOIN localized_strings a ON ep.affiliation_id = a.id`
+
+func scanExternalPerson(scanner interface{ Scan(...interface{}) error }, person *models.ExternalPerson) error {
+	var researcherID sql.NullInt64
+	err := scanner.Scan(
+		&person.ID, &person.Name.En, &person.Name.Ru, &person.Orcid,
+		&person.Affiliation.En, &person.Affiliation.Ru, &researcherID, &person.CreatedAt, &person.Publications,
+	)
+	if err != nil {
+		return err
+	}
+	if researcherID.Valid {
+		id := int(researcherID.Int64)
+		person.ResearcherID = &id
+	}
+	return nil
+}
+
+func getExternalPerson(db *sql.DB, placeholder func(i int) string, id int) (*models.ExternalPerson, error) {
+	people, err := getExternalPeople(db, " WHERE ep.id = "+placeholder(0), id)
+	if err != nil {
+		return nil, err
+	}
+	if len(people) == 0 {
+		return nil, errors.New("external person not found")
+	}
+	return &people[0], nil
+}
+
+// getExternalPeople returns the people that match where, sorted by name, with
+// their aliases.
+func getExternalPeople(db *sql.DB, where string, args ...interface{}) ([]models.ExternalPerson, error) {
+	rows, err := db.Query(externalPersonQuery+where+" ORDER BY n.en, ep.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	people := []models.ExternalPerson{}
+	byID := map[int]*models.ExternalPerson{}
+	for rows.Next() {
+		person := models.ExternalPerson{Aliases: []string{}}
+		if err := scanExternalPerson(rows, &person); err != nil {
+			return nil, err
+		}
+		people = append(people, person)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range people {
+		byID[people[i].ID] = &people[i]
+	}
+
+	aliases, err := db.Query(`SELECT a.person_id, a.alias
+		FROM external_person_aliases a
+		JOIN external_people ep ON a.person_id = ep.id`+where+`
+		ORDER BY a.alias`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer aliases.Close()
+	for aliases.Next() {
+		var personID int
+		var alias string
+		if err := aliases.Scan(&personID, &alias); err != nil {
+			return nil, err
+		}
+		if person, ok := byID[personID]; ok {
+			person.Aliases = append(person.Aliases, alias)
+		}
+	}
+	return people, aliases.Err()
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// findPersonByAlias returns the ID of the person name is an alias of, or nil
+// when it is nobody's.
+func findPersonByAlias(q queryRower, placeholder func(i int) string, name string) (*int, error) {
+	key := coauthors.AliasKey(name)
+	if key == "" {
+		return nil, nil
+	}
+	var id int
+	err := q.QueryRow("SELECT person_id FROM external_person_aliases WHERE alias_key = "+placeholder(0), key).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// addPersonAliases makes names aliases of a person, skipping those that
+// already belong to someone.
+func addPersonAliases(tx *sql.Tx, placeholder func(i int) string, personID int, names ...string) error {
+	for _, name := range names {
+		key := coauthors.AliasKey(name)
+		if key == "" {
+			continue
+		}
+		_, err := tx.Exec(
+			`INSERT INTO external_person_aliases (person_id, alias, alias_key)
+			VALUES (`+placeholder(0)+`, `+placeholder(1)+`, `+placeholder(2)+`)
+			ON CONFLICT (alias_key) DO NOTHING`,
+			personID, name, key,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveExternalPerson finds the person an external author of a byline
+// refers to: the one given by author.PersonID, else the one either spelling
+// of their name is an alias of, else a new person. Spellings nobody has yet
+// become aliases of the person. It returns the person's ID and, for a person
+// promoted to a researcher, the researcher's; both are nil for names without
+// letters, such as the ellipsis ending a truncated author list.
+func resolveExternalPerson(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, author models.Author) (personID, researcherID *int, err error) {
+	if author.PersonID != nil {
+		personID, researcherID, err = externalPersonResearcher(tx, placeholder, *author.PersonID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, name := range []string{author.Name.En, author.Name.Ru} {
+		if personID != nil {
+			break
+		}
+		id, err := findPersonByAlias(tx, placeholder, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if id != nil {
+			if personID, researcherID, err = externalPersonResearcher(tx, placeholder, *id); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if personID == nil {
+		if coauthors.AliasKey(author.Name.En) == "" && coauthors.AliasKey(author.Name.Ru) == "" {
+			return nil, nil, nil
+		}
+		id, err := createExternalPerson(tx, ls, placeholder, author.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		personID = &id
+	}
+
+	if err := addPersonAliases(tx, placeholder, *personID, author.Name.En, author.Name.Ru); err != nil {
+		return nil, nil, err
+	}
+	return personID, researcherID, nil
+}
+
+// externalPersonResearcher returns the ID of a person and of the researcher
+// they were promoted to, if any. Both are nil when the person does not exist.
+func externalPersonResearcher(tx *sql.Tx, placeholder func(i int) string, id int) (personID, researcherID *int, err error) {
+	var researcher sql.NullInt64
+	err = tx.QueryRow("SELECT researcher_id FROM external_people WHERE id = "+placeholder(0), id).Scan(&researcher)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if researcher.Valid {
+		rid := int(researcher.Int64)
+		researcherID = &rid
+	}
+	return &id, researcherID, nil
+}
+
+// createExternalPerson registers a person named name, without aliases.
+func createExternalPerson(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, name models.LocalizedString) (int, error) {
+	nameID, err := ls.CreateTx(tx, name)
+	if err != nil {
+		return 0, err
+	}
+	affiliationID, err := ls.CreateTx(tx, models.LocalizedString{})
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = tx.QueryRow(
+		"INSERT INTO external_people (name_id, affiliation_id) VALUES ("+placeholder(0)+", "+placeholder(1)+") RETURNING id",
+		nameID, affiliationID,
+	).Scan(&id)
+	return id, err
+}
+
+// updateExternalPerson saves the name, ORCID, affiliation and aliases of a
+// person. Aliases that belong to someone else are skipped; callers check them
+// with findPersonByAlias first.
+func updateExternalPerson(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, person models.ExternalPerson) error {
+	var nameID, affiliationID int64
+	err := tx.QueryRow(
+		"SELECT name_id, affiliation_id FROM external_people WHERE id = "+placeholder(0),
+		person.ID,
+	).Scan(&nameID, &affiliationID)
+	if err == sql.ErrNoRows {
+		return errors.New("external person not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := ls.UpdateTx(tx, nameID, person.Name); err != nil {
+		return err
+	}
+	if err := ls.UpdateTx(tx, affiliationID, person.Affiliation); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE external_people SET orcid = "+placeholder(0)+" WHERE id = "+placeholder(1), person.Orcid, person.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM external_person_aliases WHERE person_id = "+placeholder(0), person.ID); err != nil {
+		return err
+	}
+	return addPersonAliases(tx, placeholder, person.ID, person.Aliases...)
+}
+
+// mergeExternalPeople moves the aliases and publications of duplicates to the
+// survivor and deletes them. The survivor takes the researcher a duplicate
+// was promoted to when it was not promoted itself, and its publications then
+// move to the researcher.
+func mergeExternalPeople(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, survivorID int, duplicateIDs []int) error {
+	_, researcherID, err := externalPersonResearcher(tx, placeholder, survivorID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range duplicateIDs {
+		var nameID, affiliationID int64
+		var duplicateResearcher sql.NullInt64
+		err := tx.QueryRow(
+			"SELECT name_id, affiliation_id, researcher_id FROM external_people WHERE id = "+placeholder(0),
+			id,
+		).Scan(&nameID, &affiliationID, &duplicateResearcher)
+		if err == sql.ErrNoRows {
+			return errors.New("external person not found")
+		}
+		if err != nil {
+			return err
+		}
+		if researcherID == nil && duplicateResearcher.Valid {
+			rid := int(duplicateResearcher.Int64)
+			researcherID = &rid
+		}
+
+		for _, query := range []string{
+			"UPDATE external_person_aliases SET person_id = " + placeholder(0) + " WHERE person_id = " + placeholder(1),
+			"UPDATE publication_external_authors SET person_id = " + placeholder(0) + " WHERE person_id = " + placeholder(1),
+		} {
+			if _, err := tx.Exec(query, survivorID, id); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM external_people WHERE id = "+placeholder(0), id); err != nil {
+			return err
+		}
+		if err := ls.DeleteTx(tx, nameID); err != nil {
+			return err
+		}
+		if err := ls.DeleteTx(tx, affiliationID); err != nil {
+			return err
+		}
+	}
+
+	if researcherID == nil {
+		return nil
+	}
+	return promoteExternalPerson(tx, ls, placeholder, survivorID, *researcherID)
+}
+
+// promoteExternalPerson links a person to a researcher and turns them into
+// the researcher in the bylines of their publications, keeping their places
+// and roles. Publications that already list the researcher just lose the
+// external author.
+func promoteExternalPerson(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, personID, researcherID int) error {
+	_, err := tx.Exec(
+		"UPDATE external_people SET researcher_id = "+placeholder(0)+" WHERE id = "+placeholder(1),
+		researcherID, personID,
+	)
+	if err != nil {
+		return err
+	}
+
+	type externalAuthor struct {
+		id     int
+		nameID int64
+		slot   bylineSlot
+	}
+	rows, err := tx.Query(
+		`SELECT id, name_id, publication_id, author_order, role
+		FROM publication_external_authors WHERE person_id = `+placeholder(0),
+		personID,
+	)
+	if err != nil {
+		return err
+	}
+	var authors []externalAuthor
+	for rows.Next() {
+		var a externalAuthor
+		if err := rows.Scan(&a.id, &a.nameID, &a.slot.publicationID, &a.slot.order, &a.slot.role); err != nil {
+			rows.Close()
+			return err
+		}
+		authors = append(authors, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, a := range authors {
+		_, err := tx.Exec(
+			`INSERT INTO publication_authors (publication_id, researcher_id, author_order, role)
+			VALUES (`+placeholder(0)+`, `+placeholder(1)+`, `+placeholder(2)+`, `+placeholder(3)+`)
+			ON CONFLICT DO NOTHING`,
+			a.slot.publicationID, researcherID, a.slot.order, a.slot.role,
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM publication_external_authors WHERE id = "+placeholder(0), a.id); err != nil {
+			return err
+		}
+		if err := ls.DeleteTx(tx, a.nameID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promoteMatchingPeople promotes to a new researcher the people their full
+// name, in either order and language, is an alias of, along with every other
+// alias those people go by.
+func promoteMatchingPeople(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, researcherID int, name, lastName models.LocalizedString) error {
+	promoted := map[int]bool{}
+	for _, fullName := range []string{name.En + " " + lastName.En, name.Ru + " " + lastName.Ru} {
+		id, err := findPersonByAlias(tx, placeholder, fullName)
+		if err != nil {
+			return err
+		}
+		if id == nil || promoted[*id] {
+			continue
+		}
+		_, current, err := externalPersonResearcher(tx, placeholder, *id)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			continue
+		}
+		if err := promoteExternalPerson(tx, ls, placeholder, *id, researcherID); err != nil {
+			return err
+		}
+		promoted[*id] = true
+	}
+	return nil
+}
+
+// demotedResearcherPerson returns the person the bylines of a researcher who
+// is being deleted go back to: the person they were promoted from, else the
+// one their full name is an alias of, else a new one. Every person promoted
+// to the researcher is unlinked from them.
+func demotedResearcherPerson(tx *sql.Tx, ls LocalizedStringRepo, placeholder func(i int) string, researcherID int, fullName models.LocalizedString) (*int, error) {
+	var personID int
+	err := tx.QueryRow(
+		"SELECT id FROM external_people WHERE researcher_id = "+placeholder(0)+" ORDER BY id LIMIT 1",
+		researcherID,
+	).Scan(&personID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	_, err = tx.Exec("UPDATE external_people SET researcher_id = NULL WHERE researcher_id = "+placeholder(0), researcherID)
+	if err != nil {
+		return nil, err
+	}
+
+	if personID != 0 {
+		return &personID, nil
+	}
+	id, _, err := resolveExternalPerson(tx, ls, placeholder, models.Author{Name: fullName})
+	return id, err
+}
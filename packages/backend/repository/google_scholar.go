@@ -361,11 +361,13 @@ func (g *GoogleScholar) fetchPublicationDetails(url, title string) (*models.Publ
 
 	var authorText string
 	var publishedAt string
-	var journal string
+	var venue string
 	var volume string
 	var issue string
 	var pages string
 	var publisher string
+	var abstract string
+	var pubType string
 	var citationCount int
 
 	doc.Find("#gsc_oci_table .gs_scl").Each(func(i int, s *goquery.Selection) {
@@ -373,12 +375,27 @@ func (g *GoogleScholar) fetchPublicationDetails(url, title string) (*models.Publ
 		fieldValue := s.Find(".gsc_oci_value").Text()
 
 		switch fieldName {
-		case "Авторы", "Authors":
+		case "Авторы", "Authors", "Изобретатели", "Inventors":
 			authorText = fieldValue
 		case "Дата публикации", "Publication date":
 			publishedAt = fieldValue
 		case "Журнал", "Journal":
-			journal = fieldValue
+			venue = fieldValue
+			pubType = models.PublicationTypeArticle
+		case "Конференция", "Conference":
+			venue = fieldValue
+			pubType = models.PublicationTypeConference
+		case "Книга", "Book":
+			venue = fieldValue
+			pubType = models.PublicationTypeBookChapter
+		case "Источник", "Source":
+			venue = fieldValue
+		case "Патентное ведомство", "Patent office":
+			publisher = fieldValue
+			pubType = models.PublicationTypePatent
+		case "Учреждение", "Institution":
+			publisher = fieldValue
+			pubType = models.PublicationTypeThesis
 		case "Том", "Volume":
 			volume = fieldValue
 		case "Номер", "Issue":
@@ -387,6 +404,8 @@ func (g *GoogleScholar) fetchPublicationDetails(url, title string) (*models.Publ
 			pages = fieldValue
 		case "Издатель", "Publisher":
 			publisher = fieldValue
+		case "Описание", "Description":
+			abstract = strings.TrimSpace(fieldValue)
 		case "Total citations":
 			realValue, err := s.Find(".gsc_oci_value a").Html()
 			if err == nil {
@@ -402,18 +421,8 @@ func (g *GoogleScholar) fetchPublicationDetails(url, title string) (*models.Publ
 
 	authors := g.parseAuthors(authorText)
 
-	fullJournal := journal
-	if volume != "" {
-		fullJournal += ", Vol. " + volume
-	}
-	if issue != "" {
-		fullJournal += ", No. " + issue
-	}
-	if pages != "" {
-		fullJournal += ", pp. " + pages
-	}
-	if publisher != "" {
-		fullJournal += ", " + publisher
+	if pubType == "" {
+		pubType = models.GuessPublicationType(venue)
 	}
 
 	formattedDate, err := parseDate(publishedAt)
@@ -427,7 +436,13 @@ func (g *GoogleScholar) fetchPublicationDetails(url, title string) (*models.Publ
 			Ru: title,
 		},
 		Authors:        authors,
-		Journal:        fullJournal,
+		Type:           pubType,
+		Venue:          venue,
+		Volume:         volume,
+		Issue:          issue,
+		Pages:          pages,
+		Publisher:      publisher,
+		Abstract:       abstract,
 		PublishedAt:    formattedDate,
 		CitationsCount: citationCount,
 		Link:           url,
@@ -471,113 +486,59 @@ func parseDate(dateText string) (string, error) {
 	return "", errors.New("failed to parse date")
 }
 
+// parseAuthors splits the author line of a publication into its authors, in
+// byline order, linking the names that belong to researchers.
 func (g *GoogleScholar) parseAuthors(authorText string) []models.Author {
-	authors := strings.Split(authorText, ",")
-
-	var cleanedAuthors []string
-	for _, author := range authors {
-		cleaned := strings.TrimSpace(author)
-		if cleaned != "" {
-			cleanedAuthors = append(cleanedAuthors, cleaned)
-		}
-	}
-
-	authorObjects := make([]models.Author, 0, len(cleanedAuthors))
-	for _, authorName := range cleanedAuthors {
-		researcher, err := g.researcherRepo.FindByFullName(authorName)
-		if err == nil && researcher != nil {
-			id := researcher.ID
-			authorObjects = append(authorObjects, models.Author{
-				Name: models.LocalizedString{
-					En: authorName,
-					Ru: authorName,
-				},
-				ID: &id,
-			})
+	authors := []models.Author{}
+	for _, authorName := range strings.Split(authorText, ",") {
+		authorName = strings.TrimSpace(authorName)
+		if authorName == "" {
 			continue
 		}
-
-		nameParts := strings.Fields(authorName)
-		if len(nameParts) == 3 {
-			nameWithoutMiddle := nameParts[0] + " " + nameParts[2]
-			researcher, err = g.researcherRepo.FindByFullName(nameWithoutMiddle)
-			if err == nil && researcher != nil {
-				id := researcher.ID
-				authorObjects = append(authorObjects, models.Author{
-					Name: models.LocalizedString{
-						En: authorName,
-						Ru: authorName,
-					},
-					ID: &id,
-				})
-				continue
-			}
-		}
-
-		// Handle shorthand initial format like "DN Butusov"
-		if len(nameParts) == 2 && len(nameParts[0]) == 2 && nameParts[0] == strings.ToUpper(nameParts[0]) {
-			// Get initials and last name
-			initials := nameParts[0]
-			lastName := nameParts[1]
-
-			// Try to find researchers with matching last name
-			researchers, err := g.researcherRepo.FindByLastName(lastName)
-			if err == nil && len(researchers) > 0 {
-				// Check each researcher if their first name initial matches
-				for _, r := range researchers {
-					// First initial match
-					if len(r.Name.En) > 0 && strings.ToUpper(string(r.Name.En[0])) == string(initials[0]) {
-						// We'll assume the second initial might be from a patronymic/middle name that isn't stored in our db
-						id := r.ID
-						authorObjects = append(authorObjects, models.Author{
-							Name: models.LocalizedString{
-								En: authorName,
-								Ru: authorName,
-							},
-							ID: &id,
-						})
-						break
-					}
-				}
-			}
-		}
-
-		// Handle single initial format like "D Butusov"
-		if len(nameParts) == 2 && len(nameParts[0]) == 1 && nameParts[0] == strings.ToUpper(nameParts[0]) {
-			// Get initial and last name
-			initial := nameParts[0]
-			lastName := nameParts[1]
-
-			// Try to find researchers with matching last name
-			researchers, err := g.researcherRepo.FindByLastName(lastName)
-			if err == nil && len(researchers) > 0 {
-				// Check each researcher if their first name matches the initial
-				for _, r := range researchers {
-					// Check if first name initial matches
-					if len(r.Name.En) > 0 && strings.ToUpper(string(r.Name.En[0])) == initial {
-						id := r.ID
-						authorObjects = append(authorObjects, models.Author{
-							Name: models.LocalizedString{
-								En: authorName,
-								Ru: authorName,
-							},
-							ID: &id,
-						})
-						break
-					}
-				}
-			}
-		}
-
-		authorObjects = append(authorObjects, models.Author{
+		authors = append(authors, models.Author{
 			Name: models.LocalizedString{
 				En: authorName,
 				Ru: authorName,
 			},
+			ID: g.findResearcher(authorName),
 		})
 	}
+	return authors
+}
+
+// findResearcher returns the ID of the researcher an author name refers to, or
+// nil for an external author. Besides full names it accepts names with a
+// middle name and initials such as "DN Butusov" or "D Butusov", where a second
+// initial is taken for a patronymic, which researchers do not store.
+func (g *GoogleScholar) findResearcher(authorName string) *int {
+	researcher, err := g.researcherRepo.FindByFullName(authorName)
+	if err == nil && researcher != nil {
+		return &researcher.ID
+	}
+
+	nameParts := strings.Fields(authorName)
+	if len(nameParts) == 3 {
+		researcher, err = g.researcherRepo.FindByFullName(nameParts[0] + " " + nameParts[2])
+		if err == nil && researcher != nil {
+			return &researcher.ID
+		}
+	}
 
-	return authorObjects
+	if len(nameParts) != 2 || len(nameParts[0]) > 2 || nameParts[0] != strings.ToUpper(nameParts[0]) {
+		return nil
+	}
+	initial := string(nameParts[0][0])
+	researchers, err := g.researcherRepo.FindByLastName(nameParts[1])
+	if err != nil {
+		return nil
+	}
+	for _, r := range researchers {
+		if len(r.Name.En) > 0 && strings.ToUpper(string(r.Name.En[0])) == initial {
+			id := r.ID
+			return &id
+		}
+	}
+	return nil
 }
 
 func isValidScholarURL(inputURL string) bool {
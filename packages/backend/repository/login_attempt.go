@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteLoginAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteLoginAttemptRepo(db *sql.DB) *SQLiteLoginAttemptRepo {
+	return &SQLiteLoginAttemptRepo{db: db}
+}
+
+func (r *SQLiteLoginAttemptRepo) Get(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.QueryRow(
+		"SELECT key, failures, last_failure_at, blocked_until FROM login_attempts WHERE key = ?",
+		key,
+	).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.BlockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("login attempt not found")
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Fail counts a failed attempt for the key in one statement, so concurrent
+// failures are all counted. The count starts over when the previous failure
+// happened before windowStart. It returns the number of failures.
+func (r *SQLiteLoginAttemptRepo) Fail(key, at, windowStart string) (int, error) {
+	var failures int
+	err := r.db.QueryRow(
+		`INSERT INTO login_attempts (key, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures`,
+		key, at, windowStart,
+	).Scan(&failures)
+	return failures, err
+}
+
+func (r *SQLiteLoginAttemptRepo) Block(key, until string) error {
+	_, err := r.db.Exec("UPDATE login_attempts SET blocked_until = ? WHERE key = ?", until, key)
+	return err
+}
+
+func (r *SQLiteLoginAttemptRepo) Delete(key string) error {
+	_, err := r.db.Exec("DELETE FROM login_attempts WHERE key = ?", key)
+	return err
+}
+
+// DeleteStale removes attempts whose last failure happened before the given
+// timestamp and that are no longer blocked.
+func (r *SQLiteLoginAttemptRepo) DeleteStale(before string) error {
+	_, err := r.db.Exec(
+		"DELETE FROM login_attempts WHERE last_failure_at < ? AND (blocked_until IS NULL OR blocked_until < ?)",
+		before, time.Now().UTC().Format(models.TimestampLayout),
+	)
+	return err
+}
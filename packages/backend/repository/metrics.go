@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteMetricsRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteMetricsRepo(db *sql.DB) *SQLiteMetricsRepo {
+	return &SQLiteMetricsRepo{db: db}
+}
+
+func (r *SQLiteMetricsRepo) RecordPublicationCitations(publicationID, citations int) error {
+	_, err := r.db.Exec(
+		"INSERT INTO publication_citation_snapshots (publication_id, citations_count) VALUES (?, ?)",
+		publicationID, citations,
+	)
+	return err
+}
+
+func (r *SQLiteMetricsRepo) RecordResearcherMetrics(snapshot models.MetricsSnapshot) error {
+	_, err := r.db.Exec(
+		`INSERT INTO researcher_metric_snapshots (researcher_id, `+metricsSnapshotColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		append([]interface{}{snapshot.ResearcherID}, metricsSnapshotValues(snapshot)...)...,
+	)
+	return err
+}
+
+func (r *SQLiteMetricsRepo) GetPublicationCitationHistory(publicationID int, filter models.MetricsHistoryFilter) ([]models.CitationSnapshot, error) {
+	args := []interface{}{}
+	query := metricsHistoryQuery("publication_citation_snapshots", "publication_id", "citations_count", publicationID, filter,
+		func(v interface{}) string {
+			args = append(args, v)
+			return "?"
+		})
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanCitationSnapshots(rows)
+}
+
+func (r *SQLiteMetricsRepo) GetResearcherMetricsHistory(researcherID int, filter models.MetricsHistoryFilter) ([]models.MetricsSnapshot, error) {
+	args := []interface{}{}
+	query := metricsHistoryQuery("researcher_metric_snapshots", "researcher_id", metricsSnapshotColumns, researcherID, filter,
+		func(v interface{}) string {
+			args = append(args, v)
+			return "?"
+		})
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMetricsSnapshots(rows, researcherID)
+}
+
+// metricsSnapshotColumns are the metrics of a researcher snapshot, in the order
+// of metricsSnapshotValues.
+const metricsSnapshotColumns = "total_citations, h_index, recent_citations, recent_h_index, " +
+	"local_citations, local_h_index, local_i10_index"
+
+func metricsSnapshotValues(s models.MetricsSnapshot) []interface{} {
+	return []interface{}{s.TotalCitations, s.HIndex, s.RecentCitations, s.RecentHIndex,
+		s.LocalCitations, s.LocalHIndex, s.LocalI10Index}
+}
+
+// metricsHistoryQuery selects the period, recording time and columns of the
+// last snapshot of each period in table for the owner in ownerColumn, oldest
+// first. arg binds a parameter, returning its placeholder.
+func metricsHistoryQuery(table, ownerColumn, columns string, ownerID int, filter models.MetricsHistoryFilter, arg func(interface{}) string) string {
+	// recorded_at is text starting with YYYY-MM-DD.
+	length := 10
+	if filter.Interval == models.MetricsIntervalMonth {
+		length = 7
+	}
+	period := fmt.Sprintf("substr(recorded_at, 1, %d)", length)
+
+	where := ownerColumn + " = " + arg(ownerID)
+	if filter.From != "" {
+		where += " AND recorded_at >= " + arg(filter.From)
+	}
+	if filter.To != "" {
+		where += " AND substr(recorded_at, 1, 10) <= " + arg(filter.To)
+	}
+
+	return "SELECT " + period + ", recorded_at, " + columns + " FROM " + table +
+		" WHERE id IN (SELECT MAX(id) FROM " + table + " WHERE " + where + " GROUP BY " + period + ")" +
+		" ORDER BY id"
+}
+
+func scanCitationSnapshots(rows *sql.Rows) ([]models.CitationSnapshot, error) {
+	defer rows.Close()
+
+	snapshots := []models.CitationSnapshot{}
+	for rows.Next() {
+		var s models.CitationSnapshot
+		if err := rows.Scan(&s.Period, &s.RecordedAt, &s.CitationsCount); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+func scanMetricsSnapshots(rows *sql.Rows, researcherID int) ([]models.MetricsSnapshot, error) {
+	defer rows.Close()
+
+	snapshots := []models.MetricsSnapshot{}
+	for rows.Next() {
+		s := models.MetricsSnapshot{ResearcherID: researcherID}
+		if err := rows.Scan(&s.Period, &s.RecordedAt, &s.TotalCitations, &s.HIndex, &s.RecentCitations, &s.RecentHIndex,
+			&s.LocalCitations, &s.LocalHIndex, &s.LocalI10Index); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresAPITokenRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresAPITokenRepo(db *sql.DB) *PostgresAPITokenRepo {
+	return &PostgresAPITokenRepo{db: db}
+}
+
+func (r *PostgresAPITokenRepo) Create(token models.APIToken, tokenHash string) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		`INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		token.UserID, token.Name, tokenHash, strings.Join(token.Scopes, " "), token.ExpiresAt,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresAPITokenRepo) GetByID(id int) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(
+		`SELECT `+apiTokenColumns+`
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id
+		WHERE t.id = $1`,
+		id,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("api token not found")
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// GetByTokenHash returns the unexpired token stored under tokenHash.
+func (r *PostgresAPITokenRepo) GetByTokenHash(tokenHash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRow(
+		`SELECT `+apiTokenColumns+`
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id
+		WHERE t.token_hash = $1 AND (t.expires_at IS NULL OR t.expires_at > $2)`,
+		tokenHash, time.Now().UTC().Format(models.TimestampLayout),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("api token not found")
+		}
+		return nil, err
+	}
+	return token, nil
+}
+
+// GetAll returns all tokens, limited to one user when userID is non-zero.
+func (r *PostgresAPITokenRepo) GetAll(userID int) ([]models.APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + `
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id`
+	args := []interface{}{}
+
+	if userID != 0 {
+		query += " WHERE t.user_id = $1"
+		args = append(args, userID)
+	}
+	query += " ORDER BY t.id"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *PostgresAPITokenRepo) Touch(id int) error {
+	_, err := r.db.Exec(
+		"UPDATE api_tokens SET last_used_at = $1 WHERE id = $2",
+		time.Now().UTC().Format(models.TimestampLayout), id,
+	)
+	return err
+}
+
+func (r *PostgresAPITokenRepo) Delete(id int) error {
+	res, err := r.db.Exec("DELETE FROM api_tokens WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("api token not found")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresAttachmentRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewPostgresAttachmentRepo(db *sql.DB, lsRepo LocalizedStringRepo) *PostgresAttachmentRepo {
+	return &PostgresAttachmentRepo{db: db, localizedStringRepo: lsRepo}
+}
+
+func (r *PostgresAttachmentRepo) Create(attachment models.PublicationAttachment) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	titleID, err := r.localizedStringRepo.CreateTx(tx, attachment.Title)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO publication_attachments
+		(publication_id, type, title_id, url, file, file_name, content_type, size, attachment_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		attachment.PublicationID, attachment.Type, titleID, attachment.URL, attachment.File,
+		attachment.FileName, attachment.ContentType, attachment.Size, attachment.Order,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *PostgresAttachmentRepo) GetByID(id int) (*models.PublicationAttachment, error) {
+	var attachment models.PublicationAttachment
+	err := scanAttachment(r.db.QueryRow(attachmentQuery+" WHERE a.id = $1", id), &attachment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *PostgresAttachmentRepo) GetByPublication(publicationID int) ([]models.PublicationAttachment, error) {
+	return scanAttachments(r.db.Query(
+		attachmentQuery+" WHERE a.publication_id = $1 ORDER BY a.attachment_order, a.id",
+		publicationID,
+	))
+}
+
+// Update saves the type, title, URL and position of an attachment. The file of
+// an uploaded attachment cannot be replaced.
+func (r *PostgresAttachmentRepo) Update(attachment models.PublicationAttachment) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID int64
+	err = tx.QueryRow(
+		"UPDATE publication_attachments SET type = $1, url = $2, attachment_order = $3 WHERE id = $4 RETURNING title_id",
+		attachment.Type, attachment.URL, attachment.Order, attachment.ID,
+	).Scan(&titleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("attachment not found")
+		}
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, titleID, attachment.Title); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes an attachment along with its uploaded file.
+func (r *PostgresAttachmentRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID int64
+	var file string
+	err = tx.QueryRow(
+		"DELETE FROM publication_attachments WHERE id = $1 RETURNING title_id, file",
+		id,
+	).Scan(&titleID, &file)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = errors.New("attachment not found")
+		}
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	removeAttachmentFiles([]string{file})
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresAuditRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresAuditRepo(db *sql.DB) *PostgresAuditRepo {
+	return &PostgresAuditRepo{db: db}
+}
+
+func (r *PostgresAuditRepo) Create(entry models.AuditEntry) (int64, error) {
+	var diff []byte
+	if len(entry.Diff) > 0 {
+		var err error
+		if diff, err = json.Marshal(entry.Diff); err != nil {
+			return 0, err
+		}
+	}
+
+	var id int64
+	err := r.db.QueryRow(
+		`INSERT INTO audit_log (actor_id, actor, entity, entity_id, action, before, after, diff)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		entry.ActorID, entry.Actor, entry.Entity, entry.EntityID, entry.Action,
+		nullableJSON(entry.Before), nullableJSON(entry.After), nullableJSON(diff),
+	).Scan(&id)
+	return id, err
+}
+
+// GetAll returns audit entries matching filter, newest first.
+func (r *PostgresAuditRepo) GetAll(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := `SELECT id, actor_id, actor, entity, entity_id, action, before, after, diff, created_at
+		FROM audit_log WHERE 1 = 1`
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Entity != "" {
+		query += " AND entity = " + arg(filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		query += " AND entity_id = " + arg(filter.EntityID)
+	}
+	if filter.ActorID != 0 {
+		query += " AND actor_id = " + arg(filter.ActorID)
+	}
+	if filter.Actor != "" {
+		query += " AND actor = " + arg(filter.Actor)
+	}
+	if filter.Action != "" {
+		query += " AND action = " + arg(filter.Action)
+	}
+	if filter.From != "" {
+		query += " AND created_at >= " + arg(filter.From)
+	}
+	if filter.To != "" {
+		query += " AND created_at <= " + arg(filter.To)
+	}
+	query += " ORDER BY id DESC LIMIT " + arg(filter.Limit) + " OFFSET " + arg(filter.Offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after, diff sql.NullString
+		if err := rows.Scan(
+			&entry.ID, &entry.ActorID, &entry.Actor, &entry.Entity, &entry.EntityID,
+			&entry.Action, &before, &after, &diff, &entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		if diff.Valid {
+			if err := json.Unmarshal([]byte(diff.String), &entry.Diff); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
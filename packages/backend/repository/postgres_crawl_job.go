@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresCrawlJobRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresCrawlJobRepo(db *sql.DB) *PostgresCrawlJobRepo {
+	return &PostgresCrawlJobRepo{db: db}
+}
+
+func (r *PostgresCrawlJobRepo) Create(job models.CrawlJob) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO crawl_jobs (researcher_id, status, started_by, researchers) VALUES ($1, $2, $3, $4) RETURNING id",
+		job.ResearcherID, models.CrawlStatusRunning, job.StartedBy, job.Researchers,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresCrawlJobRepo) GetByID(id int) (*models.CrawlJob, error) {
+	job, err := scanCrawlJob(r.db.QueryRow(crawlJobQuery+" WHERE j.id = $1 GROUP BY j.id", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("crawl job not found")
+		}
+		return nil, err
+	}
+	job.Results, err = crawlResults(r.db.Query(crawlResultQuery+" WHERE job_id = $1 ORDER BY started_at, researcher_id", id))
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *PostgresCrawlJobRepo) List(status string, limit int) ([]models.CrawlJob, error) {
+	where, args := "", []interface{}{}
+	if status != "" {
+		where, args = " WHERE j.status = $1", append(args, status)
+	}
+	return scanCrawlJobs(r.db.Query(
+		crawlJobQuery+where+" GROUP BY j.id ORDER BY j.id DESC LIMIT $"+strconv.Itoa(len(args)+1),
+		append(args, limit)...,
+	))
+}
+
+func (r *PostgresCrawlJobRepo) StartResult(jobID, researcherID int) error {
+	_, err := r.db.Exec(
+		"INSERT INTO crawl_job_results (job_id, researcher_id, status) VALUES ($1, $2, $3)",
+		jobID, researcherID, models.CrawlStatusRunning,
+	)
+	return err
+}
+
+func (r *PostgresCrawlJobRepo) FinishResult(jobID int, result models.CrawlResult) error {
+	_, err := r.db.Exec(
+		`UPDATE crawl_job_results SET status = $1, new_publications = $2, updated_publications = $3, errors = $4, finished_at = $5
+		WHERE job_id = $6 AND researcher_id = $7`,
+		result.Status, result.NewPublications, result.UpdatedPublications, strings.Join(result.Errors, "\n"),
+		time.Now().UTC().Format(models.TimestampLayout), jobID, result.ResearcherID,
+	)
+	return err
+}
+
+func (r *PostgresCrawlJobRepo) Finish(id int, status, message string) error {
+	_, err := r.db.Exec(
+		"UPDATE crawl_jobs SET status = $1, error = $2, finished_at = $3 WHERE id = $4",
+		status, message, time.Now().UTC().Format(models.TimestampLayout), id,
+	)
+	return err
+}
+
+func (r *PostgresCrawlJobRepo) FailRunning(message string) (int, error) {
+	now := time.Now().UTC().Format(models.TimestampLayout)
+	_, err := r.db.Exec(
+		"UPDATE crawl_job_results SET status = $1, finished_at = $2 WHERE status = $3",
+		models.CrawlStatusFailed, now, models.CrawlStatusRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.db.Exec(
+		"UPDATE crawl_jobs SET status = $1, error = $2, finished_at = $3 WHERE status = $4",
+		models.CrawlStatusFailed, message, now, models.CrawlStatusRunning,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresDisciplineRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+	researcherRepo      ResearcherRepo
+}
+
+func NewPostgresDisciplineRepo(db *sql.DB, lsRepo LocalizedStringRepo, rRepo ResearcherRepo) *PostgresDisciplineRepo {
+	return &PostgresDisciplineRepo{
+		db:                  db,
+		localizedStringRepo: lsRepo,
+		researcherRepo:      rRepo,
+	}
+}
+
+func (r *PostgresDisciplineRepo) Create(discipline models.Discipline) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	titleID, err := r.localizedStringRepo.CreateTx(tx, discipline.Title)
+	if err != nil {
+		return 0, err
+	}
+
+	descriptionID, err := r.localizedStringRepo.CreateTx(tx, discipline.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO disciplines (title_id, description_id, image)
+		VALUES ($1, $2, $3) RETURNING id`,
+		titleID, descriptionID, discipline.Image,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = r.insertResearchers(tx, int(id), discipline.Researchers); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *PostgresDisciplineRepo) GetByID(id int) (*models.Discipline, error) {
+	var discipline models.Discipline
+	var titleID, descriptionID int64
+
+	err := r.db.QueryRow(
+		"SELECT id, title_id, description_id, image FROM disciplines WHERE id = $1",
+		id,
+	).Scan(&discipline.ID, &titleID, &descriptionID, &discipline.Image)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("discipline not found")
+		}
+		return nil, err
+	}
+
+	if err := r.load(&discipline, titleID, descriptionID); err != nil {
+		return nil, err
+	}
+	return &discipline, nil
+}
+
+func (r *PostgresDisciplineRepo) GetAll() ([]models.Discipline, error) {
+	rows, err := r.db.Query("SELECT id, title_id, description_id, image FROM disciplines ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disciplines := []models.Discipline{}
+	for rows.Next() {
+		var discipline models.Discipline
+		var titleID, descriptionID int64
+		if err := rows.Scan(&discipline.ID, &titleID, &descriptionID, &discipline.Image); err != nil {
+			return nil, err
+		}
+
+		if err := r.load(&discipline, titleID, descriptionID); err != nil {
+			return nil, err
+		}
+		disciplines = append(disciplines, discipline)
+	}
+	return disciplines, rows.Err()
+}
+
+func (r *PostgresDisciplineRepo) Update(discipline models.Discipline) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID, descriptionID int64
+	err = tx.QueryRow(
+		"SELECT title_id, description_id FROM disciplines WHERE id = $1",
+		discipline.ID,
+	).Scan(&titleID, &descriptionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("discipline not found")
+		}
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, titleID, discipline.Title); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, descriptionID, discipline.Description); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE disciplines SET image = $1 WHERE id = $2", discipline.Image, discipline.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM discipline_researchers WHERE discipline_id = $1", discipline.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.insertResearchers(tx, discipline.ID, discipline.Researchers); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresDisciplineRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec("DELETE FROM discipline_researchers WHERE discipline_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	var titleID, descriptionID int64
+	var image string
+	err = tx.QueryRow(
+		"DELETE FROM disciplines WHERE id = $1 RETURNING title_id, description_id, image",
+		id,
+	).Scan(&titleID, &descriptionID, &image)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("discipline not found")
+		}
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, descriptionID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if image != "" {
+		filePath := filepath.Join("../", image)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Error removing discipline image file: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func (r *PostgresDisciplineRepo) insertResearchers(tx *sql.Tx, disciplineID int, researchers []models.DisciplineResearcher) error {
+	for _, researcher := range researchers {
+		_, err := tx.Exec(
+			"INSERT INTO discipline_researchers (discipline_id, researcher_id) VALUES ($1, $2)",
+			disciplineID, researcher.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load fills the localized strings and researchers of a discipline.
+func (r *PostgresDisciplineRepo) load(discipline *models.Discipline, titleID, descriptionID int64) error {
+	title, err := r.localizedStringRepo.Get(titleID)
+	if err != nil {
+		return err
+	}
+	discipline.Title = *title
+
+	description, err := r.localizedStringRepo.Get(descriptionID)
+	if err != nil {
+		return err
+	}
+	discipline.Description = *description
+
+	rows, err := r.db.Query(`
+		SELECT r.id, ls_name.en, ls_name.ru, ls_last_name.en, ls_last_name.ru
+		FROM discipline_researchers dr
+		JOIN researchers r ON dr.researcher_id = r.id
+		JOIN localized_strings ls_name ON r.name_id = ls_name.id
+		JOIN localized_strings ls_last_name ON r.last_name_id = ls_last_name.id
+		WHERE dr.discipline_id = $1
+		ORDER BY r.id
+	`, discipline.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	discipline.Researchers = []models.DisciplineResearcher{}
+	for rows.Next() {
+		var researcher models.DisciplineResearcher
+		if err := rows.Scan(
+			&researcher.ID, &researcher.Name.En, &researcher.Name.Ru,
+			&researcher.LastName.En, &researcher.LastName.Ru,
+		); err != nil {
+			return err
+		}
+		discipline.Researchers = append(discipline.Researchers, researcher)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresExternalPersonRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewPostgresExternalPersonRepo(db *sql.DB, lsRepo LocalizedStringRepo) *PostgresExternalPersonRepo {
+	return &PostgresExternalPersonRepo{db: db, localizedStringRepo: lsRepo}
+}
+
+func pgPlaceholder(i int) string { return "$" + strconv.Itoa(i+1) }
+
+func (r *PostgresExternalPersonRepo) GetByID(id int) (*models.ExternalPerson, error) {
+	return getExternalPerson(r.db, pgPlaceholder, id)
+}
+
+func (r *PostgresExternalPersonRepo) GetAll() ([]models.ExternalPerson, error) {
+	return getExternalPeople(r.db, "")
+}
+
+func (r *PostgresExternalPersonRepo) FindByAlias(name string) (*int, error) {
+	return findPersonByAlias(r.db, pgPlaceholder, name)
+}
+
+func (r *PostgresExternalPersonRepo) Update(person models.ExternalPerson) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = updateExternalPerson(tx, r.localizedStringRepo, pgPlaceholder, person); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresExternalPersonRepo) Merge(survivorID int, duplicateIDs []int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = mergeExternalPeople(tx, r.localizedStringRepo, pgPlaceholder, survivorID, duplicateIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresExternalPersonRepo) Promote(personID, researcherID int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = promoteExternalPerson(tx, r.localizedStringRepo, pgPlaceholder, personID, researcherID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
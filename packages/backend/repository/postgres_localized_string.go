@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresLocalizedStringRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresLocalizedStringRepo(db *sql.DB) *PostgresLocalizedStringRepo {
+	return &PostgresLocalizedStringRepo{db: db}
+}
+
+func (r *PostgresLocalizedStringRepo) Create(ls models.LocalizedString) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO localized_strings (en, ru) VALUES ($1, $2) RETURNING id",
+		ls.En, ls.Ru,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresLocalizedStringRepo) CreateTx(tx *sql.Tx, ls models.LocalizedString) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO localized_strings (en, ru) VALUES ($1, $2) RETURNING id",
+		ls.En, ls.Ru,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresLocalizedStringRepo) Get(id int64) (*models.LocalizedString, error) {
+	var ls models.LocalizedString
+	err := r.db.QueryRow(
+		"SELECT en, ru FROM localized_strings WHERE id = $1",
+		id,
+	).Scan(&ls.En, &ls.Ru)
+	if err != nil {
+		return nil, err
+	}
+	return &ls, nil
+}
+
+func (r *PostgresLocalizedStringRepo) Update(id int64, ls models.LocalizedString) error {
+	_, err := r.db.Exec(
+		"UPDATE localized_strings SET en = $1, ru = $2 WHERE id = $3",
+		ls.En, ls.Ru, id,
+	)
+	return err
+}
+
+func (r *PostgresLocalizedStringRepo) UpdateTx(tx *sql.Tx, id int64, ls models.LocalizedString) error {
+	_, err := tx.Exec(
+		"UPDATE localized_strings SET en = $1, ru = $2 WHERE id = $3",
+		ls.En, ls.Ru, id,
+	)
+	return err
+}
+
+func (r *PostgresLocalizedStringRepo) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM localized_strings WHERE id = $1", id)
+	return err
+}
+
+func (r *PostgresLocalizedStringRepo) DeleteTx(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec("DELETE FROM localized_strings WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresLoginAttemptRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresLoginAttemptRepo(db *sql.DB) *PostgresLoginAttemptRepo {
+	return &PostgresLoginAttemptRepo{db: db}
+}
+
+func (r *PostgresLoginAttemptRepo) Get(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.QueryRow(
+		"SELECT key, failures, last_failure_at, blocked_until FROM login_attempts WHERE key = $1",
+		key,
+	).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.BlockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("login attempt not found")
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Fail counts a failed attempt for the key in one statement, so concurrent
+// failures are all counted. The count starts over when the previous failure
+// happened before windowStart. It returns the number of failures.
+func (r *PostgresLoginAttemptRepo) Fail(key, at, windowStart string) (int, error) {
+	var failures int
+	err := r.db.QueryRow(
+		`INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING failures`,
+		key, at, windowStart,
+	).Scan(&failures)
+	return failures, err
+}
+
+func (r *PostgresLoginAttemptRepo) Block(key, until string) error {
+	_, err := r.db.Exec("UPDATE login_attempts SET blocked_until = $1 WHERE key = $2", until, key)
+	return err
+}
+
+func (r *PostgresLoginAttemptRepo) Delete(key string) error {
+	_, err := r.db.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
+
+// DeleteStale removes attempts whose last failure happened before the given
+// timestamp and that are no longer blocked.
+func (r *PostgresLoginAttemptRepo) DeleteStale(before string) error {
+	_, err := r.db.Exec(
+		"DELETE FROM login_attempts WHERE last_failure_at < $1 AND (blocked_until IS NULL OR blocked_until < $2)",
+		before, time.Now().UTC().Format(models.TimestampLayout),
+	)
+	return err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresMetricsRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresMetricsRepo(db *sql.DB) *PostgresMetricsRepo {
+	return &PostgresMetricsRepo{db: db}
+}
+
+func (r *PostgresMetricsRepo) RecordPublicationCitations(publicationID, citations int) error {
+	_, err := r.db.Exec(
+		"INSERT INTO publication_citation_snapshots (publication_id, citations_count) VALUES ($1, $2)",
+		publicationID, citations,
+	)
+	return err
+}
+
+func (r *PostgresMetricsRepo) RecordResearcherMetrics(snapshot models.MetricsSnapshot) error {
+	_, err := r.db.Exec(
+		`INSERT INTO researcher_metric_snapshots (researcher_id, `+metricsSnapshotColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		append([]interface{}{snapshot.ResearcherID}, metricsSnapshotValues(snapshot)...)...,
+	)
+	return err
+}
+
+func (r *PostgresMetricsRepo) GetPublicationCitationHistory(publicationID int, filter models.MetricsHistoryFilter) ([]models.CitationSnapshot, error) {
+	args := []interface{}{}
+	query := metricsHistoryQuery("publication_citation_snapshots", "publication_id", "citations_count", publicationID, filter,
+		func(v interface{}) string {
+			args = append(args, v)
+			return "$" + strconv.Itoa(len(args))
+		})
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanCitationSnapshots(rows)
+}
+
+func (r *PostgresMetricsRepo) GetResearcherMetricsHistory(researcherID int, filter models.MetricsHistoryFilter) ([]models.MetricsSnapshot, error) {
+	args := []interface{}{}
+	query := metricsHistoryQuery("researcher_metric_snapshots", "researcher_id", metricsSnapshotColumns, researcherID, filter,
+		func(v interface{}) string {
+			args = append(args, v)
+			return "$" + strconv.Itoa(len(args))
+		})
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanMetricsSnapshots(rows, researcherID)
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresPartnerRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresPartnerRepo(db *sql.DB) *PostgresPartnerRepo {
+	return &PostgresPartnerRepo{db: db}
+}
+
+func (r *PostgresPartnerRepo) Create(partner models.Partner) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO partners (name, logo, url, type) VALUES ($1, $2, $3, $4) RETURNING id",
+		partner.Name, partner.Logo, partner.URL, partner.Type,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresPartnerRepo) GetByID(id int) (*models.Partner, error) {
+	var partner models.Partner
+	err := r.db.QueryRow(
+		"SELECT id, name, logo, url, type FROM partners WHERE id = $1",
+		id,
+	).Scan(&partner.ID, &partner.Name, &partner.Logo, &partner.URL, &partner.Type)
+	if err != nil {
+		return nil, err
+	}
+	return &partner, nil
+}
+
+func (r *PostgresPartnerRepo) GetAll(partnerType string) ([]models.Partner, error) {
+	partners := make([]models.Partner, 0)
+
+	rows, err := r.db.Query(
+		"SELECT id, name, logo, url, type FROM partners WHERE type = $1 ORDER BY id",
+		partnerType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var partner models.Partner
+		if err := rows.Scan(&partner.ID, &partner.Name, &partner.Logo, &partner.URL, &partner.Type); err != nil {
+			return nil, err
+		}
+		partners = append(partners, partner)
+	}
+	return partners, rows.Err()
+}
+
+func (r *PostgresPartnerRepo) Update(partner models.Partner) error {
+	_, err := r.db.Exec(
+		"UPDATE partners SET name = $1, logo = $2, url = $3, type = $4 WHERE id = $5",
+		partner.Name, partner.Logo, partner.URL, partner.Type, partner.ID,
+	)
+	return err
+}
+
+func (r *PostgresPartnerRepo) Delete(id int) error {
+	partner, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if partner.Logo != "" {
+		filePath := filepath.Join("../", partner.Logo)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	_, err = r.db.Exec("DELETE FROM partners WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,449 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresProjectRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewPostgresProjectRepo(db *sql.DB, lsRepo LocalizedStringRepo) *PostgresProjectRepo {
+	return &PostgresProjectRepo{db: db, localizedStringRepo: lsRepo}
+}
+
+func (r *PostgresProjectRepo) Create(project models.Project) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	titleID, err := r.localizedStringRepo.CreateTx(tx, project.Title)
+	if err != nil {
+		return 0, err
+	}
+
+	descriptionID, err := r.localizedStringRepo.CreateTx(tx, project.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO projects (title_id, description_id, github_link) VALUES ($1, $2, $3) RETURNING id",
+		titleID, descriptionID, project.GithubLink,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, image := range project.Images {
+		if err = r.addImage(tx, int(id), image); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = pgIndex(tx, models.SearchTypeProject, int(id)); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *PostgresProjectRepo) GetByID(id int) (*models.Project, error) {
+	project := models.NewProject()
+	var titleID, descriptionID int64
+
+	err := r.db.QueryRow(
+		"SELECT id, title_id, description_id, github_link FROM projects WHERE id = $1",
+		id,
+	).Scan(&project.ID, &titleID, &descriptionID, &project.GithubLink)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadStrings(&project, titleID, descriptionID); err != nil {
+		return nil, err
+	}
+
+	publications, err := r.getProjectPublications(id)
+	if err != nil {
+		return nil, err
+	}
+	if publications != nil {
+		project.Publications = publications
+	}
+
+	videos, err := r.getProjectVideos(id)
+	if err != nil {
+		return nil, err
+	}
+	if videos != nil {
+		project.Videos = videos
+	}
+
+	images, err := r.getProjectImages(id)
+	if err != nil {
+		return nil, err
+	}
+	if images != nil {
+		project.Images = images
+	}
+
+	return &project, nil
+}
+
+func (r *PostgresProjectRepo) GetAll() ([]models.Project, error) {
+	rows, err := r.db.Query("SELECT id, title_id, description_id, github_link FROM projects ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project := models.NewProject()
+		var titleID, descriptionID int64
+		if err := rows.Scan(&project.ID, &titleID, &descriptionID, &project.GithubLink); err != nil {
+			return nil, err
+		}
+
+		if err := r.loadStrings(&project, titleID, descriptionID); err != nil {
+			return nil, err
+		}
+
+		publications, err := r.getProjectPublications(project.ID)
+		if err != nil {
+			return nil, err
+		}
+		if publications != nil {
+			project.Publications = publications
+		}
+
+		images, err := r.getProjectImages(project.ID)
+		if err != nil {
+			return nil, err
+		}
+		if images != nil {
+			project.Images = images
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (r *PostgresProjectRepo) Update(project models.Project) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID, descriptionID int64
+	err = tx.QueryRow(
+		"SELECT title_id, description_id FROM projects WHERE id = $1",
+		project.ID,
+	).Scan(&titleID, &descriptionID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, titleID, project.Title); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, descriptionID, project.Description); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE projects SET github_link = $1 WHERE id = $2", project.GithubLink, project.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.deleteAttachments(tx, project.ID); err != nil {
+		return err
+	}
+
+	for _, image := range project.Images {
+		if err = r.addImage(tx, project.ID, image); err != nil {
+			return err
+		}
+	}
+
+	for _, video := range project.Videos {
+		if err = r.addVideo(tx, project.ID, video); err != nil {
+			return err
+		}
+	}
+
+	for _, pub := range project.Publications {
+		if err = r.addPublication(tx, project.ID, pub); err != nil {
+			return err
+		}
+	}
+
+	if err = pgIndex(tx, models.SearchTypeProject, project.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresProjectRepo) Delete(id int) (err error) {
+	images, err := r.getProjectImages(id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = r.deleteAttachments(tx, id); err != nil {
+		return err
+	}
+
+	var titleID, descriptionID int64
+	err = tx.QueryRow(
+		"DELETE FROM projects WHERE id = $1 RETURNING title_id, description_id",
+		id,
+	).Scan(&titleID, &descriptionID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, descriptionID); err != nil {
+		return err
+	}
+
+	if err = pgUnindex(tx, models.SearchTypeProject, id); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		if image.URL != "" {
+			filePath := filepath.Join("../", image.URL)
+			if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepo) AddPublication(projectID int, pub models.ProjectPublication) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = r.addPublication(tx, projectID, pub); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresProjectRepo) AddVideo(projectID int, video models.ProjectVideo) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = r.addVideo(tx, projectID, video); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresProjectRepo) addPublication(tx *sql.Tx, projectID int, pub models.ProjectPublication) error {
+	titleID, err := r.localizedStringRepo.CreateTx(tx, pub.Title)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO project_publications (project_id, title_id, link) VALUES ($1, $2, $3)",
+		projectID, titleID, pub.Link,
+	)
+	return err
+}
+
+func (r *PostgresProjectRepo) addVideo(tx *sql.Tx, projectID int, video models.ProjectVideo) error {
+	titleID, err := r.localizedStringRepo.CreateTx(tx, video.Title)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO project_videos (project_id, title_id, embed_url) VALUES ($1, $2, $3)",
+		projectID, titleID, video.EmbedURL,
+	)
+	return err
+}
+
+func (r *PostgresProjectRepo) addImage(tx *sql.Tx, projectID int, image models.ProjectImage) error {
+	_, err := tx.Exec(
+		"INSERT INTO project_images (project_id, url, image_order) VALUES ($1, $2, $3)",
+		projectID, image.URL, image.Order,
+	)
+	return err
+}
+
+// deleteAttachments removes the images, videos and publications of a project
+// together with the localized titles of the latter two.
+func (r *PostgresProjectRepo) deleteAttachments(tx *sql.Tx, projectID int) error {
+	if _, err := tx.Exec("DELETE FROM project_images WHERE project_id = $1", projectID); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"project_videos", "project_publications"} {
+		rows, err := tx.Query("DELETE FROM "+table+" WHERE project_id = $1 RETURNING title_id", projectID)
+		if err != nil {
+			return err
+		}
+
+		var titleIDs []int64
+		for rows.Next() {
+			var titleID int64
+			if err := rows.Scan(&titleID); err != nil {
+				rows.Close()
+				return err
+			}
+			titleIDs = append(titleIDs, titleID)
+		}
+		rows.Close()
+
+		for _, titleID := range titleIDs {
+			if err := r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *PostgresProjectRepo) loadStrings(project *models.Project, titleID, descriptionID int64) error {
+	title, err := r.localizedStringRepo.Get(titleID)
+	if err != nil {
+		return err
+	}
+	project.Title = *title
+
+	description, err := r.localizedStringRepo.Get(descriptionID)
+	if err != nil {
+		return err
+	}
+	project.Description = *description
+	return nil
+}
+
+func (r *PostgresProjectRepo) getProjectPublications(projectID int) ([]models.ProjectPublication, error) {
+	rows, err := r.db.Query(
+		`SELECT pp.id, pp.link, ls.en, ls.ru
+		FROM project_publications pp
+		JOIN localized_strings ls ON pp.title_id = ls.id
+		WHERE pp.project_id = $1
+		ORDER BY pp.id`,
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var publications []models.ProjectPublication
+	for rows.Next() {
+		var pub models.ProjectPublication
+		if err := rows.Scan(&pub.ID, &pub.Link, &pub.Title.En, &pub.Title.Ru); err != nil {
+			return nil, err
+		}
+		publications = append(publications, pub)
+	}
+	return publications, rows.Err()
+}
+
+func (r *PostgresProjectRepo) getProjectVideos(projectID int) ([]models.ProjectVideo, error) {
+	rows, err := r.db.Query(
+		`SELECT pv.id, pv.embed_url, ls.en, ls.ru
+		FROM project_videos pv
+		JOIN localized_strings ls ON pv.title_id = ls.id
+		WHERE pv.project_id = $1
+		ORDER BY pv.id`,
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []models.ProjectVideo
+	for rows.Next() {
+		var video models.ProjectVideo
+		if err := rows.Scan(&video.ID, &video.EmbedURL, &video.Title.En, &video.Title.Ru); err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+func (r *PostgresProjectRepo) getProjectImages(projectID int) ([]models.ProjectImage, error) {
+	rows, err := r.db.Query(
+		"SELECT id, url, image_order FROM project_images WHERE project_id = $1 ORDER BY image_order",
+		projectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []models.ProjectImage
+	for rows.Next() {
+		var image models.ProjectImage
+		if err := rows.Scan(&image.ID, &image.URL, &image.Order); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	return images, rows.Err()
+}
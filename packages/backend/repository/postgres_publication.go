@@ -0,0 +1,509 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/lib/pq"
+)
+
+type PostgresPublicationRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+	researcherRepo      ResearcherRepo
+	venueRepo           VenueRepo
+}
+
+func NewPostgresPublicationRepo(db *sql.DB, lsRepo LocalizedStringRepo, researcherRepo ResearcherRepo, venueRepo VenueRepo) *PostgresPublicationRepo {
+	return &PostgresPublicationRepo{db: db, localizedStringRepo: lsRepo, researcherRepo: researcherRepo, venueRepo: venueRepo}
+}
+
+const pgPublicationSelect = publicationListColumns + publicationListFrom
+
+// scanPublication reads a row of publicationListColumns.
+func scanPublication(scanner interface{ Scan(...interface{}) error }, pub *models.Publication) error {
+	dest := []interface{}{&pub.ID, &pub.Title.En, &pub.Title.Ru, &pub.Link, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible}
+	return scanner.Scan(append(dest, publicationMetadata(pub)...)...)
+}
+
+func (r *PostgresPublicationRepo) Create(pub models.Publication) (id int64, err error) {
+	var count int
+	err = r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM publications p
+		JOIN localized_strings ls ON p.title_id = ls.id
+		WHERE LOWER(ls.en) = LOWER($1) OR LOWER(ls.ru) = LOWER($2)
+	`, pub.Title.En, pub.Title.Ru).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	if count > 0 {
+		return 0, fmt.Errorf("publication with title '%s' or '%s' already exists", pub.Title.En, pub.Title.Ru)
+	}
+
+	if pub.VenueID == nil {
+		if pub.VenueID, err = r.venueRepo.Match(pub.Venue); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	titleID, err := r.localizedStringRepo.CreateTx(tx, pub.Title)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO publications (title_id, link, published_at, citations_count, `+publicationMetadataColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		append([]interface{}{titleID, pub.Link, pub.PublishedAt, pub.CitationsCount}, publicationMetadataValues(pub)...)...,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = r.insertAuthors(tx, int(id), pub.Authors); err != nil {
+		return 0, err
+	}
+
+	if err = indexTitleWords(tx, pgPlaceholder, int(id), pub.Title); err != nil {
+		return 0, err
+	}
+
+	if err = pgIndex(tx, models.SearchTypePublication, int(id)); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *PostgresPublicationRepo) GetByID(id int) (*models.Publication, error) {
+	var pub models.Publication
+	if err := scanPublication(r.db.QueryRow(pgPublicationSelect+" WHERE p.id = $1", id), &pub); err != nil {
+		return nil, err
+	}
+
+	authors, err := pgPublicationAuthors(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	pub.Authors = authors
+
+	return &pub, nil
+}
+
+func (r *PostgresPublicationRepo) GetAll() ([]models.Publication, error) {
+	return r.query(pgPublicationSelect + " ORDER BY p.id")
+}
+
+func (r *PostgresPublicationRepo) GetPossibleDuplicates() ([]models.Publication, error) {
+	return r.query(pgPublicationSelect + possibleDuplicatesWhere + " ORDER BY p.id")
+}
+
+func (r *PostgresPublicationRepo) GetByIDs(ids []int) ([]models.Publication, error) {
+	if len(ids) == 0 {
+		return []models.Publication{}, nil
+	}
+
+	found, err := r.query(pgPublicationSelect+" WHERE p.id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]models.Publication, len(found))
+	for _, pub := range found {
+		byID[pub.ID] = pub
+	}
+
+	publications := make([]models.Publication, 0, len(ids))
+	for _, id := range ids {
+		if pub, ok := byID[id]; ok {
+			publications = append(publications, pub)
+		}
+	}
+	return publications, nil
+}
+
+func (r *PostgresPublicationRepo) GetByTitle(title string) (*models.Publication, error) {
+	var pub models.Publication
+	err := scanPublication(r.db.QueryRow(
+		pgPublicationSelect+" WHERE LOWER(ls.en) = LOWER($1) OR LOWER(ls.ru) = LOWER($1) ORDER BY p.id LIMIT 1",
+		title,
+	), &pub)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pub, nil
+}
+
+func (r *PostgresPublicationRepo) Update(pub models.Publication) (err error) {
+	var count int
+	err = r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM publications p
+		JOIN localized_strings ls ON p.title_id = ls.id
+		WHERE (LOWER(ls.en) = LOWER($1) OR LOWER(ls.ru) = LOWER($2))
+		AND p.id != $3
+	`, pub.Title.En, pub.Title.Ru, pub.ID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("another publication with title '%s' or '%s' already exists", pub.Title.En, pub.Title.Ru)
+	}
+
+	if pub.VenueID == nil {
+		if pub.VenueID, err = r.venueRepo.Match(pub.Venue); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = r.update(tx, pub); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresPublicationRepo) update(tx *sql.Tx, pub models.Publication) error {
+	var titleID int64
+	set := publicationMetadataSet(func(i int) string { return "$" + strconv.Itoa(i+5) })
+	args := append([]interface{}{pub.Link, pub.PublishedAt, pub.CitationsCount, pub.Visible}, publicationMetadataValues(pub)...)
+	err := tx.QueryRow(
+		`UPDATE publications SET link = $1, published_at = $2, citations_count = $3, visible = $4, `+set+`
+		WHERE id = $`+strconv.Itoa(len(args)+1)+` RETURNING title_id`,
+		append(args, pub.ID)...,
+	).Scan(&titleID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, titleID, pub.Title); err != nil {
+		return err
+	}
+
+	if pub.Authors != nil {
+		if err = r.deleteAuthors(tx, pub.ID); err != nil {
+			return err
+		}
+
+		if err = r.insertAuthors(tx, pub.ID, pub.Authors); err != nil {
+			return err
+		}
+	}
+
+	if err = indexTitleWords(tx, pgPlaceholder, pub.ID, pub.Title); err != nil {
+		return err
+	}
+	return pgIndex(tx, models.SearchTypePublication, pub.ID)
+}
+
+func (r *PostgresPublicationRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	files, err := deletePublicationAttachments(tx, id, func(i int) string { return "$" + strconv.Itoa(i+1) })
+	if err != nil {
+		return err
+	}
+	if err = r.delete(tx, id); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	removeAttachmentFiles(files)
+	return nil
+}
+
+func (r *PostgresPublicationRepo) delete(tx *sql.Tx, id int) error {
+	if err := r.deleteAuthors(tx, id); err != nil {
+		return err
+	}
+
+	if err := unindexTitleWords(tx, pgPlaceholder, id); err != nil {
+		return err
+	}
+
+	var titleID int64
+	err := tx.QueryRow("DELETE FROM publications WHERE id = $1 RETURNING title_id", id).Scan(&titleID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+		return err
+	}
+
+	return pgUnindex(tx, models.SearchTypePublication, id)
+}
+
+// Merge saves pub, the merged record of two publications, deletes the other
+// one and points the project publications, attachments and citation history
+// of the other one at pub, all in one transaction.
+func (r *PostgresPublicationRepo) Merge(pub models.Publication, duplicateID int) (err error) {
+	if pub.VenueID == nil {
+		if pub.VenueID, err = r.venueRepo.Match(pub.Venue); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var duplicate models.Publication
+	err = tx.QueryRow(
+		`SELECT p.link, ls.en, ls.ru FROM publications p
+		JOIN localized_strings ls ON p.title_id = ls.id
+		WHERE p.id = $1`,
+		duplicateID,
+	).Scan(&duplicate.Link, &duplicate.Title.En, &duplicate.Title.Ru)
+	if err != nil {
+		return err
+	}
+
+	placeholder := func(i int) string { return "$" + strconv.Itoa(i+1) }
+	if err = relinkProjectPublications(tx, duplicate, pub, placeholder); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE publication_attachments SET publication_id = $1 WHERE publication_id = $2", pub.ID, duplicateID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE publication_citation_snapshots SET publication_id = $1 WHERE publication_id = $2", pub.ID, duplicateID)
+	if err != nil {
+		return err
+	}
+	if err = r.delete(tx, duplicateID); err != nil {
+		return err
+	}
+	if err = r.update(tx, pub); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresPublicationRepo) GetAuthors(id int) ([]models.Researcher, error) {
+	rows, err := r.db.Query(
+		"SELECT researcher_id FROM publication_authors WHERE publication_id = $1 ORDER BY researcher_id",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authorIDs := []int{}
+	for rows.Next() {
+		var authorID int
+		if err := rows.Scan(&authorID); err != nil {
+			return nil, err
+		}
+		authorIDs = append(authorIDs, authorID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(authorIDs) == 0 {
+		return []models.Researcher{}, nil
+	}
+
+	return r.researcherRepo.GetByIDs(authorIDs)
+}
+
+// List returns one page of the publications matching filter and the number of
+// all matching publications.
+func (r *PostgresPublicationRepo) List(filter models.PublicationFilter) ([]models.Publication, int, error) {
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	from := publicationListFrom + publicationWhere(filter, "ILIKE", arg)
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := publicationListColumns + from + " ORDER BY " + publicationOrderBy(filter.Sort)
+	if filter.PageSize > 0 {
+		query += " LIMIT " + arg(filter.PageSize) + " OFFSET " + arg(publicationOffset(filter))
+	}
+
+	publications, err := r.query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return publications, total, nil
+}
+
+func (r *PostgresPublicationRepo) SetVisibility(id int, visible bool) error {
+	res, err := r.db.Exec("UPDATE publications SET visible = $1 WHERE id = $2", visible, id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresPublicationRepo) GetTotalCount() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM publications").Scan(&count)
+	return count, err
+}
+
+// query runs a pgPublicationSelect query and loads the authors of every row.
+func (r *PostgresPublicationRepo) query(query string, args ...interface{}) ([]models.Publication, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	publications := []models.Publication{}
+	for rows.Next() {
+		var pub models.Publication
+		if err := scanPublication(rows, &pub); err != nil {
+			return nil, err
+		}
+		publications = append(publications, pub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range publications {
+		publications[i].Authors, err = pgPublicationAuthors(r.db, publications[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return publications, nil
+}
+
+func (r *PostgresPublicationRepo) insertAuthors(tx *sql.Tx, publicationID int, authors []models.Author) error {
+	for i, author := range authors {
+		researcherID := author.ID
+		var personID *int
+		if researcherID == nil {
+			var err error
+			personID, researcherID, err = resolveExternalPerson(tx, r.localizedStringRepo, pgPlaceholder, author)
+			if err != nil {
+				return err
+			}
+		}
+
+		if researcherID != nil {
+			_, err := tx.Exec(
+				`INSERT INTO publication_authors (publication_id, researcher_id, author_order, role)
+				VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`,
+				publicationID, *researcherID, i, author.Role,
+			)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		nameID, err := r.localizedStringRepo.CreateTx(tx, author.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO publication_external_authors (publication_id, name_id, author_order, role, person_id) VALUES ($1, $2, $3, $4, $5)",
+			publicationID, nameID, i, author.Role, personID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteAuthors unlinks all authors of a publication and removes the names of
+// its external authors.
+func (r *PostgresPublicationRepo) deleteAuthors(tx *sql.Tx, publicationID int) error {
+	if _, err := tx.Exec("DELETE FROM publication_authors WHERE publication_id = $1", publicationID); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(
+		"DELETE FROM publication_external_authors WHERE publication_id = $1 RETURNING name_id",
+		publicationID,
+	)
+	if err != nil {
+		return err
+	}
+
+	var nameIDs []int64
+	for rows.Next() {
+		var nameID int64
+		if err := rows.Scan(&nameID); err != nil {
+			rows.Close()
+			return err
+		}
+		nameIDs = append(nameIDs, nameID)
+	}
+	rows.Close()
+
+	for _, nameID := range nameIDs {
+		if err := r.localizedStringRepo.DeleteTx(tx, nameID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pgPublicationAuthors returns the authors of a publication in byline order.
+func pgPublicationAuthors(q queryer, publicationID int) ([]models.Author, error) {
+	return publicationAuthors(q, func(i int) string { return "$" + strconv.Itoa(i+1) }, publicationID)
+}
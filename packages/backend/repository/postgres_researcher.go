@@ -0,0 +1,475 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/lib/pq"
+)
+
+type PostgresResearcherRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewPostgresResearcherRepo(db *sql.DB, lsRepo LocalizedStringRepo) *PostgresResearcherRepo {
+	return &PostgresResearcherRepo{
+		db:                  db,
+		localizedStringRepo: lsRepo,
+	}
+}
+
+// pgResearcherSelect selects a researcher with its localized strings joined in;
+// scanResearcher reads the columns in the same order.
+const pgResearcherSelect = `SELECT r.id, fn.en, fn.ru, ln.en, ln.ru, pos.en, pos.ru,
+		COALESCE(bio.en, ''), COALESCE(bio.ru, ''), r.photo,
+		r.google_scholar, r.research_gate, r.publons, r.orcid, r.scopus,
+		r.total_citations, r.h_index, r.recent_citations, r.recent_h_index
+	FROM researchers r
+	JOIN localized_strings fn ON r.name_id = fn.id
+	JOIN localized_strings ln ON r.last_name_id = ln.id
+	JOIN localized_strings pos ON r.position_id = pos.id
+	LEFT JOIN localized_strings bio ON r.bio_id = bio.id`
+
+func scanResearcher(scanner interface{ Scan(...interface{}) error }, researcher *models.Researcher) error {
+	return scanner.Scan(
+		&researcher.ID, &researcher.Name.En, &researcher.Name.Ru,
+		&researcher.LastName.En, &researcher.LastName.Ru,
+		&researcher.Position.En, &researcher.Position.Ru,
+		&researcher.Bio.En, &researcher.Bio.Ru, &researcher.Photo,
+		&researcher.Profiles.GoogleScholar, &researcher.Profiles.ResearchGate,
+		&researcher.Profiles.Publons, &researcher.Profiles.Orcid, &researcher.Profiles.Scopus,
+		&researcher.TotalCitations, &researcher.HIndex, &researcher.RecentCitations, &researcher.RecentHIndex,
+	)
+}
+
+func (r *PostgresResearcherRepo) Create(researcher models.Researcher) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	bioID, err := r.localizedStringRepo.CreateTx(tx, researcher.Bio)
+	if err != nil {
+		return 0, err
+	}
+
+	nameID, err := r.localizedStringRepo.CreateTx(tx, researcher.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	lastNameID, err := r.localizedStringRepo.CreateTx(tx, researcher.LastName)
+	if err != nil {
+		return 0, err
+	}
+
+	positionID, err := r.localizedStringRepo.CreateTx(tx, researcher.Position)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO researchers (name_id, last_name_id, position_id, photo, bio_id, google_scholar, research_gate,
+			publons, orcid, scopus, total_citations, h_index, recent_citations, recent_h_index)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+		nameID, lastNameID, positionID, researcher.Photo, bioID,
+		researcher.Profiles.GoogleScholar, researcher.Profiles.ResearchGate,
+		researcher.Profiles.Publons, researcher.Profiles.Orcid, researcher.Profiles.Scopus,
+		researcher.TotalCitations, researcher.HIndex, researcher.RecentCitations, researcher.RecentHIndex,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = r.syncExternalAuthors(tx, int(id), researcher.Name, researcher.LastName); err != nil {
+		return 0, err
+	}
+
+	if err = pgIndex(tx, models.SearchTypeResearcher, int(id)); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *PostgresResearcherRepo) CalculateTotalCitations(researcherID int) (int, error) {
+	var totalCitations int
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(p.citations_count), 0)
+		FROM publications p
+		JOIN publication_authors pa ON p.id = pa.publication_id
+		WHERE pa.researcher_id = $1
+	`, researcherID).Scan(&totalCitations)
+	return totalCitations, err
+}
+
+func (r *PostgresResearcherRepo) GetByID(id int) (*models.ResearcherWithPublicationsCount, error) {
+	researcher := models.ResearcherWithPublicationsCount{}
+	err := scanResearcher(r.db.QueryRow(pgResearcherSelect+" WHERE r.id = $1", id), &researcher.Researcher)
+	if err != nil {
+		return nil, err
+	}
+
+	publications, err := r.GetResearcherPublications(id)
+	if err != nil {
+		return nil, err
+	}
+	if publications != nil {
+		researcher.Publications = publications
+	}
+
+	researcher.PublicationsCount, err = r.GetResearcherPublicationsCount(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &researcher, nil
+}
+
+func (r *PostgresResearcherRepo) GetByIDs(ids []int) ([]models.Researcher, error) {
+	rows, err := r.db.Query(pgResearcherSelect+" WHERE r.id = ANY($1) ORDER BY r.id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	researchers := []models.Researcher{}
+	for rows.Next() {
+		var researcher models.Researcher
+		if err := scanResearcher(rows, &researcher); err != nil {
+			return nil, err
+		}
+		researchers = append(researchers, researcher)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range researchers {
+		researchers[i].Publications, err = r.GetResearcherPublications(researchers[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		researchers[i].TotalCitations, err = r.CalculateTotalCitations(researchers[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return researchers, nil
+}
+
+func (r *PostgresResearcherRepo) GetAll() ([]models.ResearcherWithPublicationsCount, error) {
+	rows, err := r.db.Query(pgResearcherSelect + " ORDER BY r.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	researchers := []models.ResearcherWithPublicationsCount{}
+	for rows.Next() {
+		var researcher models.ResearcherWithPublicationsCount
+		if err := scanResearcher(rows, &researcher.Researcher); err != nil {
+			return nil, err
+		}
+		researchers = append(researchers, researcher)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range researchers {
+		researchers[i].PublicationsCount, err = r.GetResearcherPublicationsCount(researchers[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return researchers, nil
+}
+
+func (r *PostgresResearcherRepo) Update(researcher models.Researcher) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var bioID, positionID, nameID, lastNameID int64
+	err = tx.QueryRow(
+		"SELECT bio_id, position_id, name_id, last_name_id FROM researchers WHERE id = $1",
+		researcher.ID,
+	).Scan(&bioID, &positionID, &nameID, &lastNameID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, bioID, researcher.Bio); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, nameID, researcher.Name); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, lastNameID, researcher.LastName); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, positionID, researcher.Position); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE researchers SET photo = $1, google_scholar = $2, research_gate = $3,
+			publons = $4, orcid = $5, scopus = $6, total_citations = $7, h_index = $8,
+			recent_citations = $9, recent_h_index = $10 WHERE id = $11`,
+		researcher.Photo,
+		researcher.Profiles.GoogleScholar, researcher.Profiles.ResearchGate,
+		researcher.Profiles.Publons, researcher.Profiles.Orcid, researcher.Profiles.Scopus,
+		researcher.TotalCitations, researcher.HIndex, researcher.RecentCitations, researcher.RecentHIndex,
+		researcher.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = pgIndex(tx, models.SearchTypeResearcher, researcher.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresResearcherRepo) Delete(id int) (err error) {
+	researcher, err := r.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = r.movePublicationsToExternalAuthors(tx, id, researcher.Name, researcher.LastName); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE users SET researcher_id = NULL WHERE researcher_id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	var bioID, positionID, nameID, lastNameID int64
+	err = tx.QueryRow(
+		"DELETE FROM researchers WHERE id = $1 RETURNING bio_id, position_id, name_id, last_name_id",
+		id,
+	).Scan(&bioID, &positionID, &nameID, &lastNameID)
+	if err != nil {
+		return err
+	}
+
+	for _, stringID := range []int64{bioID, positionID, nameID, lastNameID} {
+		if err = r.localizedStringRepo.DeleteTx(tx, stringID); err != nil {
+			return err
+		}
+	}
+
+	if err = pgUnindex(tx, models.SearchTypeResearcher, id); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if researcher.Photo != "" {
+		filePath := filepath.Join("../", researcher.Photo)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresResearcherRepo) movePublicationsToExternalAuthors(tx *sql.Tx, researcherID int, name, lastName models.LocalizedString) error {
+	rows, err := tx.Query(
+		"DELETE FROM publication_authors WHERE researcher_id = $1 RETURNING publication_id, author_order, role",
+		researcherID,
+	)
+	if err != nil {
+		return err
+	}
+	slots, err := scanBylineSlots(rows)
+	if err != nil {
+		return err
+	}
+
+	fullName := models.LocalizedString{
+		En: name.En + " " + lastName.En,
+		Ru: name.Ru + " " + lastName.Ru,
+	}
+
+	personID, err := demotedResearcherPerson(tx, r.localizedStringRepo, pgPlaceholder, researcherID, fullName)
+	if err != nil {
+		return err
+	}
+
+	// Each publication gets its own name row, as publication deletion removes
+	// the names of its external authors.
+	for _, slot := range slots {
+		nameID, err := r.localizedStringRepo.CreateTx(tx, fullName)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO publication_external_authors (publication_id, name_id, author_order, role, person_id) VALUES ($1, $2, $3, $4, $5)",
+			slot.publicationID, nameID, slot.order, slot.role, personID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *PostgresResearcherRepo) GetResearcherPublicationsCount(researcherID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM publication_authors WHERE researcher_id = $1",
+		researcherID,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *PostgresResearcherRepo) GetResearcherPublications(researcherID int) ([]models.Publication, error) {
+	rows, err := r.db.Query(`
+		SELECT p.id, ls.en, ls.ru, p.link, p.published_at, p.citations_count,
+			p.type, p.venue, p.venue_id, p.volume, p.issue, p.pages, p.publisher, p.doi, p.abstract
+		FROM publications p
+		JOIN publication_authors pa ON p.id = pa.publication_id
+		JOIN localized_strings ls ON p.title_id = ls.id
+		WHERE pa.researcher_id = $1 AND p.visible
+		ORDER BY p.published_at DESC
+	`, researcherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var publications []models.Publication
+	for rows.Next() {
+		var pub models.Publication
+		dest := []interface{}{&pub.ID, &pub.Title.En, &pub.Title.Ru, &pub.Link, &pub.PublishedAt, &pub.CitationsCount}
+		if err := rows.Scan(append(dest, publicationMetadata(&pub)...)...); err != nil {
+			return nil, err
+		}
+		publications = append(publications, pub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range publications {
+		authors, err := pgPublicationAuthors(r.db, publications[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(authors) > 0 {
+			publications[i].Authors = authors
+		}
+	}
+
+	return publications, nil
+}
+
+func (r *PostgresResearcherRepo) FindByFullName(fullName string) (*models.ResearcherWithPublicationsCount, error) {
+	nameParts := strings.Split(fullName, " ")
+	if len(nameParts) != 2 {
+		return nil, fmt.Errorf("invalid full name format: %s", fullName)
+	}
+
+	firstName, lastName := nameParts[0], nameParts[1]
+
+	var researcherID int
+	err := r.db.QueryRow(`
+		SELECT r.id
+		FROM researchers r
+		JOIN localized_strings fn ON r.name_id = fn.id
+		JOIN localized_strings ln ON r.last_name_id = ln.id
+		WHERE (fn.en = $1 AND ln.en = $2) OR (fn.ru = $1 AND ln.ru = $2)
+		ORDER BY r.id
+		LIMIT 1
+	`, firstName, lastName).Scan(&researcherID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("researcher not found: %s", fullName)
+		}
+		return nil, err
+	}
+
+	return r.GetByID(researcherID)
+}
+
+// syncExternalAuthors promotes the external people a new researcher's name is
+// an alias of to the researcher.
+func (r *PostgresResearcherRepo) syncExternalAuthors(tx *sql.Tx, researcherID int, name, lastName models.LocalizedString) error {
+	return promoteMatchingPeople(tx, r.localizedStringRepo, pgPlaceholder, researcherID, name, lastName)
+}
+
+func (r *PostgresResearcherRepo) FindByLastName(lastName string) ([]models.ResearcherWithPublicationsCount, error) {
+	rows, err := r.db.Query(`
+		SELECT r.id
+		FROM researchers r
+		JOIN localized_strings ln ON r.last_name_id = ln.id
+		WHERE ln.en = $1 OR ln.ru = $1
+		ORDER BY r.id
+	`, lastName)
+	if err != nil {
+		return nil, err
+	}
+
+	var researcherIDs []int
+	for rows.Next() {
+		var researcherID int
+		if err := rows.Scan(&researcherID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		researcherIDs = append(researcherIDs, researcherID)
+	}
+	rows.Close()
+
+	var results []models.ResearcherWithPublicationsCount
+	for _, researcherID := range researcherIDs {
+		researcher, err := r.GetByID(researcherID)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *researcher)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresSearchRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresSearchRepo(db *sql.DB) *PostgresSearchRepo {
+	return &PostgresSearchRepo{db: db}
+}
+
+// pgHeadlineOptions configure ts_headline to mark matches the way SQLite's
+// highlight() does.
+const pgHeadlineOptions = `StartSel="` + highlightStart + `", StopSel="` + highlightEnd + `"`
+
+// Search matches every query term as a prefix using the language-neutral
+// "simple" configuration, so inflected Russian words are still found by their
+// stem. Titles weigh more than bodies.
+func (r *PostgresSearchRepo) Search(query models.SearchQuery) (models.SearchResults, error) {
+	results := emptySearchResults(query.Types)
+	terms := searchTerms(query.Query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = term + ":*"
+	}
+
+	titleColumn, bodyColumn := "title_en", "body_en"
+	if query.Lang == "ru" {
+		titleColumn, bodyColumn = "title_ru", "body_ru"
+	}
+
+	for _, entity := range query.Types {
+		q := fmt.Sprintf(`SELECT entity_id,
+				ts_headline('simple', %[1]s, q, $4 || ', HighlightAll=true'),
+				ts_headline('simple', %[2]s, q, $4 || ', MaxWords=24, MinWords=8'),
+				ts_rank(document, q) AS score
+			FROM search_index, to_tsquery('simple', $1) q
+			WHERE document @@ q AND entity = $2`, titleColumn, bodyColumn)
+		if entity == models.SearchTypePublication {
+			q += " AND entity_id NOT IN (SELECT id FROM publications WHERE NOT visible)"
+		}
+		q += " ORDER BY score DESC, entity_id LIMIT $3"
+
+		rows, err := r.db.Query(q, strings.Join(match, " & "), entity, query.Limit, pgHeadlineOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		hits, err := scanSearchHits(rows)
+		if err != nil {
+			return nil, err
+		}
+		results[entity] = hits
+	}
+	return results, nil
+}
+
+// pgIndex refreshes the search index entry of an entity from its rows. Call it
+// after writing the entity, in the same transaction.
+func pgIndex(db execer, entity string, id int) error {
+	_, err := db.Exec(
+		"INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru) "+
+			fmt.Sprintf(searchDocumentQueries[entity], "$1")+`
+		ON CONFLICT (entity, entity_id) DO UPDATE SET
+			title_en = EXCLUDED.title_en, title_ru = EXCLUDED.title_ru,
+			body_en = EXCLUDED.body_en, body_ru = EXCLUDED.body_ru`,
+		id,
+	)
+	return err
+}
+
+func pgUnindex(db execer, entity string, id int) error {
+	_, err := db.Exec("DELETE FROM search_index WHERE entity = $1 AND entity_id = $2", entity, id)
+	return err
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresTrainingMaterialRepo struct {
+	db                  *sql.DB
+	localizedStringRepo LocalizedStringRepo
+}
+
+func NewPostgresTrainingMaterialRepo(db *sql.DB, lsRepo LocalizedStringRepo) *PostgresTrainingMaterialRepo {
+	return &PostgresTrainingMaterialRepo{db: db, localizedStringRepo: lsRepo}
+}
+
+func (r *PostgresTrainingMaterialRepo) Create(material models.TrainingMaterial) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	titleID, err := r.localizedStringRepo.CreateTx(tx, material.Title)
+	if err != nil {
+		return 0, err
+	}
+
+	descriptionID, err := r.localizedStringRepo.CreateTx(tx, material.Description)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRow(
+		"INSERT INTO training_materials (title_id, description_id, url, image) VALUES ($1, $2, $3, $4) RETURNING id",
+		titleID, descriptionID, material.URL, material.Image,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = pgIndex(tx, models.SearchTypeTraining, int(id)); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+func (r *PostgresTrainingMaterialRepo) GetByID(id int) (*models.TrainingMaterial, error) {
+	var material models.TrainingMaterial
+	var titleID, descriptionID int64
+	err := r.db.QueryRow(
+		"SELECT id, title_id, description_id, url, image FROM training_materials WHERE id = $1",
+		id,
+	).Scan(&material.ID, &titleID, &descriptionID, &material.URL, &material.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadStrings(&material, titleID, descriptionID); err != nil {
+		return nil, err
+	}
+	return &material, nil
+}
+
+func (r *PostgresTrainingMaterialRepo) GetAll() ([]models.TrainingMaterial, error) {
+	rows, err := r.db.Query(
+		"SELECT id, title_id, description_id, url, image FROM training_materials ORDER BY id",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	materials := []models.TrainingMaterial{}
+	for rows.Next() {
+		var material models.TrainingMaterial
+		var titleID, descriptionID int64
+		if err := rows.Scan(&material.ID, &titleID, &descriptionID, &material.URL, &material.Image); err != nil {
+			return nil, err
+		}
+
+		if err := r.loadStrings(&material, titleID, descriptionID); err != nil {
+			return nil, err
+		}
+		materials = append(materials, material)
+	}
+	return materials, rows.Err()
+}
+
+func (r *PostgresTrainingMaterialRepo) Update(material models.TrainingMaterial) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID, descriptionID int64
+	err = tx.QueryRow(
+		"SELECT title_id, description_id FROM training_materials WHERE id = $1",
+		material.ID,
+	).Scan(&titleID, &descriptionID)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, titleID, material.Title); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.UpdateTx(tx, descriptionID, material.Description); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE training_materials SET url = $1, image = $2 WHERE id = $3",
+		material.URL, material.Image, material.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err = pgIndex(tx, models.SearchTypeTraining, material.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresTrainingMaterialRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var titleID, descriptionID int64
+	var image string
+	err = tx.QueryRow(
+		"DELETE FROM training_materials WHERE id = $1 RETURNING title_id, description_id, image",
+		id,
+	).Scan(&titleID, &descriptionID, &image)
+	if err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, titleID); err != nil {
+		return err
+	}
+
+	if err = r.localizedStringRepo.DeleteTx(tx, descriptionID); err != nil {
+		return err
+	}
+
+	if err = pgUnindex(tx, models.SearchTypeTraining, id); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	if image != "" {
+		filePath := filepath.Join("../", image)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PostgresTrainingMaterialRepo) loadStrings(material *models.TrainingMaterial, titleID, descriptionID int64) error {
+	title, err := r.localizedStringRepo.Get(titleID)
+	if err != nil {
+		return err
+	}
+	material.Title = *title
+
+	description, err := r.localizedStringRepo.Get(descriptionID)
+	if err != nil {
+		return err
+	}
+	material.Description = *description
+	return nil
+}
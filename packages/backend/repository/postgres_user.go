@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresUserRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepo(db *sql.DB) *PostgresUserRepo {
+	return &PostgresUserRepo{db: db}
+}
+
+func (r *PostgresUserRepo) Create(user models.User) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO users (username, password_hash, role, researcher_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		user.Username, user.PasswordHash, user.Role, user.ResearcherID,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *PostgresUserRepo) GetByID(id int) (*models.User, error) {
+	return r.get("id = $1", id)
+}
+
+func (r *PostgresUserRepo) GetByUsername(username string) (*models.User, error) {
+	return r.get("username = $1", username)
+}
+
+func (r *PostgresUserRepo) get(where string, arg interface{}) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(
+		"SELECT id, username, password_hash, role, researcher_id, created_at FROM users WHERE "+where,
+		arg,
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.ResearcherID, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepo) GetAll() ([]models.User, error) {
+	rows, err := r.db.Query("SELECT id, username, password_hash, role, researcher_id, created_at FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.ResearcherID, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *PostgresUserRepo) Update(user models.User) error {
+	res, err := r.db.Exec(
+		"UPDATE users SET username = $1, role = $2, researcher_id = $3 WHERE id = $4",
+		user.Username, user.Role, user.ResearcherID, user.ID,
+	)
+	return userAffected(res, err)
+}
+
+func (r *PostgresUserRepo) UpdatePassword(id int, passwordHash string) error {
+	res, err := r.db.Exec("UPDATE users SET password_hash = $1 WHERE id = $2", passwordHash, id)
+	return userAffected(res, err)
+}
+
+// Delete removes the user; sessions and API tokens go with it through
+// ON DELETE CASCADE.
+func (r *PostgresUserRepo) Delete(id int) error {
+	res, err := r.db.Exec("DELETE FROM users WHERE id = $1", id)
+	return userAffected(res, err)
+}
+
+func (r *PostgresUserRepo) Count() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+func userAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type PostgresVenueRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresVenueRepo(db *sql.DB) *PostgresVenueRepo {
+	return &PostgresVenueRepo{db: db}
+}
+
+func (r *PostgresVenueRepo) Create(venue models.Venue) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRow(
+		`INSERT INTO venues (name, issn, eissn, publisher, scopus, wos, rsci)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		venue.Name, venue.ISSN, venue.EISSN, venue.Publisher, venue.Scopus, venue.WoS, venue.RSCI,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = r.insertDetails(tx, int(id), venue); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *PostgresVenueRepo) GetByID(id int) (*models.Venue, error) {
+	venue := models.NewVenue()
+	err := scanVenue(r.db.QueryRow("SELECT "+venueColumns+" FROM venues WHERE id = $1", id), &venue)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("venue not found")
+		}
+		return nil, err
+	}
+
+	list := []models.Venue{venue}
+	if err := loadVenueDetails(r.db, list, "WHERE venue_id = $1", id); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func (r *PostgresVenueRepo) GetAll() ([]models.Venue, error) {
+	return getAllVenues(r.db)
+}
+
+func (r *PostgresVenueRepo) Update(venue models.Venue) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	res, err := tx.Exec(
+		`UPDATE venues SET name = $1, issn = $2, eissn = $3, publisher = $4, scopus = $5, wos = $6, rsci = $7
+		WHERE id = $8`,
+		venue.Name, venue.ISSN, venue.EISSN, venue.Publisher, venue.Scopus, venue.WoS, venue.RSCI, venue.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("venue not found")
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM venue_aliases WHERE venue_id = $1", venue.ID); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM venue_quartiles WHERE venue_id = $1", venue.ID); err != nil {
+		return err
+	}
+	if err = r.insertDetails(tx, venue.ID, venue); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes a venue and unlinks its publications, which keep their
+// venue names.
+func (r *PostgresVenueRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, query := range []string{
+		"UPDATE publications SET venue_id = NULL WHERE venue_id = $1",
+		"DELETE FROM venue_aliases WHERE venue_id = $1",
+		"DELETE FROM venue_quartiles WHERE venue_id = $1",
+	} {
+		if _, err = tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec("DELETE FROM venues WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("venue not found")
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresVenueRepo) SetQuartiles(venueID int, quartiles []models.VenueQuartile) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, q := range quartiles {
+		_, err = tx.Exec(
+			`INSERT INTO venue_quartiles (venue_id, year, quartile) VALUES ($1, $2, $3)
+			ON CONFLICT (venue_id, year) DO UPDATE SET quartile = excluded.quartile`,
+			venueID, q.Year, q.Quartile,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresVenueRepo) Match(name string) (*int, error) {
+	return matchVenue(r.db, name)
+}
+
+func (r *PostgresVenueRepo) LinkPublications() (int, error) {
+	return linkVenuePublications(r.db, "UPDATE publications SET venue_id = $1 WHERE venue_id IS NULL AND venue = $2")
+}
+
+func (r *PostgresVenueRepo) YearStats(filter models.VenueReportFilter) ([]models.VenueYearStats, error) {
+	args := []interface{}{}
+	query := venueYearStatsQuery(filter, func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	})
+	return scanVenueYearStats(r.db.Query(query, args...))
+}
+
+func (r *PostgresVenueRepo) ResearcherStats(filter models.VenueReportFilter) ([]models.VenueResearcherStats, error) {
+	args := []interface{}{}
+	query := venueResearcherStatsQuery(filter, func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	})
+	return scanVenueResearcherStats(r.db.Query(query, args...))
+}
+
+func (r *PostgresVenueRepo) insertDetails(tx *sql.Tx, venueID int, venue models.Venue) error {
+	for _, alias := range venue.Aliases {
+		_, err := tx.Exec(
+			"INSERT INTO venue_aliases (venue_id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			venueID, alias,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	for _, q := range venue.Quartiles {
+		_, err := tx.Exec(
+			`INSERT INTO venue_quartiles (venue_id, year, quartile) VALUES ($1, $2, $3)
+			ON CONFLICT (venue_id, year) DO UPDATE SET quartile = excluded.quartile`,
+			venueID, q.Year, q.Quartile,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -47,6 +47,10 @@ func (r *SQLiteProjectRepo) Create(project models.Project) (int64, error) {
 		}
 	}
 
+	if err := sqliteIndex(r.db, models.SearchTypeProject, int(id)); err != nil {
+		return 0, err
+	}
+
 	return id, nil
 }
 
@@ -215,7 +219,7 @@ func (r *SQLiteProjectRepo) Update(project models.Project) error {
 		}
 	}
 
-	return nil
+	return sqliteIndex(r.db, models.SearchTypeProject, project.ID)
 }
 
 func (r *SQLiteProjectRepo) Delete(id int) error {
@@ -261,7 +265,11 @@ func (r *SQLiteProjectRepo) Delete(id int) error {
 	}
 
 	_, err = r.db.Exec("DELETE FROM projects WHERE id = ?", id)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return sqliteUnindex(r.db, models.SearchTypeProject, id)
 }
 
 func (r *SQLiteProjectRepo) AddPublication(projectID int, pub models.ProjectPublication) error {
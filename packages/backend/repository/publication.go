@@ -3,7 +3,10 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/damirahm/diplom/backend/duplicates"
 	"github.com/damirahm/diplom/backend/models"
 )
 
@@ -11,10 +14,11 @@ type SQLitePublicationRepo struct {
 	db                  *sql.DB
 	localizedStringRepo LocalizedStringRepo
 	researcherRepo      ResearcherRepo
+	venueRepo           VenueRepo
 }
 
-func NewSQLitePublicationRepo(db *sql.DB, lsRepo LocalizedStringRepo, researcherRepo ResearcherRepo) *SQLitePublicationRepo {
-	return &SQLitePublicationRepo{db: db, localizedStringRepo: lsRepo, researcherRepo: researcherRepo}
+func NewSQLitePublicationRepo(db *sql.DB, lsRepo LocalizedStringRepo, researcherRepo ResearcherRepo, venueRepo VenueRepo) *SQLitePublicationRepo {
+	return &SQLitePublicationRepo{db: db, localizedStringRepo: lsRepo, researcherRepo: researcherRepo, venueRepo: venueRepo}
 }
 
 func (r *SQLitePublicationRepo) Create(pub models.Publication) (int64, error) {
@@ -36,6 +40,12 @@ func (r *SQLitePublicationRepo) Create(pub models.Publication) (int64, error) {
 		return 0, fmt.Errorf("publication with title '%s' or '%s' already exists", pub.Title.En, pub.Title.Ru)
 	}
 
+	if pub.VenueID == nil {
+		if pub.VenueID, err = r.venueRepo.Match(pub.Venue); err != nil {
+			return 0, err
+		}
+	}
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return 0, err
@@ -60,8 +70,9 @@ func (r *SQLitePublicationRepo) Create(pub models.Publication) (int64, error) {
 	}
 
 	res, err := tx.Exec(
-		"INSERT INTO publications (title_id, link, journal, published_at, citations_count) VALUES (?, ?, ?, ?, ?)",
-		titleID, pub.Link, pub.Journal, pub.PublishedAt, pub.CitationsCount,
+		"INSERT INTO publications (title_id, link, published_at, citations_count, "+publicationMetadataColumns+
+			") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		append([]interface{}{titleID, pub.Link, pub.PublishedAt, pub.CitationsCount}, publicationMetadataValues(pub)...)...,
 	)
 	if err != nil {
 		return 0, err
@@ -72,37 +83,17 @@ func (r *SQLitePublicationRepo) Create(pub models.Publication) (int64, error) {
 		return 0, err
 	}
 
-	for _, author := range pub.Authors {
-		if author.ID != nil {
-			_, err = tx.Exec(
-				"INSERT INTO publication_authors (publication_id, researcher_id) VALUES (?, ?)",
-				id, *author.ID,
-			)
-			if err != nil {
-				return 0, err
-			}
-		} else {
-			result, err := tx.Exec(
-				"INSERT INTO localized_strings (en, ru) VALUES (?, ?)",
-				author.Name.En, author.Name.Ru,
-			)
-			if err != nil {
-				return 0, err
-			}
+	if err = r.insertAuthors(tx, int(id), pub.Authors); err != nil {
+		return 0, err
+	}
 
-			nameID, err := result.LastInsertId()
-			if err != nil {
-				return 0, err
-			}
+	if err = indexTitleWords(tx, func(int) string { return "?" }, int(id), pub.Title); err != nil {
+		return 0, err
+	}
 
-			_, err = tx.Exec(
-				"INSERT INTO publication_external_authors (publication_id, name_id) VALUES (?, ?)",
-				id, nameID,
-			)
-			if err != nil {
-				return 0, err
-			}
-		}
+	err = sqliteIndex(tx, models.SearchTypePublication, int(id))
+	if err != nil {
+		return 0, err
 	}
 
 	err = tx.Commit()
@@ -118,9 +109,10 @@ func (r *SQLitePublicationRepo) GetByID(id int) (*models.Publication, error) {
 	var titleID int64
 
 	err := r.db.QueryRow(
-		"SELECT id, title_id, link, journal, published_at, citations_count, visible FROM publications WHERE id = ?",
+		"SELECT id, title_id, link, published_at, citations_count, visible, "+publicationMetadataColumns+" FROM publications WHERE id = ?",
 		id,
-	).Scan(&pub.ID, &titleID, &pub.Link, &pub.Journal, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible)
+	).Scan(append([]interface{}{&pub.ID, &titleID, &pub.Link, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible},
+		publicationMetadata(&pub)...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -131,75 +123,21 @@ func (r *SQLitePublicationRepo) GetByID(id int) (*models.Publication, error) {
 	}
 	pub.Title = *title
 
-	rows, err := r.db.Query(
-		`SELECT r.id, r.name_id, r.last_name_id FROM publication_authors as pa 
-		JOIN researchers as r ON pa.researcher_id = r.id
-		WHERE pa.publication_id = ?`,
-		id,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	authors := []models.Author{}
-
-	for rows.Next() {
-		var authorID int
-		var nameID int64
-		var lastNameID int64
-		if err := rows.Scan(&authorID, &nameID, &lastNameID); err != nil {
-			return nil, err
-		}
-		name, err := r.localizedStringRepo.Get(nameID)
-		if err != nil {
-			return nil, err
-		}
-		lastName, err := r.localizedStringRepo.Get(lastNameID)
-		if err != nil {
-			return nil, err
-		}
-		authors = append(authors, models.Author{
-			Name: models.LocalizedString{
-				En: name.En + " " + lastName.En,
-				Ru: name.Ru + " " + lastName.Ru,
-			},
-			ID: &authorID,
-		})
-	}
-
-	externalRows, err := r.db.Query(
-		`SELECT ls.en, ls.ru 
-		FROM publication_external_authors pea
-		JOIN localized_strings ls ON pea.name_id = ls.id
-		WHERE pea.publication_id = ?`,
-		pub.ID,
-	)
+	pub.Authors, err = r.authors(id)
 	if err != nil {
 		return nil, err
 	}
-	defer externalRows.Close()
-
-	for externalRows.Next() {
-		var nameEn, nameRu string
-		if err := externalRows.Scan(&nameEn, &nameRu); err != nil {
-			return nil, err
-		}
-		authors = append(authors, models.Author{
-			Name: models.LocalizedString{
-				En: nameEn,
-				Ru: nameRu,
-			},
-		})
-	}
-
-	pub.Authors = authors
 
 	return &pub, nil
 }
 
+// authors returns the researchers of a publication followed by its external authors.
+func (r *SQLitePublicationRepo) authors(id int) ([]models.Author, error) {
+	return publicationAuthors(r.db, func(int) string { return "?" }, id)
+}
+
 func (r *SQLitePublicationRepo) GetAll() ([]models.Publication, error) {
-	rows, err := r.db.Query("SELECT id, title_id, link, journal, published_at, citations_count, visible FROM publications")
+	rows, err := r.db.Query("SELECT id, title_id, link, published_at, citations_count, visible, " + publicationMetadataColumns + " FROM publications")
 	if err != nil {
 		return nil, err
 	}
@@ -209,7 +147,8 @@ func (r *SQLitePublicationRepo) GetAll() ([]models.Publication, error) {
 	for rows.Next() {
 		var pub models.Publication
 		var titleID int64
-		if err := rows.Scan(&pub.ID, &titleID, &pub.Link, &pub.Journal, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible); err != nil {
+		dest := []interface{}{&pub.ID, &titleID, &pub.Link, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible}
+		if err := rows.Scan(append(dest, publicationMetadata(&pub)...)...); err != nil {
 			return nil, err
 		}
 
@@ -219,96 +158,42 @@ func (r *SQLitePublicationRepo) GetAll() ([]models.Publication, error) {
 		}
 		pub.Title = *title
 
-		authorRows, err := r.db.Query(
-			"SELECT researcher_id FROM publication_authors WHERE publication_id = ?",
-			pub.ID,
-		)
+		pub.Authors, err = r.authors(pub.ID)
 		if err != nil {
 			return nil, err
 		}
 
-		var authorIDs []int
-		for authorRows.Next() {
-			var authorID int
-			if err := authorRows.Scan(&authorID); err != nil {
-				authorRows.Close()
-				return nil, err
-			}
-			authorIDs = append(authorIDs, authorID)
-		}
-		authorRows.Close()
-
-		authors := []models.Author{}
-		if len(authorIDs) > 0 {
-			researcherQuery := `
-				SELECT r.id, fn.en, ln.en, fn.ru, ln.ru
-				FROM researchers r
-				JOIN localized_strings fn ON r.name_id = fn.id
-				JOIN localized_strings ln ON r.last_name_id = ln.id
-				WHERE r.id IN (`
-
-			placeholders := make([]string, len(authorIDs))
-			args := make([]interface{}, len(authorIDs))
-			for i, id := range authorIDs {
-				placeholders[i] = "?"
-				args[i] = id
-			}
-			researcherQuery += Join(placeholders, ",") + ")"
+		publications = append(publications, pub)
+	}
 
-			researcherRows, err := r.db.Query(researcherQuery, args...)
-			if err != nil {
-				return nil, err
-			}
+	return publications, nil
+}
 
-			for researcherRows.Next() {
-				var id int
-				var nameEn, lastNameEn, nameRu, lastNameRu string
-				if err := researcherRows.Scan(&id, &nameEn, &lastNameEn, &nameRu, &lastNameRu); err != nil {
-					researcherRows.Close()
-					return nil, err
-				}
-				authors = append(authors, models.Author{
-					Name: models.LocalizedString{
-						En: nameEn + " " + lastNameEn,
-						Ru: nameRu + " " + lastNameRu,
-					},
-					ID: &id,
-				})
-			}
-			researcherRows.Close()
-		}
+func (r *SQLitePublicationRepo) GetPossibleDuplicates() ([]models.Publication, error) {
+	rows, err := r.db.Query(publicationListColumns + publicationListFrom + possibleDuplicatesWhere + " ORDER BY p.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		externalRows, err := r.db.Query(
-			`SELECT ls.en, ls.ru 
-			FROM publication_external_authors pea
-			JOIN localized_strings ls ON pea.name_id = ls.id
-			WHERE pea.publication_id = ?`,
-			pub.ID,
-		)
-		if err != nil {
+	publications := []models.Publication{}
+	for rows.Next() {
+		var pub models.Publication
+		if err := scanPublication(rows, &pub); err != nil {
 			return nil, err
 		}
-
-		for externalRows.Next() {
-			var nameEn, nameRu string
-			if err := externalRows.Scan(&nameEn, &nameRu); err != nil {
-				externalRows.Close()
-				return nil, err
-			}
-			authors = append(authors, models.Author{
-				Name: models.LocalizedString{
-					En: nameEn,
-					Ru: nameRu,
-				},
-			})
-		}
-		externalRows.Close()
-
-		pub.Authors = authors
-
 		publications = append(publications, pub)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	for i := range publications {
+		publications[i].Authors, err = r.authors(publications[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return publications, nil
 }
 
@@ -332,6 +217,12 @@ func (r *SQLitePublicationRepo) Update(pub models.Publication) error {
 		return fmt.Errorf("another publication with title '%s' or '%s' already exists", pub.Title.En, pub.Title.Ru)
 	}
 
+	if pub.VenueID == nil {
+		if pub.VenueID, err = r.venueRepo.Match(pub.Venue); err != nil {
+			return err
+		}
+	}
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -342,8 +233,15 @@ func (r *SQLitePublicationRepo) Update(pub models.Publication) error {
 		}
 	}()
 
+	if err = r.update(tx, pub); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLitePublicationRepo) update(tx *sql.Tx, pub models.Publication) error {
 	var titleID int64
-	err = tx.QueryRow(
+	err := tx.QueryRow(
 		"SELECT title_id FROM publications WHERE id = ?",
 		pub.ID,
 	).Scan(&titleID)
@@ -359,9 +257,11 @@ func (r *SQLitePublicationRepo) Update(pub models.Publication) error {
 		return err
 	}
 
+	args := append([]interface{}{titleID, pub.Link, pub.PublishedAt, pub.CitationsCount, pub.Visible}, publicationMetadataValues(pub)...)
 	_, err = tx.Exec(
-		"UPDATE publications SET title_id = ?, link = ?, journal = ?, published_at = ?, citations_count = ?, visible = ? WHERE id = ?",
-		titleID, pub.Link, pub.Journal, pub.PublishedAt, pub.CitationsCount, pub.Visible, pub.ID,
+		"UPDATE publications SET title_id = ?, link = ?, published_at = ?, citations_count = ?, visible = ?, "+
+			publicationMetadataSet(func(int) string { return "?" })+" WHERE id = ?",
+		append(args, pub.ID)...,
 	)
 	if err != nil {
 		return err
@@ -409,41 +309,79 @@ func (r *SQLitePublicationRepo) Update(pub models.Publication) error {
 			}
 		}
 
-		for _, author := range pub.Authors {
-			if author.ID != nil {
-				_, err = tx.Exec(
-					"INSERT INTO publication_authors (publication_id, researcher_id) VALUES (?, ?)",
-					pub.ID, *author.ID,
-				)
-				if err != nil {
-					return err
-				}
-			} else {
-				result, err := tx.Exec(
-					"INSERT INTO localized_strings (en, ru) VALUES (?, ?)",
-					author.Name.En, author.Name.Ru,
-				)
-				if err != nil {
-					return err
-				}
-
-				nameID, err := result.LastInsertId()
-				if err != nil {
-					return err
-				}
-
-				_, err = tx.Exec(
-					"INSERT INTO publication_external_authors (publication_id, name_id) VALUES (?, ?)",
-					pub.ID, nameID,
-				)
-				if err != nil {
-					return err
-				}
-			}
+		if err = r.insertAuthors(tx, pub.ID, pub.Authors); err != nil {
+			return err
 		}
 	}
 
-	return tx.Commit()
+	if err = indexTitleWords(tx, func(int) string { return "?" }, pub.ID, pub.Title); err != nil {
+		return err
+	}
+	return sqliteIndex(tx, models.SearchTypePublication, pub.ID)
+}
+
+// bylineSlot is the place of an author in the byline of a publication, kept
+// when a researcher turns into an external author or back.
+type bylineSlot struct {
+	publicationID int
+	order         int
+	role          string
+}
+
+// scanBylineSlots reads rows of publication_id, author_order and role.
+func scanBylineSlots(rows *sql.Rows) ([]bylineSlot, error) {
+	defer rows.Close()
+	var slots []bylineSlot
+	for rows.Next() {
+		var slot bylineSlot
+		if err := rows.Scan(&slot.publicationID, &slot.order, &slot.role); err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return slots, rows.Err()
+}
+
+// insertAuthors adds the authors of a publication, numbering them in the
+// order given. External authors are linked to the external people registry,
+// and those promoted to a researcher are added as the researcher.
+func (r *SQLitePublicationRepo) insertAuthors(tx *sql.Tx, publicationID int, authors []models.Author) error {
+	for i, author := range authors {
+		researcherID := author.ID
+		var personID *int
+		if researcherID == nil {
+			var err error
+			personID, researcherID, err = resolveExternalPerson(tx, r.localizedStringRepo, func(int) string { return "?" }, author)
+			if err != nil {
+				return err
+			}
+		}
+
+		if researcherID != nil {
+			_, err := tx.Exec(
+				"INSERT OR IGNORE INTO publication_authors (publication_id, researcher_id, author_order, role) VALUES (?, ?, ?, ?)",
+				publicationID, *researcherID, i, author.Role,
+			)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		nameID, err := r.localizedStringRepo.CreateTx(tx, author.Name)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO publication_external_authors (publication_id, name_id, author_order, role, person_id) VALUES (?, ?, ?, ?, ?)",
+			publicationID, nameID, i, author.Role, personID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *SQLitePublicationRepo) Delete(id int) error {
@@ -457,8 +395,24 @@ func (r *SQLitePublicationRepo) Delete(id int) error {
 		}
 	}()
 
+	files, err := deletePublicationAttachments(tx, id, func(int) string { return "?" })
+	if err != nil {
+		return err
+	}
+	if err = r.delete(tx, id); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	removeAttachmentFiles(files)
+	return nil
+}
+
+func (r *SQLitePublicationRepo) delete(tx *sql.Tx, id int) error {
 	var titleID int64
-	err = tx.QueryRow(
+	err := tx.QueryRow(
 		"SELECT title_id FROM publications WHERE id = ?",
 		id,
 	).Scan(&titleID)
@@ -512,11 +466,71 @@ func (r *SQLitePublicationRepo) Delete(id int) error {
 		return err
 	}
 
+	_, err = tx.Exec("DELETE FROM publication_citation_snapshots WHERE publication_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if err = unindexTitleWords(tx, func(int) string { return "?" }, id); err != nil {
+		return err
+	}
+
 	_, err = tx.Exec("DELETE FROM publications WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
 
+	return sqliteUnindex(tx, models.SearchTypePublication, id)
+}
+
+// Merge saves pub, the merged record of two publications, deletes the other
+// one and points the project publications, attachments and citation history
+// of the other one at pub, all in one transaction.
+func (r *SQLitePublicationRepo) Merge(pub models.Publication, duplicateID int) (err error) {
+	if pub.VenueID == nil {
+		if pub.VenueID, err = r.venueRepo.Match(pub.Venue); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var duplicate models.Publication
+	err = tx.QueryRow(
+		`SELECT p.link, ls.en, ls.ru FROM publications p
+		JOIN localized_strings ls ON p.title_id = ls.id
+		WHERE p.id = ?`,
+		duplicateID,
+	).Scan(&duplicate.Link, &duplicate.Title.En, &duplicate.Title.Ru)
+	if err != nil {
+		return err
+	}
+
+	if err = relinkProjectPublications(tx, duplicate, pub, func(int) string { return "?" }); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE publication_attachments SET publication_id = ? WHERE publication_id = ?", pub.ID, duplicateID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE publication_citation_snapshots SET publication_id = ? WHERE publication_id = ?", pub.ID, duplicateID)
+	if err != nil {
+		return err
+	}
+	if err = r.delete(tx, duplicateID); err != nil {
+		return err
+	}
+	if err = r.update(tx, pub); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
@@ -557,7 +571,7 @@ func (r *SQLitePublicationRepo) GetByIDs(ids []int) ([]models.Publication, error
 		return []models.Publication{}, nil
 	}
 
-	query := `SELECT id, title_id, link, journal, published_at, citations_count, visible 
+	query := `SELECT id, title_id, link, published_at, citations_count, visible, ` + publicationMetadataColumns + `
 	          FROM publications WHERE id IN (`
 
 	placeholders := make([]string, len(ids))
@@ -575,12 +589,12 @@ func (r *SQLitePublicationRepo) GetByIDs(ids []int) ([]models.Publication, error
 	defer rows.Close()
 
 	publicationsMap := make(map[int]models.Publication)
-	var publicationIDs []int
 
 	for rows.Next() {
 		var pub models.Publication
 		var titleID int64
-		if err := rows.Scan(&pub.ID, &titleID, &pub.Link, &pub.Journal, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible); err != nil {
+		dest := []interface{}{&pub.ID, &titleID, &pub.Link, &pub.PublishedAt, &pub.CitationsCount, &pub.Visible}
+		if err := rows.Scan(append(dest, publicationMetadata(&pub)...)...); err != nil {
 			return nil, err
 		}
 
@@ -591,157 +605,365 @@ func (r *SQLitePublicationRepo) GetByIDs(ids []int) ([]models.Publication, error
 		pub.Title = *title
 
 		publicationsMap[pub.ID] = pub
-		publicationIDs = append(publicationIDs, pub.ID)
 	}
 
-	authorQuery := `SELECT publication_id, researcher_id 
-	                FROM publication_authors 
-	                WHERE publication_id IN (`
+	for id, pub := range publicationsMap {
+		pub.Authors, err = r.authors(id)
+		if err != nil {
+			return nil, err
+		}
+		publicationsMap[id] = pub
+	}
 
-	authorPlaceholders := make([]string, len(publicationIDs))
-	authorArgs := make([]interface{}, len(publicationIDs))
-	for i, id := range publicationIDs {
-		authorPlaceholders[i] = "?"
-		authorArgs[i] = id
+	publications := make([]models.Publication, 0, len(ids))
+	for _, id := range ids {
+		if pub, ok := publicationsMap[id]; ok {
+			publications = append(publications, pub)
+		}
 	}
-	authorQuery += Join(authorPlaceholders, ",") + ")"
 
-	authorRows, err := r.db.Query(authorQuery, authorArgs...)
+	return publications, nil
+}
+
+func (r *SQLitePublicationRepo) GetByTitle(title string) (*models.Publication, error) {
+	query := `
+		` + publicationListColumns + publicationListFrom + `
+		WHERE LOWER(ls.en) = LOWER(?) OR LOWER(ls.ru) = LOWER(?)
+	`
+
+	var pub models.Publication
+	err := scanPublication(r.db.QueryRow(query, title, title), &pub)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
 		return nil, err
 	}
-	defer authorRows.Close()
 
-	pubToAuthors := make(map[int][]int)
-	for authorRows.Next() {
-		var pubID, authorID int
-		if err := authorRows.Scan(&pubID, &authorID); err != nil {
-			return nil, err
-		}
+	return &pub, nil
+}
 
-		pubToAuthors[pubID] = append(pubToAuthors[pubID], authorID)
+func (r *SQLitePublicationRepo) SetVisibility(id int, visible bool) error {
+	res, err := r.db.Exec("UPDATE publications SET visible = ? WHERE id = ?", visible, id)
+	if err != nil {
+		return err
 	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
 
-	allAuthorIDs := []int{}
-	for _, authorIDs := range pubToAuthors {
-		allAuthorIDs = append(allAuthorIDs, authorIDs...)
+func (r *SQLitePublicationRepo) GetTotalCount() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM publications").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// List returns one page of the publications matching filter and the number of
+// all matching publications.
+func (r *SQLitePublicationRepo) List(filter models.PublicationFilter) ([]models.Publication, int, error) {
+	args := []interface{}{}
+	from := publicationListFrom + publicationWhere(filter, "LIKE", func(v interface{}) string {
+		args = append(args, v)
+		return "?"
+	})
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*)"+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
 	}
 
-	researcherMap := make(map[int]*models.Researcher)
-	if len(allAuthorIDs) > 0 {
-		researchers, err := r.researcherRepo.GetByIDs(allAuthorIDs)
-		if err != nil {
-			return nil, err
+	query := publicationListColumns + from + " ORDER BY " + publicationOrderBy(filter.Sort)
+	if filter.PageSize > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.PageSize, publicationOffset(filter))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	publications := []models.Publication{}
+	for rows.Next() {
+		var pub models.Publication
+		if err := scanPublication(rows, &pub); err != nil {
+			return nil, 0, err
 		}
+		publications = append(publications, pub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
 
-		for i, researcher := range researchers {
-			researcherMap[researcher.ID] = &researchers[i]
+	for i := range publications {
+		publications[i].Authors, err = r.authors(publications[i].ID)
+		if err != nil {
+			return nil, 0, err
 		}
 	}
+	return publications, total, nil
+}
 
-	for pubID, authorIDs := range pubToAuthors {
-		pub := publicationsMap[pubID]
-		authors := []models.Author{}
+// Shared parts of the List queries: the columns read by scanPublication and the
+// publications p joined with their titles ls.
+const (
+	publicationListColumns = "SELECT p.id, ls.en, ls.ru, p.link, p.published_at, p.citations_count, p.visible, " +
+		"p.type, p.venue, p.venue_id, p.volume, p.issue, p.pages, p.publisher, p.doi, p.abstract"
+	publicationListFrom = " FROM publications p JOIN localized_strings ls ON p.title_id = ls.id"
+)
 
-		for _, authorID := range authorIDs {
-			if researcher, ok := researcherMap[authorID]; ok {
-				id := researcher.ID
-				authors = append(authors, models.Author{
-					Name: models.LocalizedString{
-						En: researcher.Name.En + " " + researcher.LastName.En,
-						Ru: researcher.Name.Ru + " " + researcher.LastName.Ru,
-					},
-					ID: &id,
-				})
-			}
+// possibleDuplicatesWhere keeps the publications of a publicationListFrom
+// query that duplicates.Find could pair with another: those sharing a DOI, or
+// sharing at least half of the title words of the one with fewer words in
+// publication_title_words.
+const possibleDuplicatesWhere = ` WHERE (p.doi <> '' AND LOWER(p.doi) IN (
+		SELECT LOWER(doi) FROM publications WHERE doi <> '' GROUP BY LOWER(doi) HAVING COUNT(*) > 1
+	)) OR p.id IN (
+		SELECT a.publication_id FROM publication_title_words a
+		JOIN publication_title_words b ON b.word = a.word AND b.publication_id <> a.publication_id
+		GROUP BY a.publication_id, b.publication_id
+		HAVING 2 * COUNT(*) >= (SELECT COUNT(*) FROM publication_title_words WHERE publication_id = a.publication_id)
+			OR 2 * COUNT(*) >= (SELECT COUNT(*) FROM publication_title_words WHERE publication_id = b.publication_id)
+	)`
+
+// indexTitleWords replaces the words of a publication's title in
+// publication_title_words with its duplicates.TitleWords. Call it in the
+// transaction that writes the title.
+func indexTitleWords(db execer, placeholder func(int) string, id int, title models.LocalizedString) error {
+	if err := unindexTitleWords(db, placeholder, id); err != nil {
+		return err
+	}
+	for _, word := range duplicates.TitleWords(title) {
+		_, err := db.Exec(
+			"INSERT INTO publication_title_words (publication_id, word) VALUES ("+placeholder(0)+", "+placeholder(1)+")",
+			id, word,
+		)
+		if err != nil {
+			return err
 		}
-
-		pub.Authors = authors
-		publicationsMap[pubID] = pub
 	}
+	return nil
+}
 
-	externalAuthorQuery := `SELECT publication_id, name_id 
-	                       FROM publication_external_authors 
-	                       WHERE publication_id IN (`
+func unindexTitleWords(db execer, placeholder func(int) string, id int) error {
+	_, err := db.Exec("DELETE FROM publication_title_words WHERE publication_id = "+placeholder(0), id)
+	return err
+}
 
-	externalAuthorQuery += Join(authorPlaceholders, ",") + ")"
+// publicationMetadataColumns are the bibliographic fields of a publication, in
+// the order of publicationMetadata and publicationMetadataValues.
+const publicationMetadataColumns = "type, venue, venue_id, volume, issue, pages, publisher, doi, abstract"
 
-	externalAuthorRows, err := r.db.Query(externalAuthorQuery, authorArgs...)
-	if err != nil {
-		return nil, err
+// publicationMetadata returns the fields stored in publicationMetadataColumns
+// for scanning.
+func publicationMetadata(pub *models.Publication) []interface{} {
+	return []interface{}{&pub.Type, &pub.Venue, &pub.VenueID, &pub.Volume, &pub.Issue, &pub.Pages, &pub.Publisher, &pub.DOI, &pub.Abstract}
+}
+
+// publicationMetadataValues returns the values stored in
+// publicationMetadataColumns; a publication without a type is an article.
+func publicationMetadataValues(pub models.Publication) []interface{} {
+	if pub.Type == "" {
+		pub.Type = models.PublicationTypeArticle
 	}
-	defer externalAuthorRows.Close()
+	return []interface{}{pub.Type, pub.Venue, pub.VenueID, pub.Volume, pub.Issue, pub.Pages, pub.Publisher, pub.DOI, pub.Abstract}
+}
 
-	for externalAuthorRows.Next() {
-		var pubID int
-		var nameID int64
-		if err := externalAuthorRows.Scan(&pubID, &nameID); err != nil {
-			return nil, err
-		}
+// publicationMetadataSet returns the SET assignments of
+// publicationMetadataColumns, taking each placeholder from placeholder by the
+// column's index.
+func publicationMetadataSet(placeholder func(i int) string) string {
+	columns := strings.Split(publicationMetadataColumns, ", ")
+	for i, column := range columns {
+		columns[i] = column + " = " + placeholder(i)
+	}
+	return Join(columns, ", ")
+}
 
-		if pub, ok := publicationsMap[pubID]; ok {
-			pub.Authors = append(pub.Authors, models.Author{
-				Name: models.LocalizedString{
-					En: "",
-					Ru: "",
-				},
-			})
-			publicationsMap[pubID] = pub
+// relinkProjectPublications points the project publications that refer to
+// duplicate, by title or link, at pub. placeholder returns the placeholder of
+// the i-th parameter, counting from 0.
+func relinkProjectPublications(tx *sql.Tx, duplicate, pub models.Publication, placeholder func(i int) string) error {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, title := range []string{duplicate.Title.En, duplicate.Title.Ru} {
+		if title != "" {
+			conditions = append(conditions, "LOWER(ls.en) = LOWER("+placeholder(len(args))+")")
+			args = append(args, title)
 		}
 	}
+	if duplicate.Link != "" {
+		conditions = append(conditions, "pp.link = "+placeholder(len(args)))
+		args = append(args, duplicate.Link)
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
 
-	publications := make([]models.Publication, 0, len(ids))
-	for _, id := range ids {
-		if pub, ok := publicationsMap[id]; ok {
-			publications = append(publications, pub)
+	rows, err := tx.Query(
+		`SELECT pp.id, pp.title_id FROM project_publications pp
+		JOIN localized_strings ls ON pp.title_id = ls.id
+		WHERE `+Join(conditions, " OR "),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	var ids, titleIDs []int64
+	for rows.Next() {
+		var id, titleID int64
+		if err := rows.Scan(&id, &titleID); err != nil {
+			rows.Close()
+			return err
 		}
+		ids = append(ids, id)
+		titleIDs = append(titleIDs, titleID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	return publications, nil
+	for i, id := range ids {
+		_, err := tx.Exec(
+			"UPDATE localized_strings SET en = "+placeholder(0)+", ru = "+placeholder(1)+" WHERE id = "+placeholder(2),
+			pub.Title.En, pub.Title.Ru, titleIDs[i],
+		)
+		if err != nil {
+			return err
+		}
+		if pub.Link != "" {
+			_, err = tx.Exec("UPDATE project_publications SET link = "+placeholder(0)+" WHERE id = "+placeholder(1), pub.Link, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (r *SQLitePublicationRepo) GetByTitle(title string) (*models.Publication, error) {
-	query := `
-		SELECT p.id, ls.en, ls.ru, p.link, p.journal, p.published_at, p.citations_count, p.visible
-		FROM publications p
-		JOIN localized_strings ls ON p.title_id = ls.id
-		WHERE LOWER(ls.en) = LOWER(?) OR LOWER(ls.ru) = LOWER(?)
-	`
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
 
-	var pubID int
-	var titleEn, titleRu string
-	var link string
-	var journal string
-	var publishedAt string
-	var citationsCount int
-	var visible bool
-	err := r.db.QueryRow(query, title, title).Scan(&pubID, &titleEn, &titleRu, &link, &journal, &publishedAt, &citationsCount, &visible)
+// publicationAuthors returns the authors of a publication, researchers and
+// external authors together, in byline order. placeholder returns the
+// placeholder of the i-th parameter, counting from 0.
+func publicationAuthors(q queryer, placeholder func(i int) string, publicationID int) ([]models.Author, error) {
+	rows, err := q.Query(`
+		SELECT a.researcher_id, a.person_id, a.name_en, a.name_ru, a.role FROM (
+			SELECT pa.author_order, 0 AS kind, r.id AS seq, r.id AS researcher_id, NULL AS person_id,
+				fn.en || ' ' || ln.en AS name_en, fn.ru || ' ' || ln.ru AS name_ru, pa.role
+			FROM publication_authors pa
+			JOIN researchers r ON pa.researcher_id = r.id
+			JOIN localized_strings fn ON r.name_id = fn.id
+			JOIN localized_strings ln ON r.last_name_id = ln.id
+			WHERE pa.publication_id = `+placeholder(0)+`
+			UNION ALL
+			SELECT pea.author_order, 1, pea.id, NULL, pea.person_id, ls.en, ls.ru, pea.role
+			FROM publication_external_authors pea
+			JOIN localized_strings ls ON pea.name_id = ls.id
+			WHERE pea.publication_id = `+placeholder(1)+`
+		) a
+		ORDER BY a.author_order, a.kind, a.seq
+	`, publicationID, publicationID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &models.Publication{
-		ID: pubID,
-		Title: models.LocalizedString{
-			En: titleEn,
-			Ru: titleRu,
-		},
-		Link:           link,
-		Journal:        journal,
-		PublishedAt:    publishedAt,
-		CitationsCount: citationsCount,
-		Visible:        visible,
-	}, nil
+	authors := []models.Author{}
+	for rows.Next() {
+		var author models.Author
+		var researcherID, personID sql.NullInt64
+		if err := rows.Scan(&researcherID, &personID, &author.Name.En, &author.Name.Ru, &author.Role); err != nil {
+			return nil, err
+		}
+		if researcherID.Valid {
+			id := int(researcherID.Int64)
+			author.ID = &id
+		}
+		if personID.Valid {
+			id := int(personID.Int64)
+			author.PersonID = &id
+		}
+		authors = append(authors, author)
+	}
+	return authors, rows.Err()
 }
 
-func (r *SQLitePublicationRepo) GetTotalCount() (int, error) {
-	var count int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM publications").Scan(&count)
-	if err != nil {
-		return 0, err
+// publicationWhere builds the WHERE clause of a publication listing. like is the
+// dialect's case-insensitive LIKE operator and arg binds a parameter, returning
+// its placeholder.
+func publicationWhere(filter models.PublicationFilter, like string, arg func(interface{}) string) string {
+	conditions := []string{}
+
+	if filter.Visible != nil {
+		conditions = append(conditions, "p.visible = "+arg(*filter.Visible))
 	}
-	return count, nil
+	if filter.Year != 0 {
+		// published_at starts with the year, so a range keeps the index usable.
+		conditions = append(conditions, fmt.Sprintf("p.published_at >= %s AND p.published_at < %s",
+			arg(strconv.Itoa(filter.Year)), arg(strconv.Itoa(filter.Year+1))))
+	}
+	if filter.FromYear != 0 {
+		conditions = append(conditions, "p.published_at >= "+arg(strconv.Itoa(filter.FromYear)))
+	}
+	if filter.ToYear != 0 {
+		conditions = append(conditions, "p.published_at < "+arg(strconv.Itoa(filter.ToYear+1)))
+	}
+	if filter.ResearcherID != 0 {
+		conditions = append(conditions,
+			"p.id IN (SELECT publication_id FROM publication_authors WHERE researcher_id = "+arg(filter.ResearcherID)+")")
+	}
+	if filter.Venue != "" {
+		conditions = append(conditions, "p.venue "+like+" "+arg("%"+filter.Venue+"%"))
+	}
+	if filter.VenueID != 0 {
+		conditions = append(conditions, "p.venue_id = "+arg(filter.VenueID))
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "p.type = "+arg(filter.Type))
+	}
+	if filter.Query != "" {
+		pattern := "%" + filter.Query + "%"
+		conditions = append(conditions, "(ls.en "+like+" "+arg(pattern)+
+			" OR ls.ru "+like+" "+arg(pattern)+
+			" OR p.venue "+like+" "+arg(pattern)+")")
+	}
+
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + Join(conditions, " AND ")
+}
+
+// publicationOrderBy returns the ORDER BY clause for a sort order, newest first
+// by default. Ties are broken by ID so pages do not overlap.
+func publicationOrderBy(sort string) string {
+	switch sort {
+	case models.PublicationSortCitations:
+		return "p.citations_count DESC, p.id DESC"
+	case models.PublicationSortTitle:
+		return "LOWER(ls.en), p.id"
+	default:
+		return "p.published_at DESC, p.id DESC"
+	}
+}
+
+func publicationOffset(filter models.PublicationFilter) int {
+	if filter.Page <= 1 {
+		return 0
+	}
+	return (filter.Page - 1) * filter.PageSize
 }
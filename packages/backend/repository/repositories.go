@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/damirahm/diplom/backend/config"
+)
+
+// Repositories holds one implementation of every repository, all backed by the
+// same database.
+type Repositories struct {
+	LocalizedStrings  LocalizedStringRepo
+	Partners          PartnerRepo
+	Researchers       ResearcherRepo
+	Publications      PublicationRepo
+	Attachments       AttachmentRepo
+	ExternalPeople    ExternalPersonRepo
+	Venues            VenueRepo
+	Projects          ProjectRepo
+	TrainingMaterials TrainingMaterialRepo
+	Disciplines       DisciplineRepo
+	Users             UserRepo
+	Sessions          SessionRepo
+	APITokens         APITokenRepo
+	Audit             AuditRepo
+	Metrics           MetricsRepo
+	CrawlJobs         CrawlJobRepo
+	LoginAttempts     LoginAttemptRepo
+	Search            SearchRepo
+}
+
+// New returns the repositories for the given driver, config.DriverSQLite or
+// config.DriverPostgres.
+func New(driver string, db *sql.DB) *Repositories {
+	if driver == config.DriverPostgres {
+		lsRepo := NewPostgresLocalizedStringRepo(db)
+		researcherRepo := NewPostgresResearcherRepo(db, lsRepo)
+		venueRepo := NewPostgresVenueRepo(db)
+		return &Repositories{
+			LocalizedStrings:  lsRepo,
+			Partners:          NewPostgresPartnerRepo(db),
+			Researchers:       researcherRepo,
+			Publications:      NewPostgresPublicationRepo(db, lsRepo, researcherRepo, venueRepo),
+			Attachments:       NewPostgresAttachmentRepo(db, lsRepo),
+			ExternalPeople:    NewPostgresExternalPersonRepo(db, lsRepo),
+			Venues:            venueRepo,
+			Projects:          NewPostgresProjectRepo(db, lsRepo),
+			TrainingMaterials: NewPostgresTrainingMaterialRepo(db, lsRepo),
+			Disciplines:       NewPostgresDisciplineRepo(db, lsRepo, researcherRepo),
+			Users:             NewPostgresUserRepo(db),
+			Sessions:          NewPostgresSessionRepo(db),
+			APITokens:         NewPostgresAPITokenRepo(db),
+			Audit:             NewPostgresAuditRepo(db),
+			Metrics:           NewPostgresMetricsRepo(db),
+			CrawlJobs:         NewPostgresCrawlJobRepo(db),
+			LoginAttempts:     NewPostgresLoginAttemptRepo(db),
+			Search:            NewPostgresSearchRepo(db),
+		}
+	}
+
+	lsRepo := NewSQLiteLocalizedStringRepo(db)
+	researcherRepo := NewSQLiteResearcherRepo(db, lsRepo)
+	venueRepo := NewSQLiteVenueRepo(db)
+	return &Repositories{
+		LocalizedStrings:  lsRepo,
+		Partners:          NewSQLitePartnerRepo(db),
+		Researchers:       researcherRepo,
+		Publications:      NewSQLitePublicationRepo(db, lsRepo, researcherRepo, venueRepo),
+		Attachments:       NewSQLiteAttachmentRepo(db, lsRepo),
+		ExternalPeople:    NewSQLiteExternalPersonRepo(db, lsRepo),
+		Venues:            venueRepo,
+		Projects:          NewSQLiteProjectRepo(db, lsRepo),
+		TrainingMaterials: NewSQLiteTrainingMaterialRepo(db, lsRepo),
+		Disciplines:       NewSQLiteDisciplineRepo(db, lsRepo, researcherRepo),
+		Users:             NewSQLiteUserRepo(db),
+		Sessions:          NewSQLiteSessionRepo(db),
+		APITokens:         NewSQLiteAPITokenRepo(db),
+		Audit:             NewSQLiteAuditRepo(db),
+		Metrics:           NewSQLiteMetricsRepo(db),
+		CrawlJobs:         NewSQLiteCrawlJobRepo(db),
+		LoginAttempts:     NewSQLiteLoginAttemptRepo(db),
+		Search:            NewSQLiteSearchRepo(db),
+	}
+}
@@ -29,13 +29,45 @@ type PublicationRepo interface {
 	GetByID(id int) (*models.Publication, error)
 	GetByIDs(ids []int) ([]models.Publication, error)
 	GetAll() ([]models.Publication, error)
+	// GetPossibleDuplicates returns the publications sharing a DOI or at
+	// least half of their title words with another publication, the only ones
+	// duplicates.Find can pair.
+	GetPossibleDuplicates() ([]models.Publication, error)
+	List(filter models.PublicationFilter) ([]models.Publication, int, error)
 	GetByTitle(title string) (*models.Publication, error)
 	Update(pub models.Publication) error
+	// SetVisibility shows or hides a publication without touching its other
+	// fields, returning sql.ErrNoRows when it does not exist.
+	SetVisibility(id int, visible bool) error
 	Delete(id int) error
+	// Merge saves pub and deletes the publication duplicateID merged into it,
+	// moving the project publications referring to the duplicate, its
+	// attachments and its citation history to pub.
+	Merge(pub models.Publication, duplicateID int) error
 	GetAuthors(id int) ([]models.Researcher, error)
 	GetTotalCount() (int, error)
 }
 
+// VenueRepo manages the venue registry. Match and LinkPublications resolve
+// venue names with venues.Matcher.
+type VenueRepo interface {
+	Create(venue models.Venue) (int64, error)
+	GetByID(id int) (*models.Venue, error)
+	GetAll() ([]models.Venue, error)
+	Update(venue models.Venue) error
+	Delete(id int) error
+	// SetQuartiles adds quartiles to a venue, replacing those of the same years.
+	SetQuartiles(venueID int, quartiles []models.VenueQuartile) error
+	// Match returns the ID of the venue a publication's venue name refers to,
+	// or nil when no venue matches.
+	Match(name string) (*int, error)
+	// LinkPublications links the publications without a venue ID to the
+	// venues their venue names match, returning how many were linked.
+	LinkPublications() (int, error)
+	YearStats(filter models.VenueReportFilter) ([]models.VenueYearStats, error)
+	ResearcherStats(filter models.VenueReportFilter) ([]models.VenueResearcherStats, error)
+}
+
 type ResearcherRepo interface {
 	Create(researcher models.Researcher) (int64, error)
 	GetByID(id int) (*models.ResearcherWithPublicationsCount, error)
@@ -43,6 +75,7 @@ type ResearcherRepo interface {
 	GetAll() ([]models.ResearcherWithPublicationsCount, error)
 	FindByFullName(fullName string) (*models.ResearcherWithPublicationsCount, error)
 	FindByLastName(lastName string) ([]models.ResearcherWithPublicationsCount, error)
+	GetResearcherPublications(researcherID int) ([]models.Publication, error)
 	Update(researcher models.Researcher) error
 	Delete(id int) error
 }
@@ -72,3 +105,109 @@ type DisciplineRepo interface {
 	Update(discipline models.Discipline) error
 	Delete(id int) error
 }
+
+type UserRepo interface {
+	Create(user models.User) (int64, error)
+	GetByID(id int) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	GetAll() ([]models.User, error)
+	Update(user models.User) error
+	UpdatePassword(id int, passwordHash string) error
+	Delete(id int) error
+	Count() (int, error)
+}
+
+type SessionRepo interface {
+	Create(session models.Session, tokenHash string) (int64, error)
+	GetByTokenHash(tokenHash string) (*models.Session, error)
+	GetAll(userID int) ([]models.Session, error)
+	Touch(id int) error
+	Delete(id int) error
+	DeleteByTokenHash(tokenHash string) error
+	DeleteByUserID(userID int) error
+	DeleteOthers(userID, keepID int) error
+	DeleteExpired() error
+}
+
+type APITokenRepo interface {
+	Create(token models.APIToken, tokenHash string) (int64, error)
+	GetByID(id int) (*models.APIToken, error)
+	GetByTokenHash(tokenHash string) (*models.APIToken, error)
+	GetAll(userID int) ([]models.APIToken, error)
+	Touch(id int) error
+	Delete(id int) error
+}
+
+type AuditRepo interface {
+	Create(entry models.AuditEntry) (int64, error)
+	GetAll(filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// AttachmentRepo stores the attachments of publications. Deleting a
+// publication deletes its attachments along with their files.
+type AttachmentRepo interface {
+	Create(attachment models.PublicationAttachment) (int64, error)
+	GetByID(id int) (*models.PublicationAttachment, error)
+	GetByPublication(publicationID int) ([]models.PublicationAttachment, error)
+	Update(attachment models.PublicationAttachment) error
+	Delete(id int) error
+}
+
+// ExternalPersonRepo is the registry of external co-authors. People are
+// registered as publications list them, under the spelling of their name,
+// and researchers are linked to the people their names are aliases of when
+// they are created.
+type ExternalPersonRepo interface {
+	GetByID(id int) (*models.ExternalPerson, error)
+	GetAll() ([]models.ExternalPerson, error)
+	// FindByAlias returns the ID of the person name is an alias of, or nil
+	// when it is nobody's.
+	FindByAlias(name string) (*int, error)
+	// Update saves the name, ORCID, affiliation and aliases of a person,
+	// skipping aliases that belong to someone else.
+	Update(person models.ExternalPerson) error
+	// Merge moves the aliases and publications of the people duplicateIDs to
+	// survivorID and deletes them.
+	Merge(survivorID int, duplicateIDs []int) error
+	// Promote links a person to a researcher, who replaces them in the bylines
+	// of their publications and of those added later under their aliases.
+	Promote(personID, researcherID int) error
+}
+
+// MetricsRepo stores the citation metrics recorded on every crawl.
+type MetricsRepo interface {
+	RecordPublicationCitations(publicationID, citations int) error
+	RecordResearcherMetrics(snapshot models.MetricsSnapshot) error
+	GetPublicationCitationHistory(publicationID int, filter models.MetricsHistoryFilter) ([]models.CitationSnapshot, error)
+	GetResearcherMetricsHistory(researcherID int, filter models.MetricsHistoryFilter) ([]models.MetricsSnapshot, error)
+}
+
+// CrawlJobRepo records crawl jobs and the result of every researcher they
+// crawl.
+type CrawlJobRepo interface {
+	// Create records a running job and returns its ID.
+	Create(job models.CrawlJob) (int64, error)
+	GetByID(id int) (*models.CrawlJob, error)
+	// List returns the latest jobs, newest first, without their results.
+	// An empty status matches every job.
+	List(status string, limit int) ([]models.CrawlJob, error)
+	// StartResult records that a job started crawling a researcher.
+	StartResult(jobID, researcherID int) error
+	FinishResult(jobID int, result models.CrawlResult) error
+	Finish(id int, status, message string) error
+	// FailRunning marks the jobs a previous run of the server left running as
+	// failed with message and returns how many there were.
+	FailRunning(message string) (int, error)
+}
+
+type SearchRepo interface {
+	Search(query models.SearchQuery) (models.SearchResults, error)
+}
+
+type LoginAttemptRepo interface {
+	Get(key string) (*models.LoginAttempt, error)
+	Fail(key, at, windowStart string) (int, error)
+	Block(key, until string) error
+	Delete(key string) error
+	DeleteStale(before string) error
+}
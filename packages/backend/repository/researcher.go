@@ -71,8 +71,13 @@ func (r *SQLiteResearcherRepo) Create(researcher models.Researcher) (int64, erro
 		return 0, err
 	}
 
-	if err := r.syncExternalAuthors(tx, int(id), researcher.Name, researcher.LastName); err != nil {
-		return id, err
+	if err = r.syncExternalAuthors(tx, int(id), researcher.Name, researcher.LastName); err != nil {
+		return 0, err
+	}
+
+	err = sqliteIndex(tx, models.SearchTypeResearcher, int(id))
+	if err != nil {
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -340,6 +345,11 @@ func (r *SQLiteResearcherRepo) Update(researcher models.Researcher) error {
 		return err
 	}
 
+	err = sqliteIndex(tx, models.SearchTypeResearcher, researcher.ID)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -397,52 +407,74 @@ func (r *SQLiteResearcherRepo) Delete(id int) error {
 		}
 	}
 
+	_, err = tx.Exec("UPDATE users SET researcher_id = NULL WHERE researcher_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM researcher_metric_snapshots WHERE researcher_id = ?", id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec("DELETE FROM researchers WHERE id = ?", id)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if err := sqliteUnindex(tx, models.SearchTypeResearcher, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit()
 }
 
 func (r *SQLiteResearcherRepo) movePublicationsToExternalAuthors(tx *sql.Tx, researcherID int, name, lastName models.LocalizedString) error {
 	rows, err := tx.Query(`
-		SELECT publication_id 
+		SELECT publication_id, author_order, role
 		FROM publication_authors 
 		WHERE researcher_id = ?
 	`, researcherID)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
+	slots, err := scanBylineSlots(rows)
+	if err != nil {
+		return err
+	}
 
 	fullName := models.LocalizedString{
 		En: name.En + " " + lastName.En,
 		Ru: name.Ru + " " + lastName.Ru,
 	}
 
-	nameID, err := r.localizedStringRepo.CreateTx(tx, fullName)
+	personID, err := demotedResearcherPerson(tx, r.localizedStringRepo, func(int) string { return "?" }, researcherID, fullName)
 	if err != nil {
 		return err
 	}
 
-	for rows.Next() {
-		var publicationID int
-		if err := rows.Scan(&publicationID); err != nil {
+	// Each publication gets its own name row, as publication deletion removes
+	// the names of its external authors.
+	for _, slot := range slots {
+		nameID, err := r.localizedStringRepo.CreateTx(tx, fullName)
+		if err != nil {
 			return err
 		}
 
 		_, err = tx.Exec(
-			"INSERT INTO publication_external_authors (publication_id, name_id) VALUES (?, ?)",
-			publicationID, nameID)
+			"INSERT INTO publication_external_authors (publication_id, name_id, author_order, role, person_id) VALUES (?, ?, ?, ?, ?)",
+			slot.publicationID, nameID, slot.order, slot.role, personID)
 		if err != nil {
 			return err
 		}
 
 		_, err = tx.Exec(
 			"DELETE FROM publication_authors WHERE researcher_id = ? AND publication_id = ?",
-			researcherID, publicationID)
+			researcherID, slot.publicationID)
 		if err != nil {
 			return err
 		}
@@ -464,7 +496,8 @@ func (r *SQLiteResearcherRepo) GetResearcherPublicationsCount(researcherID int)
 
 func (r *SQLiteResearcherRepo) GetResearcherPublications(researcherID int) ([]models.Publication, error) {
 	rows, err := r.db.Query(`
-		SELECT p.id, p.title_id, p.link, p.journal, p.published_at, p.citations_count
+		SELECT p.id, p.title_id, p.link, p.published_at, p.citations_count,
+			p.type, p.venue, p.venue_id, p.volume, p.issue, p.pages, p.publisher, p.doi, p.abstract
 		FROM publications p
 		JOIN publication_authors pa ON p.id = pa.publication_id
 		WHERE pa.researcher_id = ? and p.visible = 1
@@ -479,7 +512,8 @@ func (r *SQLiteResearcherRepo) GetResearcherPublications(researcherID int) ([]mo
 	for rows.Next() {
 		var pub models.Publication
 		var titleID int64
-		err := rows.Scan(&pub.ID, &titleID, &pub.Link, &pub.Journal, &pub.PublishedAt, &pub.CitationsCount)
+		dest := []interface{}{&pub.ID, &titleID, &pub.Link, &pub.PublishedAt, &pub.CitationsCount}
+		err := rows.Scan(append(dest, publicationMetadata(&pub)...)...)
 		if err != nil {
 			return nil, err
 		}
@@ -490,63 +524,10 @@ func (r *SQLiteResearcherRepo) GetResearcherPublications(researcherID int) ([]mo
 		}
 		pub.Title = *title
 
-		authorRows, err := r.db.Query(`
-			SELECT r.id, fn.en, ln.en, fn.ru, ln.ru
-			FROM researchers r
-			JOIN publication_authors pa ON r.id = pa.researcher_id
-			JOIN localized_strings fn ON r.name_id = fn.id
-			JOIN localized_strings ln ON r.last_name_id = ln.id
-			WHERE pa.publication_id = ?
-		`, pub.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var authors []models.Author
-		for authorRows.Next() {
-			var id int
-			var nameEn, lastNameEn, nameRu, lastNameRu string
-			err := authorRows.Scan(&id, &nameEn, &lastNameEn, &nameRu, &lastNameRu)
-			if err != nil {
-				authorRows.Close()
-				return nil, err
-			}
-			authors = append(authors, models.Author{
-				ID: &id,
-				Name: models.LocalizedString{
-					En: nameEn + " " + lastNameEn,
-					Ru: nameRu + " " + lastNameRu,
-				},
-			})
-		}
-		authorRows.Close()
-
-		externalRows, err := r.db.Query(`
-			SELECT ls.en, ls.ru 
-			FROM publication_external_authors pea
-			JOIN localized_strings ls ON pea.name_id = ls.id
-			WHERE pea.publication_id = ?
-		`, pub.ID)
+		pub.Authors, err = publicationAuthors(r.db, func(int) string { return "?" }, pub.ID)
 		if err != nil {
 			return nil, err
 		}
-
-		for externalRows.Next() {
-			var nameEn, nameRu string
-			if err := externalRows.Scan(&nameEn, &nameRu); err != nil {
-				externalRows.Close()
-				return nil, err
-			}
-			authors = append(authors, models.Author{
-				Name: models.LocalizedString{
-					En: nameEn,
-					Ru: nameRu,
-				},
-			})
-		}
-		externalRows.Close()
-
-		pub.Authors = authors
 		publications = append(publications, pub)
 	}
 
@@ -581,44 +562,10 @@ func (r *SQLiteResearcherRepo) FindByFullName(fullName string) (*models.Research
 	return r.GetByID(researcherID)
 }
 
+// syncExternalAuthors promotes the external people a new researcher's name is
+// an alias of to the researcher.
 func (r *SQLiteResearcherRepo) syncExternalAuthors(tx *sql.Tx, researcherID int, name, lastName models.LocalizedString) error {
-	rows, err := tx.Query(`
-		SELECT pea.id, pea.publication_id, pea.name_id
-		FROM publication_external_authors pea
-		JOIN localized_strings ls ON pea.name_id = ls.id
-		WHERE (ls.en = ? OR ls.en = ? OR ls.ru = ? OR ls.ru = ?)
-	`,
-		name.En+" "+lastName.En,
-		lastName.En+" "+name.En,
-		name.Ru+" "+lastName.Ru,
-		lastName.Ru+" "+name.Ru)
-
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var externalAuthorID, publicationID, nameID int64
-
-		if err := rows.Scan(&externalAuthorID, &publicationID, &nameID); err != nil {
-			return err
-		}
-
-		_, err = tx.Exec(
-			"INSERT INTO publication_authors (researcher_id, publication_id) VALUES (?, ?)",
-			researcherID, publicationID)
-		if err != nil {
-			return err
-		}
-
-		_, err = tx.Exec("DELETE FROM publication_external_authors WHERE id = ?", externalAuthorID)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return promoteMatchingPeople(tx, r.localizedStringRepo, func(int) string { return "?" }, researcherID, name, lastName)
 }
 
 func (r *SQLiteResearcherRepo) FindByLastName(lastName string) ([]models.ResearcherWithPublicationsCount, error) {
@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// Highlighted terms are wrapped in these control characters by the database
+// and turned into <mark> tags once the surrounding text has been escaped.
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+)
+
+// maxSearchTerms caps the number of words of a query that are searched for.
+const maxSearchTerms = 10
+
+// snippetWords is the length of a snippet in words, as asked of FTS5.
+const snippetWords = 24
+
+// searchDocumentQueries select the searchable text of one entity as (entity,
+// entity_id, title_en, title_ru, body_en, body_ru); %s is the placeholder of
+// the entity ID. The queries work in both SQLite and PostgreSQL.
+var searchDocumentQueries = map[string]string{
+	models.SearchTypePublication: `SELECT 'publication', p.id, t.en, t.ru, p.venue || ' ' || p.abstract, p.venue || ' ' || p.abstract
+		FROM publications p
+		JOIN localized_strings t ON p.title_id = t.id
+		WHERE p.id = %s`,
+	models.SearchTypeResearcher: `SELECT 'researcher', r.id, n.en || ' ' || ln.en, n.ru || ' ' || ln.ru,
+			pos.en || ' ' || COALESCE(b.en, ''), pos.ru || ' ' || COALESCE(b.ru, '')
+		FROM researchers r
+		JOIN localized_strings n ON r.name_id = n.id
+		JOIN localized_strings ln ON r.last_name_id = ln.id
+		JOIN localized_strings pos ON r.position_id = pos.id
+		LEFT JOIN localized_strings b ON r.bio_id = b.id
+		WHERE r.id = %s`,
+	models.SearchTypeProject: `SELECT 'project', p.id, t.en, t.ru, COALESCE(d.en, ''), COALESCE(d.ru, '')
+		FROM projects p
+		JOIN localized_strings t ON p.title_id = t.id
+		LEFT JOIN localized_strings d ON p.description_id = d.id
+		WHERE p.id = %s`,
+	models.SearchTypeTraining: `SELECT 'training', m.id, t.en, t.ru, d.en, d.ru
+		FROM training_materials m
+		JOIN localized_strings t ON m.title_id = t.id
+		JOIN localized_strings d ON m.description_id = d.id
+		WHERE m.id = %s`,
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type SQLiteSearchRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteSearchRepo(db *sql.DB) *SQLiteSearchRepo {
+	return &SQLiteSearchRepo{db: db}
+}
+
+// Search matches every query term as a prefix, so inflected Russian words are
+// still found by their stem. Titles weigh ten times more than bodies.
+func (r *SQLiteSearchRepo) Search(query models.SearchQuery) (models.SearchResults, error) {
+	results := emptySearchResults(query.Types)
+	terms := searchTerms(query.Query)
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	fts, err := r.hasFTS5()
+	if err != nil {
+		return nil, err
+	}
+	if !fts {
+		return r.scan(query, terms, results)
+	}
+
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"*`
+	}
+
+	// Column numbers of the title and body in the requested language.
+	titleColumn, bodyColumn := 2, 4
+	if query.Lang == "ru" {
+		titleColumn, bodyColumn = 3, 5
+	}
+
+	for _, entity := range query.Types {
+		q := fmt.Sprintf(`SELECT entity_id,
+				highlight(search_index, %d, char(2), char(3)),
+				snippet(search_index, %d, char(2), char(3), '…', %d),
+				bm25(search_index, 0, 0, 10, 10, 1, 1) AS score
+			FROM search_index
+			WHERE search_index MATCH ? AND entity = ?`, titleColumn, bodyColumn, snippetWords)
+		if entity == models.SearchTypePublication {
+			q += " AND entity_id NOT IN (SELECT id FROM publications WHERE visible = 0)"
+		}
+		q += " ORDER BY score LIMIT ?"
+
+		rows, err := r.db.Query(q, strings.Join(match, " "), entity, query.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		hits, err := scanSearchHits(rows)
+		if err != nil {
+			return nil, err
+		}
+		// bm25 scores are negative, lower being better.
+		for i := range hits {
+			hits[i].Score = -hits[i].Score
+		}
+		results[entity] = hits
+	}
+	return results, nil
+}
+
+// hasFTS5 reports whether the search index is an FTS5 table. Databases created
+// by a build without FTS5 have a plain table instead.
+func (r *SQLiteSearchRepo) hasFTS5() (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE name = 'search_index' AND lower(sql) LIKE '%fts5%'",
+	).Scan(&count)
+	return count > 0, err
+}
+
+// scan searches a plain search index by reading it whole, which is fast enough
+// for the size of the lab's data. It is matched in Go rather than with LIKE,
+// since SQLite folds only ASCII letters to lower case. As with FTS5, every term
+// has to start a word of any column, and a matched word in the title weighs
+// ten times more than one in the body.
+func (r *SQLiteSearchRepo) scan(query models.SearchQuery, terms []string, results models.SearchResults) (models.SearchResults, error) {
+	for _, entity := range query.Types {
+		q := `SELECT entity_id, COALESCE(title_en, ''), COALESCE(title_ru, ''), COALESCE(body_en, ''), COALESCE(body_ru, '')
+			FROM search_index
+			WHERE entity = ?`
+		if entity == models.SearchTypePublication {
+			q += " AND entity_id NOT IN (SELECT id FROM publications WHERE visible = 0)"
+		}
+
+		rows, err := r.db.Query(q, entity)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := []models.SearchHit{}
+		for rows.Next() {
+			var hit models.SearchHit
+			var titleEn, titleRu, bodyEn, bodyRu string
+			if err := rows.Scan(&hit.ID, &titleEn, &titleRu, &bodyEn, &bodyRu); err != nil {
+				rows.Close()
+				return nil, err
+			}
+
+			matched := map[string]bool{}
+			for _, text := range []string{titleEn, titleRu, bodyEn, bodyRu} {
+				markWords(text, terms, matched)
+			}
+			if len(matched) < len(terms) {
+				continue
+			}
+
+			title, body := titleEn, bodyEn
+			if query.Lang == "ru" {
+				title, body = titleRu, bodyRu
+			}
+			title, titleMatches := markWords(title, terms, nil)
+			snippet, bodyMatches := markWords(snippetOf(body, terms), terms, nil)
+
+			hit.Title = markHighlights(title)
+			hit.Snippet = markHighlights(snippet)
+			hit.Score = float64(10*titleMatches + bodyMatches)
+			hits = append(hits, hit)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+		if len(hits) > query.Limit {
+			hits = hits[:query.Limit]
+		}
+		results[entity] = hits
+	}
+	return results, nil
+}
+
+// wordSpans returns the start and end offsets of the words of text, split the
+// way searchTerms splits a query.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, c := range text {
+		isWord := unicode.IsLetter(c) || unicode.IsDigit(c)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// markWords wraps the words of text that start with one of the terms in the
+// highlight markers and returns the number of such words. The matched terms
+// are added to matched unless it is nil.
+func markWords(text string, terms []string, matched map[string]bool) (string, int) {
+	var b strings.Builder
+	count, last := 0, 0
+	for _, span := range wordSpans(text) {
+		word := strings.ToLower(text[span[0]:span[1]])
+		for _, term := range terms {
+			if !strings.HasPrefix(word, term) {
+				continue
+			}
+			if matched != nil {
+				matched[term] = true
+			}
+			b.WriteString(text[last:span[0]])
+			b.WriteString(highlightStart + text[span[0]:span[1]] + highlightEnd)
+			last = span[1]
+			count++
+			break
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String(), count
+}
+
+// snippetOf cuts text down to snippetWords words, starting a little before the
+// first word that matches a term.
+func snippetOf(text string, terms []string) string {
+	spans := wordSpans(text)
+	if len(spans) <= snippetWords {
+		return text
+	}
+
+	first := 0
+	for i, span := range spans {
+		if _, n := markWords(text[span[0]:span[1]], terms, nil); n > 0 {
+			first = i
+			break
+		}
+	}
+	start := max(0, min(first-snippetWords/4, len(spans)-snippetWords))
+	end := start + snippetWords
+
+	snippet := text[spans[start][0]:spans[end-1][1]]
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(spans) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// sqliteIndex refreshes the search index entry of an entity from its rows. Call
+// it after writing the entity, in the same transaction.
+func sqliteIndex(db execer, entity string, id int) error {
+	if err := sqliteUnindex(db, entity, id); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(
+		"INSERT INTO search_index (entity, entity_id, title_en, title_ru, body_en, body_ru) "+
+			fmt.Sprintf(searchDocumentQueries[entity], "?"),
+		id,
+	)
+	return err
+}
+
+func sqliteUnindex(db execer, entity string, id int) error {
+	_, err := db.Exec("DELETE FROM search_index WHERE entity = ? AND entity_id = ?", entity, id)
+	return err
+}
+
+// searchTerms splits a query into lower-cased words, dropping punctuation and
+// anything that could be taken for query syntax.
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+func emptySearchResults(types []string) models.SearchResults {
+	results := models.SearchResults{}
+	for _, entity := range types {
+		results[entity] = []models.SearchHit{}
+	}
+	return results
+}
+
+// scanSearchHits reads (id, title, snippet, score) rows and converts the
+// highlight markers to HTML.
+func scanSearchHits(rows *sql.Rows) ([]models.SearchHit, error) {
+	defer rows.Close()
+
+	hits := []models.SearchHit{}
+	for rows.Next() {
+		var hit models.SearchHit
+		if err := rows.Scan(&hit.ID, &hit.Title, &hit.Snippet, &hit.Score); err != nil {
+			return nil, err
+		}
+		hit.Title = markHighlights(hit.Title)
+		hit.Snippet = markHighlights(hit.Snippet)
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func markHighlights(text string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightEnd, "</mark>").Replace(html.EscapeString(text))
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteSessionRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteSessionRepo(db *sql.DB) *SQLiteSessionRepo {
+	return &SQLiteSessionRepo{db: db}
+}
+
+func (r *SQLiteSessionRepo) Create(session models.Session, tokenHash string) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO sessions (token_hash, user_id, expires_at, ip, user_agent, csrf_token_hash) VALUES (?, ?, ?, ?, ?, ?)",
+		tokenHash, session.UserID, session.ExpiresAt, session.IP, session.UserAgent, session.CSRFTokenHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// GetByTokenHash returns the unexpired session stored under tokenHash.
+func (r *SQLiteSessionRepo) GetByTokenHash(tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.QueryRow(
+		`SELECT s.id, s.user_id, u.username, s.created_at, s.expires_at, s.last_seen_at, s.ip, s.user_agent, s.csrf_token_hash
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.token_hash = ? AND s.expires_at > ?`,
+		tokenHash, time.Now().UTC().Format(models.TimestampLayout),
+	).Scan(
+		&session.ID, &session.UserID, &session.Username, &session.CreatedAt,
+		&session.ExpiresAt, &session.LastSeenAt, &session.IP, &session.UserAgent, &session.CSRFTokenHash,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetAll returns unexpired sessions, limited to one user when userID is non-zero.
+func (r *SQLiteSessionRepo) GetAll(userID int) ([]models.Session, error) {
+	query := `SELECT s.id, s.user_id, u.username, s.created_at, s.expires_at, s.last_seen_at, s.ip, s.user_agent
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.expires_at > ?`
+	args := []interface{}{time.Now().UTC().Format(models.TimestampLayout)}
+
+	if userID != 0 {
+		query += " AND s.user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY s.last_seen_at DESC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(
+			&session.ID, &session.UserID, &session.Username, &session.CreatedAt,
+			&session.ExpiresAt, &session.LastSeenAt, &session.IP, &session.UserAgent,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+func (r *SQLiteSessionRepo) Touch(id int) error {
+	_, err := r.db.Exec(
+		"UPDATE sessions SET last_seen_at = ? WHERE id = ?",
+		time.Now().UTC().Format(models.TimestampLayout), id,
+	)
+	return err
+}
+
+func (r *SQLiteSessionRepo) Delete(id int) error {
+	res, err := r.db.Exec("DELETE FROM sessions WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+func (r *SQLiteSessionRepo) DeleteByTokenHash(tokenHash string) error {
+	_, err := r.db.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
+	return err
+}
+
+func (r *SQLiteSessionRepo) DeleteByUserID(userID int) error {
+	_, err := r.db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	return err
+}
+
+// DeleteOthers revokes every session of the user except keepID.
+func (r *SQLiteSessionRepo) DeleteOthers(userID, keepID int) error {
+	_, err := r.db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, keepID)
+	return err
+}
+
+func (r *SQLiteSessionRepo) DeleteExpired() error {
+	_, err := r.db.Exec(
+		"DELETE FROM sessions WHERE expires_at <= ?",
+		time.Now().UTC().Format(models.TimestampLayout),
+	)
+	return err
+}
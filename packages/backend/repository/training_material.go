@@ -37,7 +37,11 @@ func (r *SQLiteTrainingMaterialRepo) Create(material models.TrainingMaterial) (i
 	}
 
 	id, err := res.LastInsertId()
-	return id, err
+	if err != nil {
+		return 0, err
+	}
+
+	return id, sqliteIndex(r.db, models.SearchTypeTraining, int(id))
 }
 
 func (r *SQLiteTrainingMaterialRepo) GetByID(id int) (*models.TrainingMaterial, error) {
@@ -122,7 +126,11 @@ func (r *SQLiteTrainingMaterialRepo) Update(material models.TrainingMaterial) er
 		"UPDATE training_materials SET url = ?, image = ? WHERE id = ?",
 		material.URL, material.Image, material.ID,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return sqliteIndex(r.db, models.SearchTypeTraining, material.ID)
 }
 
 func (r *SQLiteTrainingMaterialRepo) Delete(id int) error {
@@ -152,5 +160,9 @@ func (r *SQLiteTrainingMaterialRepo) Delete(id int) error {
 	}
 
 	_, err = r.db.Exec("DELETE FROM training_materials WHERE id = ?", id)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return sqliteUnindex(r.db, models.SearchTypeTraining, id)
 }
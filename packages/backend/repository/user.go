@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+type SQLiteUserRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteUserRepo(db *sql.DB) *SQLiteUserRepo {
+	return &SQLiteUserRepo{db: db}
+}
+
+func (r *SQLiteUserRepo) Create(user models.User) (int64, error) {
+	res, err := r.db.Exec(
+		"INSERT INTO users (username, password_hash, role, researcher_id) VALUES (?, ?, ?, ?)",
+		user.Username, user.PasswordHash, user.Role, user.ResearcherID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *SQLiteUserRepo) GetByID(id int) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(
+		"SELECT id, username, password_hash, role, researcher_id, created_at FROM users WHERE id = ?",
+		id,
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.ResearcherID, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *SQLiteUserRepo) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(
+		"SELECT id, username, password_hash, role, researcher_id, created_at FROM users WHERE username = ?",
+		username,
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.ResearcherID, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *SQLiteUserRepo) GetAll() ([]models.User, error) {
+	rows, err := r.db.Query("SELECT id, username, password_hash, role, researcher_id, created_at FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.ResearcherID, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *SQLiteUserRepo) Update(user models.User) error {
+	res, err := r.db.Exec(
+		"UPDATE users SET username = ?, role = ?, researcher_id = ? WHERE id = ?",
+		user.Username, user.Role, user.ResearcherID, user.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepo) UpdatePassword(id int, passwordHash string) error {
+	res, err := r.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *SQLiteUserRepo) Delete(id int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.Exec("DELETE FROM sessions WHERE user_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM api_tokens WHERE user_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec("DELETE FROM users WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		err = errors.New("user not found")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteUserRepo) Count() (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,487 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/damirahm/diplom/backend/models"
+	"github.com/damirahm/diplom/backend/venues"
+)
+
+type SQLiteVenueRepo struct {
+	db *sql.DB
+}
+
+func NewSQLiteVenueRepo(db *sql.DB) *SQLiteVenueRepo {
+	return &SQLiteVenueRepo{db: db}
+}
+
+func (r *SQLiteVenueRepo) Create(venue models.Venue) (id int64, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	res, err := tx.Exec(
+		`INSERT INTO venues (name, issn, eissn, publisher, scopus, wos, rsci)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		venue.Name, venue.ISSN, venue.EISSN, venue.Publisher, venue.Scopus, venue.WoS, venue.RSCI,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if id, err = res.LastInsertId(); err != nil {
+		return 0, err
+	}
+
+	if err = r.insertDetails(tx, int(id), venue); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *SQLiteVenueRepo) GetByID(id int) (*models.Venue, error) {
+	venue := models.NewVenue()
+	err := scanVenue(r.db.QueryRow("SELECT "+venueColumns+" FROM venues WHERE id = ?", id), &venue)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("venue not found")
+		}
+		return nil, err
+	}
+
+	list := []models.Venue{venue}
+	if err := loadVenueDetails(r.db, list, "WHERE venue_id = ?", id); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func (r *SQLiteVenueRepo) GetAll() ([]models.Venue, error) {
+	return getAllVenues(r.db)
+}
+
+func (r *SQLiteVenueRepo) Update(venue models.Venue) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	res, err := tx.Exec(
+		`UPDATE venues SET name = ?, issn = ?, eissn = ?, publisher = ?, scopus = ?, wos = ?, rsci = ?
+		WHERE id = ?`,
+		venue.Name, venue.ISSN, venue.EISSN, venue.Publisher, venue.Scopus, venue.WoS, venue.RSCI, venue.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("venue not found")
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM venue_aliases WHERE venue_id = ?", venue.ID); err != nil {
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM venue_quartiles WHERE venue_id = ?", venue.ID); err != nil {
+		return err
+	}
+	if err = r.insertDetails(tx, venue.ID, venue); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes a venue and unlinks its publications, which keep their
+// venue names.
+func (r *SQLiteVenueRepo) Delete(id int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, query := range []string{
+		"UPDATE publications SET venue_id = NULL WHERE venue_id = ?",
+		"DELETE FROM venue_aliases WHERE venue_id = ?",
+		"DELETE FROM venue_quartiles WHERE venue_id = ?",
+	} {
+		if _, err = tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.Exec("DELETE FROM venues WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("venue not found")
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteVenueRepo) SetQuartiles(venueID int, quartiles []models.VenueQuartile) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, q := range quartiles {
+		_, err = tx.Exec(
+			`INSERT INTO venue_quartiles (venue_id, year, quartile) VALUES (?, ?, ?)
+			ON CONFLICT (venue_id, year) DO UPDATE SET quartile = excluded.quartile`,
+			venueID, q.Year, q.Quartile,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteVenueRepo) Match(name string) (*int, error) {
+	return matchVenue(r.db, name)
+}
+
+func (r *SQLiteVenueRepo) LinkPublications() (int, error) {
+	return linkVenuePublications(r.db, "UPDATE publications SET venue_id = ? WHERE venue_id IS NULL AND venue = ?")
+}
+
+func (r *SQLiteVenueRepo) YearStats(filter models.VenueReportFilter) ([]models.VenueYearStats, error) {
+	args := []interface{}{}
+	query := venueYearStatsQuery(filter, func(v interface{}) string {
+		args = append(args, v)
+		return "?"
+	})
+	return scanVenueYearStats(r.db.Query(query, args...))
+}
+
+func (r *SQLiteVenueRepo) ResearcherStats(filter models.VenueReportFilter) ([]models.VenueResearcherStats, error) {
+	args := []interface{}{}
+	query := venueResearcherStatsQuery(filter, func(v interface{}) string {
+		args = append(args, v)
+		return "?"
+	})
+	return scanVenueResearcherStats(r.db.Query(query, args...))
+}
+
+func (r *SQLiteVenueRepo) insertDetails(tx *sql.Tx, venueID int, venue models.Venue) error {
+	for _, alias := range venue.Aliases {
+		_, err := tx.Exec("INSERT OR IGNORE INTO venue_aliases (venue_id, name) VALUES (?, ?)", venueID, alias)
+		if err != nil {
+			return err
+		}
+	}
+	for _, q := range venue.Quartiles {
+		_, err := tx.Exec(
+			"INSERT OR REPLACE INTO venue_quartiles (venue_id, year, quartile) VALUES (?, ?, ?)",
+			venueID, q.Year, q.Quartile,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The queries below do not depend on the SQL dialect and are shared with
+// PostgresVenueRepo.
+
+const venueColumns = "id, name, issn, eissn, publisher, scopus, wos, rsci"
+
+func scanVenue(scanner interface{ Scan(...interface{}) error }, venue *models.Venue) error {
+	return scanner.Scan(
+		&venue.ID, &venue.Name, &venue.ISSN, &venue.EISSN, &venue.Publisher,
+		&venue.Scopus, &venue.WoS, &venue.RSCI,
+	)
+}
+
+func getAllVenues(db *sql.DB) ([]models.Venue, error) {
+	rows, err := db.Query("SELECT " + venueColumns + " FROM venues ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Venue{}
+	for rows.Next() {
+		venue := models.NewVenue()
+		if err := scanVenue(rows, &venue); err != nil {
+			return nil, err
+		}
+		list = append(list, venue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadVenueDetails(db, list, ""); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// loadVenueDetails fills in the aliases and quartiles of list, reading the
+// rows that match where.
+func loadVenueDetails(db *sql.DB, list []models.Venue, where string, args ...interface{}) error {
+	byID := make(map[int]*models.Venue, len(list))
+	for i := range list {
+		byID[list[i].ID] = &list[i]
+	}
+
+	rows, err := db.Query("SELECT venue_id, name FROM venue_aliases "+where+" ORDER BY name", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var venueID int
+		var alias string
+		if err := rows.Scan(&venueID, &alias); err != nil {
+			return err
+		}
+		if venue, ok := byID[venueID]; ok {
+			venue.Aliases = append(venue.Aliases, alias)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.Query("SELECT venue_id, year, quartile FROM venue_quartiles "+where+" ORDER BY year", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var venueID int
+		var q models.VenueQuartile
+		if err := rows.Scan(&venueID, &q.Year, &q.Quartile); err != nil {
+			return err
+		}
+		if venue, ok := byID[venueID]; ok {
+			venue.Quartiles = append(venue.Quartiles, q)
+		}
+	}
+	return rows.Err()
+}
+
+// newVenueMatcher loads the names and aliases of all venues into a matcher.
+func newVenueMatcher(db *sql.DB) (*venues.Matcher, error) {
+	rows, err := db.Query("SELECT id, name, issn, eissn FROM venues")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int]*models.Venue{}
+	for rows.Next() {
+		var venue models.Venue
+		if err := rows.Scan(&venue.ID, &venue.Name, &venue.ISSN, &venue.EISSN); err != nil {
+			return nil, err
+		}
+		byID[venue.ID] = &venue
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query("SELECT venue_id, name FROM venue_aliases")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var venueID int
+		var alias string
+		if err := rows.Scan(&venueID, &alias); err != nil {
+			return nil, err
+		}
+		if venue, ok := byID[venueID]; ok {
+			venue.Aliases = append(venue.Aliases, alias)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	matcher := venues.NewMatcher(nil)
+	for _, venue := range byID {
+		matcher.Add(*venue)
+	}
+	return matcher, nil
+}
+
+func matchVenue(db *sql.DB, name string) (*int, error) {
+	if name == "" {
+		return nil, nil
+	}
+	matcher, err := newVenueMatcher(db)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := matcher.Match(name); ok {
+		return &id, nil
+	}
+	return nil, nil
+}
+
+// linkVenuePublications matches the venue names of unlinked publications and
+// runs update, which takes a venue ID and a venue name, for every match.
+func linkVenuePublications(db *sql.DB, update string) (int, error) {
+	matcher, err := newVenueMatcher(db)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.Query("SELECT DISTINCT venue FROM publications WHERE venue_id IS NULL AND venue <> ''")
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	linked := 0
+	for _, name := range names {
+		id, ok := matcher.Match(name)
+		if !ok {
+			continue
+		}
+		res, err := db.Exec(update, id, name)
+		if err != nil {
+			return linked, err
+		}
+		n, _ := res.RowsAffected()
+		linked += int(n)
+	}
+	return linked, nil
+}
+
+// venueStatsColumns aggregates the rows of venueStatsSource into the fields of
+// models.VenueStats.
+const venueStatsColumns = `COUNT(*),
+	SUM(CASE WHEN s.quartile = 1 THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.quartile = 2 THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.quartile = 3 THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.quartile = 4 THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.quartile IS NULL THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.scopus THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.wos THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.rsci THEN 1 ELSE 0 END),
+	SUM(CASE WHEN s.venue_id IS NULL THEN 1 ELSE 0 END)`
+
+// venueStatsSource selects every publication in the filter's years with its
+// year, venue flags and the quartile of its venue in that year, falling back
+// to the latest earlier year.
+func venueStatsSource(filter models.VenueReportFilter, arg func(interface{}) string) string {
+	where := ""
+	if filter.FromYear != 0 {
+		where += " AND p.published_at >= " + arg(fmt.Sprint(filter.FromYear))
+	}
+	if filter.ToYear != 0 {
+		where += " AND p.published_at < " + arg(fmt.Sprint(filter.ToYear+1))
+	}
+
+	return `(SELECT p.id, p.venue_id,
+			CAST(substr(p.published_at, 1, 4) AS INTEGER) AS year,
+			(SELECT q.quartile FROM venue_quartiles q
+				WHERE q.venue_id = p.venue_id AND q.year <= CAST(substr(p.published_at, 1, 4) AS INTEGER)
+				ORDER BY q.year DESC LIMIT 1) AS quartile,
+			COALESCE(v.scopus, FALSE) AS scopus,
+			COALESCE(v.wos, FALSE) AS wos,
+			COALESCE(v.rsci, FALSE) AS rsci
+		FROM publications p
+		LEFT JOIN venues v ON p.venue_id = v.id
+		WHERE 1 = 1` + where + `) s`
+}
+
+func venueYearStatsQuery(filter models.VenueReportFilter, arg func(interface{}) string) string {
+	return "SELECT s.year, " + venueStatsColumns + " FROM " + venueStatsSource(filter, arg) +
+		" GROUP BY s.year ORDER BY s.year"
+}
+
+func venueResearcherStatsQuery(filter models.VenueReportFilter, arg func(interface{}) string) string {
+	return `SELECT r.id, n.en, n.ru, ln.en, ln.ru, ` + venueStatsColumns + `
+		FROM ` + venueStatsSource(filter, arg) + `
+		JOIN publication_authors pa ON pa.publication_id = s.id
+		JOIN researchers r ON pa.researcher_id = r.id
+		JOIN localized_strings n ON r.name_id = n.id
+		JOIN localized_strings ln ON r.last_name_id = ln.id
+		GROUP BY r.id, n.en, n.ru, ln.en, ln.ru
+		ORDER BY ln.en, n.en`
+}
+
+func venueStatsFields(stats *models.VenueStats) []interface{} {
+	return []interface{}{
+		&stats.Total, &stats.Q1, &stats.Q2, &stats.Q3, &stats.Q4, &stats.NoQuartile,
+		&stats.Scopus, &stats.WoS, &stats.RSCI, &stats.NoVenue,
+	}
+}
+
+func scanVenueYearStats(rows *sql.Rows, err error) ([]models.VenueYearStats, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.VenueYearStats{}
+	for rows.Next() {
+		var row models.VenueYearStats
+		if err := rows.Scan(append([]interface{}{&row.Year}, venueStatsFields(&row.VenueStats)...)...); err != nil {
+			return nil, err
+		}
+		stats = append(stats, row)
+	}
+	return stats, rows.Err()
+}
+
+func scanVenueResearcherStats(rows *sql.Rows, err error) ([]models.VenueResearcherStats, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.VenueResearcherStats{}
+	for rows.Next() {
+		var row models.VenueResearcherStats
+		dest := []interface{}{&row.ResearcherID, &row.Name.En, &row.Name.Ru, &row.LastName.En, &row.LastName.Ru}
+		if err := rows.Scan(append(dest, venueStatsFields(&row.VenueStats)...)...); err != nil {
+			return nil, err
+		}
+		stats = append(stats, row)
+	}
+	return stats, rows.Err()
+}
@@ -3,8 +3,10 @@ package utils
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"runtime"
+	"strings"
 )
 
 type ErrorResponse struct {
@@ -34,3 +36,16 @@ func RespondWithError(w http.ResponseWriter, status int, message string, err err
 
 	http.Error(w, message, status)
 }
+
+// ClientIP returns the address of the client, preferring the header set by the nginx proxy.
+func ClientIP(r *http.Request) string {
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "testing"
+
+func TestGenerateToken(t *testing.T) {
+	a, err := GenerateToken(16)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	b, _ := GenerateToken(16)
+	if len(a) != 32 || a == b {
+		t.Errorf("GenerateToken(16) = %q, %q; want two different 32-digit tokens", a, b)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	// SHA-256 of "abc".
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashToken("abc"); got != want {
+		t.Errorf("HashToken(abc) = %s, want %s", got, want)
+	}
+}
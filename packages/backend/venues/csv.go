@@ -0,0 +1,190 @@
+package venues
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// QuartileRow is a row of a quartile CSV file. Flags are nil when the file
+// has no column for them.
+type QuartileRow struct {
+	Line      int
+	Title     string
+	ISSNs     []string
+	Publisher string
+	Year      int
+	// Quartile is 0 when the venue was not ranked.
+	Quartile int
+	Scopus   *bool
+	WoS      *bool
+	RSCI     *bool
+}
+
+// csvColumns maps the lower-cased header names a quartile file may use to the
+// fields they fill.
+var csvColumns = map[string]string{
+	"title": "title", "name": "title", "journal": "title", "venue": "title", "source title": "title",
+	"название": "title", "журнал": "title",
+	"issn": "issn", "eissn": "issn", "e-issn": "issn", "print issn": "issn", "issns": "issn",
+	"publisher": "publisher", "издатель": "publisher", "издательство": "publisher",
+	"year": "year", "год": "year",
+	"quartile": "quartile", "sjr best quartile": "quartile", "best quartile": "quartile", "квартиль": "quartile",
+	"scopus": "scopus", "wos": "wos", "web of science": "wos", "rsci": "rsci", "ринц": "rsci",
+}
+
+// ParseQuartileCSV reads venue quartiles from a CSV file with a header row.
+// Columns are recognised by name, so that both the registry's own files and
+// SCImago Journal Rank exports work; fields are separated by commas or
+// semicolons. Files without a year column, such as SJR exports, take
+// defaultYear. Rows that cannot be read are returned as failed.
+func ParseQuartileCSV(r io.Reader, defaultYear int) ([]QuartileRow, []models.QuartileImportRow, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(4096)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	firstLine, _, _ := strings.Cut(strings.TrimPrefix(string(header), "\ufeff"), "\n")
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	names, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make([]string, len(names))
+	found := map[string]bool{}
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[i] = csvColumns[name]
+		found[columns[i]] = true
+	}
+	if !found["title"] && !found["issn"] {
+		return nil, nil, fmt.Errorf("the CSV file needs a title or an ISSN column")
+	}
+	if !found["quartile"] {
+		return nil, nil, fmt.Errorf("the CSV file needs a quartile column")
+	}
+	if !found["year"] && defaultYear == 0 {
+		return nil, nil, fmt.Errorf("the CSV file has no year column, so a year must be given")
+	}
+
+	var rows []QuartileRow
+	var failed []models.QuartileImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				failed = append(failed, models.QuartileImportRow{Line: parseErr.Line, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		// Spreadsheets export empty rows as bare separators.
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		row, err := parseQuartileRecord(record, columns, defaultYear)
+		row.Line = line
+		if err != nil {
+			failed = append(failed, models.QuartileImportRow{Line: line, Title: row.Title, ISSN: strings.Join(row.ISSNs, ", "), Error: err.Error()})
+			continue
+		}
+		if row.Title == "" && len(row.ISSNs) == 0 {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, failed, nil
+}
+
+func parseQuartileRecord(record, columns []string, defaultYear int) (QuartileRow, error) {
+	row := QuartileRow{Year: defaultYear}
+	for i, value := range record {
+		if i >= len(columns) {
+			break
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch columns[i] {
+		case "title":
+			row.Title = value
+		case "issn":
+			// SJR lists several ISSNs in one field, without hyphens.
+			for _, issn := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+				if normalized := NormalizeISSN(issn); normalized != "" {
+					row.ISSNs = append(row.ISSNs, normalized)
+				}
+			}
+		case "publisher":
+			row.Publisher = value
+		case "year":
+			if value != "" {
+				row.Year, err = strconv.Atoi(value)
+				if err != nil || row.Year < 1000 || row.Year > 9999 {
+					err = fmt.Errorf("invalid year %q", value)
+				}
+			}
+		case "quartile":
+			row.Quartile, err = parseQuartile(value)
+		case "scopus":
+			row.Scopus, err = parseFlag(value)
+		case "wos":
+			row.WoS, err = parseFlag(value)
+		case "rsci":
+			row.RSCI, err = parseFlag(value)
+		}
+		if err != nil {
+			return row, err
+		}
+	}
+	if row.Year == 0 {
+		return row, fmt.Errorf("missing year")
+	}
+	return row, nil
+}
+
+// parseQuartile reads "Q1" or "1"; an empty value or a dash means unranked.
+func parseQuartile(value string) (int, error) {
+	if value == "" || value == "-" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(value), "Q"))
+	if err != nil || n < 1 || n > 4 {
+		return 0, fmt.Errorf("invalid quartile %q", value)
+	}
+	return n, nil
+}
+
+func parseFlag(value string) (*bool, error) {
+	var flag bool
+	switch strings.ToLower(value) {
+	case "":
+		return nil, nil
+	case "1", "true", "yes", "y", "x", "+", "да":
+		flag = true
+	case "0", "false", "no", "n", "-", "нет":
+		flag = false
+	default:
+		return nil, fmt.Errorf("invalid flag %q", value)
+	}
+	return &flag, nil
+}
@@ -0,0 +1,92 @@
+package venues
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestParseQuartileCSV(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name        string
+		csv         string
+		defaultYear int
+		rows        []QuartileRow
+		failed      []models.QuartileImportRow
+	}{
+		{
+			name: "registry file",
+			csv: "title,issn,publisher,year,quartile,scopus,wos,rsci\n" +
+				"Neural Computation,0899-7667,MIT Press,2022,Q1,yes,1,\n" +
+				"Вестник МГУ,,МГУ,2022,-,да,нет,+\n",
+			rows: []QuartileRow{
+				{Line: 2, Title: "Neural Computation", ISSNs: []string{"0899-7667"}, Publisher: "MIT Press", Year: 2022, Quartile: 1, Scopus: &yes, WoS: &yes},
+				{Line: 3, Title: "Вестник МГУ", Publisher: "МГУ", Year: 2022, Scopus: &yes, WoS: &no, RSCI: &yes},
+			},
+		},
+		{
+			name: "SJR export",
+			csv: "\ufeffRank;Sourceid;Title;Issn;SJR Best Quartile;Publisher\n" +
+				"1;123;\"Neural Networks\";\"08936080, 18792782\";Q2;Elsevier\n",
+			defaultYear: 2023,
+			rows: []QuartileRow{
+				{Line: 2, Title: "Neural Networks", ISSNs: []string{"0893-6080", "1879-2782"}, Publisher: "Elsevier", Year: 2023, Quartile: 2},
+			},
+		},
+		{
+			name: "bad rows",
+			csv: "title,year,quartile\n" +
+				"A,2022,Q5\n" +
+				"B,20,Q1\n" +
+				",,\n" +
+				"C,,Q1\n" +
+				"D,2021,3\n",
+			rows: []QuartileRow{
+				{Line: 6, Title: "D", Year: 2021, Quartile: 3},
+			},
+			failed: []models.QuartileImportRow{
+				{Line: 2, Title: "A", Error: `invalid quartile "Q5"`},
+				{Line: 3, Title: "B", Error: `invalid year "20"`},
+				{Line: 5, Title: "C", Error: "missing year"},
+			},
+		},
+		{
+			name: "bad value before the title",
+			csv:  "year,title,quartile\n20,X,Q1\n",
+			failed: []models.QuartileImportRow{
+				{Line: 2, Error: `invalid year "20"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, failed, err := ParseQuartileCSV(strings.NewReader(tt.csv), tt.defaultYear)
+			if err != nil {
+				t.Fatalf("ParseQuartileCSV: %v", err)
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("rows = %+v, want %+v", rows, tt.rows)
+			}
+			if !reflect.DeepEqual(failed, tt.failed) {
+				t.Errorf("failed = %+v, want %+v", failed, tt.failed)
+			}
+		})
+	}
+}
+
+func TestParseQuartileCSVHeaders(t *testing.T) {
+	for _, csv := range []string{
+		"publisher,year,quartile\nX,2022,Q1\n",
+		"title,year\nX,2022\n",
+		"title,quartile\nX,Q1\n",
+	} {
+		if _, _, err := ParseQuartileCSV(strings.NewReader(csv), 0); err == nil {
+			t.Errorf("ParseQuartileCSV(%q) succeeded, want a header error", csv)
+		}
+	}
+}
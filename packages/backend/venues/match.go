@@ -0,0 +1,190 @@
+// Package venues matches publication venue names to the venue registry and
+// reads venue rankings from CSV files.
+package venues
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+// MinSimilarity is the similarity two normalized names need to be taken as
+// the same venue when they are not equal.
+const MinSimilarity = 0.9
+
+// abbreviations expands the abbreviations common in venue names, so that
+// "J. Appl. Phys." and "Journal of Applied Physics" come close.
+var abbreviations = map[string]string{
+	"j": "journal", "jour": "journal", "proc": "proceedings", "int": "international",
+	"intl": "international", "conf": "conference", "symp": "symposium", "trans": "transactions",
+	"lett": "letters", "rev": "review", "res": "research", "natl": "national",
+	"appl": "applied", "phys": "physics", "math": "mathematics", "chem": "chemistry",
+	"журн": "журнал", "тр": "труды", "конф": "конференция", "междунар": "международная",
+	"науч": "научный", "вестн": "вестник",
+}
+
+// stopWords are dropped from normalized names.
+var stopWords = map[string]bool{
+	"the": true, "of": true, "and": true, "on": true, "in": true, "for": true, "a": true, "an": true,
+	"и": true, "в": true, "по": true, "на": true,
+}
+
+// Normalize folds a venue name for comparison: it is lower-cased, "&" reads
+// as "and", punctuation is dropped, abbreviations are expanded, and stop
+// words and numbers are removed. Numbers go so that the yearly editions of a
+// conference, "21st Conference on X 2021", match their series.
+func Normalize(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(name), "&", " and ")
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	out := words[:0]
+	for _, word := range words {
+		if full, ok := abbreviations[word]; ok {
+			word = full
+		}
+		if stopWords[word] || isNumber(word) {
+			continue
+		}
+		out = append(out, word)
+	}
+	return strings.Join(out, " ")
+}
+
+// isNumber reports whether word is a number or an English ordinal such as
+// "21st".
+func isNumber(word string) bool {
+	digits := strings.TrimRightFunc(word, unicode.IsLetter)
+	if digits == "" || strings.TrimFunc(digits, unicode.IsDigit) != "" {
+		return false
+	}
+	switch word[len(digits):] {
+	case "", "st", "nd", "rd", "th":
+		return true
+	}
+	return false
+}
+
+// NormalizeISSN writes an ISSN as "1234-567X", returning "" when s does not
+// hold eight ISSN digits.
+func NormalizeISSN(s string) string {
+	var digits []byte
+	for _, c := range strings.ToUpper(s) {
+		switch {
+		case c >= '0' && c <= '9', c == 'X':
+			digits = append(digits, byte(c))
+		case c == '-' || c == ' ':
+		default:
+			return ""
+		}
+	}
+	if len(digits) != 8 || strings.IndexByte(string(digits[:7]), 'X') >= 0 {
+		return ""
+	}
+	return string(digits[:4]) + "-" + string(digits[4:])
+}
+
+// Similarity scores two normalized names from 0 to 1 by their edit distance.
+func Similarity(a, b string) float64 {
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein([]rune(a), []rune(b)))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Matcher finds the registry venue a name or ISSN refers to.
+type Matcher struct {
+	names map[string]int
+	issns map[string]int
+}
+
+// ambiguous marks a name or ISSN shared by several venues.
+const ambiguous = -1
+
+func NewMatcher(venues []models.Venue) *Matcher {
+	m := &Matcher{names: map[string]int{}, issns: map[string]int{}}
+	for _, venue := range venues {
+		m.Add(venue)
+	}
+	return m
+}
+
+// Add makes venue known to the matcher.
+func (m *Matcher) Add(venue models.Venue) {
+	add := func(index map[string]int, key string) {
+		if key == "" {
+			return
+		}
+		if id, ok := index[key]; ok && id != venue.ID {
+			index[key] = ambiguous
+			return
+		}
+		index[key] = venue.ID
+	}
+
+	add(m.names, Normalize(venue.Name))
+	for _, alias := range venue.Aliases {
+		add(m.names, Normalize(alias))
+	}
+	add(m.issns, NormalizeISSN(venue.ISSN))
+	add(m.issns, NormalizeISSN(venue.EISSN))
+}
+
+// Match returns the ID of the venue called name. Failing an exact match of
+// the normalized names it takes the most similar venue name, provided that
+// it reaches MinSimilarity and no other venue scores as high.
+func (m *Matcher) Match(name string) (int, bool) {
+	name = Normalize(name)
+	if name == "" {
+		return 0, false
+	}
+	if id, ok := m.names[name]; ok {
+		return id, id != ambiguous
+	}
+
+	best, bestID := 0.0, ambiguous
+	for candidate, id := range m.names {
+		score := Similarity(name, candidate)
+		switch {
+		case score > best:
+			best, bestID = score, id
+		case score == best && id != bestID:
+			bestID = ambiguous
+		}
+	}
+	if best < MinSimilarity || bestID == ambiguous {
+		return 0, false
+	}
+	return bestID, true
+}
+
+// MatchISSN returns the ID of the venue with the given print or electronic
+// ISSN.
+func (m *Matcher) MatchISSN(issn string) (int, bool) {
+	id, ok := m.issns[NormalizeISSN(issn)]
+	return id, ok && id != ambiguous
+}
@@ -0,0 +1,105 @@
+package venues
+
+import (
+	"testing"
+
+	"github.com/damirahm/diplom/backend/models"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"Journal of Applied Physics", "journal applied physics"},
+		{"J. Appl. Phys.", "journal applied physics"},
+		{"Physics & Chemistry", "physics chemistry"},
+		{"21st Int. Conf. on Neural Networks 2021", "international conference neural networks"},
+		{"Proceedings of the 3rd Symp.", "proceedings symposium"},
+		{"Covid19 Research", "covid19 research"},
+		{"Вестн. Моск. ун-та", "вестник моск ун та"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeISSN(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"0021-8979", "0021-8979"},
+		{"00218979", "0021-8979"},
+		{"1234 567x", "1234-567X"},
+		{"1234-56X7", ""},
+		{"1234-567", ""},
+		{"ISSN 1234-5678", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeISSN(tt.in); got != tt.want {
+			t.Errorf("NormalizeISSN(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"neural", "neural", 1},
+		{"neural", "", 0},
+		{"kitten", "sitting", 1 - 3.0/7},
+		{"журнал", "журнал физики", 6.0 / 13},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher([]models.Venue{
+		{ID: 1, Name: "Journal of Applied Physics", Aliases: []string{"JAP"}, ISSN: "0021-8979", EISSN: "1089-7550"},
+		{ID: 2, Name: "Neural Computation", ISSN: "0899-7667"},
+		{ID: 3, Name: "Proceedings of IJCNN"},
+		{ID: 4, Name: "Proc. IJCNN"},
+		{ID: 5, Name: "Neural Networks", ISSN: "0899-7667"},
+	})
+
+	names := []struct {
+		name string
+		id   int
+		ok   bool
+	}{
+		{"J. Appl. Phys.", 1, true},
+		{"jap", 1, true},
+		{"Neural Computaton", 2, true},
+		{"Proceedings IJCNN", 0, false},
+		{"Proceedings IJCNNN", 0, false},
+		{"Nature", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range names {
+		if id, ok := m.Match(tt.name); ok != tt.ok || (ok && id != tt.id) {
+			t.Errorf("Match(%q) = %d, %v, want %d, %v", tt.name, id, ok, tt.id, tt.ok)
+		}
+	}
+
+	issns := []struct {
+		issn string
+		id   int
+		ok   bool
+	}{
+		{"10897550", 1, true},
+		{"0021-8979", 1, true},
+		{"0899-7667", 0, false},
+		{"1111-1111", 0, false},
+	}
+	for _, tt := range issns {
+		if id, ok := m.MatchISSN(tt.issn); ok != tt.ok || (ok && id != tt.id) {
+			t.Errorf("MatchISSN(%q) = %d, %v, want %d, %v", tt.issn, id, ok, tt.id, tt.ok)
+		}
+	}
+}
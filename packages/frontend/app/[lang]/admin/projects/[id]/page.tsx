@@ -17,7 +17,7 @@ import { useToast } from "@/hooks/use-toast";
 import { Label } from "@/components/ui/label";
 import { Input } from "@/components/ui/input";
 import { ArrowLeft, Plus, Trash2 } from "lucide-react";
-import { api } from "../../../../../lib/api";
+import { api, PUBLICATIONS_MAX_PAGE_SIZE } from "../../../../../lib/api";
 import { useForm } from "react-hook-form";
 import { zodResolver } from "@hookform/resolvers/zod";
 import { Form } from "@/components/ui/form";
@@ -112,8 +112,10 @@ export default function ProjectFormPage({
 
   const fetchPublications = async () => {
     try {
-      const data = await api.publications.getAll();
-      setPublications(data);
+      const data = await api.publications.getAll({
+        pageSize: PUBLICATIONS_MAX_PAGE_SIZE,
+      });
+      setPublications(data.items);
     } catch (error) {
       console.error("Failed to fetch publications:", error);
     }
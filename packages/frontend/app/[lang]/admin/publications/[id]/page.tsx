@@ -3,7 +3,7 @@
 import { useState, useEffect } from "react";
 import { useRouter } from "next/navigation";
 import { getDictionary } from "@/app/dictionaries";
-import type { Locale, Researcher } from "@/app/types";
+import type { Author, Locale, Researcher } from "@/app/types";
 import { Button } from "@/components/ui/button";
 import { useToast } from "@/hooks/use-toast";
 import { ArrowLeft, Check, Plus, Trash2 } from "lucide-react";
@@ -14,6 +14,8 @@ import { useForm } from "react-hook-form";
 import { Form } from "@/components/ui/form";
 import {
   TextField,
+  TextareaField,
+  SelectField,
   LocalizedTextField,
   ExternalAuthorsField,
 } from "@/components/ui/form-fields";
@@ -30,15 +32,66 @@ import { ImageWithFallback } from "@/app/components/ImageWithFallback";
 import { ResearcherSelector } from "@/components/ui/researcher-selector";
 import { createPublication, updatePublication } from "../../actions";
 
+const publicationTypes = [
+  "article",
+  "conference",
+  "book_chapter",
+  "thesis",
+  "patent",
+] as const;
+
 const emptyPublication: PublicationFormData = {
   title: { en: "", ru: "" },
   authors: [],
   externalAuthors: [],
   publishedAt: new Date().toISOString().split("T")[0], // Format: YYYY-MM-DD
-  journal: "",
+  type: "article",
+  venue: "",
+  volume: "",
+  issue: "",
+  pages: "",
+  publisher: "",
+  doi: "",
+  abstract: "",
   link: "",
 };
 
+// bylineAuthors builds the author list to save: the loaded authors still
+// selected keep their byline order and roles, and new authors follow them.
+function bylineAuthors(
+  loaded: Author[],
+  formData: PublicationFormData,
+  researchers: Researcher[]
+): Author[] {
+  const researcherIds = new Set(formData.authors);
+  const externalNames = [...formData.externalAuthors];
+  const authors: Author[] = [];
+
+  for (const author of loaded) {
+    if (author.id !== undefined) {
+      if (researcherIds.delete(author.id)) {
+        authors.push(author);
+      }
+      continue;
+    }
+    const index = externalNames.findIndex(
+      (name) => name.en === author.name.en && name.ru === author.name.ru
+    );
+    if (index >= 0) {
+      authors.push({ ...author, name: externalNames.splice(index, 1)[0] });
+    }
+  }
+
+  researcherIds.forEach((id) =>
+    authors.push({
+      id,
+      name: researchers.find((r) => r.id === id)?.name ?? { en: "", ru: "" },
+    })
+  );
+  externalNames.forEach((name) => authors.push({ name }));
+  return authors;
+}
+
 export default function PublicationFormPage({
   params: { lang, id },
 }: {
@@ -54,6 +107,7 @@ export default function PublicationFormPage({
     new Set()
   );
   const [showResearcherSelector, setShowResearcherSelector] = useState(false);
+  const [loadedAuthors, setLoadedAuthors] = useState<Author[]>([]);
 
   const form = useForm<PublicationFormData>({
     resolver: zodResolver(publicationFormSchema),
@@ -73,6 +127,7 @@ export default function PublicationFormPage({
   const fetchPublication = async () => {
     try {
       const data = await api.publications.getById(Number(id));
+      setLoadedAuthors(data.authors);
 
       const internalAuthors = data.authors
         .filter((author) => author.id !== undefined)
@@ -87,7 +142,14 @@ export default function PublicationFormPage({
         authors: internalAuthors,
         externalAuthors: externalAuthors,
         publishedAt: data.publishedAt,
-        journal: data.journal,
+        type: data.type,
+        venue: data.venue,
+        volume: data.volume,
+        issue: data.issue,
+        pages: data.pages,
+        publisher: data.publisher,
+        doi: data.doi,
+        abstract: data.abstract,
         link: data.link,
       });
 
@@ -119,13 +181,7 @@ export default function PublicationFormPage({
     try {
       const apiData = {
         ...formData,
-        authors: [
-          ...formData.authors.map((id) => ({
-            id,
-            name: researchers.find((r) => r.id === id)?.name,
-          })),
-          ...formData.externalAuthors.map((name) => ({ name })),
-        ],
+        authors: bylineAuthors(loadedAuthors, formData, researchers),
         citationsCount: 0,
       };
 
@@ -241,12 +297,43 @@ export default function PublicationFormPage({
               required
             />
 
-            <TextField
-              name="journal"
-              label={dictionary.publications.journal}
+            <SelectField
+              name="type"
+              label={dictionary.publications.type}
+              options={publicationTypes.map((type) => ({
+                value: type,
+                label: dictionary.publications.types[type],
+              }))}
               required
             />
 
+            <TextField
+              name="venue"
+              label={dictionary.publications.venue}
+            />
+
+            <div className="grid grid-cols-3 gap-4">
+              <TextField
+                name="volume"
+                label={dictionary.publications.volume}
+              />
+              <TextField name="issue" label={dictionary.publications.issue} />
+              <TextField name="pages" label={dictionary.publications.pages} />
+            </div>
+
+            <TextField
+              name="publisher"
+              label={dictionary.publications.publisher}
+            />
+
+            <TextField name="doi" label="DOI" />
+
+            <TextareaField
+              name="abstract"
+              label={dictionary.publications.abstract}
+              rows={6}
+            />
+
             <TextField name="link" label="URL" type="url" />
           </div>
 
@@ -9,7 +9,7 @@ import { useToast } from "@/hooks/use-toast";
 import Link from "next/link";
 import { Column, DataTable } from "@/components/ui/data-table";
 import { ConfirmDialog } from "@/components/ui/confirm-dialog";
-import { api } from "@/lib/api";
+import { api, PUBLICATIONS_MAX_PAGE_SIZE } from "@/lib/api";
 import { deletePublication } from "../actions";
 
 export default function PublicationsAdminPage({
@@ -30,8 +30,10 @@ export default function PublicationsAdminPage({
 
   const fetchPublications = async () => {
     try {
-      const data = await api.publications.getAll();
-      setPublications(data);
+      const data = await api.publications.getAll({
+        pageSize: PUBLICATIONS_MAX_PAGE_SIZE,
+      });
+      setPublications(data.items);
     } catch (error) {
       toast({
         variant: "destructive",
@@ -15,7 +15,14 @@ export const publicationSchema = z.object({
     title: localizedStringSchema,
     authors: z.array(authorSchema),
     publishedAt: z.string(),
-    journal: z.string(),
+    type: z.enum(["article", "conference", "book_chapter", "thesis", "patent"]),
+    venue: z.string(),
+    volume: z.string(),
+    issue: z.string(),
+    pages: z.string(),
+    publisher: z.string(),
+    doi: z.string(),
+    abstract: z.string(),
     link: z.string().url({ message: "Must be a valid URL" }),
 });
 
@@ -24,7 +31,14 @@ export const publicationFormSchema = z.object({
     authors: z.array(z.number()),
     externalAuthors: z.array(localizedStringSchema),
     publishedAt: z.string(),
-    journal: z.string(),
+    type: z.enum(["article", "conference", "book_chapter", "thesis", "patent"]),
+    venue: z.string(),
+    volume: z.string(),
+    issue: z.string(),
+    pages: z.string(),
+    publisher: z.string(),
+    doi: z.string(),
+    abstract: z.string(),
     link: z.string().url({ message: "Must be a valid URL" }),
 });
 
@@ -36,6 +50,13 @@ export const emptyPublication: PublicationFormData = {
     authors: [],
     externalAuthors: [],
     publishedAt: new Date().toISOString().split("T")[0],
-    journal: "",
+    type: "article",
+    venue: "",
+    volume: "",
+    issue: "",
+    pages: "",
+    publisher: "",
+    doi: "",
+    abstract: "",
     link: "",
 }; 
\ No newline at end of file
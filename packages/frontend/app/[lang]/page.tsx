@@ -16,7 +16,7 @@ export default async function HomePage(args: HomePageProps) {
   const dictionary = getDictionary(lang);
 
   const researchers = await api.researchers.getAll();
-  const publicationsData = await api.publications.getPublic();
+  const publicationsData = await api.publications.getPublic({ pageSize: 1 });
 
   const gridItems = [
     {
@@ -209,7 +209,7 @@ export default async function HomePage(args: HomePageProps) {
             </div>
             <div className="text-center">
               <p className="text-4xl font-heading font-bold text-primary dark:text-indigo-400">
-                {publicationsData.length}
+                {publicationsData.total}
               </p>
               <p className="text-foreground/70 text-sm mt-1">
                 {dictionary.home.publications}
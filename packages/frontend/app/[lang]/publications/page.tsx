@@ -1,7 +1,7 @@
 import { Locale } from "../../types";
 import { getDictionary } from "../../dictionaries";
 import { PublicationsTable } from "./PublicationsTable";
-import { api } from "@/lib/api";
+import { api, PUBLICATIONS_MAX_PAGE_SIZE } from "@/lib/api";
 
 const PublicationsPage = async ({
   params,
@@ -10,7 +10,9 @@ const PublicationsPage = async ({
 }) => {
   const { lang } = await params || {};
   const dictionary = getDictionary(lang);
-  const publications = await api.publications.getPublic();
+  const { items: publications } = await api.publications.getPublic({
+    pageSize: PUBLICATIONS_MAX_PAGE_SIZE,
+  });
 
   return (
     <div className="container mx-auto px-4 py-16">
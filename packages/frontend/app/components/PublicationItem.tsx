@@ -50,10 +50,19 @@ const PublicationItem: React.FC<PublicationItemProps> = ({ publication, lang })
         )}
 
         <div className="flex flex-wrap gap-x-4 gap-y-2 text-sm">
-          {publication.journal && (
+          {publication.venue && (
             <div className="flex items-center gap-1.5 text-foreground/70">
               <BookOpen size={16} className="text-primary dark:text-indigo-400 flex-shrink-0" />
-              <span className="line-clamp-1">{publication.journal}</span>
+              <span className="line-clamp-1">
+                {[
+                  publication.venue,
+                  publication.volume && `${dictionary.publications.volume} ${publication.volume}`,
+                  publication.issue && `${dictionary.publications.issue} ${publication.issue}`,
+                  publication.pages && `${dictionary.publications.pages} ${publication.pages}`,
+                ]
+                  .filter(Boolean)
+                  .join(", ")}
+              </span>
             </div>
           )}
 
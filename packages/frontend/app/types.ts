@@ -18,22 +18,282 @@ export interface PartnersData {
   enterprises: Partner[];
 }
 
+export type AuthorRole = "corresponding" | "supervisor";
+
 export interface Author {
   name: LocalizedString;
   id?: number;
+  personId?: number;
+  role?: AuthorRole;
 }
 
+export type PublicationType =
+  | "article"
+  | "conference"
+  | "book_chapter"
+  | "thesis"
+  | "patent";
+
 export interface Publication {
   id: number;
   title: LocalizedString;
   link: string;
-  journal: string;
+  type: PublicationType;
+  venue: string;
+  venueId?: number;
+  volume: string;
+  issue: string;
+  pages: string;
+  publisher: string;
+  doi: string;
+  abstract: string;
   publishedAt: string;
   citationsCount: number;
   authors: Author[];
   visible: boolean;
 }
 
+export type AttachmentType =
+  | "preprint"
+  | "slides"
+  | "code"
+  | "dataset"
+  | "supplementary"
+  | "other";
+
+// An uploaded file has a fileName and is downloaded through the publication;
+// an external resource has a url instead.
+export interface PublicationAttachment {
+  id: number;
+  publicationId: number;
+  type: AttachmentType;
+  title: LocalizedString;
+  url?: string;
+  fileName?: string;
+  contentType?: string;
+  size?: number;
+  order: number;
+  createdAt: string;
+}
+
+export interface PublicationQuery {
+  page?: number;
+  pageSize?: number;
+  sort?: "date" | "citations" | "title";
+  year?: number;
+  researcherId?: number;
+  venue?: string;
+  venueId?: number;
+  type?: PublicationType;
+  q?: string;
+}
+
+export interface DuplicateCandidate {
+  first: Publication;
+  second: Publication;
+  score: number;
+  titleScore: number;
+  yearScore: number;
+  authorScore: number;
+}
+
+export type MetricsInterval = "day" | "month";
+
+export interface MetricsHistoryQuery {
+  interval?: MetricsInterval;
+  from?: string;
+  to?: string;
+}
+
+export interface CitationSnapshot {
+  period: string;
+  recordedAt: string;
+  citationsCount: number;
+}
+
+export interface MetricsSnapshot {
+  researcherId: number;
+  period: string;
+  recordedAt: string;
+  totalCitations: number;
+  hIndex: number;
+  recentCitations: number;
+  recentHIndex: number;
+  localCitations: number;
+  localHIndex: number;
+  localI10Index: number;
+}
+
+export interface PublicationPage {
+  items: Publication[];
+  total: number;
+  page: number;
+  pageSize: number;
+}
+
+export type BibliographyFormat = "bibtex" | "ris" | "csl-json";
+
+export interface BibliographyQuery {
+  style?: "gost" | "apa" | "ieee";
+  lang?: Locale;
+  format?: "text" | "html" | "docx";
+  from?: number;
+  to?: number;
+}
+
+export interface ImportResult {
+  key: string;
+  title?: string;
+  publicationId?: number;
+  error?: string;
+}
+
+export interface ImportReport {
+  created: ImportResult[];
+  skipped: ImportResult[];
+  failed: ImportResult[];
+}
+
+export interface VenueQuartile {
+  year: number;
+  quartile: 1 | 2 | 3 | 4;
+}
+
+export interface Venue {
+  id: number;
+  name: string;
+  aliases: string[];
+  issn: string;
+  eissn: string;
+  publisher: string;
+  scopus: boolean;
+  wos: boolean;
+  rsci: boolean;
+  quartiles: VenueQuartile[];
+}
+
+export interface QuartileImportRow {
+  line: number;
+  title?: string;
+  issn?: string;
+  error?: string;
+}
+
+export interface QuartileImportReport {
+  updated: number;
+  created: number;
+  unmatched: QuartileImportRow[];
+  failed: QuartileImportRow[];
+}
+
+// Q1 to Q4 and noQuartile add up to total.
+export interface VenueStats {
+  total: number;
+  q1: number;
+  q2: number;
+  q3: number;
+  q4: number;
+  noQuartile: number;
+  scopus: number;
+  wos: number;
+  rsci: number;
+  noVenue: number;
+}
+
+export interface VenueYearStats extends VenueStats {
+  year: number;
+}
+
+export interface VenueResearcherStats extends VenueStats {
+  researcherId: number;
+  name: LocalizedString;
+  lastName: LocalizedString;
+}
+
+export interface ExternalPerson {
+  id: number;
+  name: LocalizedString;
+  aliases: string[];
+  orcid: string;
+  affiliation: LocalizedString;
+  researcherId?: number;
+  publications: number;
+  createdAt: string;
+}
+
+export type CrawlStatus =
+  | "running"
+  | "completed"
+  | "failed"
+  | "cancelled"
+  | "skipped";
+
+export interface CrawlResult {
+  researcherId: number;
+  status: CrawlStatus;
+  newPublications: number;
+  updatedPublications: number;
+  errors: string[];
+  startedAt: string;
+  finishedAt?: string;
+}
+
+export interface CrawlJob {
+  id: number;
+  researcherId?: number;
+  status: CrawlStatus;
+  startedBy: string;
+  researchers: number;
+  crawled: number;
+  failed: number;
+  newPublications: number;
+  updatedPublications: number;
+  error?: string;
+  startedAt: string;
+  finishedAt?: string;
+  results?: CrawlResult[];
+}
+
+export interface CoauthorGraphQuery {
+  fromYear?: number;
+  toYear?: number;
+  disciplineId?: number;
+  external?: boolean;
+}
+
+// Node IDs are "researcher:<id>" or "external:<name key>".
+export interface CoauthorNode {
+  id: string;
+  researcherId?: number;
+  name: LocalizedString;
+  external: boolean;
+  publications: number;
+}
+
+export interface CoauthorEdge {
+  source: string;
+  target: string;
+  publications: number;
+  years: number[];
+}
+
+export interface CoauthorGraph {
+  nodes: CoauthorNode[];
+  edges: CoauthorEdge[];
+}
+
+export type SearchType = "publication" | "researcher" | "project" | "training";
+
+// Titles and snippets are HTML-escaped with matches wrapped in <mark> tags.
+export interface SearchHit {
+  id: number;
+  title: string;
+  snippet: string;
+  score: number;
+}
+
+export type SearchResults = Partial<Record<SearchType, SearchHit[]>>;
+
 export interface ResearcherProfiles {
   researchgate?: string;
   googleScholar?: string;
@@ -9,13 +9,34 @@ import {
   Partner,
   Project,
   Publication,
+  BibliographyFormat,
+  BibliographyQuery,
+  CitationSnapshot,
+  CoauthorGraph,
+  CoauthorGraphQuery,
+  CrawlJob,
+  CrawlStatus,
+  DuplicateCandidate,
+  ExternalPerson,
+  MetricsHistoryQuery,
+  MetricsSnapshot,
+  ImportReport,
+  QuartileImportReport,
+  PublicationAttachment,
+  PublicationPage,
+  PublicationQuery,
+  SearchResults,
+  SearchType,
   TrainingMaterial,
   Discipline,
   ResearcherWithCount,
   CreateDiscipline,
+  Locale,
+  Venue,
+  VenueYearStats,
+  VenueResearcherStats,
 } from "../app/types";
 import { API_URL } from "../constants/ApiUrl";
-import { hashPassword } from "./password";
 
 interface ApiError extends Error {
   status?: number;
@@ -29,7 +50,21 @@ interface RequestOptions extends RequestInit {
 }
 
 interface LoginResponse {
-  token: string;
+  message: string;
+  csrfToken: string;
+}
+
+const CSRF_COOKIE_NAME = "csrf_token";
+const CSRF_HEADER_NAME = "X-CSRF-Token";
+
+function getCsrfToken(): string | undefined {
+  if (typeof document === "undefined") {
+    return undefined;
+  }
+  const match = document.cookie
+    .split("; ")
+    .find((cookie) => cookie.startsWith(`${CSRF_COOKIE_NAME}=`));
+  return match ? decodeURIComponent(match.split("=")[1]) : undefined;
 }
 
 async function request<T>(
@@ -37,14 +72,15 @@ async function request<T>(
   options: RequestOptions = {}
 ): Promise<T> {
   const { data, method = "GET", headers = {}, ...customConfig } = options;
+  const csrfToken = method !== "GET" ? getCsrfToken() : undefined;
 
   const config: RequestInit = {
     method,
     credentials: "include",
     headers: {
       ...(!options.body && { "Content-Type": "application/json" }),
+      ...(csrfToken && { [CSRF_HEADER_NAME]: csrfToken }),
       ...headers,
-      Cookie: "admin_session=true",
     },
     ...customConfig,
   };
@@ -173,6 +209,22 @@ export async function processSuperpixelImage(
   return response.json();
 }
 
+function queryString(
+  query: Record<string, string | number | undefined>
+): string {
+  const params = new URLSearchParams();
+  Object.entries(query).forEach(([key, value]) => {
+    if (value !== undefined && value !== "") {
+      params.set(key, String(value));
+    }
+  });
+  const search = params.toString();
+  return search ? `?${search}` : "";
+}
+
+// Page size used by views that still list every publication at once.
+export const PUBLICATIONS_MAX_PAGE_SIZE = 1000;
+
 export const api = {
   get: <T>(
     endpoint: string,
@@ -193,13 +245,16 @@ export const api = {
     options?: Omit<RequestOptions, "method" | "data">
   ) => request<T>(endpoint, { ...options, method: "DELETE" }),
   auth: {
-    login: async (username: string, password: string) => {
-      const hashedPassword = await hashPassword(password);
-      return request<LoginResponse>("/auth/login", {
+    login: (username: string, password: string) =>
+      request<LoginResponse>("/auth/login", {
         method: "POST",
-        data: { username, password: hashedPassword },
-      });
-    },
+        data: { username, password },
+      }),
+    changePassword: (currentPassword: string, newPassword: string) =>
+      request<{ message: string }>("/auth/password", {
+        method: "POST",
+        data: { currentPassword, newPassword },
+      }),
     logout: () => request<void>("/auth/logout", { method: "POST" }),
   },
   researchers: {
@@ -215,6 +270,12 @@ export const api = {
       request<Researcher>(`/researchers/${id}`, { method: "PUT", data }),
     delete: (id: string) =>
       request<void>(`/researchers/${id}`, { method: "DELETE" }),
+    bibliographyUrl: (id: number, query: BibliographyQuery = {}) =>
+      `${API_URL}/researchers/${id}/bibliography${queryString({ ...query })}`,
+    getMetricsHistory: (id: number, query: MetricsHistoryQuery = {}) =>
+      request<MetricsSnapshot[]>(
+        `/researchers/${id}/metrics/history${queryString({ ...query })}`
+      ),
   },
   projects: {
     getAll: () => request<Project[]>("/projects"),
@@ -227,8 +288,12 @@ export const api = {
       request<void>(`/projects/${id}`, { method: "DELETE" }),
   },
   publications: {
-    getAll: () => api.get<Publication[]>("/publications"),
-    getPublic: () => api.get<Publication[]>("/publications/public"),
+    getAll: (query: PublicationQuery = {}) =>
+      api.get<PublicationPage>(`/publications${queryString({ ...query })}`),
+    getPublic: (query: PublicationQuery = {}) =>
+      api.get<PublicationPage>(
+        `/publications/public${queryString({ ...query })}`
+      ),
     getById: (id: number) => api.get<Publication>(`/publications/${id}`),
     create: (data: Publication) => api.post<Publication>("/publications", data),
     update: (id: number, data: Publication) =>
@@ -242,6 +307,102 @@ export const api = {
       const res = await api.get<{ count: number }>("/publications/count");
       return res.count;
     },
+    exportUrl: (
+      format: BibliographyFormat,
+      query: Pick<PublicationQuery, "researcherId" | "year"> & {
+        visible?: boolean;
+      } = {}
+    ) =>
+      `${API_URL}/publications/export${queryString({
+        ...query,
+        format,
+        visible: query.visible === undefined ? undefined : String(query.visible),
+      })}`,
+    publicationExportUrl: (id: number, format: BibliographyFormat) =>
+      `${API_URL}/publications/${id}/export${queryString({ format })}`,
+    researcherExportUrl: (id: number, format: BibliographyFormat) =>
+      `${API_URL}/researchers/${id}/publications/export${queryString({ format })}`,
+    projectExportUrl: (id: number, format: BibliographyFormat) =>
+      `${API_URL}/projects/${id}/publications/export${queryString({ format })}`,
+    import: (file: File, format?: BibliographyFormat) => {
+      const formData = new FormData();
+      formData.append("file", file);
+      if (format) {
+        formData.append("format", format);
+      }
+      return request<ImportReport>("/publications/import", {
+        method: "POST",
+        body: formData,
+        headers: {},
+      });
+    },
+    getDuplicates: (query: { minScore?: number; limit?: number } = {}) =>
+      api.get<DuplicateCandidate[]>(
+        `/publications/duplicates${queryString({ ...query })}`
+      ),
+    merge: (id: number, duplicateId: number) =>
+      api.post<Publication>(`/publications/${id}/merge`, { duplicateId }),
+    getCitationHistory: (id: number, query: MetricsHistoryQuery = {}) =>
+      api.get<CitationSnapshot[]>(
+        `/publications/${id}/citations/history${queryString({ ...query })}`
+      ),
+  },
+  attachments: {
+    getAll: (publicationId: number) =>
+      api.get<PublicationAttachment[]>(
+        `/publications/${publicationId}/attachments`
+      ),
+    getPublic: (publicationId: number) =>
+      api.get<PublicationAttachment[]>(
+        `/publications/${publicationId}/attachments/public`
+      ),
+    upload: (
+      publicationId: number,
+      file: File,
+      data: Partial<Pick<PublicationAttachment, "type" | "title" | "order">> = {}
+    ) => {
+      const formData = new FormData();
+      formData.append("file", file);
+      if (data.type) {
+        formData.append("type", data.type);
+      }
+      if (data.title) {
+        formData.append("titleEn", data.title.en);
+        formData.append("titleRu", data.title.ru);
+      }
+      if (data.order !== undefined) {
+        formData.append("order", String(data.order));
+      }
+      return request<PublicationAttachment>(
+        `/publications/${publicationId}/attachments`,
+        {
+          method: "POST",
+          body: formData,
+          headers: {},
+        }
+      );
+    },
+    createLink: (
+      publicationId: number,
+      data: Pick<PublicationAttachment, "type" | "title" | "url" | "order">
+    ) =>
+      api.post<PublicationAttachment>(
+        `/publications/${publicationId}/attachments`,
+        data
+      ),
+    update: (
+      publicationId: number,
+      id: number,
+      data: Pick<PublicationAttachment, "type" | "title" | "url" | "order">
+    ) =>
+      api.put<PublicationAttachment>(
+        `/publications/${publicationId}/attachments/${id}`,
+        data
+      ),
+    delete: (publicationId: number, id: number) =>
+      api.delete(`/publications/${publicationId}/attachments/${id}`),
+    downloadUrl: (publicationId: number, id: number) =>
+      `${API_URL}/publications/${publicationId}/attachments/${id}/download`,
   },
   partners: {
     getAll: () =>
@@ -277,8 +438,95 @@ export const api = {
     delete: (id: string) =>
       request<void>(`/disciplines/${id}`, { method: "DELETE" }),
   },
+  venues: {
+    getAll: () => request<Venue[]>("/venues"),
+    getOne: (id: number) => request<Venue>(`/venues/${id}`),
+    create: (data: Omit<Venue, "id">) =>
+      request<Venue>("/venues", { method: "POST", data }),
+    update: (id: number, data: Omit<Venue, "id">) =>
+      request<Venue>(`/venues/${id}`, { method: "PUT", data }),
+    delete: (id: number) => request<void>(`/venues/${id}`, { method: "DELETE" }),
+    linkPublications: () =>
+      request<{ linked: number }>("/venues/link", { method: "POST" }),
+    importQuartiles: (file: File, options: { year?: number; create?: boolean } = {}) => {
+      const formData = new FormData();
+      formData.append("file", file);
+      if (options.year) {
+        formData.append("year", String(options.year));
+      }
+      if (options.create) {
+        formData.append("create", "true");
+      }
+      return request<QuartileImportReport>("/venues/quartiles/import", {
+        method: "POST",
+        body: formData,
+        headers: {},
+      });
+    },
+  },
+  reports: {
+    venueYears: (query: { fromYear?: number; toYear?: number } = {}) =>
+      request<VenueYearStats[]>(`/reports/venues/years${queryString({ ...query })}`),
+    venueResearchers: (query: { fromYear?: number; toYear?: number } = {}) =>
+      request<VenueResearcherStats[]>(
+        `/reports/venues/researchers${queryString({ ...query })}`
+      ),
+  },
+  coauthorship: {
+    get: (query: CoauthorGraphQuery = {}) =>
+      api.get<CoauthorGraph>(
+        `/coauthorship${queryString({
+          ...query,
+          external:
+            query.external === undefined ? undefined : String(query.external),
+        })}`
+      ),
+    graphmlUrl: (query: CoauthorGraphQuery = {}) =>
+      `${API_URL}/coauthorship${queryString({
+        ...query,
+        external:
+          query.external === undefined ? undefined : String(query.external),
+        format: "graphml",
+      })}`,
+  },
+  externalPeople: {
+    getAll: () => api.get<ExternalPerson[]>("/external-people"),
+    getOne: (id: number) => api.get<ExternalPerson>(`/external-people/${id}`),
+    update: (
+      id: number,
+      data: Pick<ExternalPerson, "name" | "aliases" | "orcid" | "affiliation">
+    ) => api.put<ExternalPerson>(`/external-people/${id}`, data),
+    merge: (id: number, duplicateIds: number[]) =>
+      api.post<ExternalPerson>(`/external-people/${id}/merge`, {
+        duplicateIds,
+      }),
+    promote: (id: number, researcherId: number) =>
+      api.post<ExternalPerson>(`/external-people/${id}/promote`, {
+        researcherId,
+      }),
+  },
+  crawlJobs: {
+    getAll: (query: { status?: CrawlStatus; limit?: number } = {}) =>
+      api.get<CrawlJob[]>(`/crawl-jobs${queryString({ ...query })}`),
+    getOne: (id: number) => api.get<CrawlJob>(`/crawl-jobs/${id}`),
+    start: (data: { researcherId?: number; withCitations?: boolean } = {}) =>
+      api.post<CrawlJob>("/crawl-jobs", data),
+    cancel: (id: number) => api.post<CrawlJob>(`/crawl-jobs/${id}/cancel`, {}),
+  },
   neuron: {
     simulate: (data: NeuronSimulationRequest) =>
       api.post<SimulationResponse>("/neuron/simulate", data),
   },
+  search: (
+    q: string,
+    options: { types?: SearchType[]; lang?: Locale; limit?: number } = {}
+  ) =>
+    api.get<SearchResults>(
+      `/search${queryString({
+        q,
+        types: options.types?.join(","),
+        lang: options.lang,
+        limit: options.limit,
+      })}`
+    ),
 };